@@ -0,0 +1,82 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"github.com/niaga-platform/service-marketplace/internal/models"
+	"github.com/niaga-platform/service-marketplace/internal/services"
+)
+
+// runBackfill runs an order backfill in the foreground:
+//
+//	server backfill -connection <id> [-days 90 | -from <RFC3339> -to <RFC3339>] [-skip-push]
+//	server backfill -resume <job-id>
+//
+// Interrupting the command leaves the job failed at its last checkpoint, so it
+// can be resumed with -resume or through the API.
+func runBackfill(service *services.OrderBackfillService, args []string, logger *zap.Logger) error {
+	fs := flag.NewFlagSet("backfill", flag.ContinueOnError)
+	connection := fs.String("connection", "", "Connection ID to backfill")
+	days := fs.Int("days", 90, "Days of orders to backfill, ignored when -from is set")
+	from := fs.String("from", "", "Start of the range (RFC3339)")
+	to := fs.String("to", "", "End of the range (RFC3339, default now)")
+	skipPush := fs.Bool("skip-push", false, "Do not create the historical orders in service-order")
+	resume := fs.String("resume", "", "Resume the backfill job with this ID")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	var jobID uuid.UUID
+	if *resume != "" {
+		id, err := uuid.Parse(*resume)
+		if err != nil {
+			return fmt.Errorf("invalid job ID: %w", err)
+		}
+		jobID = id
+	} else {
+		connectionID, err := uuid.Parse(*connection)
+		if err != nil {
+			return fmt.Errorf("invalid connection ID: %w", err)
+		}
+
+		req := &models.OrderBackfillRequest{Days: *days, SkipPush: *skipPush}
+		if *from != "" {
+			t, err := time.Parse(time.RFC3339, *from)
+			if err != nil {
+				return fmt.Errorf("invalid -from: %w", err)
+			}
+			req.TimeFrom = &t
+		}
+		if *to != "" {
+			t, err := time.Parse(time.RFC3339, *to)
+			if err != nil {
+				return fmt.Errorf("invalid -to: %w", err)
+			}
+			req.TimeTo = &t
+		}
+
+		job, err := service.CreateBackfill(ctx, connectionID, req)
+		if err != nil {
+			return err
+		}
+		jobID = job.ID
+	}
+
+	logger.Info("Order backfill running", zap.String("job_id", jobID.String()))
+	if err := service.RunBackfill(ctx, jobID); err != nil {
+		return fmt.Errorf("job %s: %w", jobID, err)
+	}
+	return nil
+}
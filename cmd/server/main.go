@@ -20,6 +20,7 @@ import (
 	"github.com/niaga-platform/service-marketplace/internal/repository"
 	"github.com/niaga-platform/service-marketplace/internal/routes"
 	"github.com/niaga-platform/service-marketplace/internal/services"
+	"github.com/niaga-platform/service-marketplace/internal/storage"
 
 	"github.com/nats-io/nats.go"
 	"github.com/redis/go-redis/v9"
@@ -120,11 +121,65 @@ func main() {
 	categoryMappingRepo := repository.NewCategoryMappingRepository(db)
 	syncJobRepo := repository.NewSyncJobRepository(db)
 	orderRepo := repository.NewMarketplaceOrderRepository(db)
+	orderStatusHistoryRepo := repository.NewOrderStatusHistoryRepository(db)
+	orderPollStateRepo := repository.NewOrderPollStateRepository(db)
 	importedProductRepo := repository.NewImportedProductRepository(db)
+	inventoryLogRepo := repository.NewInventorySyncLogRepository(db)
+	warehouseRepo := repository.NewWarehouseMappingRepository(db)
+	reservedStockRepo := repository.NewReservedStockRepository(db)
+	webhookEventRepo := repository.NewWebhookEventRepository(db)
+	returnRepo := repository.NewReturnRepository(db)
+	returnRuleRepo := repository.NewReturnRuleRepository(db)
+	returnDecisionRepo := repository.NewReturnDecisionRepository(db)
+	settlementRepo := repository.NewSettlementRepository(db)
+	shippingDocumentRepo := repository.NewShippingDocumentRepository(db)
 
 	// Initialize catalog client
 	catalogClient := clients.NewCatalogClient(cfg.Services.CatalogURL, logger)
 
+	// Initialize order client
+	orderClient := clients.NewOrderClient(cfg.Services.OrderURL, logger)
+
+	// Initialize order sync service
+	orderSyncService, err := services.NewOrderSyncService(
+		connectionRepo,
+		orderRepo,
+		orderStatusHistoryRepo,
+		productMappingRepo,
+		orderClient,
+		&services.OrderSyncServiceConfig{
+			ShopeePartnerID:  cfg.Shopee.PartnerID,
+			ShopeePartnerKey: cfg.Shopee.PartnerKey,
+			ShopeeSandbox:    cfg.Shopee.IsSandbox,
+			TikTokAppKey:     cfg.TikTok.AppKey,
+			TikTokAppSecret:  cfg.TikTok.AppSecret,
+			EncryptionKey:    cfg.Security.EncryptionKey,
+		},
+		logger,
+	)
+	if err != nil {
+		logger.Fatal("Failed to initialize order sync service", zap.Error(err))
+	}
+
+	// Initialize historical order backfill
+	orderBackfillService := services.NewOrderBackfillService(
+		connectionRepo,
+		syncJobRepo,
+		orderSyncService,
+		services.OrderBackfillConfig{
+			Window: cfg.Orders.BackfillWindow,
+		},
+		logger,
+	)
+
+	// Run a backfill from the command line instead of serving
+	if len(os.Args) > 1 && os.Args[1] == "backfill" {
+		if err := runBackfill(orderBackfillService, os.Args[2:], logger); err != nil {
+			logger.Fatal("Order backfill failed", zap.Error(err))
+		}
+		return
+	}
+
 	// Log repository initialization
 	logger.Info("Repositories initialized",
 		zap.Bool("connectionRepo", connectionRepo != nil),
@@ -250,6 +305,9 @@ func main() {
 	inventorySyncService, err := services.NewInventorySyncService(
 		connectionRepo,
 		productMappingRepo,
+		inventoryLogRepo,
+		warehouseRepo,
+		reservedStockRepo,
 		eventPublisher,
 		&services.InventorySyncServiceConfig{
 			ShopeePartnerID:  cfg.Shopee.PartnerID,
@@ -258,6 +316,9 @@ func main() {
 			TikTokAppKey:     cfg.TikTok.AppKey,
 			TikTokAppSecret:  cfg.TikTok.AppSecret,
 			EncryptionKey:    cfg.Security.EncryptionKey,
+
+			StockDebounceWindow: cfg.Inventory.DebounceWindow,
+			StockBatchSize:      cfg.Inventory.BatchSize,
 		},
 		logger,
 	)
@@ -271,6 +332,7 @@ func main() {
 		productMappingRepo,
 		categoryMappingRepo,
 		catalogClient,
+		inventorySyncService,
 		eventPublisher,
 		&services.MarketplaceSyncHandlerConfig{
 			ShopeePartnerID:  cfg.Shopee.PartnerID,
@@ -288,6 +350,7 @@ func main() {
 	// Start NATS subscriber if connected
 	if natsConn != nil && marketplaceSyncHandler != nil {
 		eventSubscriber = events.NewSubscriber(natsConn, marketplaceSyncHandler, logger)
+		eventSubscriber.SetOrderHandler(orderSyncService) // Ship marketplace orders shipped by the warehouse
 		if err := eventSubscriber.Start(); err != nil {
 			logger.Warn("Failed to start event subscriber", zap.Error(err))
 		}
@@ -295,23 +358,68 @@ func main() {
 	} else if natsConn != nil {
 		// Fallback to inventory sync service if marketplace sync handler failed
 		eventSubscriber = events.NewSubscriber(natsConn, inventorySyncService, logger)
+		eventSubscriber.SetOrderHandler(orderSyncService)
 		if err := eventSubscriber.Start(); err != nil {
 			logger.Warn("Failed to start event subscriber", zap.Error(err))
 		}
 	}
 
+	// Initialize inventory reconciliation (scheduled drift check against marketplace stock)
+	inventoryReconciliationService := services.NewInventoryReconciliationService(
+		connectionRepo,
+		productMappingRepo,
+		syncJobRepo,
+		catalogClient,
+		inventorySyncService,
+		services.InventoryReconciliationConfig{
+			Interval:    cfg.Inventory.ReconcileInterval,
+			AutoCorrect: cfg.Inventory.ReconcileAutoCorrect,
+		},
+		logger,
+	)
+	if cfg.Inventory.ReconcileEnabled {
+		if err := inventoryReconciliationService.Start(context.Background()); err != nil {
+			logger.Warn("Failed to start inventory reconciliation", zap.Error(err))
+		}
+		defer inventoryReconciliationService.Stop()
+	}
+
 	// Initialize inventory handler
-	inventoryHandler := handlers.NewInventoryHandler(inventorySyncService, logger)
+	inventoryHandler := handlers.NewInventoryHandler(inventorySyncService, inventoryReconciliationService, logger)
 
-	// Initialize order client
-	orderClient := clients.NewOrderClient(cfg.Services.OrderURL, logger)
+	// Initialize scheduled order polling (safety net for missed webhooks)
+	orderPollService := services.NewOrderPollService(
+		connectionRepo,
+		orderPollStateRepo,
+		orderSyncService,
+		services.OrderPollConfig{
+			Interval:               cfg.Orders.PollInterval,
+			Overlap:                cfg.Orders.PollOverlap,
+			Lookback:               cfg.Orders.PollLookback,
+			MaxImportAttempts:      cfg.Orders.PollMaxAttempts,
+			MaxFulfillmentAttempts: cfg.Orders.FulfillmentMaxAttempts,
+		},
+		logger,
+	)
+	if cfg.Orders.PollEnabled {
+		if err := orderPollService.Start(context.Background()); err != nil {
+			logger.Warn("Failed to start order poll", zap.Error(err))
+		}
+		defer orderPollService.Stop()
+	}
 
-	// Initialize order sync service
-	orderSyncService, err := services.NewOrderSyncService(
+	// Initialize order handler
+	orderHandler := handlers.NewOrderHandler(orderSyncService, orderPollService, orderBackfillService, logger)
+
+	// Initialize return sync service
+	returnSyncService, err := services.NewReturnSyncService(
 		connectionRepo,
 		orderRepo,
+		returnRepo,
+		productMappingRepo,
 		orderClient,
-		&services.OrderSyncServiceConfig{
+		eventPublisher,
+		&services.ReturnSyncServiceConfig{
 			ShopeePartnerID:  cfg.Shopee.PartnerID,
 			ShopeePartnerKey: cfg.Shopee.PartnerKey,
 			ShopeeSandbox:    cfg.Shopee.IsSandbox,
@@ -322,17 +430,77 @@ func main() {
 		logger,
 	)
 	if err != nil {
-		logger.Fatal("Failed to initialize order sync service", zap.Error(err))
+		logger.Fatal("Failed to initialize return sync service", zap.Error(err))
 	}
 
-	// Initialize order handler
-	orderHandler := handlers.NewOrderHandler(orderSyncService, logger)
+	// Initialize return handler
+	returnHandler := handlers.NewReturnHandler(returnSyncService, logger)
 
-	// Initialize webhook handler
-	webhookHandler := handlers.NewWebhookHandler(orderSyncService, &handlers.WebhookConfig{
+	// Initialize return rules (automatic accept/dispute decisions on new returns)
+	returnRuleService := services.NewReturnRuleService(
+		connectionRepo,
+		orderRepo,
+		orderStatusHistoryRepo,
+		returnRepo,
+		productMappingRepo,
+		returnRuleRepo,
+		returnDecisionRepo,
+		returnSyncService,
+		services.ReturnRuleConfig{
+			Interval: cfg.Returns.DecisionInterval,
+		},
+		logger,
+	)
+	returnSyncService.SetDecisionMaker(returnRuleService)
+	if err := returnRuleService.Start(context.Background()); err != nil {
+		logger.Warn("Failed to start return decision runner", zap.Error(err))
+	}
+	defer returnRuleService.Stop()
+	returnRuleHandler := handlers.NewReturnRuleHandler(returnRuleService, logger)
+
+	// Initialize payout statement ingestion and reconciliation reports
+	settlementService := services.NewSettlementService(connectionRepo, settlementRepo, orderSyncService, logger)
+	settlementHandler := handlers.NewSettlementHandler(settlementService, logger)
+
+	// Initialize shipping document storage
+	documentStore, err := storage.New(storage.Config{
+		Driver:    cfg.Storage.Driver,
+		LocalPath: cfg.Storage.LocalPath,
+	})
+	if err != nil {
+		logger.Fatal("Failed to initialize shipping document storage", zap.Error(err))
+	}
+	shippingDocumentService := services.NewShippingDocumentService(orderRepo, shippingDocumentRepo, orderSyncService, documentStore, services.ShippingDocumentConfig{
+		ReadyTimeout: cfg.Orders.ShippingDocumentTimeout,
+	}, logger)
+	orderSyncService.SetShippingDocuments(shippingDocumentService)
+
+	// Initialize batch shipment and AWB printing
+	shipmentBatchService := services.NewShipmentBatchService(
+		connectionRepo,
+		orderRepo,
+		orderSyncService,
+		shippingDocumentService,
+		logger,
+	)
+	shipmentHandler := handlers.NewShipmentHandler(shipmentBatchService, shippingDocumentService, logger)
+
+	// Initialize webhook service; events stored before a restart are processed in the background
+	webhookService, err := services.NewWebhookService(webhookEventRepo, connectionService, productSyncService, orderSyncService, inventorySyncService, services.WebhookServiceConfig{
 		ShopeePartnerKey: cfg.Shopee.PartnerKey,
+		ShopeeWebhookURL: cfg.Shopee.WebhookURL,
+		TikTokAppKey:     cfg.TikTok.AppKey,
 		TikTokAppSecret:  cfg.TikTok.AppSecret,
+		VerifyMode:       cfg.Webhook.VerifyMode,
 	}, logger)
+	if err != nil {
+		logger.Fatal("Failed to initialize webhook service", zap.Error(err))
+	}
+	webhookService.SetReturnHandler(returnSyncService)
+	webhookService.Start()
+
+	// Initialize webhook handler
+	webhookHandler := handlers.NewWebhookHandler(webhookService, logger)
 
 	// Set Gin mode
 	if cfg.App.Env == "production" {
@@ -378,6 +546,10 @@ func main() {
 		CategoryHandler:   categoryHandler,
 		InventoryHandler:  inventoryHandler,
 		OrderHandler:      orderHandler,
+		ReturnHandler:     returnHandler,
+		ReturnRuleHandler: returnRuleHandler,
+		SettlementHandler: settlementHandler,
+		ShipmentHandler:   shipmentHandler,
 		WebhookHandler:    webhookHandler,
 		AnalyticsHandler:  analyticsHandler,
 		JWTManager:        jwtManager,
@@ -413,6 +585,10 @@ func main() {
 		logger.Fatal("Server forced to shutdown", zap.Error(err))
 	}
 
+	// Let in-flight webhooks finish, then push stock changes still waiting in the debounce window
+	webhookService.Stop()
+	inventorySyncService.Stop()
+
 	logger.Info("Server exited")
 }
 
@@ -2,22 +2,28 @@ package config
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/spf13/viper"
 )
 
 // Config holds all configuration for the marketplace service
 type Config struct {
-	App      AppConfig      `mapstructure:"app"`
-	Database DatabaseConfig `mapstructure:"database"`
-	Redis    RedisConfig    `mapstructure:"redis"`
-	NATS     NATSConfig     `mapstructure:"nats"`
-	JWT      JWTConfig      `mapstructure:"jwt"`
-	Sentry   SentryConfig   `mapstructure:"sentry"`
-	Shopee   ShopeeConfig   `mapstructure:"shopee"`
-	TikTok   TikTokConfig   `mapstructure:"tiktok"`
-	Security SecurityConfig `mapstructure:"security"`
-	Services ServicesConfig `mapstructure:"services"`
+	App       AppConfig       `mapstructure:"app"`
+	Database  DatabaseConfig  `mapstructure:"database"`
+	Redis     RedisConfig     `mapstructure:"redis"`
+	NATS      NATSConfig      `mapstructure:"nats"`
+	JWT       JWTConfig       `mapstructure:"jwt"`
+	Sentry    SentryConfig    `mapstructure:"sentry"`
+	Shopee    ShopeeConfig    `mapstructure:"shopee"`
+	TikTok    TikTokConfig    `mapstructure:"tiktok"`
+	Security  SecurityConfig  `mapstructure:"security"`
+	Services  ServicesConfig  `mapstructure:"services"`
+	Inventory InventoryConfig `mapstructure:"inventory"`
+	Webhook   WebhookConfig   `mapstructure:"webhook"`
+	Returns   ReturnsConfig   `mapstructure:"returns"`
+	Orders    OrdersConfig    `mapstructure:"orders"`
+	Storage   StorageConfig   `mapstructure:"storage"`
 }
 
 // RedisConfig holds Redis cache configuration
@@ -67,6 +73,7 @@ type ShopeeConfig struct {
 	PartnerID   string `mapstructure:"partner_id"`
 	PartnerKey  string `mapstructure:"partner_key"`
 	RedirectURL string `mapstructure:"redirect_url"`
+	WebhookURL  string `mapstructure:"webhook_url"` // Push callback URL registered in the Shopee console
 	IsSandbox   bool   `mapstructure:"is_sandbox"`
 }
 
@@ -89,6 +96,44 @@ type ServicesConfig struct {
 	OrderURL     string `mapstructure:"order_url"`
 }
 
+// InventoryConfig holds inventory sync configuration
+type InventoryConfig struct {
+	ReconcileEnabled     bool          `mapstructure:"reconcile_enabled"`
+	ReconcileInterval    time.Duration `mapstructure:"reconcile_interval"`
+	ReconcileAutoCorrect bool          `mapstructure:"reconcile_auto_correct"` // Correct drift on every connection
+	DebounceWindow       time.Duration `mapstructure:"debounce_window"`        // Coalesce stock events for this long before pushing
+	BatchSize            int           `mapstructure:"batch_size"`             // Flush early once this many listings are pending
+}
+
+// WebhookConfig holds inbound webhook configuration
+type WebhookConfig struct {
+	VerifyMode string `mapstructure:"verify_mode"` // strict rejects unverified deliveries, grace only records them; empty picks one from the Shopee settings
+}
+
+// ReturnsConfig holds return automation configuration
+type ReturnsConfig struct {
+	DecisionInterval time.Duration `mapstructure:"decision_interval"` // How often scheduled return decisions are executed
+}
+
+// OrdersConfig holds scheduled order polling and backfill configuration
+type OrdersConfig struct {
+	PollEnabled            bool          `mapstructure:"poll_enabled"`
+	PollInterval           time.Duration `mapstructure:"poll_interval"`
+	PollOverlap            time.Duration `mapstructure:"poll_overlap"`             // Each poll starts this far before the last high-water mark
+	PollLookback           time.Duration `mapstructure:"poll_lookback"`            // Range of the first poll of a connection
+	PollMaxAttempts        int           `mapstructure:"poll_max_attempts"`        // Polls an order may fail to import before it is dead-lettered
+	FulfillmentMaxAttempts int           `mapstructure:"fulfillment_max_attempts"` // Failed fulfillment syncs of an order the poll retries
+	BackfillWindow         time.Duration `mapstructure:"backfill_window"`          // Range imported between backfill checkpoints
+
+	ShippingDocumentTimeout time.Duration `mapstructure:"shipping_document_timeout"` // How long AWB downloads and batch printing wait for Shopee documents
+}
+
+// StorageConfig holds the blob store keeping shipping documents
+type StorageConfig struct {
+	Driver    string `mapstructure:"driver"`     // local
+	LocalPath string `mapstructure:"local_path"` // Root directory of the local driver
+}
+
 // Load loads configuration from environment variables
 func Load() (*Config, error) {
 	v := viper.New()
@@ -128,6 +173,7 @@ func Load() (*Config, error) {
 	_ = v.BindEnv("shopee.partner_key", "SHOPEE_PARTNER_KEY")
 	_ = v.BindEnv("shopee.redirect_url", "SHOPEE_REDIRECT_URL")
 	_ = v.BindEnv("shopee.is_sandbox", "SHOPEE_SANDBOX")
+	_ = v.BindEnv("shopee.webhook_url", "SHOPEE_WEBHOOK_URL")
 
 	// TikTok
 	_ = v.BindEnv("tiktok.app_key", "TIKTOK_APP_KEY")
@@ -142,6 +188,33 @@ func Load() (*Config, error) {
 	_ = v.BindEnv("services.inventory_url", "SERVICE_INVENTORY_URL")
 	_ = v.BindEnv("services.order_url", "SERVICE_ORDER_URL")
 
+	// Inventory
+	_ = v.BindEnv("inventory.reconcile_enabled", "INVENTORY_RECONCILE_ENABLED")
+	_ = v.BindEnv("inventory.reconcile_interval", "INVENTORY_RECONCILE_INTERVAL")
+	_ = v.BindEnv("inventory.reconcile_auto_correct", "INVENTORY_RECONCILE_AUTO_CORRECT")
+	_ = v.BindEnv("inventory.debounce_window", "INVENTORY_DEBOUNCE_WINDOW")
+	_ = v.BindEnv("inventory.batch_size", "INVENTORY_BATCH_SIZE")
+
+	// Webhooks
+	_ = v.BindEnv("webhook.verify_mode", "WEBHOOK_VERIFY_MODE")
+
+	// Returns
+	_ = v.BindEnv("returns.decision_interval", "RETURN_DECISION_INTERVAL")
+
+	// Orders
+	_ = v.BindEnv("orders.poll_enabled", "ORDER_POLL_ENABLED")
+	_ = v.BindEnv("orders.poll_interval", "ORDER_POLL_INTERVAL")
+	_ = v.BindEnv("orders.poll_overlap", "ORDER_POLL_OVERLAP")
+	_ = v.BindEnv("orders.poll_lookback", "ORDER_POLL_LOOKBACK")
+	_ = v.BindEnv("orders.poll_max_attempts", "ORDER_POLL_MAX_ATTEMPTS")
+	_ = v.BindEnv("orders.fulfillment_max_attempts", "ORDER_FULFILLMENT_MAX_ATTEMPTS")
+	_ = v.BindEnv("orders.backfill_window", "ORDER_BACKFILL_WINDOW")
+	_ = v.BindEnv("orders.shipping_document_timeout", "SHIPPING_DOCUMENT_TIMEOUT")
+
+	// Storage
+	_ = v.BindEnv("storage.driver", "STORAGE_DRIVER")
+	_ = v.BindEnv("storage.local_path", "STORAGE_LOCAL_PATH")
+
 	// Set defaults
 	setDefaults(v)
 
@@ -185,6 +258,30 @@ func setDefaults(v *viper.Viper) {
 	v.SetDefault("services.inventory_url", "http://localhost:8083")
 	v.SetDefault("services.order_url", "http://localhost:8005")
 
+	// Inventory
+	v.SetDefault("inventory.reconcile_enabled", false)
+	v.SetDefault("inventory.reconcile_interval", "6h")
+	v.SetDefault("inventory.reconcile_auto_correct", false)
+	v.SetDefault("inventory.debounce_window", "2s")
+	v.SetDefault("inventory.batch_size", 50)
+
+	// Returns
+	v.SetDefault("returns.decision_interval", "1m")
+
+	// Orders
+	v.SetDefault("orders.poll_enabled", true)
+	v.SetDefault("orders.poll_interval", "10m")
+	v.SetDefault("orders.poll_overlap", "10m")
+	v.SetDefault("orders.poll_lookback", "24h")
+	v.SetDefault("orders.poll_max_attempts", 5)
+	v.SetDefault("orders.fulfillment_max_attempts", 5)
+	v.SetDefault("orders.backfill_window", "24h")
+	v.SetDefault("orders.shipping_document_timeout", "2m")
+
+	// Storage
+	v.SetDefault("storage.driver", "local")
+	v.SetDefault("storage.local_path", "./data/shipping-documents")
+
 	// Sentry
 	v.SetDefault("sentry.dsn", "")
 	v.SetDefault("sentry.environment", "development")
@@ -0,0 +1,110 @@
+package shared
+
+import (
+	"errors"
+	"fmt"
+)
+
+// OrderStatus represents the canonical lifecycle status of a marketplace order.
+// Platform statuses are mapped onto it by each provider.
+type OrderStatus string
+
+// Order status constants
+const (
+	OrderUnpaid    OrderStatus = "unpaid"
+	OrderToShip    OrderStatus = "to_ship"
+	OrderShipped   OrderStatus = "shipped"
+	OrderDelivered OrderStatus = "delivered"
+	OrderCompleted OrderStatus = "completed"
+	OrderCancelled OrderStatus = "cancelled"
+	OrderReturned  OrderStatus = "returned"
+)
+
+// FallbackOrderStatus is given to new orders whose platform status has no
+// mapping. It is the start of the lifecycle, so the next mapped status moves
+// the order on.
+const FallbackOrderStatus = OrderUnpaid
+
+// ErrInvalidOrderStatus is returned for invalid status values.
+var ErrInvalidOrderStatus = errors.New("invalid order status")
+
+// ErrInvalidOrderTransition is returned when a status change is not allowed.
+var ErrInvalidOrderTransition = errors.New("invalid order status transition")
+
+// orderProgress orders the forward lifecycle. Platforms do not report every
+// step and polls can miss states, so any forward move along it is allowed.
+var orderProgress = map[OrderStatus]int{
+	OrderUnpaid:    1,
+	OrderToShip:    2,
+	OrderShipped:   3,
+	OrderDelivered: 4,
+	OrderCompleted: 5,
+}
+
+// AllOrderStatuses returns all valid statuses.
+func AllOrderStatuses() []OrderStatus {
+	return []OrderStatus{OrderUnpaid, OrderToShip, OrderShipped, OrderDelivered, OrderCompleted, OrderCancelled, OrderReturned}
+}
+
+// IsValid returns true if the status is valid.
+func (s OrderStatus) IsValid() bool {
+	switch s {
+	case OrderUnpaid, OrderToShip, OrderShipped, OrderDelivered, OrderCompleted, OrderCancelled, OrderReturned:
+		return true
+	default:
+		return false
+	}
+}
+
+// String returns the string representation.
+func (s OrderStatus) String() string {
+	return string(s)
+}
+
+// IsFinal returns true if the order can no longer change status.
+func (s OrderStatus) IsFinal() bool {
+	return s == OrderCancelled || s == OrderReturned
+}
+
+// IsFulfilled returns true once the order has left the warehouse.
+func (s OrderStatus) IsFulfilled() bool {
+	return s == OrderShipped || s == OrderDelivered || s == OrderCompleted || s == OrderReturned
+}
+
+// CanTransitionTo returns true if the order may move from s to next.
+// Orders can be cancelled until delivered (failed deliveries are cancelled
+// in transit) and returned once shipped.
+func (s OrderStatus) CanTransitionTo(next OrderStatus) bool {
+	if !s.IsValid() || !next.IsValid() || s == next || s.IsFinal() {
+		return false
+	}
+
+	switch next {
+	case OrderCancelled:
+		return s == OrderUnpaid || s == OrderToShip || s == OrderShipped
+	case OrderReturned:
+		return s == OrderShipped || s == OrderDelivered || s == OrderCompleted
+	default:
+		return orderProgress[next] > orderProgress[s]
+	}
+}
+
+// ValidateTransition returns an error if the order may not move from s to next.
+func (s OrderStatus) ValidateTransition(next OrderStatus) error {
+	if !next.IsValid() {
+		return fmt.Errorf("%w: %s", ErrInvalidOrderStatus, next)
+	}
+	if !s.CanTransitionTo(next) {
+		return fmt.Errorf("%w: %s to %s", ErrInvalidOrderTransition, s, next)
+	}
+	return nil
+}
+
+// ParseOrderStatus parses a string into an OrderStatus.
+func ParseOrderStatus(str string) (OrderStatus, error) {
+	s := OrderStatus(str)
+	if !s.IsValid() {
+		return "", fmt.Errorf("%w: %s", ErrInvalidOrderStatus, str)
+	}
+	return s, nil
+}
@@ -0,0 +1,102 @@
+package shared
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestOrderStatusTransitions(t *testing.T) {
+	tests := []struct {
+		from OrderStatus
+		to   OrderStatus
+		want bool
+	}{
+		// Forward moves, including skipped steps
+		{OrderUnpaid, OrderToShip, true},
+		{OrderUnpaid, OrderShipped, true},
+		{OrderToShip, OrderShipped, true},
+		{OrderToShip, OrderCompleted, true},
+		{OrderShipped, OrderDelivered, true},
+		{OrderDelivered, OrderCompleted, true},
+
+		// Backward moves and repeats
+		{OrderToShip, OrderUnpaid, false},
+		{OrderShipped, OrderToShip, false},
+		{OrderCompleted, OrderDelivered, false},
+		{OrderShipped, OrderShipped, false},
+
+		// Cancellation until delivered
+		{OrderUnpaid, OrderCancelled, true},
+		{OrderToShip, OrderCancelled, true},
+		{OrderShipped, OrderCancelled, true},
+		{OrderDelivered, OrderCancelled, false},
+		{OrderCompleted, OrderCancelled, false},
+
+		// Returns once shipped
+		{OrderUnpaid, OrderReturned, false},
+		{OrderToShip, OrderReturned, false},
+		{OrderShipped, OrderReturned, true},
+		{OrderDelivered, OrderReturned, true},
+		{OrderCompleted, OrderReturned, true},
+
+		// Final statuses
+		{OrderCancelled, OrderToShip, false},
+		{OrderCancelled, OrderReturned, false},
+		{OrderReturned, OrderCompleted, false},
+
+		// Invalid statuses
+		{OrderStatus(""), OrderToShip, false},
+		{OrderToShip, OrderStatus("pending"), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.from)+"->"+string(tt.to), func(t *testing.T) {
+			if got := tt.from.CanTransitionTo(tt.to); got != tt.want {
+				t.Errorf("CanTransitionTo() = %v, want %v", got, tt.want)
+			}
+
+			err := tt.from.ValidateTransition(tt.to)
+			if tt.want && err != nil {
+				t.Errorf("ValidateTransition() error = %v, want nil", err)
+			}
+			if !tt.want && err == nil {
+				t.Error("ValidateTransition() error = nil, want an error")
+			}
+		})
+	}
+}
+
+func TestOrderStatusValidateTransitionErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		from    OrderStatus
+		to      OrderStatus
+		wantErr error
+	}{
+		{name: "unknown target", from: OrderToShip, to: OrderStatus("pending"), wantErr: ErrInvalidOrderStatus},
+		{name: "disallowed move", from: OrderCompleted, to: OrderToShip, wantErr: ErrInvalidOrderTransition},
+		{name: "from final", from: OrderCancelled, to: OrderShipped, wantErr: ErrInvalidOrderTransition},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.from.ValidateTransition(tt.to); !errors.Is(err, tt.wantErr) {
+				t.Errorf("ValidateTransition() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestFallbackOrderStatusCanProgress(t *testing.T) {
+	if !FallbackOrderStatus.IsValid() {
+		t.Fatalf("FallbackOrderStatus %q is not valid", FallbackOrderStatus)
+	}
+	for _, next := range AllOrderStatuses() {
+		if next == FallbackOrderStatus || next == OrderReturned {
+			continue
+		}
+		if !FallbackOrderStatus.CanTransitionTo(next) {
+			t.Errorf("FallbackOrderStatus cannot move to %q", next)
+		}
+	}
+}
@@ -11,16 +11,25 @@ import (
 
 // Event subjects
 const (
-	SubjectInventoryStockChanged = "inventory.stock.changed"
-	SubjectMarketplaceSyncOK     = "marketplace.sync.completed"
-	SubjectMarketplaceSyncFailed = "marketplace.sync.failed"
+	SubjectInventoryStockChanged    = "inventory.stock.changed"
+	SubjectMarketplaceSyncOK        = "marketplace.sync.completed"
+	SubjectMarketplaceSyncFailed    = "marketplace.sync.failed"
+	SubjectMarketplaceStockReserved = "marketplace.stock.reserved"
+	SubjectMarketplaceReturnRestock = "marketplace.return.restock"
 
 	// Catalog events - subscribe to product changes for auto-sync
 	SubjectProductCreated = "product.created"
 	SubjectProductUpdated = "product.updated"
 	SubjectProductDeleted = "product.deleted"
+
+	// Order events - subscribe to fulfillment in service-order to ship marketplace orders
+	SubjectOrderShipped = "order.shipped"
 )
 
+// QueueGroup is the queue group of subscriptions that must be handled by one
+// replica only, such as shipping an order on its marketplace
+const QueueGroup = "service-marketplace"
+
 // StockChangedEvent represents an inventory change event
 type StockChangedEvent struct {
 	ProductID   uuid.UUID  `json:"product_id"`
@@ -50,6 +59,16 @@ type ProductDeletedEvent struct {
 	DeletedAt time.Time `json:"deleted_at"`
 }
 
+// OrderShippedEvent represents an order shipped by the warehouse in service-order
+type OrderShippedEvent struct {
+	OrderID        uuid.UUID  `json:"order_id"` // Order in service-order
+	OrderNumber    string     `json:"order_number,omitempty"`
+	Courier        string     `json:"courier,omitempty"`
+	TrackingNumber string     `json:"tracking_number,omitempty"`
+	ShippedAt      *time.Time `json:"shipped_at,omitempty"`
+	Timestamp      time.Time  `json:"timestamp"`
+}
+
 // SyncCompletedEvent represents a successful sync event
 type SyncCompletedEvent struct {
 	ConnectionID uuid.UUID `json:"connection_id"`
@@ -69,12 +88,51 @@ type SyncFailedEvent struct {
 	Timestamp    time.Time `json:"timestamp"`
 }
 
+// StockReservedEvent tells the inventory service that marketplace stock was
+// locked or released by a promotion
+type StockReservedEvent struct {
+	ConnectionID      uuid.UUID  `json:"connection_id"`
+	Platform          string     `json:"platform"`
+	ProductID         *uuid.UUID `json:"product_id,omitempty"` // Internal product, when the listing is mapped
+	ExternalProductID string     `json:"external_product_id"`
+	ExternalModelID   string     `json:"external_model_id,omitempty"`
+	PreviousReserved  int        `json:"previous_reserved"`
+	ReservedQuantity  int        `json:"reserved_quantity"`
+	Action            string     `json:"action,omitempty"`
+	PromotionType     string     `json:"promotion_type,omitempty"`
+	PromotionID       string     `json:"promotion_id,omitempty"`
+	Timestamp         time.Time  `json:"timestamp"`
+}
+
+// ReturnRestockEvent asks the inventory service to put the items of a refunded
+// marketplace return back into stock
+type ReturnRestockEvent struct {
+	ConnectionID     uuid.UUID           `json:"connection_id"`
+	Platform         string              `json:"platform"`
+	ReturnID         uuid.UUID           `json:"return_id"`
+	ExternalReturnID string              `json:"external_return_id"`
+	ExternalOrderID  string              `json:"external_order_id"`
+	WarehouseID      string              `json:"warehouse_id,omitempty"` // Empty means the inventory service default
+	Items            []ReturnRestockItem `json:"items"`
+	Timestamp        time.Time           `json:"timestamp"`
+}
+
+// ReturnRestockItem is one returned product or variant to restock
+type ReturnRestockItem struct {
+	ProductID         uuid.UUID  `json:"product_id"`
+	VariantID         *uuid.UUID `json:"variant_id,omitempty"`
+	ExternalProductID string     `json:"external_product_id"`
+	ExternalVariantID string     `json:"external_variant_id,omitempty"`
+	Quantity          int        `json:"quantity"`
+}
+
 // Subscriber handles NATS event subscriptions
 type Subscriber struct {
-	nc      *nats.Conn
-	logger  *zap.Logger
-	handler EventHandler
-	subs    []*nats.Subscription
+	nc           *nats.Conn
+	logger       *zap.Logger
+	handler      EventHandler
+	orderHandler OrderEventHandler
+	subs         []*nats.Subscription
 }
 
 // EventHandler defines the interface for handling events
@@ -84,6 +142,11 @@ type EventHandler interface {
 	HandleProductDeleted(event *ProductDeletedEvent) error
 }
 
+// OrderEventHandler defines the interface for handling order fulfillment events
+type OrderEventHandler interface {
+	HandleOrderShipped(event *OrderShippedEvent) error
+}
+
 // NewSubscriber creates a new NATS subscriber
 func NewSubscriber(nc *nats.Conn, handler EventHandler, logger *zap.Logger) *Subscriber {
 	return &Subscriber{
@@ -94,6 +157,12 @@ func NewSubscriber(nc *nats.Conn, handler EventHandler, logger *zap.Logger) *Sub
 	}
 }
 
+// SetOrderHandler sets the handler of order fulfillment events. Order events
+// are only subscribed to when a handler is set before Start.
+func (s *Subscriber) SetOrderHandler(handler OrderEventHandler) {
+	s.orderHandler = handler
+}
+
 // Start subscribes to all relevant events
 func (s *Subscriber) Start() error {
 	// Subscribe to inventory changes
@@ -120,6 +189,16 @@ func (s *Subscriber) Start() error {
 	s.subs = append(s.subs, sub)
 	s.logger.Info("Subscribed to event", zap.String("subject", SubjectProductDeleted))
 
+	// Subscribe to shipped orders to ship them on the marketplace, once per event
+	if s.orderHandler != nil {
+		sub, err = s.nc.QueueSubscribe(SubjectOrderShipped, QueueGroup, s.handleOrderShipped)
+		if err != nil {
+			return err
+		}
+		s.subs = append(s.subs, sub)
+		s.logger.Info("Subscribed to event", zap.String("subject", SubjectOrderShipped), zap.String("queue", QueueGroup))
+	}
+
 	s.logger.Info("NATS subscriber started with all subscriptions")
 	return nil
 }
@@ -192,6 +271,27 @@ func (s *Subscriber) handleProductDeleted(msg *nats.Msg) {
 	}
 }
 
+// handleOrderShipped processes order shipped events
+func (s *Subscriber) handleOrderShipped(msg *nats.Msg) {
+	var event OrderShippedEvent
+	if err := json.Unmarshal(msg.Data, &event); err != nil {
+		s.logger.Error("Failed to unmarshal order shipped event", zap.Error(err))
+		return
+	}
+
+	s.logger.Info("Received order shipped event",
+		zap.String("order_id", event.OrderID.String()),
+		zap.String("tracking_number", event.TrackingNumber),
+	)
+
+	if err := s.orderHandler.HandleOrderShipped(&event); err != nil {
+		s.logger.Error("Failed to handle order shipped event",
+			zap.String("order_id", event.OrderID.String()),
+			zap.Error(err),
+		)
+	}
+}
+
 // Publisher handles publishing events to NATS
 type Publisher struct {
 	nc     *nats.Conn
@@ -220,3 +320,21 @@ func (p *Publisher) PublishSyncFailed(event *SyncFailedEvent) error {
 	}
 	return p.nc.Publish(SubjectMarketplaceSyncFailed, data)
 }
+
+// PublishStockReserved publishes a marketplace reserved stock change
+func (p *Publisher) PublishStockReserved(event *StockReservedEvent) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	return p.nc.Publish(SubjectMarketplaceStockReserved, data)
+}
+
+// PublishReturnRestock publishes a restock request for a refunded marketplace return
+func (p *Publisher) PublishReturnRestock(event *ReturnRestockEvent) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	return p.nc.Publish(SubjectMarketplaceReturnRestock, data)
+}
@@ -1,6 +1,8 @@
 package handlers
 
 import (
+	"encoding/json"
+	"errors"
 	"net/http"
 	"strconv"
 
@@ -254,3 +256,51 @@ func (h *ConnectionHandler) RefreshToken(c *gin.Context) {
 		"message": "Token refreshed successfully",
 	})
 }
+
+// UpdateSettings updates per-connection settings such as inventory allocation rules
+// PUT /api/v1/admin/marketplace/connections/:id/settings
+func (h *ConnectionHandler) UpdateSettings(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := uuid.Parse(idStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid connection ID",
+			"message": "ID must be a valid UUID",
+		})
+		return
+	}
+
+	var req map[string]json.RawMessage
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request body",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	connection, err := h.service.UpdateSettings(c.Request.Context(), id, req)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrConnectionNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "Connection not found"})
+		case errors.Is(err, services.ErrInvalidSettings):
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "Invalid settings",
+				"message": err.Error(),
+			})
+		default:
+			h.logger.Error("Failed to update settings", zap.String("id", idStr), zap.Error(err))
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error":   "Failed to update settings",
+				"message": err.Error(),
+			})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":    "Settings updated",
+		"connection": connection,
+	})
+}
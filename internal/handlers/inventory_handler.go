@@ -1,27 +1,33 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	"go.uber.org/zap"
 
+	"github.com/niaga-platform/service-marketplace/internal/models"
 	"github.com/niaga-platform/service-marketplace/internal/providers"
 	"github.com/niaga-platform/service-marketplace/internal/services"
 )
 
 // InventoryHandler handles inventory sync API requests
 type InventoryHandler struct {
-	service *services.InventorySyncService
-	logger  *zap.Logger
+	service        *services.InventorySyncService
+	reconciliation *services.InventoryReconciliationService
+	logger         *zap.Logger
 }
 
 // NewInventoryHandler creates a new InventoryHandler
-func NewInventoryHandler(service *services.InventorySyncService, logger *zap.Logger) *InventoryHandler {
+func NewInventoryHandler(service *services.InventorySyncService, reconciliation *services.InventoryReconciliationService, logger *zap.Logger) *InventoryHandler {
 	return &InventoryHandler{
-		service: service,
-		logger:  logger,
+		service:        service,
+		reconciliation: reconciliation,
+		logger:         logger,
 	}
 }
 
@@ -29,7 +35,9 @@ func NewInventoryHandler(service *services.InventorySyncService, logger *zap.Log
 type PushInventoryRequest struct {
 	Updates []struct {
 		ExternalProductID string `json:"external_product_id" binding:"required"`
+		ExternalVariantID string `json:"external_variant_id"` // Marketplace model or SKU ID, empty for the listing
 		ExternalSKU       string `json:"external_sku"`
+		LocationID        string `json:"location_id"` // Marketplace location, empty for the default
 		Quantity          int    `json:"quantity" binding:"min=0"`
 	} `json:"updates" binding:"required,min=1"`
 }
@@ -53,7 +61,9 @@ func (h *InventoryHandler) PushInventory(c *gin.Context) {
 	for i, u := range req.Updates {
 		updates[i] = providers.InventoryUpdate{
 			ExternalProductID: u.ExternalProductID,
+			ExternalVariantID: u.ExternalVariantID,
 			ExternalSKU:       u.ExternalSKU,
+			LocationID:        u.LocationID,
 			Quantity:          u.Quantity,
 		}
 	}
@@ -112,3 +122,283 @@ func (h *InventoryHandler) GetInventoryStatus(c *gin.Context) {
 		"total":     len(items),
 	})
 }
+
+// GetInventoryLogs lists the stock push audit log for a connection
+// GET /api/v1/admin/marketplace/connections/:id/inventory/logs
+func (h *InventoryHandler) GetInventoryLogs(c *gin.Context) {
+	connectionID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid connection ID"})
+		return
+	}
+
+	filter := &models.InventorySyncLogFilter{
+		ExternalProductID: c.Query("external_product_id"),
+		Source:            c.Query("source"),
+		SyncStatus:        c.Query("status"),
+		Page:              1,
+		PageSize:          20,
+	}
+
+	if productIDStr := c.Query("product_id"); productIDStr != "" {
+		productID, err := uuid.Parse(productIDStr)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid product_id"})
+			return
+		}
+		filter.InternalProductID = &productID
+	}
+	if startDateStr := c.Query("start_date"); startDateStr != "" {
+		startDate, err := time.Parse("2006-01-02", startDateStr)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid start_date format"})
+			return
+		}
+		filter.StartDate = &startDate
+	}
+	if endDateStr := c.Query("end_date"); endDateStr != "" {
+		endDate, err := time.Parse("2006-01-02", endDateStr)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid end_date format"})
+			return
+		}
+		// Include the whole end day
+		endDate = endDate.Add(24*time.Hour - time.Nanosecond)
+		filter.EndDate = &endDate
+	}
+	if pageStr := c.Query("page"); pageStr != "" {
+		if page, err := strconv.Atoi(pageStr); err == nil && page > 0 {
+			filter.Page = page
+		}
+	}
+	if pageSizeStr := c.Query("page_size"); pageSizeStr != "" {
+		if pageSize, err := strconv.Atoi(pageSizeStr); err == nil && pageSize > 0 {
+			filter.PageSize = pageSize
+		}
+	}
+
+	logs, total, err := h.service.GetInventoryLogs(c.Request.Context(), connectionID, filter)
+	if err != nil {
+		if errors.Is(err, services.ErrConnectionNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Connection not found"})
+			return
+		}
+		h.logger.Error("Failed to get inventory logs", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"logs":     logs,
+		"total":    total,
+		"page":     filter.Page,
+		"pageSize": filter.PageSize,
+	})
+}
+
+// ReconcileInventoryRequest represents the request to reconcile inventory
+type ReconcileInventoryRequest struct {
+	AutoCorrect *bool `json:"auto_correct"` // Defaults to the connection/global setting
+}
+
+// ReconcileInventory starts a reconciliation run against marketplace stock
+// POST /api/v1/admin/marketplace/connections/:id/inventory/reconcile
+func (h *InventoryHandler) ReconcileInventory(c *gin.Context) {
+	connectionID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid connection ID"})
+		return
+	}
+
+	var req ReconcileInventoryRequest
+	_ = c.ShouldBindJSON(&req) // Empty body uses defaults
+
+	job, err := h.reconciliation.StartReconciliation(c.Request.Context(), connectionID, req.AutoCorrect)
+	if err != nil {
+		if errors.Is(err, services.ErrConnectionNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Connection not found"})
+			return
+		}
+		h.logger.Error("Failed to start inventory reconciliation", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"message": "Inventory reconciliation started",
+		"job":     job,
+	})
+}
+
+// GetReconciliations lists reconciliation runs and their drift reports
+// GET /api/v1/admin/marketplace/connections/:id/inventory/reconciliations
+func (h *InventoryHandler) GetReconciliations(c *gin.Context) {
+	connectionID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid connection ID"})
+		return
+	}
+
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 {
+		pageSize = 20
+	}
+
+	jobs, total, err := h.reconciliation.GetReconciliations(c.Request.Context(), connectionID, page, pageSize)
+	if err != nil {
+		h.logger.Error("Failed to get reconciliations", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"reconciliations": jobs,
+		"total":           total,
+		"page":            page,
+		"pageSize":        pageSize,
+	})
+}
+
+// GetWarehouseMappings lists how internal warehouses map to marketplace locations
+// GET /api/v1/admin/marketplace/connections/:id/warehouses
+func (h *InventoryHandler) GetWarehouseMappings(c *gin.Context) {
+	connectionID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid connection ID"})
+		return
+	}
+
+	mappings, err := h.service.GetWarehouseMappings(c.Request.Context(), connectionID)
+	if err != nil {
+		if errors.Is(err, services.ErrConnectionNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Connection not found"})
+			return
+		}
+		h.logger.Error("Failed to get warehouse mappings", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"warehouses": mappings,
+		"total":      len(mappings),
+	})
+}
+
+// SetWarehouseMapping maps an internal warehouse to a marketplace location
+// PUT /api/v1/admin/marketplace/connections/:id/warehouses
+func (h *InventoryHandler) SetWarehouseMapping(c *gin.Context) {
+	connectionID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid connection ID"})
+		return
+	}
+
+	var req models.SetWarehouseMappingRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	mapping, err := h.service.SetWarehouseMapping(c.Request.Context(), connectionID, &req)
+	if err != nil {
+		if errors.Is(err, services.ErrConnectionNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Connection not found"})
+			return
+		}
+		h.logger.Error("Failed to set warehouse mapping", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, mapping)
+}
+
+// DeleteWarehouseMapping removes the mapping of an internal warehouse
+// DELETE /api/v1/admin/marketplace/connections/:id/warehouses/:warehouse_id
+func (h *InventoryHandler) DeleteWarehouseMapping(c *gin.Context) {
+	connectionID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid connection ID"})
+		return
+	}
+
+	if err := h.service.DeleteWarehouseMapping(c.Request.Context(), connectionID, c.Param("warehouse_id")); err != nil {
+		if errors.Is(err, services.ErrConnectionNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Connection not found"})
+			return
+		}
+		h.logger.Error("Failed to delete warehouse mapping", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Warehouse mapping deleted"})
+}
+
+// GetMarketplaceWarehouses lists the warehouses configured on the marketplace shop
+// GET /api/v1/admin/marketplace/connections/:id/warehouses/external
+func (h *InventoryHandler) GetMarketplaceWarehouses(c *gin.Context) {
+	connectionID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid connection ID"})
+		return
+	}
+
+	warehouses, err := h.service.GetMarketplaceWarehouses(c.Request.Context(), connectionID)
+	if err != nil {
+		if errors.Is(err, services.ErrConnectionNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Connection not found"})
+			return
+		}
+		h.logger.Error("Failed to get marketplace warehouses", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"warehouses": warehouses,
+		"total":      len(warehouses),
+	})
+}
+
+// GetReservedStock lists listings with stock locked by marketplace promotions
+// GET /api/v1/admin/marketplace/connections/:id/inventory/reserved
+func (h *InventoryHandler) GetReservedStock(c *gin.Context) {
+	connectionID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid connection ID"})
+		return
+	}
+
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 {
+		pageSize = 20
+	}
+
+	reserved, total, err := h.service.GetReservedStock(c.Request.Context(), connectionID, page, pageSize)
+	if err != nil {
+		if errors.Is(err, services.ErrConnectionNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Connection not found"})
+			return
+		}
+		h.logger.Error("Failed to get reserved stock", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"reserved": reserved,
+		"total":    total,
+		"page":     page,
+		"pageSize": pageSize,
+	})
+}
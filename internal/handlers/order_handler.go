@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
 	"time"
@@ -9,21 +10,27 @@ import (
 	"github.com/google/uuid"
 	"go.uber.org/zap"
 
+	"github.com/niaga-platform/service-marketplace/internal/domain/shared"
 	"github.com/niaga-platform/service-marketplace/internal/models"
+	"github.com/niaga-platform/service-marketplace/internal/providers"
 	"github.com/niaga-platform/service-marketplace/internal/services"
 )
 
 // OrderHandler handles order sync API requests
 type OrderHandler struct {
-	service *services.OrderSyncService
-	logger  *zap.Logger
+	service         *services.OrderSyncService
+	pollService     *services.OrderPollService
+	backfillService *services.OrderBackfillService
+	logger          *zap.Logger
 }
 
 // NewOrderHandler creates a new OrderHandler
-func NewOrderHandler(service *services.OrderSyncService, logger *zap.Logger) *OrderHandler {
+func NewOrderHandler(service *services.OrderSyncService, pollService *services.OrderPollService, backfillService *services.OrderBackfillService, logger *zap.Logger) *OrderHandler {
 	return &OrderHandler{
-		service: service,
-		logger:  logger,
+		service:         service,
+		pollService:     pollService,
+		backfillService: backfillService,
+		logger:          logger,
 	}
 }
 
@@ -44,6 +51,17 @@ func (h *OrderHandler) GetOrders(c *gin.Context) {
 	if status := c.Query("status"); status != "" {
 		filter.Status = status
 	}
+	if unresolvedStr := c.Query("unresolved"); unresolvedStr != "" {
+		unresolved, err := strconv.ParseBool(unresolvedStr)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid unresolved value"})
+			return
+		}
+		filter.Unresolved = &unresolved
+	}
+	if fulfillmentSync := c.Query("fulfillment_sync"); fulfillmentSync != "" {
+		filter.FulfillmentSync = fulfillmentSync
+	}
 	if pageStr := c.Query("page"); pageStr != "" {
 		if page, err := strconv.Atoi(pageStr); err == nil && page > 0 {
 			filter.Page = page
@@ -130,9 +148,132 @@ func (h *OrderHandler) SyncOrders(c *gin.Context) {
 	})
 }
 
+// GetPollState returns the scheduled order poll state of a connection
+// GET /api/v1/admin/marketplace/connections/:id/orders/poll-state
+func (h *OrderHandler) GetPollState(c *gin.Context) {
+	connectionID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid connection ID"})
+		return
+	}
+
+	state, err := h.pollService.GetPollState(c.Request.Context(), connectionID)
+	if err != nil {
+		if errors.Is(err, services.ErrConnectionNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Connection not found"})
+			return
+		}
+		h.logger.Error("Failed to get order poll state", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, state)
+}
+
+// StartBackfill starts a historical order backfill in the background
+// POST /api/v1/admin/marketplace/connections/:id/orders/backfill
+func (h *OrderHandler) StartBackfill(c *gin.Context) {
+	connectionID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid connection ID"})
+		return
+	}
+
+	var req models.OrderBackfillRequest
+	_ = c.ShouldBindJSON(&req) // Empty body backfills the last 90 days
+
+	job, err := h.backfillService.StartBackfill(c.Request.Context(), connectionID, &req)
+	if err != nil {
+		h.respondBackfillError(c, "Failed to start order backfill", err)
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"message": "Order backfill started",
+		"job":     job,
+	})
+}
+
+// GetBackfills lists order backfill jobs and their progress
+// GET /api/v1/admin/marketplace/connections/:id/orders/backfills
+func (h *OrderHandler) GetBackfills(c *gin.Context) {
+	connectionID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid connection ID"})
+		return
+	}
+
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 {
+		pageSize = 20
+	}
+
+	jobs, total, err := h.backfillService.GetBackfills(c.Request.Context(), connectionID, page, pageSize)
+	if err != nil {
+		h.logger.Error("Failed to get order backfills", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"backfills": jobs,
+		"total":     total,
+		"page":      page,
+		"pageSize":  pageSize,
+	})
+}
+
+// ResumeBackfill resumes an unfinished order backfill from its checkpoint
+// POST /api/v1/admin/marketplace/connections/:id/orders/backfills/:job_id/resume
+func (h *OrderHandler) ResumeBackfill(c *gin.Context) {
+	connectionID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid connection ID"})
+		return
+	}
+	jobID, err := uuid.Parse(c.Param("job_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid job ID"})
+		return
+	}
+
+	job, err := h.backfillService.ResumeBackfill(c.Request.Context(), connectionID, jobID)
+	if err != nil {
+		h.respondBackfillError(c, "Failed to resume order backfill", err)
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"message": "Order backfill resumed",
+		"job":     job,
+	})
+}
+
+// respondBackfillError maps order backfill service errors to HTTP responses
+func (h *OrderHandler) respondBackfillError(c *gin.Context, message string, err error) {
+	switch {
+	case errors.Is(err, services.ErrConnectionNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Connection not found"})
+	case errors.Is(err, services.ErrBackfillJobNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Backfill job not found"})
+	case errors.Is(err, services.ErrBackfillRunning):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrInvalidBackfill):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		h.logger.Error(message, zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
+
 // UpdateOrderStatusRequest represents the request to update order status
 type UpdateOrderStatusRequest struct {
-	Status string `json:"status" binding:"required"`
+	Status string `json:"status" binding:"required"` // Canonical status: unpaid, to_ship, shipped, delivered, completed, cancelled, returned
 }
 
 // UpdateOrderStatus updates order status on the marketplace
@@ -151,68 +292,150 @@ func (h *OrderHandler) UpdateOrderStatus(c *gin.Context) {
 	}
 
 	if err := h.service.UpdateOrderStatus(c.Request.Context(), orderID, req.Status); err != nil {
-		h.logger.Error("Failed to update order status", zap.Error(err))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		switch {
+		case errors.Is(err, services.ErrOrderNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "Order not found"})
+		case errors.Is(err, shared.ErrInvalidOrderStatus), errors.Is(err, services.ErrInvalidShipment):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case errors.Is(err, shared.ErrInvalidOrderTransition):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		default:
+			h.logger.Error("Failed to update order status", zap.Error(err))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{"message": "Order status updated"})
 }
 
-// ArrangeShipment arranges shipment for an order
-// POST /api/v1/admin/marketplace/connections/:id/orders/:order_id/ship
-func (h *OrderHandler) ArrangeShipment(c *gin.Context) {
+// GetOrderStatusHistory lists the status changes of an order, oldest first
+// GET /api/v1/admin/marketplace/connections/:id/orders/:order_id/status-history
+func (h *OrderHandler) GetOrderStatusHistory(c *gin.Context) {
 	orderID, err := uuid.Parse(c.Param("order_id"))
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid order ID"})
 		return
 	}
 
-	result, err := h.service.ArrangeShipment(c.Request.Context(), orderID)
+	history, err := h.service.GetOrderStatusHistory(c.Request.Context(), orderID)
 	if err != nil {
-		h.logger.Error("Failed to arrange shipment", zap.Error(err))
+		if errors.Is(err, services.ErrOrderNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Order not found"})
+			return
+		}
+		h.logger.Error("Failed to get order status history", zap.Error(err))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"success":    result.Success,
-		"awb_url":    result.AWBUrl,
-		"message":    result.Message,
-	})
+	c.JSON(http.StatusOK, gin.H{"history": history})
 }
 
-// GetAWBRequest represents the request to get AWB
-type GetAWBRequest struct {
-	DocumentType string `json:"document_type"` // NORMAL_AIR_WAYBILL, THERMAL_AIR_WAYBILL
+// SyncOrderFinancials fetches the fee, voucher and payout breakdown of an order
+// POST /api/v1/admin/marketplace/connections/:id/orders/:order_id/financials/sync
+func (h *OrderHandler) SyncOrderFinancials(c *gin.Context) {
+	orderID, err := uuid.Parse(c.Param("order_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid order ID"})
+		return
+	}
+
+	order, err := h.service.SyncOrderFinancials(c.Request.Context(), orderID)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrOrderNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "Order not found"})
+		case errors.Is(err, services.ErrConnectionNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "Connection not found"})
+		default:
+			h.logger.Error("Failed to sync order financials", zap.Error(err))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, order)
 }
 
-// GetAWB gets the AWB download URL for an order
-// POST /api/v1/admin/marketplace/connections/:id/orders/:order_id/awb
-func (h *OrderHandler) GetAWB(c *gin.Context) {
+// GetShippingOptions lists the shipping options available for an order
+// GET /api/v1/admin/marketplace/connections/:id/orders/:order_id/shipping-options
+func (h *OrderHandler) GetShippingOptions(c *gin.Context) {
 	orderID, err := uuid.Parse(c.Param("order_id"))
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid order ID"})
 		return
 	}
 
-	var req GetAWBRequest
-	_ = c.ShouldBindJSON(&req) // Optional, use default if not provided
-
-	documentType := req.DocumentType
-	if documentType == "" {
-		documentType = "NORMAL_AIR_WAYBILL"
+	options, err := h.service.GetShippingOptions(c.Request.Context(), orderID)
+	if err != nil {
+		h.respondShipmentError(c, "Failed to get shipping options", err)
+		return
 	}
 
-	url, err := h.service.GetAWBDownloadURL(c.Request.Context(), orderID, documentType)
+	c.JSON(http.StatusOK, options)
+}
+
+// ArrangeShipmentRequest represents the request to arrange shipment. All
+// fields are optional; missing ones come from the connection's shipping
+// settings, then from the first option available.
+type ArrangeShipmentRequest struct {
+	Method             string `json:"method"` // dropoff, pickup or non_integrated
+	BranchID           int64  `json:"branch_id"`
+	AddressID          int64  `json:"address_id"`
+	PickupTimeID       string `json:"pickup_time_id"`
+	TrackingNumber     string `json:"tracking_number"`
+	ShippingProviderID string `json:"shipping_provider_id"` // TikTok Shop seller-fulfilled orders
+}
+
+// ArrangeShipment arranges shipment for an order
+// POST /api/v1/admin/marketplace/connections/:id/orders/:order_id/ship
+func (h *OrderHandler) ArrangeShipment(c *gin.Context) {
+	orderID, err := uuid.Parse(c.Param("order_id"))
 	if err != nil {
-		h.logger.Error("Failed to get AWB", zap.Error(err))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid order ID"})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"download_url":  url,
-		"document_type": documentType,
+	var req ArrangeShipmentRequest
+	_ = c.ShouldBindJSON(&req) // Optional, use defaults if not provided
+
+	result, err := h.service.ArrangeShipment(c.Request.Context(), orderID, &providers.ShipmentSelection{
+		Method:             req.Method,
+		BranchID:           req.BranchID,
+		AddressID:          req.AddressID,
+		PickupTimeID:       req.PickupTimeID,
+		TrackingNumber:     req.TrackingNumber,
+		ShippingProviderID: req.ShippingProviderID,
 	})
+	if err != nil {
+		h.respondShipmentError(c, "Failed to arrange shipment", err)
+		return
+	}
+
+	response := gin.H{
+		"success":  result.Success,
+		"shipment": result.Shipment,
+		"message":  result.Message,
+	}
+	if result.Document != nil {
+		response["document"] = result.Document
+		response["awb_url"] = documentURL(c.Param("id"), result.Document)
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// respondShipmentError maps shipping errors to HTTP responses
+func (h *OrderHandler) respondShipmentError(c *gin.Context, message string, err error) {
+	switch {
+	case errors.Is(err, services.ErrOrderNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Order not found"})
+	case errors.Is(err, services.ErrConnectionNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Connection not found"})
+	case errors.Is(err, services.ErrInvalidShipment), errors.Is(err, services.ErrShipmentNotSupported):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		h.logger.Error(message, zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
 }
@@ -0,0 +1,247 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"github.com/niaga-platform/service-marketplace/internal/models"
+	"github.com/niaga-platform/service-marketplace/internal/services"
+)
+
+// ReturnHandler handles marketplace return API requests
+type ReturnHandler struct {
+	service *services.ReturnSyncService
+	logger  *zap.Logger
+}
+
+// NewReturnHandler creates a new ReturnHandler
+func NewReturnHandler(service *services.ReturnSyncService, logger *zap.Logger) *ReturnHandler {
+	return &ReturnHandler{
+		service: service,
+		logger:  logger,
+	}
+}
+
+// GetReturns lists marketplace returns for a connection
+// GET /api/v1/admin/marketplace/connections/:id/returns
+func (h *ReturnHandler) GetReturns(c *gin.Context) {
+	connectionID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid connection ID"})
+		return
+	}
+
+	filter := &models.MarketplaceReturnFilter{
+		Status:          c.Query("status"),
+		Type:            c.Query("type"),
+		ExternalOrderID: c.Query("order_sn"),
+		Page:            1,
+		PageSize:        20,
+	}
+
+	if startDate := c.Query("start_date"); startDate != "" {
+		if t, err := time.Parse(time.RFC3339, startDate); err == nil {
+			filter.StartDate = &t
+		}
+	}
+	if endDate := c.Query("end_date"); endDate != "" {
+		if t, err := time.Parse(time.RFC3339, endDate); err == nil {
+			filter.EndDate = &t
+		}
+	}
+	if pageStr := c.Query("page"); pageStr != "" {
+		if page, err := strconv.Atoi(pageStr); err == nil && page > 0 {
+			filter.Page = page
+		}
+	}
+	if pageSizeStr := c.Query("page_size"); pageSizeStr != "" {
+		if pageSize, err := strconv.Atoi(pageSizeStr); err == nil && pageSize > 0 {
+			filter.PageSize = pageSize
+		}
+	}
+
+	returns, total, err := h.service.GetReturns(c.Request.Context(), connectionID, filter)
+	if err != nil {
+		h.logger.Error("Failed to get returns", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"returns":  returns,
+		"total":    total,
+		"page":     filter.Page,
+		"pageSize": filter.PageSize,
+	})
+}
+
+// GetReturn gets a single marketplace return
+// GET /api/v1/admin/marketplace/connections/:id/returns/:return_id
+func (h *ReturnHandler) GetReturn(c *gin.Context) {
+	connectionID, returnID, ok := h.parseIDs(c)
+	if !ok {
+		return
+	}
+
+	ret, err := h.service.GetReturn(c.Request.Context(), connectionID, returnID)
+	if err != nil {
+		h.respondError(c, "Failed to get return", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, ret)
+}
+
+// SyncReturnsRequest represents the request to sync returns
+type SyncReturnsRequest struct {
+	TimeFrom string `json:"time_from"` // RFC3339 format
+	TimeTo   string `json:"time_to"`   // RFC3339 format
+}
+
+// SyncReturns pulls returns from the marketplace
+// POST /api/v1/admin/marketplace/connections/:id/returns/sync
+func (h *ReturnHandler) SyncReturns(c *gin.Context) {
+	connectionID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid connection ID"})
+		return
+	}
+
+	var req SyncReturnsRequest
+	_ = c.ShouldBindJSON(&req) // Ignore binding errors, use defaults for empty fields
+
+	// Default to last 15 days, the longest window Shopee accepts
+	timeFrom := time.Now().Add(-15 * 24 * time.Hour)
+	timeTo := time.Now()
+	if req.TimeFrom != "" {
+		if timeFrom, err = time.Parse(time.RFC3339, req.TimeFrom); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid time_from format, use RFC3339"})
+			return
+		}
+	}
+	if req.TimeTo != "" {
+		if timeTo, err = time.Parse(time.RFC3339, req.TimeTo); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid time_to format, use RFC3339"})
+			return
+		}
+	}
+
+	count, err := h.service.SyncReturns(c.Request.Context(), connectionID, timeFrom, timeTo)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrConnectionNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "Connection not found"})
+		case errors.Is(err, services.ErrReturnsNotSupported):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		default:
+			h.logger.Error("Failed to sync returns", zap.Error(err))
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error":          err.Error(),
+				"returns_synced": count,
+			})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":        "Returns synced successfully",
+		"returns_synced": count,
+		"time_from":      timeFrom,
+		"time_to":        timeTo,
+	})
+}
+
+// AcceptReturn accepts a return on the marketplace
+// POST /api/v1/admin/marketplace/connections/:id/returns/:return_id/accept
+func (h *ReturnHandler) AcceptReturn(c *gin.Context) {
+	connectionID, returnID, ok := h.parseIDs(c)
+	if !ok {
+		return
+	}
+
+	ret, err := h.service.AcceptReturn(c.Request.Context(), connectionID, returnID)
+	if err != nil {
+		h.respondError(c, "Failed to accept return", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, ret)
+}
+
+// DisputeReturn disputes (Shopee) or rejects (TikTok) a return on the marketplace
+// POST /api/v1/admin/marketplace/connections/:id/returns/:return_id/dispute
+func (h *ReturnHandler) DisputeReturn(c *gin.Context) {
+	connectionID, returnID, ok := h.parseIDs(c)
+	if !ok {
+		return
+	}
+
+	var req models.DisputeReturnRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ret, err := h.service.DisputeReturn(c.Request.Context(), connectionID, returnID, &req)
+	if err != nil {
+		h.respondError(c, "Failed to dispute return", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, ret)
+}
+
+// GetRejectReasons lists the reason codes accepted when disputing a return
+// GET /api/v1/admin/marketplace/connections/:id/returns/:return_id/reject-reasons
+func (h *ReturnHandler) GetRejectReasons(c *gin.Context) {
+	connectionID, returnID, ok := h.parseIDs(c)
+	if !ok {
+		return
+	}
+
+	reasons, err := h.service.GetRejectReasons(c.Request.Context(), connectionID, returnID)
+	if err != nil {
+		h.respondError(c, "Failed to get reject reasons", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"reasons": reasons})
+}
+
+// parseIDs parses the connection and return IDs from the path
+func (h *ReturnHandler) parseIDs(c *gin.Context) (uuid.UUID, uuid.UUID, bool) {
+	connectionID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid connection ID"})
+		return uuid.Nil, uuid.Nil, false
+	}
+	returnID, err := uuid.Parse(c.Param("return_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid return ID"})
+		return uuid.Nil, uuid.Nil, false
+	}
+	return connectionID, returnID, true
+}
+
+// respondError maps return service errors to HTTP responses
+func (h *ReturnHandler) respondError(c *gin.Context, message string, err error) {
+	switch {
+	case errors.Is(err, services.ErrConnectionNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Connection not found"})
+	case errors.Is(err, services.ErrReturnNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Return not found"})
+	case errors.Is(err, services.ErrReturnNotActionable):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrReturnsNotSupported), errors.Is(err, services.ErrInvalidDispute):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		h.logger.Error(message, zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
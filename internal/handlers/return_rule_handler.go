@@ -0,0 +1,217 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"github.com/niaga-platform/service-marketplace/internal/models"
+	"github.com/niaga-platform/service-marketplace/internal/services"
+)
+
+// ReturnRuleHandler handles return rule and automatic decision API requests
+type ReturnRuleHandler struct {
+	service *services.ReturnRuleService
+	logger  *zap.Logger
+}
+
+// NewReturnRuleHandler creates a new ReturnRuleHandler
+func NewReturnRuleHandler(service *services.ReturnRuleService, logger *zap.Logger) *ReturnRuleHandler {
+	return &ReturnRuleHandler{
+		service: service,
+		logger:  logger,
+	}
+}
+
+// GetRules lists the return rules of a connection in evaluation order
+// GET /api/v1/admin/marketplace/connections/:id/return-rules
+func (h *ReturnRuleHandler) GetRules(c *gin.Context) {
+	connectionID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid connection ID"})
+		return
+	}
+
+	rules, err := h.service.GetRules(c.Request.Context(), connectionID)
+	if err != nil {
+		h.logger.Error("Failed to get return rules", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"rules": rules})
+}
+
+// CreateRule creates a return rule
+// POST /api/v1/admin/marketplace/connections/:id/return-rules
+func (h *ReturnRuleHandler) CreateRule(c *gin.Context) {
+	connectionID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid connection ID"})
+		return
+	}
+
+	var req models.ReturnRuleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	rule, err := h.service.CreateRule(c.Request.Context(), connectionID, &req)
+	if err != nil {
+		h.respondError(c, "Failed to create return rule", err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Return rule created",
+		"rule":    rule,
+	})
+}
+
+// UpdateRule replaces a return rule
+// PUT /api/v1/admin/marketplace/connections/:id/return-rules/:rule_id
+func (h *ReturnRuleHandler) UpdateRule(c *gin.Context) {
+	connectionID, ruleID, ok := h.parseRuleIDs(c)
+	if !ok {
+		return
+	}
+
+	var req models.ReturnRuleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	rule, err := h.service.UpdateRule(c.Request.Context(), connectionID, ruleID, &req)
+	if err != nil {
+		h.respondError(c, "Failed to update return rule", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Return rule updated",
+		"rule":    rule,
+	})
+}
+
+// DeleteRule deletes a return rule
+// DELETE /api/v1/admin/marketplace/connections/:id/return-rules/:rule_id
+func (h *ReturnRuleHandler) DeleteRule(c *gin.Context) {
+	connectionID, ruleID, ok := h.parseRuleIDs(c)
+	if !ok {
+		return
+	}
+
+	if err := h.service.DeleteRule(c.Request.Context(), connectionID, ruleID); err != nil {
+		h.respondError(c, "Failed to delete return rule", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Return rule deleted"})
+}
+
+// GetDecisions lists automatic return decisions; status=review is the review queue
+// GET /api/v1/admin/marketplace/connections/:id/return-decisions
+func (h *ReturnRuleHandler) GetDecisions(c *gin.Context) {
+	connectionID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid connection ID"})
+		return
+	}
+
+	filter := &models.ReturnDecisionFilter{
+		Status:   c.Query("status"),
+		Action:   c.Query("action"),
+		Page:     1,
+		PageSize: 20,
+	}
+	if pageStr := c.Query("page"); pageStr != "" {
+		if page, err := strconv.Atoi(pageStr); err == nil && page > 0 {
+			filter.Page = page
+		}
+	}
+	if pageSizeStr := c.Query("page_size"); pageSizeStr != "" {
+		if pageSize, err := strconv.Atoi(pageSizeStr); err == nil && pageSize > 0 {
+			filter.PageSize = pageSize
+		}
+	}
+
+	decisions, total, err := h.service.GetDecisions(c.Request.Context(), connectionID, filter)
+	if err != nil {
+		h.logger.Error("Failed to get return decisions", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"decisions": decisions,
+		"total":     total,
+		"page":      filter.Page,
+		"pageSize":  filter.PageSize,
+	})
+}
+
+// RevertDecision cancels the scheduled automatic decision on a return
+// POST /api/v1/admin/marketplace/connections/:id/returns/:return_id/decision/revert
+func (h *ReturnRuleHandler) RevertDecision(c *gin.Context) {
+	connectionID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid connection ID"})
+		return
+	}
+	returnID, err := uuid.Parse(c.Param("return_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid return ID"})
+		return
+	}
+
+	var req models.RevertReturnDecisionRequest
+	_ = c.ShouldBindJSON(&req) // Reason is optional
+
+	decision, err := h.service.RevertDecision(c.Request.Context(), connectionID, returnID, req.Reason)
+	if err != nil {
+		h.respondError(c, "Failed to revert return decision", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, decision)
+}
+
+// parseRuleIDs parses the connection and rule IDs from the path
+func (h *ReturnRuleHandler) parseRuleIDs(c *gin.Context) (uuid.UUID, uuid.UUID, bool) {
+	connectionID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid connection ID"})
+		return uuid.Nil, uuid.Nil, false
+	}
+	ruleID, err := uuid.Parse(c.Param("rule_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid rule ID"})
+		return uuid.Nil, uuid.Nil, false
+	}
+	return connectionID, ruleID, true
+}
+
+// respondError maps return rule service errors to HTTP responses
+func (h *ReturnRuleHandler) respondError(c *gin.Context, message string, err error) {
+	switch {
+	case errors.Is(err, services.ErrConnectionNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Connection not found"})
+	case errors.Is(err, services.ErrReturnRuleNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Return rule not found"})
+	case errors.Is(err, services.ErrReturnDecisionNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Return decision not found"})
+	case errors.Is(err, services.ErrReturnDecisionNotRevertible):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrInvalidReturnRule):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		h.logger.Error(message, zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
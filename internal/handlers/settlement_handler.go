@@ -0,0 +1,221 @@
+package handlers
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"github.com/niaga-platform/service-marketplace/internal/models"
+	"github.com/niaga-platform/service-marketplace/internal/services"
+)
+
+// SettlementHandler handles payout statement and reconciliation report API requests
+type SettlementHandler struct {
+	service *services.SettlementService
+	logger  *zap.Logger
+}
+
+// NewSettlementHandler creates a new SettlementHandler
+func NewSettlementHandler(service *services.SettlementService, logger *zap.Logger) *SettlementHandler {
+	return &SettlementHandler{
+		service: service,
+		logger:  logger,
+	}
+}
+
+// SyncSettlementsRequest represents the request to sync payout statements
+type SyncSettlementsRequest struct {
+	TimeFrom string `json:"time_from"` // RFC3339 format
+	TimeTo   string `json:"time_to"`   // RFC3339 format
+}
+
+// SyncSettlements pulls payout statement lines and matches them to orders
+// POST /api/v1/admin/marketplace/connections/:id/settlements/sync
+func (h *SettlementHandler) SyncSettlements(c *gin.Context) {
+	connectionID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid connection ID"})
+		return
+	}
+
+	var req SyncSettlementsRequest
+	_ = c.ShouldBindJSON(&req) // Ignore binding errors, use defaults for empty fields
+
+	// Default to last 7 days
+	timeFrom := time.Now().Add(-7 * 24 * time.Hour)
+	timeTo := time.Now()
+	if req.TimeFrom != "" {
+		if timeFrom, err = time.Parse(time.RFC3339, req.TimeFrom); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid time_from format, use RFC3339"})
+			return
+		}
+	}
+	if req.TimeTo != "" {
+		if timeTo, err = time.Parse(time.RFC3339, req.TimeTo); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid time_to format, use RFC3339"})
+			return
+		}
+	}
+
+	count, err := h.service.SyncSettlements(c.Request.Context(), connectionID, timeFrom, timeTo)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrConnectionNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "Connection not found"})
+		case errors.Is(err, services.ErrSettlementsNotSupported):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		default:
+			h.logger.Error("Failed to sync settlements", zap.Error(err))
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error":              err.Error(),
+				"settlements_synced": count,
+			})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":            "Settlements synced successfully",
+		"settlements_synced": count,
+		"time_from":          timeFrom,
+		"time_to":            timeTo,
+	})
+}
+
+// MatchSettlements matches unmatched payout lines to orders imported since they were synced
+// POST /api/v1/admin/marketplace/connections/:id/settlements/match
+func (h *SettlementHandler) MatchSettlements(c *gin.Context) {
+	connectionID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid connection ID"})
+		return
+	}
+
+	matched, err := h.service.MatchSettlements(c.Request.Context(), connectionID)
+	if err != nil {
+		if errors.Is(err, services.ErrConnectionNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Connection not found"})
+			return
+		}
+		h.logger.Error("Failed to match settlements", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":             "Settlements matched successfully",
+		"settlements_matched": matched,
+	})
+}
+
+// GetReport returns a reconciliation report as JSON, or as CSV with format=csv
+// GET /api/v1/admin/marketplace/connections/:id/settlements/report
+func (h *SettlementHandler) GetReport(c *gin.Context) {
+	connectionID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid connection ID"})
+		return
+	}
+
+	filter := &models.SettlementReportFilter{
+		Type: c.Query("type"),
+	}
+	if startDate := c.Query("start_date"); startDate != "" {
+		t, err := time.Parse(time.RFC3339, startDate)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid start_date format, use RFC3339"})
+			return
+		}
+		filter.StartDate = &t
+	}
+	if endDate := c.Query("end_date"); endDate != "" {
+		t, err := time.Parse(time.RFC3339, endDate)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid end_date format, use RFC3339"})
+			return
+		}
+		filter.EndDate = &t
+	}
+	if toleranceStr := c.Query("tolerance"); toleranceStr != "" {
+		tolerance, err := strconv.ParseFloat(toleranceStr, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid tolerance"})
+			return
+		}
+		filter.Tolerance = tolerance
+	}
+
+	report, err := h.service.GetReport(c.Request.Context(), connectionID, filter)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrConnectionNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "Connection not found"})
+		case errors.Is(err, services.ErrInvalidSettlementReport):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		default:
+			h.logger.Error("Failed to build settlement report", zap.Error(err))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	if c.Query("format") == "csv" {
+		h.writeCSV(c, report)
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+// writeCSV writes a settlement report as a CSV attachment
+func (h *SettlementHandler) writeCSV(c *gin.Context, report *models.SettlementReport) {
+	filename := fmt.Sprintf("%s_%s.csv", report.Type, report.GeneratedAt.Format("20060102150405"))
+	c.Header("Content-Type", "text/csv")
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	c.Status(http.StatusOK)
+
+	w := csv.NewWriter(c.Writer)
+	_ = w.Write([]string{
+		"external_order_id", "order_id", "order_status", "transaction_id", "transaction_type",
+		"expected", "settled", "difference", "fees", "currency", "date",
+	})
+	for _, entry := range report.Entries {
+		orderID := ""
+		if entry.OrderID != nil {
+			orderID = entry.OrderID.String()
+		}
+		expected := ""
+		if entry.Expected != nil {
+			expected = strconv.FormatFloat(*entry.Expected, 'f', 2, 64)
+		}
+		fees := make([]string, len(entry.Fees))
+		for i, fee := range entry.Fees {
+			fees[i] = fee.Fee + ":" + strconv.FormatFloat(fee.Difference, 'f', 2, 64)
+		}
+		_ = w.Write([]string{
+			entry.ExternalOrderID,
+			orderID,
+			entry.OrderStatus,
+			entry.TransactionID,
+			entry.TransactionType,
+			expected,
+			strconv.FormatFloat(entry.Settled, 'f', 2, 64),
+			strconv.FormatFloat(entry.Difference, 'f', 2, 64),
+			strings.Join(fees, ";"),
+			entry.Currency,
+			entry.Date.Format(time.RFC3339),
+		})
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		h.logger.Error("Failed to write settlement report CSV", zap.Error(err))
+	}
+}
@@ -0,0 +1,210 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"github.com/niaga-platform/service-marketplace/internal/models"
+	"github.com/niaga-platform/service-marketplace/internal/providers"
+	"github.com/niaga-platform/service-marketplace/internal/services"
+)
+
+// ShipmentHandler handles batch shipment, AWB printing and shipping document API requests
+type ShipmentHandler struct {
+	service         *services.ShipmentBatchService
+	documentService *services.ShippingDocumentService
+	logger          *zap.Logger
+}
+
+// NewShipmentHandler creates a new ShipmentHandler
+func NewShipmentHandler(service *services.ShipmentBatchService, documentService *services.ShippingDocumentService, logger *zap.Logger) *ShipmentHandler {
+	return &ShipmentHandler{
+		service:         service,
+		documentService: documentService,
+		logger:          logger,
+	}
+}
+
+// BatchShipRequest represents the request to arrange shipment for many orders.
+// The shipping fields are optional and apply to every order; missing ones come
+// from the connection's shipping settings, then from the first option available.
+type BatchShipRequest struct {
+	OrderIDs     []uuid.UUID `json:"order_ids" binding:"required,min=1"`
+	Method       string      `json:"method"` // dropoff or pickup
+	BranchID     int64       `json:"branch_id"`
+	AddressID    int64       `json:"address_id"`
+	PickupTimeID string      `json:"pickup_time_id"`
+}
+
+// BatchShip arranges shipment for many orders and reports the outcome per order
+// POST /api/v1/admin/marketplace/connections/:id/orders/batch-ship
+func (h *ShipmentHandler) BatchShip(c *gin.Context) {
+	connectionID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid connection ID"})
+		return
+	}
+
+	var req BatchShipRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	report, err := h.service.ArrangeShipments(c.Request.Context(), connectionID, req.OrderIDs, &providers.ShipmentSelection{
+		Method:       req.Method,
+		BranchID:     req.BranchID,
+		AddressID:    req.AddressID,
+		PickupTimeID: req.PickupTimeID,
+	})
+	if err != nil {
+		h.respondError(c, "Failed to arrange shipment batch", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+// BatchAWBRequest represents the request to print the AWBs of many orders
+type BatchAWBRequest struct {
+	OrderIDs     []uuid.UUID `json:"order_ids" binding:"required,min=1"`
+	DocumentType string      `json:"document_type"` // NORMAL_AIR_WAYBILL, THERMAL_AIR_WAYBILL
+}
+
+// BatchAWB creates the AWBs of many orders and returns them merged into one
+// PDF, base64 encoded in "document", with the outcome per order
+// POST /api/v1/admin/marketplace/connections/:id/orders/batch-awb
+func (h *ShipmentHandler) BatchAWB(c *gin.Context) {
+	connectionID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid connection ID"})
+		return
+	}
+
+	var req BatchAWBRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	report, err := h.service.PrintShippingDocuments(c.Request.Context(), connectionID, req.OrderIDs, req.DocumentType)
+	if err != nil {
+		h.respondError(c, "Failed to print shipping documents", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+// GetAWBRequest represents the request to get the AWB of an order
+type GetAWBRequest struct {
+	DocumentType string `json:"document_type"` // NORMAL_AIR_WAYBILL, THERMAL_AIR_WAYBILL
+	Regenerate   bool   `json:"regenerate"`    // Download a new version even if one is stored
+}
+
+// GetAWB stores the AWB of an order, unless already stored, and returns where to download it
+// POST /api/v1/admin/marketplace/connections/:id/orders/:order_id/awb
+func (h *ShipmentHandler) GetAWB(c *gin.Context) {
+	orderID, err := uuid.Parse(c.Param("order_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid order ID"})
+		return
+	}
+
+	var req GetAWBRequest
+	_ = c.ShouldBindJSON(&req) // Optional, use default if not provided
+
+	document, err := h.documentService.GetDocument(c.Request.Context(), orderID, req.DocumentType, req.Regenerate)
+	if err != nil {
+		h.respondError(c, "Failed to get AWB", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"document":      document,
+		"download_url":  documentURL(c.Param("id"), document),
+		"document_type": document.DocumentType,
+	})
+}
+
+// GetDocuments lists the stored shipping documents of an order with their print history
+// GET /api/v1/admin/marketplace/connections/:id/orders/:order_id/documents
+func (h *ShipmentHandler) GetDocuments(c *gin.Context) {
+	orderID, err := uuid.Parse(c.Param("order_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid order ID"})
+		return
+	}
+
+	documents, err := h.documentService.GetDocuments(c.Request.Context(), orderID)
+	if err != nil {
+		h.respondError(c, "Failed to get shipping documents", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"documents": documents,
+		"total":     len(documents),
+	})
+}
+
+// DownloadDocument serves a stored shipping document and records the print
+// GET /api/v1/admin/marketplace/connections/:id/orders/:order_id/documents/:document_id/file
+func (h *ShipmentHandler) DownloadDocument(c *gin.Context) {
+	orderID, err := uuid.Parse(c.Param("order_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid order ID"})
+		return
+	}
+	documentID, err := uuid.Parse(c.Param("document_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid document ID"})
+		return
+	}
+
+	document, reader, err := h.documentService.OpenDocument(c.Request.Context(), orderID, documentID, c.ClientIP())
+	if err != nil {
+		h.respondError(c, "Failed to open shipping document", err)
+		return
+	}
+	defer reader.Close()
+
+	filename := fmt.Sprintf("%s_%s.pdf", document.DocumentType, document.GeneratedAt.Format("20060102150405"))
+	c.Header("Content-Type", document.ContentType)
+	c.Header("Content-Length", strconv.FormatInt(document.Size, 10))
+	c.Header("Content-Disposition", fmt.Sprintf("inline; filename=%q", filename))
+	c.Status(http.StatusOK)
+	if _, err := io.Copy(c.Writer, reader); err != nil {
+		h.logger.Error("Failed to write shipping document", zap.Error(err))
+	}
+}
+
+// documentURL returns the path a stored shipping document is served from
+func documentURL(connectionID string, document *models.ShippingDocument) string {
+	return fmt.Sprintf("/api/v1/admin/marketplace/connections/%s/orders/%s/documents/%s/file", connectionID, document.OrderID, document.ID)
+}
+
+// respondError maps shipment and shipping document errors to HTTP responses
+func (h *ShipmentHandler) respondError(c *gin.Context, message string, err error) {
+	switch {
+	case errors.Is(err, services.ErrConnectionNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Connection not found"})
+	case errors.Is(err, services.ErrOrderNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Order not found"})
+	case errors.Is(err, services.ErrShippingDocumentNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrInvalidShipmentBatch), errors.Is(err, services.ErrShipmentNotSupported),
+		errors.Is(err, services.ErrShippingDocumentsNotSupported):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		h.logger.Error(message, zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
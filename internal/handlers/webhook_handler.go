@@ -1,40 +1,36 @@
 package handlers
 
 import (
-	"crypto/hmac"
-	"crypto/sha256"
-	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"io"
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 	"go.uber.org/zap"
 
+	"github.com/niaga-platform/service-marketplace/internal/models"
 	"github.com/niaga-platform/service-marketplace/internal/services"
 )
 
+// maxWebhookEventsPageSize caps page_size when listing stored events, which
+// carry their raw payloads
+const maxWebhookEventsPageSize = 100
+
 // WebhookHandler handles incoming webhooks from marketplaces
 type WebhookHandler struct {
-	orderService *services.OrderSyncService
-	shopeeKey    string
-	tiktokSecret string
-	logger       *zap.Logger
-}
-
-// WebhookConfig holds configuration for webhook handlers
-type WebhookConfig struct {
-	ShopeePartnerKey string
-	TikTokAppSecret  string
+	webhookService *services.WebhookService
+	logger         *zap.Logger
 }
 
 // NewWebhookHandler creates a new WebhookHandler
-func NewWebhookHandler(orderService *services.OrderSyncService, cfg *WebhookConfig, logger *zap.Logger) *WebhookHandler {
+func NewWebhookHandler(webhookService *services.WebhookService, logger *zap.Logger) *WebhookHandler {
 	return &WebhookHandler{
-		orderService: orderService,
-		shopeeKey:    cfg.ShopeePartnerKey,
-		tiktokSecret: cfg.TikTokAppSecret,
-		logger:       logger,
+		webhookService: webhookService,
+		logger:         logger,
 	}
 }
 
@@ -67,125 +63,235 @@ func (h *WebhookHandler) HandleShopeeWebhook(c *gin.Context) {
 		return
 	}
 
-	// Verify signature if key is configured
-	if h.shopeeKey != "" {
-		signature := c.GetHeader("Authorization")
-		h.logger.Info("Shopee signature check",
-			zap.String("authorization_header", signature),
-			zap.Bool("has_key", h.shopeeKey != ""),
+	h.receive(c, "shopee", body)
+}
+
+// HandleTikTokWebhook handles incoming TikTok webhooks
+func (h *WebhookHandler) HandleTikTokWebhook(c *gin.Context) {
+	// Read body
+	body, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		h.logger.Error("Failed to read webhook body", zap.Error(err))
+		c.JSON(http.StatusBadRequest, gin.H{"error": "failed to read body"})
+		return
+	}
+
+	h.receive(c, "tiktok", body)
+}
+
+// receive verifies a delivery, stores it and acknowledges it. The event is
+// stored before acknowledging so it survives a crash during processing.
+func (h *WebhookHandler) receive(c *gin.Context, platform string, body []byte) {
+	headers := make(map[string]string, len(c.Request.Header))
+	for key := range c.Request.Header {
+		headers[key] = c.Request.Header.Get(key)
+	}
+
+	verification := h.webhookService.Verify(c.Request.Context(), platform, body, headers)
+	if !h.webhookService.Accepts(verification) {
+		h.logger.Warn("Rejected webhook",
+			zap.String("platform", platform),
+			zap.String("reason", verification.Reason),
+			zap.String("remote_addr", c.ClientIP()),
 		)
-		if signature != "" && !h.verifyShopeeSignature(body, signature) {
-			h.logger.Warn("Invalid Shopee webhook signature",
-				zap.String("received_signature", signature),
-			)
-			// Don't reject - just log warning for now
+		if _, err := h.webhookService.Reject(c.Request.Context(), platform, body, verification); err != nil {
+			h.logger.Error("Failed to store rejected webhook", zap.Error(err))
 		}
+		c.JSON(http.StatusUnauthorized, gin.H{"error": verification.Reason})
+		return
 	}
-
-	// Parse event
-	var event struct {
-		Code      int   `json:"code"`
-		ShopID    int64 `json:"shop_id"`
-		Timestamp int64 `json:"timestamp"`
-		Data      struct {
-			OrderSN string `json:"ordersn"`
-			Status  string `json:"status"`
-		} `json:"data"`
+	if verification.Status == models.WebhookVerificationFailed {
+		h.logger.Warn("Webhook failed verification, processing in grace mode",
+			zap.String("platform", platform),
+			zap.String("reason", verification.Reason),
+		)
 	}
 
-	if err := json.Unmarshal(body, &event); err != nil {
-		h.logger.Error("Failed to parse Shopee webhook", zap.Error(err), zap.String("body", string(body)))
-		// Still return 200 to acknowledge receipt
-		c.JSON(http.StatusOK, gin.H{"status": "received", "warning": "parse error"})
+	event, duplicate, err := h.webhookService.Receive(c.Request.Context(), platform, body, verification)
+	if err != nil {
+		h.logger.Error("Failed to store webhook", zap.String("platform", platform), zap.Error(err))
+		// Non-2xx makes the marketplace retry the delivery
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to store webhook"})
 		return
 	}
 
-	h.logger.Info("Received Shopee webhook event",
-		zap.Int("code", event.Code),
-		zap.Int64("shop_id", event.ShopID),
-		zap.String("order_sn", event.Data.OrderSN),
-		zap.String("status", event.Data.Status),
+	h.logger.Info("Received webhook event",
+		zap.String("platform", platform),
+		zap.String("event_id", event.ID.String()),
+		zap.String("event_type", event.EventType),
+		zap.String("shop_id", event.ShopID),
+		zap.Bool("duplicate", duplicate),
 	)
 
-	// Process order event
-	if event.Data.OrderSN != "" {
-		go h.orderService.HandleShopeeOrderEvent(event.ShopID, event.Data.OrderSN, event.Data.Status)
+	if duplicate {
+		c.JSON(http.StatusOK, gin.H{"status": "duplicate"})
+		return
 	}
-
 	c.JSON(http.StatusOK, gin.H{"status": "received"})
 }
 
-func (h *WebhookHandler) verifyShopeeSignature(body []byte, signature string) bool {
-	if signature == "" {
-		return false
+// ListWebhookEvents lists stored webhook events
+// GET /api/v1/admin/marketplace/webhooks/events
+func (h *WebhookHandler) ListWebhookEvents(c *gin.Context) {
+	filter := &models.WebhookEventFilter{
+		Platform:  c.Query("platform"),
+		EventType: c.Query("event_type"),
+		ShopID:    c.Query("shop_id"),
+		Search:    c.Query("search"),
+		Page:      1,
+		PageSize:  20,
+	}
+
+	if processedStr := c.Query("processed"); processedStr != "" {
+		processed, err := strconv.ParseBool(processedStr)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid processed value"})
+			return
+		}
+		filter.Processed = &processed
+	}
+	if startDateStr := c.Query("start_date"); startDateStr != "" {
+		startDate, err := time.Parse("2006-01-02", startDateStr)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid start_date format"})
+			return
+		}
+		filter.StartDate = &startDate
+	}
+	if endDateStr := c.Query("end_date"); endDateStr != "" {
+		endDate, err := time.Parse("2006-01-02", endDateStr)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid end_date format"})
+			return
+		}
+		// Include the whole end day
+		endDate = endDate.Add(24*time.Hour - time.Nanosecond)
+		filter.EndDate = &endDate
+	}
+	if pageStr := c.Query("page"); pageStr != "" {
+		if page, err := strconv.Atoi(pageStr); err == nil && page > 0 {
+			filter.Page = page
+		}
+	}
+	if pageSizeStr := c.Query("page_size"); pageSizeStr != "" {
+		if pageSize, err := strconv.Atoi(pageSizeStr); err == nil && pageSize > 0 {
+			filter.PageSize = min(pageSize, maxWebhookEventsPageSize)
+		}
 	}
 
-	mac := hmac.New(sha256.New, []byte(h.shopeeKey))
-	mac.Write(body)
-	expected := hex.EncodeToString(mac.Sum(nil))
+	events, total, err := h.webhookService.ListEvents(c.Request.Context(), filter)
+	if err != nil {
+		h.logger.Error("Failed to list webhook events", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
 
-	return hmac.Equal([]byte(expected), []byte(signature))
+	c.JSON(http.StatusOK, gin.H{
+		"events":   events,
+		"total":    total,
+		"page":     filter.Page,
+		"pageSize": filter.PageSize,
+	})
 }
 
-// HandleTikTokWebhook handles incoming TikTok webhooks
-func (h *WebhookHandler) HandleTikTokWebhook(c *gin.Context) {
-	// Read body
-	body, err := io.ReadAll(c.Request.Body)
+// GetWebhookEvent returns a stored webhook event with its payload and replay history
+// GET /api/v1/admin/marketplace/webhooks/events/:event_id
+func (h *WebhookHandler) GetWebhookEvent(c *gin.Context) {
+	eventID, err := uuid.Parse(c.Param("event_id"))
 	if err != nil {
-		h.logger.Error("Failed to read webhook body", zap.Error(err))
-		c.JSON(http.StatusBadRequest, gin.H{"error": "failed to read body"})
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid event ID"})
 		return
 	}
 
-	// Verify signature if secret is configured
-	if h.tiktokSecret != "" {
-		signature := c.GetHeader("X-Tts-Signature")
-		if !h.verifyTikTokSignature(body, signature) {
-			h.logger.Warn("Invalid TikTok webhook signature")
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid signature"})
+	event, replays, err := h.webhookService.GetEvent(c.Request.Context(), eventID)
+	if err != nil {
+		if errors.Is(err, services.ErrWebhookEventNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Webhook event not found"})
 			return
 		}
+		h.logger.Error("Failed to get webhook event", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
 	}
 
-	// Parse event
-	var event struct {
-		Type      string `json:"type"`
-		ShopID    string `json:"shop_id"`
-		Timestamp int64  `json:"timestamp"`
-		Data      struct {
-			OrderID     string `json:"order_id"`
-			OrderStatus int    `json:"order_status"`
-		} `json:"data"`
+	c.JSON(http.StatusOK, gin.H{
+		"event":   event,
+		"replays": replays,
+	})
+}
+
+// ReplayWebhookEvent re-runs a single stored webhook event
+// POST /api/v1/admin/marketplace/webhooks/events/:event_id/replay
+func (h *WebhookHandler) ReplayWebhookEvent(c *gin.Context) {
+	eventID, err := uuid.Parse(c.Param("event_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid event ID"})
+		return
 	}
 
-	if err := json.Unmarshal(body, &event); err != nil {
-		h.logger.Error("Failed to parse TikTok webhook", zap.Error(err))
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"})
+	result, err := h.webhookService.ReplayEvent(c.Request.Context(), eventID)
+	if err != nil {
+		if errors.Is(err, services.ErrWebhookEventNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Webhook event not found"})
+			return
+		}
+		h.logger.Error("Failed to replay webhook event", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	h.logger.Info("Received TikTok webhook",
-		zap.String("type", event.Type),
-		zap.String("shop_id", event.ShopID),
-		zap.String("order_id", event.Data.OrderID),
-	)
+	c.JSON(http.StatusOK, result)
+}
 
-	// Process order event
-	if event.Type == "ORDER_STATUS_CHANGE" && event.Data.OrderID != "" {
-		go h.orderService.HandleTikTokOrderEvent(event.ShopID, event.Data.OrderID, event.Data.OrderStatus)
+// ReplayWebhookEvents re-runs several stored webhook events
+// POST /api/v1/admin/marketplace/webhooks/replay
+func (h *WebhookHandler) ReplayWebhookEvents(c *gin.Context) {
+	var req models.ReplayWebhookEventsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"status": "received"})
+	results, err := h.webhookService.Replay(c.Request.Context(), req.EventIDs)
+	if err != nil {
+		h.logger.Error("Failed to replay webhook events", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	successCount := 0
+	for _, r := range results {
+		if r.Success {
+			successCount++
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":       "Webhook replay completed",
+		"success_count": successCount,
+		"total_count":   len(results),
+		"results":       results,
+	})
 }
 
-func (h *WebhookHandler) verifyTikTokSignature(body []byte, signature string) bool {
-	if signature == "" {
-		return false
+// MarkWebhookEventsProcessed flags events as handled without running them
+// POST /api/v1/admin/marketplace/webhooks/mark-processed
+func (h *WebhookHandler) MarkWebhookEventsProcessed(c *gin.Context) {
+	var req models.MarkProcessedRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
 	}
 
-	mac := hmac.New(sha256.New, []byte(h.tiktokSecret))
-	mac.Write(body)
-	expected := hex.EncodeToString(mac.Sum(nil))
+	updated, err := h.webhookService.MarkProcessed(c.Request.Context(), &req)
+	if err != nil {
+		h.logger.Error("Failed to mark webhook events processed", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
 
-	return hmac.Equal([]byte(expected), []byte(signature))
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Webhook events marked as processed",
+		"updated": updated,
+	})
 }
@@ -1,6 +1,7 @@
 package models
 
 import (
+	"encoding/json"
 	"time"
 
 	"github.com/google/uuid"
@@ -33,6 +34,58 @@ func (Connection) TableName() string {
 	return "marketplace.connections"
 }
 
+// ConnectionSettings holds per-connection behaviour stored in Connection.Settings
+type ConnectionSettings struct {
+	Inventory InventorySettings `json:"inventory"`
+	Returns   ReturnSettings    `json:"returns"`
+	Shipping  ShippingSettings  `json:"shipping"`
+}
+
+// InventorySettings controls how catalog stock is allocated to a marketplace
+type InventorySettings struct {
+	AllocationPercent int  `json:"allocation_percent,omitempty"` // Share of catalog stock offered on this channel (0 means 100)
+	SafetyBuffer      int  `json:"safety_buffer,omitempty"`      // Units held back from this channel
+	AutoCorrect       bool `json:"auto_correct,omitempty"`       // Reconciliation pushes expected stock when drift is found
+}
+
+// ReturnSettings controls automatic return decisions and what happens in our
+// systems when a marketplace return is refunded
+type ReturnSettings struct {
+	Restock            bool   `json:"restock,omitempty"`              // Publish a restock event for returned items
+	RestockWarehouseID string `json:"restock_warehouse_id,omitempty"` // Warehouse returned items go back to (inventory service default if empty)
+	RefundOrder        bool   `json:"refund_order,omitempty"`         // Mark the internal order refunded in service-order
+	DecisionHoldHours  int    `json:"decision_hold_hours,omitempty"`  // How long automatic decisions wait before executing (0 means 24)
+}
+
+// ShippingSettings holds the defaults used when arranging shipment without an
+// explicit choice. Defaults no longer offered by the marketplace are skipped.
+type ShippingSettings struct {
+	Method          string `json:"method,omitempty"`            // "dropoff" or "pickup" (dropoff first when empty)
+	PickupAddressID int64  `json:"pickup_address_id,omitempty"` // Preferred pickup address (marketplace default address when empty)
+	DropoffBranchID int64  `json:"dropoff_branch_id,omitempty"` // Preferred dropoff branch
+}
+
+// AllocateStock applies the allocation rules to a catalog quantity
+func (s InventorySettings) AllocateStock(quantity int) int {
+	if s.AllocationPercent > 0 && s.AllocationPercent < 100 {
+		quantity = quantity * s.AllocationPercent / 100
+	}
+	quantity -= s.SafetyBuffer
+	if quantity < 0 {
+		return 0
+	}
+	return quantity
+}
+
+// GetSettings decodes the connection settings, falling back to defaults
+func (c *Connection) GetSettings() ConnectionSettings {
+	var settings ConnectionSettings
+	if len(c.Settings) > 0 {
+		_ = json.Unmarshal(c.Settings, &settings)
+	}
+	return settings
+}
+
 // ConnectionResponse represents a connection response without sensitive data
 type ConnectionResponse struct {
 	ID             uuid.UUID      `json:"id"`
@@ -0,0 +1,39 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// InventoryReconciliationReport summarises a reconciliation run for a connection.
+// It is stored as the payload of an inventory_reconcile sync job.
+type InventoryReconciliationReport struct {
+	ConnectionID    uuid.UUID        `json:"connection_id"`
+	AutoCorrect     bool             `json:"auto_correct"`
+	MappingsChecked int              `json:"mappings_checked"`
+	DriftCount      int              `json:"drift_count"`
+	CorrectedCount  int              `json:"corrected_count"`
+	ErrorCount      int              `json:"error_count"`
+	Drifts          []InventoryDrift `json:"drifts"`
+	Errors          []string         `json:"errors,omitempty"`
+	StartedAt       time.Time        `json:"started_at"`
+	CompletedAt     *time.Time       `json:"completed_at,omitempty"`
+}
+
+// InventoryDrift describes a listing whose marketplace stock differs from the catalog
+type InventoryDrift struct {
+	ProductMappingID    uuid.UUID      `json:"product_mapping_id"`
+	InternalProductID   uuid.UUID      `json:"internal_product_id"`
+	InternalVariantID   *uuid.UUID     `json:"internal_variant_id,omitempty"`
+	ExternalProductID   string         `json:"external_product_id"`
+	ExternalVariantID   string         `json:"external_variant_id,omitempty"` // Marketplace model or SKU ID, empty for the whole listing
+	ExternalSKU         string         `json:"external_sku,omitempty"`        // Seller SKU of the listing or variant
+	CatalogQuantity     int            `json:"catalog_quantity"`
+	ExpectedQuantity    int            `json:"expected_quantity"` // Catalog quantity after allocation rules and reservations
+	MarketplaceQuantity int            `json:"marketplace_quantity"`
+	Difference          int            `json:"difference"`          // Marketplace minus expected
+	Locations           map[string]int `json:"locations,omitempty"` // Expected quantity per marketplace location
+	Corrected           bool           `json:"corrected"`
+	Error               string         `json:"error,omitempty"`
+}
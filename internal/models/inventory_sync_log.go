@@ -0,0 +1,56 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// InventorySyncLog records a single stock push to a marketplace listing
+type InventorySyncLog struct {
+	ID                 uuid.UUID  `gorm:"type:uuid;primaryKey;default:gen_random_uuid()" json:"id"`
+	ConnectionID       uuid.UUID  `gorm:"type:uuid;not null" json:"connection_id"`
+	ProductMappingID   *uuid.UUID `gorm:"type:uuid" json:"product_mapping_id,omitempty"`
+	InternalProductID  *uuid.UUID `gorm:"type:uuid" json:"internal_product_id,omitempty"`
+	ExternalProductID  string     `gorm:"type:varchar(100)" json:"external_product_id"`
+	ExternalSKU        string     `gorm:"type:varchar(100)" json:"external_sku,omitempty"`
+	ExternalLocationID string     `gorm:"type:varchar(100)" json:"external_location_id,omitempty"`
+	PreviousQuantity   *int       `json:"previous_quantity"`
+	NewQuantity        int        `gorm:"not null" json:"new_quantity"`
+	Source             string     `gorm:"type:varchar(50);not null;default:'event'" json:"source"` // event, manual, reconciliation
+	SourceReference    string     `gorm:"type:varchar(255)" json:"source_reference,omitempty"`
+	SyncStatus         string     `gorm:"type:varchar(50);default:'pending'" json:"sync_status"` // pending, success, failed
+	ErrorMessage       string     `gorm:"type:text" json:"error_message,omitempty"`
+	CreatedAt          time.Time  `gorm:"autoCreateTime" json:"created_at"`
+}
+
+// TableName specifies the table name for InventorySyncLog
+func (InventorySyncLog) TableName() string {
+	return "marketplace.inventory_sync_logs"
+}
+
+// Inventory sync source constants
+const (
+	InventorySyncSourceEvent          = "event"
+	InventorySyncSourceManual         = "manual"
+	InventorySyncSourceReconciliation = "reconciliation"
+)
+
+// Inventory sync status constants
+const (
+	InventorySyncStatusPending = "pending"
+	InventorySyncStatusSuccess = "success"
+	InventorySyncStatusFailed  = "failed"
+)
+
+// InventorySyncLogFilter represents filter options for inventory sync logs
+type InventorySyncLogFilter struct {
+	InternalProductID *uuid.UUID `json:"internal_product_id"`
+	ExternalProductID string     `json:"external_product_id"`
+	Source            string     `json:"source"`
+	SyncStatus        string     `json:"sync_status"`
+	StartDate         *time.Time `json:"start_date"`
+	EndDate           *time.Time `json:"end_date"`
+	Page              int        `json:"page"`
+	PageSize          int        `json:"page_size"`
+}
@@ -5,24 +5,36 @@ import (
 
 	"github.com/google/uuid"
 	"gorm.io/datatypes"
+
+	"github.com/niaga-platform/service-marketplace/internal/domain/shared"
 )
 
 // MarketplaceOrder represents an order received from a marketplace
 type MarketplaceOrder struct {
-	ID              uuid.UUID      `gorm:"type:uuid;primaryKey;default:gen_random_uuid()" json:"id"`
-	ConnectionID    uuid.UUID      `gorm:"type:uuid" json:"connection_id"`
-	InternalOrderID *uuid.UUID     `gorm:"type:uuid" json:"internal_order_id"` // Linked order in service-order
-	ExternalOrderID string         `gorm:"type:varchar(100);not null" json:"external_order_id"`
-	Platform        string         `gorm:"type:varchar(50);not null" json:"platform"`
-	Status          string         `gorm:"type:varchar(50);not null" json:"status"` // Platform-specific status
-	OrderData       datatypes.JSON `gorm:"type:jsonb;not null" json:"order_data"`
-	ShippingInfo    datatypes.JSON `gorm:"type:jsonb" json:"shipping_info"`
-	BuyerInfo       datatypes.JSON `gorm:"type:jsonb" json:"buyer_info"`
-	TotalAmount     float64        `gorm:"type:decimal(12,2)" json:"total_amount"`
-	Currency        string         `gorm:"type:varchar(10);default:'MYR'" json:"currency"`
-	SyncedAt        *time.Time     `gorm:"type:timestamptz" json:"synced_at"`
-	CreatedAt       time.Time      `gorm:"autoCreateTime" json:"created_at"`
-	UpdatedAt       time.Time      `gorm:"autoUpdateTime" json:"updated_at"`
+	ID                   uuid.UUID      `gorm:"type:uuid;primaryKey;default:gen_random_uuid()" json:"id"`
+	ConnectionID         uuid.UUID      `gorm:"type:uuid" json:"connection_id"`
+	InternalOrderID      *uuid.UUID     `gorm:"type:uuid" json:"internal_order_id"` // Linked order in service-order
+	ExternalOrderID      string         `gorm:"type:varchar(100);not null" json:"external_order_id"`
+	Platform             string         `gorm:"type:varchar(50);not null" json:"platform"`
+	Status               string         `gorm:"type:varchar(50);not null" json:"status"` // Canonical shared.OrderStatus
+	PlatformStatus       string         `gorm:"type:varchar(50)" json:"platform_status"` // Raw status reported by the platform
+	OrderData            datatypes.JSON `gorm:"type:jsonb;not null" json:"order_data"`
+	ShippingInfo         datatypes.JSON `gorm:"type:jsonb" json:"shipping_info"`
+	BuyerInfo            datatypes.JSON `gorm:"type:jsonb" json:"buyer_info"`
+	TotalAmount          float64        `gorm:"type:decimal(12,2)" json:"total_amount"`
+	Currency             string         `gorm:"type:varchar(10);default:'MYR'" json:"currency"`
+	UnresolvedItems      int            `gorm:"not null;default:0" json:"unresolved_items"`          // Lines not matched to an internal product or variant
+	ServiceOrderPending  bool           `gorm:"not null;default:false" json:"service_order_pending"` // Created in service-order once every line resolves
+	NetPayout            *float64       `gorm:"type:decimal(12,2)" json:"net_payout"`                // Amount the marketplace pays out, once known
+	FinancialsAt         *time.Time     `gorm:"type:timestamptz" json:"financials_at"`               // Last fetch of the escrow or settlement breakdown
+	FulfillmentSync      string         `gorm:"type:varchar(20)" json:"fulfillment_sync,omitempty"`  // Result of shipping the order from a service-order event: synced, failed
+	FulfillmentSyncError string         `gorm:"type:text" json:"fulfillment_sync_error,omitempty"`   // Why shipping on the marketplace failed
+	FulfillmentSyncedAt  *time.Time     `gorm:"type:timestamptz" json:"fulfillment_synced_at"`       // Last attempt to ship the order from an event
+	FulfillmentTracking  datatypes.JSON `gorm:"type:jsonb" json:"fulfillment_tracking,omitempty"`    // Tracking from the event, kept to retry a failed sync
+	FulfillmentAttempts  int            `gorm:"not null;default:0" json:"fulfillment_attempts"`      // Failed attempts since the last sync
+	SyncedAt             *time.Time     `gorm:"type:timestamptz" json:"synced_at"`
+	CreatedAt            time.Time      `gorm:"autoCreateTime" json:"created_at"`
+	UpdatedAt            time.Time      `gorm:"autoUpdateTime" json:"updated_at"`
 
 	// Relations
 	Connection *Connection `gorm:"foreignKey:ConnectionID" json:"connection,omitempty"`
@@ -33,24 +45,66 @@ func (MarketplaceOrder) TableName() string {
 	return "marketplace.orders"
 }
 
-// Order status constants (platform-specific statuses will vary)
+// Order status constants; see shared.OrderStatus for the allowed transitions
+const (
+	OrderStatusUnpaid    = string(shared.OrderUnpaid)
+	OrderStatusToShip    = string(shared.OrderToShip)
+	OrderStatusShipped   = string(shared.OrderShipped)
+	OrderStatusDelivered = string(shared.OrderDelivered)
+	OrderStatusCompleted = string(shared.OrderCompleted)
+	OrderStatusCancelled = string(shared.OrderCancelled)
+	OrderStatusReturned  = string(shared.OrderReturned)
+)
+
+// OrderStatusRefunded is the service-order status set when a marketplace return is refunded
+const OrderStatusRefunded = "refunded"
+
+// Order status change sources
+const (
+	OrderStatusSourceWebhook     = "webhook"
+	OrderStatusSourcePoll        = "poll"
+	OrderStatusSourceAdmin       = "admin"
+	OrderStatusSourceFulfillment = "fulfillment" // Shipped by the warehouse in service-order
+)
+
+// Fulfillment sync results
 const (
-	OrderStatusPending   = "pending"
-	OrderStatusConfirmed = "confirmed"
-	OrderStatusShipped   = "shipped"
-	OrderStatusDelivered = "delivered"
-	OrderStatusCancelled = "cancelled"
-	OrderStatusRefunded  = "refunded"
-	OrderStatusReturned  = "returned"
+	FulfillmentSyncSynced = "synced"
+	FulfillmentSyncFailed = "failed"
 )
 
-// OrderDataJSON represents the structure stored in order_data
+// OrderStatusHistory records a change of an order's canonical status
+type OrderStatusHistory struct {
+	ID             uuid.UUID `gorm:"type:uuid;primaryKey;default:gen_random_uuid()" json:"id"`
+	OrderID        uuid.UUID `gorm:"type:uuid;not null" json:"order_id"`
+	FromStatus     string    `gorm:"type:varchar(50)" json:"from_status"` // Empty for the first status of the order
+	ToStatus       string    `gorm:"type:varchar(50);not null" json:"to_status"`
+	PlatformStatus string    `gorm:"type:varchar(50)" json:"platform_status"`
+	Source         string    `gorm:"type:varchar(20);not null" json:"source"` // webhook, poll, admin, fulfillment
+	Note           string    `gorm:"type:text" json:"note,omitempty"`
+	CreatedAt      time.Time `gorm:"autoCreateTime" json:"created_at"`
+}
+
+// TableName specifies the table name for OrderStatusHistory
+func (OrderStatusHistory) TableName() string {
+	return "marketplace.order_status_history"
+}
+
+// OrderDataJSON represents the structure stored in order_data. Fee, voucher
+// and payout amounts come from the Shopee escrow or TikTok Shop settlements.
 type OrderDataJSON struct {
 	Items           []OrderItemJSON `json:"items"`
 	SubtotalAmount  float64         `json:"subtotal_amount"`
 	ShippingFee     float64         `json:"shipping_fee"`
 	DiscountAmount  float64         `json:"discount_amount"`
-	PlatformFee     float64         `json:"platform_fee"`
+	SellerVoucher   float64         `json:"seller_voucher"`
+	PlatformVoucher float64         `json:"platform_voucher"`
+	Coins           float64         `json:"coins"`
+	CommissionFee   float64         `json:"commission_fee"`
+	TransactionFee  float64         `json:"transaction_fee"`
+	ServiceFee      float64         `json:"service_fee"`
+	PlatformFee     float64         `json:"platform_fee"` // Commission, transaction and service fees
+	NetPayout       float64         `json:"net_payout"`
 	PaymentMethod   string          `json:"payment_method"`
 	Notes           string          `json:"notes"`
 	MarketplaceData interface{}     `json:"marketplace_data"` // Platform-specific data
@@ -59,13 +113,17 @@ type OrderDataJSON struct {
 // OrderItemJSON represents an item in the order
 type OrderItemJSON struct {
 	ExternalProductID string  `json:"external_product_id"`
+	ExternalVariantID string  `json:"external_variant_id,omitempty"`
 	InternalProductID string  `json:"internal_product_id,omitempty"`
+	InternalVariantID string  `json:"internal_variant_id,omitempty"`
 	SKU               string  `json:"sku"`
 	Name              string  `json:"name"`
 	Quantity          int     `json:"quantity"`
 	UnitPrice         float64 `json:"unit_price"`
+	OriginalPrice     float64 `json:"original_price,omitempty"` // Unit price before discounts; 0 when unknown
 	TotalPrice        float64 `json:"total_price"`
 	VariantName       string  `json:"variant_name,omitempty"`
+	Unresolved        bool    `json:"unresolved,omitempty"` // No product or variant mapping matched the line
 }
 
 // BuyerInfoJSON represents buyer information
@@ -88,6 +146,7 @@ type ShippingInfoJSON struct {
 	Country        string `json:"country"`
 	Courier        string `json:"courier,omitempty"`
 	TrackingNumber string `json:"tracking_number,omitempty"`
+	ShippingStatus string `json:"shipping_status,omitempty"` // Latest logistics status reported by the marketplace
 }
 
 // MarketplaceOrderFilter represents filter options for marketplace orders
@@ -96,7 +155,9 @@ type MarketplaceOrderFilter struct {
 	Platform        string     `json:"platform"`
 	Status          string     `json:"status"`
 	ExternalOrderID string     `json:"external_order_id"`
-	ImportedOnly    *bool      `json:"imported_only"` // Only orders with internal_order_id
+	ImportedOnly    *bool      `json:"imported_only"`    // Only orders with internal_order_id
+	Unresolved      *bool      `json:"unresolved"`       // Only orders with unresolved lines
+	FulfillmentSync string     `json:"fulfillment_sync"` // Only orders with this fulfillment sync result
 	StartDate       *time.Time `json:"start_date"`
 	EndDate         *time.Time `json:"end_date"`
 	Page            int        `json:"page"`
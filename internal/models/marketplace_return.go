@@ -0,0 +1,72 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/datatypes"
+)
+
+// MarketplaceReturn represents a return/refund request raised by a buyer on a marketplace
+type MarketplaceReturn struct {
+	ID                uuid.UUID      `gorm:"type:uuid;primaryKey;default:gen_random_uuid()" json:"id"`
+	ConnectionID      uuid.UUID      `gorm:"type:uuid;not null" json:"connection_id"`
+	OrderID           *uuid.UUID     `gorm:"type:uuid" json:"order_id"` // Linked marketplace order, if imported
+	Platform          string         `gorm:"type:varchar(50);not null" json:"platform"`
+	ExternalReturnID  string         `gorm:"type:varchar(100);not null" json:"external_return_id"`
+	ExternalOrderID   string         `gorm:"type:varchar(100);not null" json:"external_order_id"`
+	ReturnType        string         `gorm:"type:varchar(50)" json:"return_type,omitempty"` // providers.ReturnType* value
+	Status            string         `gorm:"type:varchar(50);not null" json:"status"`       // providers.ReturnStatus* value
+	PlatformStatus    string         `gorm:"type:varchar(100)" json:"platform_status,omitempty"`
+	Reason            string         `gorm:"type:varchar(100)" json:"reason,omitempty"`
+	ReasonText        string         `gorm:"type:text" json:"reason_text,omitempty"`
+	RefundAmount      float64        `gorm:"type:decimal(12,2);not null;default:0" json:"refund_amount"`
+	Currency          string         `gorm:"type:varchar(10);default:'MYR'" json:"currency"`
+	NeedsLogistics    bool           `gorm:"not null;default:false" json:"needs_logistics"`
+	TrackingNumber    string         `gorm:"type:varchar(100)" json:"tracking_number,omitempty"`
+	BuyerID           string         `gorm:"type:varchar(100)" json:"buyer_id,omitempty"`
+	BuyerName         string         `gorm:"type:varchar(255)" json:"buyer_name,omitempty"`
+	Items             datatypes.JSON `gorm:"type:jsonb;not null;default:'[]'" json:"items"`  // []providers.ExternalReturnItem
+	Images            datatypes.JSON `gorm:"type:jsonb;not null;default:'[]'" json:"images"` // Buyer evidence image URLs
+	ShipDueDate       *time.Time     `gorm:"type:timestamptz" json:"ship_due_date,omitempty"`
+	DisputeReason     string         `gorm:"type:text" json:"dispute_reason,omitempty"`
+	DisputeImages     datatypes.JSON `gorm:"type:jsonb" json:"dispute_images,omitempty"` // Seller evidence image URLs
+	AcceptedAt        *time.Time     `gorm:"type:timestamptz" json:"accepted_at,omitempty"`
+	DisputedAt        *time.Time     `gorm:"type:timestamptz" json:"disputed_at,omitempty"`
+	RestockedAt       *time.Time     `gorm:"type:timestamptz" json:"restocked_at,omitempty"`
+	RefundPropagated  *time.Time     `gorm:"column:refund_propagated_at;type:timestamptz" json:"refund_propagated_at,omitempty"`
+	PropagationError  string         `gorm:"type:text" json:"propagation_error,omitempty"`
+	ExternalCreatedAt *time.Time     `gorm:"type:timestamptz" json:"external_created_at,omitempty"`
+	ExternalUpdatedAt *time.Time     `gorm:"type:timestamptz" json:"external_updated_at,omitempty"`
+	SyncedAt          *time.Time     `gorm:"type:timestamptz" json:"synced_at,omitempty"`
+	CreatedAt         time.Time      `gorm:"autoCreateTime" json:"created_at"`
+	UpdatedAt         time.Time      `gorm:"autoUpdateTime" json:"updated_at"`
+
+	// Relations
+	Order *MarketplaceOrder `gorm:"foreignKey:OrderID" json:"order,omitempty"`
+}
+
+// TableName specifies the table name for MarketplaceReturn
+func (MarketplaceReturn) TableName() string {
+	return "marketplace.returns"
+}
+
+// MarketplaceReturnFilter represents filter options for marketplace returns
+type MarketplaceReturnFilter struct {
+	Status          string     `json:"status"`
+	Type            string     `json:"type"`
+	ExternalOrderID string     `json:"external_order_id"`
+	StartDate       *time.Time `json:"start_date"`
+	EndDate         *time.Time `json:"end_date"`
+	Page            int        `json:"page"`
+	PageSize        int        `json:"page_size"`
+}
+
+// DisputeReturnRequest represents a request to dispute a marketplace return.
+// Shopee requires an email; TikTok requires a reason code from the reject reasons.
+type DisputeReturnRequest struct {
+	Email      string   `json:"email" binding:"omitempty,email"`
+	Reason     string   `json:"reason" binding:"required"`
+	ReasonCode string   `json:"reason_code"`
+	Images     []string `json:"images" binding:"max=9"` // Evidence image URLs
+}
@@ -0,0 +1,54 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/datatypes"
+)
+
+// OrderPollState tracks the scheduled order poll of a connection. Orders updated
+// up to HighWaterMark have been fetched; the next poll starts there, less an overlap.
+type OrderPollState struct {
+	ConnectionID        uuid.UUID      `gorm:"type:uuid;primaryKey" json:"connection_id"`
+	HighWaterMark       *time.Time     `gorm:"type:timestamptz" json:"high_water_mark"`
+	LastRunAt           *time.Time     `gorm:"type:timestamptz" json:"last_run_at"`
+	LastSuccessAt       *time.Time     `gorm:"type:timestamptz" json:"last_success_at"`
+	LastError           string         `gorm:"type:text" json:"last_error,omitempty"`
+	LastOrdersSeen      int            `gorm:"not null;default:0" json:"last_orders_seen"` // Orders fetched by the last run
+	ConsecutiveFailures int            `gorm:"not null;default:0" json:"consecutive_failures"`
+	ImportFailures      datatypes.JSON `gorm:"type:jsonb;not null;default:'{}'" json:"import_failures"` // map[external order ID]OrderImportFailure
+	LockedUntil         *time.Time     `gorm:"type:timestamptz" json:"locked_until,omitempty"`          // Set while a replica polls the connection
+	CreatedAt           time.Time      `gorm:"autoCreateTime" json:"created_at"`
+	UpdatedAt           time.Time      `gorm:"autoUpdateTime" json:"updated_at"`
+}
+
+// OrderImportFailure tracks a polled order that failed to import. It holds back
+// the high-water mark until it is dead-lettered at the attempt limit.
+type OrderImportFailure struct {
+	Attempts     int       `json:"attempts"`
+	LastError    string    `json:"last_error"`
+	FailedAt     time.Time `json:"failed_at"`
+	DeadLettered bool      `json:"dead_lettered,omitempty"` // Given up on; later polls no longer wait for it
+}
+
+// GetImportFailures decodes the import failures of the poll
+func (s *OrderPollState) GetImportFailures() map[string]OrderImportFailure {
+	failures := make(map[string]OrderImportFailure)
+	if len(s.ImportFailures) > 0 {
+		_ = json.Unmarshal(s.ImportFailures, &failures)
+	}
+	return failures
+}
+
+// SetImportFailures encodes the import failures of the poll
+func (s *OrderPollState) SetImportFailures(failures map[string]OrderImportFailure) {
+	data, _ := json.Marshal(failures)
+	s.ImportFailures = datatypes.JSON(data)
+}
+
+// TableName specifies the table name for OrderPollState
+func (OrderPollState) TableName() string {
+	return "marketplace.order_poll_states"
+}
@@ -16,6 +16,9 @@ type ProductMapping struct {
 	SyncStatus        string     `gorm:"type:varchar(50);default:'synced'" json:"sync_status"` // synced, pending, error
 	LastSyncedAt      *time.Time `gorm:"type:timestamptz" json:"last_synced_at"`
 	SyncError         string     `gorm:"type:text" json:"sync_error,omitempty"`
+	ListingStatus     string     `gorm:"type:varchar(50);default:'normal'" json:"listing_status"` // normal, banned, deleted, unlisted
+	ListingReason     string     `gorm:"column:listing_status_reason;type:text" json:"listing_status_reason,omitempty"`
+	ListingUpdatedAt  *time.Time `gorm:"column:listing_status_updated_at;type:timestamptz" json:"listing_status_updated_at,omitempty"`
 	CreatedAt         time.Time  `gorm:"autoCreateTime" json:"created_at"`
 	UpdatedAt         time.Time  `gorm:"autoUpdateTime" json:"updated_at"`
 
@@ -36,6 +39,14 @@ const (
 	SyncStatusError   = "error"
 )
 
+// ListingStatus constants
+const (
+	ListingStatusNormal   = "normal"
+	ListingStatusBanned   = "banned"
+	ListingStatusDeleted  = "deleted"
+	ListingStatusUnlisted = "unlisted"
+)
+
 // CreateProductMappingRequest represents a request to create a product mapping
 type CreateProductMappingRequest struct {
 	InternalProductID uuid.UUID `json:"internal_product_id" binding:"required"`
@@ -56,6 +67,7 @@ type ProductMappingFilter struct {
 	ConnectionID      *uuid.UUID `json:"connection_id"`
 	InternalProductID *uuid.UUID `json:"internal_product_id"`
 	SyncStatus        string     `json:"sync_status"`
+	WithVariants      bool       `json:"-"` // Load the variant mappings of each mapping
 	Page              int        `json:"page"`
 	PageSize          int        `json:"page_size"`
 }
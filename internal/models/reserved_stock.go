@@ -0,0 +1,29 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ReservedStock is stock of a marketplace listing locked by a promotion or campaign.
+// The marketplace holds it outside the normal stock, so it is excluded from the
+// quantity we push for the listing.
+type ReservedStock struct {
+	ID                uuid.UUID `gorm:"type:uuid;primaryKey;default:gen_random_uuid()" json:"id"`
+	ConnectionID      uuid.UUID `gorm:"type:uuid;not null" json:"connection_id"`
+	ExternalProductID string    `gorm:"type:varchar(100);not null" json:"external_product_id"`
+	ExternalModelID   string    `gorm:"type:varchar(100);not null;default:''" json:"external_model_id,omitempty"`
+	ReservedQuantity  int       `gorm:"not null;default:0" json:"reserved_quantity"`
+	Action            string    `gorm:"type:varchar(100)" json:"action,omitempty"`
+	PromotionType     string    `gorm:"type:varchar(100)" json:"promotion_type,omitempty"`
+	PromotionID       string    `gorm:"type:varchar(100)" json:"promotion_id,omitempty"`
+	ChangedAt         time.Time `gorm:"type:timestamptz;not null" json:"changed_at"` // Time of the change on the marketplace
+	CreatedAt         time.Time `gorm:"autoCreateTime" json:"created_at"`
+	UpdatedAt         time.Time `gorm:"autoUpdateTime" json:"updated_at"`
+}
+
+// TableName specifies the table name for ReservedStock
+func (ReservedStock) TableName() string {
+	return "marketplace.reserved_stocks"
+}
@@ -0,0 +1,203 @@
+package models
+
+import (
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/datatypes"
+)
+
+// Return rule actions
+const (
+	ReturnActionAccept  = "accept"
+	ReturnActionDispute = "dispute"
+	ReturnActionReview  = "review"
+)
+
+// DefaultReturnRulePriority is the priority of rules created without one, so
+// they are evaluated after rules given an explicit, lower priority
+const DefaultReturnRulePriority = 100
+
+// Return decision statuses
+const (
+	ReturnDecisionScheduled = "scheduled" // Waiting for execute_at; can still be reverted
+	ReturnDecisionExecuting = "executing"
+	ReturnDecisionExecuted  = "executed"
+	ReturnDecisionReview    = "review"   // Left for a human
+	ReturnDecisionReverted  = "reverted" // Cancelled by an admin before execution
+	ReturnDecisionSkipped   = "skipped"  // Return was no longer actionable at execution time
+	ReturnDecisionFailed    = "failed"
+)
+
+// ReturnRule decides what to do with new returns of a connection
+type ReturnRule struct {
+	ID                uuid.UUID      `gorm:"type:uuid;primaryKey;default:gen_random_uuid()" json:"id"`
+	ConnectionID      uuid.UUID      `gorm:"type:uuid;not null" json:"connection_id"`
+	Name              string         `gorm:"type:varchar(255);not null" json:"name"`
+	Priority          int            `gorm:"not null;default:100" json:"priority"` // Lowest is evaluated first; DefaultReturnRulePriority when not given
+	Enabled           bool           `gorm:"not null;default:true" json:"enabled"`
+	Action            string         `gorm:"type:varchar(20);not null" json:"action"`
+	Conditions        datatypes.JSON `gorm:"type:jsonb;not null;default:'{}'" json:"conditions"` // ReturnRuleConditions
+	DisputeReason     string         `gorm:"type:text" json:"dispute_reason,omitempty"`
+	DisputeReasonCode string         `gorm:"type:varchar(100)" json:"dispute_reason_code,omitempty"`
+	DisputeEmail      string         `gorm:"type:varchar(255)" json:"dispute_email,omitempty"`
+	CreatedAt         time.Time      `gorm:"autoCreateTime" json:"created_at"`
+	UpdatedAt         time.Time      `gorm:"autoUpdateTime" json:"updated_at"`
+}
+
+// TableName specifies the table name for ReturnRule
+func (ReturnRule) TableName() string {
+	return "marketplace.return_rules"
+}
+
+// GetConditions decodes the rule conditions
+func (r *ReturnRule) GetConditions() ReturnRuleConditions {
+	var conditions ReturnRuleConditions
+	if len(r.Conditions) > 0 {
+		_ = json.Unmarshal(r.Conditions, &conditions)
+	}
+	return conditions
+}
+
+// ReturnRuleConditions holds the conditions of a rule. Empty conditions are
+// ignored; a rule matches when every set condition holds.
+type ReturnRuleConditions struct {
+	Reasons              []string `json:"reasons,omitempty"` // Return reasons, compared case-insensitively
+	Types                []string `json:"types,omitempty"`   // providers.ReturnType* values
+	MinRefundAmount      *float64 `json:"min_refund_amount,omitempty"`
+	MaxRefundAmount      *float64 `json:"max_refund_amount,omitempty"`
+	ProductIDs           []string `json:"product_ids,omitempty"`       // Internal product IDs; any returned item matches
+	MinBuyerReturns      *int     `json:"min_buyer_returns,omitempty"` // Earlier returns by the same buyer on this connection
+	MaxBuyerReturns      *int     `json:"max_buyer_returns,omitempty"`
+	MaxDaysSinceDelivery *int     `json:"max_days_since_delivery,omitempty"` // Undelivered orders never match
+	OnSale               *bool    `json:"on_sale,omitempty"`                 // A returned line was (true) or none was (false) sold below its original price
+}
+
+// Matches reports whether the inputs satisfy every set condition. Conditions on
+// values that are unknown for the return (buyer, delivery) do not match.
+func (c ReturnRuleConditions) Matches(in *ReturnDecisionInputs) bool {
+	if len(c.Reasons) > 0 && !containsFold(c.Reasons, in.Reason) {
+		return false
+	}
+	if len(c.Types) > 0 && !containsFold(c.Types, in.Type) {
+		return false
+	}
+	if c.MinRefundAmount != nil && in.RefundAmount < *c.MinRefundAmount {
+		return false
+	}
+	if c.MaxRefundAmount != nil && in.RefundAmount > *c.MaxRefundAmount {
+		return false
+	}
+	if len(c.ProductIDs) > 0 {
+		found := false
+		for _, id := range in.ProductIDs {
+			if containsFold(c.ProductIDs, id) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if c.MinBuyerReturns != nil || c.MaxBuyerReturns != nil {
+		if in.BuyerReturns == nil {
+			return false
+		}
+		if c.MinBuyerReturns != nil && *in.BuyerReturns < *c.MinBuyerReturns {
+			return false
+		}
+		if c.MaxBuyerReturns != nil && *in.BuyerReturns > *c.MaxBuyerReturns {
+			return false
+		}
+	}
+	if c.MaxDaysSinceDelivery != nil {
+		if in.DaysSinceDelivery == nil || *in.DaysSinceDelivery > *c.MaxDaysSinceDelivery {
+			return false
+		}
+	}
+	if c.OnSale != nil {
+		if in.OnSale == nil || *in.OnSale != *c.OnSale {
+			return false
+		}
+	}
+	return true
+}
+
+func containsFold(values []string, value string) bool {
+	for _, v := range values {
+		if strings.EqualFold(v, value) {
+			return true
+		}
+	}
+	return false
+}
+
+// ReturnDecision is the automatic decision taken on a return
+type ReturnDecision struct {
+	ID                uuid.UUID      `gorm:"type:uuid;primaryKey;default:gen_random_uuid()" json:"id"`
+	ReturnID          uuid.UUID      `gorm:"type:uuid;not null" json:"return_id"`
+	ConnectionID      uuid.UUID      `gorm:"type:uuid;not null" json:"connection_id"`
+	RuleID            *uuid.UUID     `gorm:"type:uuid" json:"rule_id,omitempty"` // Nil when no rule matched
+	RuleName          string         `gorm:"type:varchar(255)" json:"rule_name,omitempty"`
+	Action            string         `gorm:"type:varchar(20);not null" json:"action"`
+	Status            string         `gorm:"type:varchar(20);not null" json:"status"`
+	Inputs            datatypes.JSON `gorm:"type:jsonb;not null;default:'{}'" json:"inputs"` // ReturnDecisionInputs
+	DisputeReason     string         `gorm:"type:text" json:"dispute_reason,omitempty"`
+	DisputeReasonCode string         `gorm:"type:varchar(100)" json:"dispute_reason_code,omitempty"`
+	DisputeEmail      string         `gorm:"type:varchar(255)" json:"dispute_email,omitempty"`
+	ExecuteAt         *time.Time     `gorm:"type:timestamptz" json:"execute_at,omitempty"`
+	Deadline          *time.Time     `gorm:"type:timestamptz" json:"deadline,omitempty"`
+	ExecutedAt        *time.Time     `gorm:"type:timestamptz" json:"executed_at,omitempty"`
+	RevertedAt        *time.Time     `gorm:"type:timestamptz" json:"reverted_at,omitempty"`
+	RevertReason      string         `gorm:"type:text" json:"revert_reason,omitempty"`
+	ErrorMessage      string         `gorm:"type:text" json:"error_message,omitempty"`
+	CreatedAt         time.Time      `gorm:"autoCreateTime" json:"created_at"`
+	UpdatedAt         time.Time      `gorm:"autoUpdateTime" json:"updated_at"`
+
+	// Relations
+	Return *MarketplaceReturn `gorm:"foreignKey:ReturnID" json:"return,omitempty"`
+}
+
+// TableName specifies the table name for ReturnDecision
+func (ReturnDecision) TableName() string {
+	return "marketplace.return_decisions"
+}
+
+// ReturnDecisionInputs are the values a return was evaluated against
+type ReturnDecisionInputs struct {
+	Reason            string   `json:"reason"`
+	Type              string   `json:"type,omitempty"`
+	RefundAmount      float64  `json:"refund_amount"`
+	ProductIDs        []string `json:"product_ids"`
+	BuyerReturns      *int     `json:"buyer_returns,omitempty"`       // Nil when the buyer is unknown
+	DaysSinceDelivery *int     `json:"days_since_delivery,omitempty"` // Nil when the order is not delivered
+	OnSale            *bool    `json:"on_sale,omitempty"`             // Nil when the order lines carry no original price
+}
+
+// ReturnDecisionFilter represents filter options for return decisions
+type ReturnDecisionFilter struct {
+	Status   string `json:"status"`
+	Action   string `json:"action"`
+	Page     int    `json:"page"`
+	PageSize int    `json:"page_size"`
+}
+
+// ReturnRuleRequest represents a request to create or replace a return rule
+type ReturnRuleRequest struct {
+	Name              string               `json:"name" binding:"required"`
+	Priority          *int                 `json:"priority"` // Defaults to DefaultReturnRulePriority
+	Enabled           *bool                `json:"enabled"`
+	Action            string               `json:"action" binding:"required,oneof=accept dispute review"`
+	Conditions        ReturnRuleConditions `json:"conditions"`
+	DisputeReason     string               `json:"dispute_reason"`
+	DisputeReasonCode string               `json:"dispute_reason_code"`
+	DisputeEmail      string               `json:"dispute_email" binding:"omitempty,email"`
+}
+
+// RevertReturnDecisionRequest represents a request to revert a scheduled decision
+type RevertReturnDecisionRequest struct {
+	Reason string `json:"reason"`
+}
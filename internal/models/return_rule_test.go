@@ -0,0 +1,69 @@
+package models
+
+import "testing"
+
+func TestReturnRuleConditionsMatches(t *testing.T) {
+	intPtr := func(v int) *int { return &v }
+	floatPtr := func(v float64) *float64 { return &v }
+	boolPtr := func(v bool) *bool { return &v }
+
+	inputs := &ReturnDecisionInputs{
+		Reason:            "DAMAGED",
+		Type:              "return_refund",
+		RefundAmount:      50,
+		ProductIDs:        []string{"product-a", "product-b"},
+		BuyerReturns:      intPtr(2),
+		DaysSinceDelivery: intPtr(3),
+		OnSale:            boolPtr(true),
+	}
+	unknown := &ReturnDecisionInputs{
+		Reason:       "damaged",
+		RefundAmount: 50,
+		ProductIDs:   []string{},
+	}
+
+	tests := []struct {
+		name       string
+		conditions ReturnRuleConditions
+		inputs     *ReturnDecisionInputs
+		want       bool
+	}{
+		{name: "no conditions", conditions: ReturnRuleConditions{}, inputs: inputs, want: true},
+		{name: "reason case-insensitive", conditions: ReturnRuleConditions{Reasons: []string{"damaged"}}, inputs: inputs, want: true},
+		{name: "other reason", conditions: ReturnRuleConditions{Reasons: []string{"wrong_item"}}, inputs: inputs, want: false},
+		{name: "type", conditions: ReturnRuleConditions{Types: []string{"refund_only", "return_refund"}}, inputs: inputs, want: true},
+		{name: "other type", conditions: ReturnRuleConditions{Types: []string{"refund_only"}}, inputs: inputs, want: false},
+		{name: "refund within range", conditions: ReturnRuleConditions{MinRefundAmount: floatPtr(50), MaxRefundAmount: floatPtr(50)}, inputs: inputs, want: true},
+		{name: "refund below minimum", conditions: ReturnRuleConditions{MinRefundAmount: floatPtr(50.01)}, inputs: inputs, want: false},
+		{name: "refund above maximum", conditions: ReturnRuleConditions{MaxRefundAmount: floatPtr(49.99)}, inputs: inputs, want: false},
+		{name: "any product", conditions: ReturnRuleConditions{ProductIDs: []string{"product-b", "product-c"}}, inputs: inputs, want: true},
+		{name: "no product", conditions: ReturnRuleConditions{ProductIDs: []string{"product-c"}}, inputs: inputs, want: false},
+		{name: "buyer returns within range", conditions: ReturnRuleConditions{MinBuyerReturns: intPtr(1), MaxBuyerReturns: intPtr(2)}, inputs: inputs, want: true},
+		{name: "buyer returns above maximum", conditions: ReturnRuleConditions{MaxBuyerReturns: intPtr(1)}, inputs: inputs, want: false},
+		{name: "unknown buyer", conditions: ReturnRuleConditions{MaxBuyerReturns: intPtr(5)}, inputs: unknown, want: false},
+		{name: "delivered recently", conditions: ReturnRuleConditions{MaxDaysSinceDelivery: intPtr(3)}, inputs: inputs, want: true},
+		{name: "delivered too long ago", conditions: ReturnRuleConditions{MaxDaysSinceDelivery: intPtr(2)}, inputs: inputs, want: false},
+		{name: "not delivered", conditions: ReturnRuleConditions{MaxDaysSinceDelivery: intPtr(30)}, inputs: unknown, want: false},
+		{name: "on sale", conditions: ReturnRuleConditions{OnSale: boolPtr(true)}, inputs: inputs, want: true},
+		{name: "not on sale", conditions: ReturnRuleConditions{OnSale: boolPtr(false)}, inputs: inputs, want: false},
+		{name: "sale unknown", conditions: ReturnRuleConditions{OnSale: boolPtr(false)}, inputs: unknown, want: false},
+		{
+			name: "every condition must hold",
+			conditions: ReturnRuleConditions{
+				Reasons:         []string{"damaged"},
+				MinRefundAmount: floatPtr(10),
+				OnSale:          boolPtr(false),
+			},
+			inputs: inputs,
+			want:   false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.conditions.Matches(tt.inputs); got != tt.want {
+				t.Errorf("Matches() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,117 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// SettlementLine represents a line of a marketplace payout statement: a Shopee
+// wallet transaction or a TikTok Shop settlement
+type SettlementLine struct {
+	ID              uuid.UUID  `gorm:"type:uuid;primaryKey;default:gen_random_uuid()" json:"id"`
+	ConnectionID    uuid.UUID  `gorm:"type:uuid;not null" json:"connection_id"`
+	Platform        string     `gorm:"type:varchar(50);not null" json:"platform"`
+	ExternalID      string     `gorm:"type:varchar(100);not null" json:"external_id"`
+	ExternalOrderID string     `gorm:"type:varchar(100)" json:"external_order_id,omitempty"` // Empty for lines not tied to an order
+	OrderID         *uuid.UUID `gorm:"type:uuid" json:"order_id"`                            // Matched marketplace order
+	TransactionType string     `gorm:"type:varchar(100)" json:"transaction_type"`
+	Amount          float64    `gorm:"type:decimal(12,2);not null" json:"amount"`
+	FeeAmount       float64    `gorm:"type:decimal(12,2);not null;default:0" json:"fee_amount"`
+	CommissionFee   *float64   `gorm:"type:decimal(12,2)" json:"commission_fee"` // Fee breakdown; nil when the statement does not report it
+	ServiceFee      *float64   `gorm:"type:decimal(12,2)" json:"service_fee"`
+	TransactionFee  *float64   `gorm:"type:decimal(12,2)" json:"transaction_fee"`
+	ShippingFee     *float64   `gorm:"type:decimal(12,2)" json:"shipping_fee"`
+	Currency        string     `gorm:"type:varchar(10)" json:"currency"`
+	Description     string     `gorm:"type:text" json:"description,omitempty"`
+	SettledAt       time.Time  `gorm:"type:timestamptz;not null" json:"settled_at"`
+	CreatedAt       time.Time  `gorm:"autoCreateTime" json:"created_at"`
+	UpdatedAt       time.Time  `gorm:"autoUpdateTime" json:"updated_at"`
+}
+
+// TableName specifies the table name for SettlementLine
+func (SettlementLine) TableName() string {
+	return "marketplace.settlement_lines"
+}
+
+// Settlement report types
+const (
+	SettlementReportUnmatched     = "unmatched_payouts" // Order payouts with no imported order
+	SettlementReportUnpaid        = "unpaid_orders"     // Completed orders without a payout
+	SettlementReportFeeMismatches = "fee_mismatches"    // Orders paid out or charged fees different from their financials
+)
+
+// Settlement fees compared by the fee mismatch report
+const (
+	SettlementFeeCommission  = "commission_fee"
+	SettlementFeeService     = "service_fee"
+	SettlementFeeTransaction = "transaction_fee"
+	SettlementFeeShipping    = "shipping_fee"
+)
+
+// SettlementReportFilter represents filter options for settlement reports.
+// Dates apply to the settlement time of payouts and the creation time of orders.
+type SettlementReportFilter struct {
+	Type      string     `json:"type"`
+	StartDate *time.Time `json:"start_date"`
+	EndDate   *time.Time `json:"end_date"`
+	Tolerance float64    `json:"tolerance"` // Largest difference not reported as a mismatch
+}
+
+// SettlementReportEntry represents a row of a settlement report. Unmatched
+// payouts fill the transaction fields; unpaid orders and mismatches the order
+// and expected amount fields, and mismatches the fees that differ.
+type SettlementReportEntry struct {
+	ExternalOrderID string                    `json:"external_order_id"`
+	OrderID         *uuid.UUID                `json:"order_id,omitempty"`
+	OrderStatus     string                    `json:"order_status,omitempty"`
+	TransactionID   string                    `json:"transaction_id,omitempty"`
+	TransactionType string                    `json:"transaction_type,omitempty"`
+	Expected        *float64                  `json:"expected,omitempty"` // Order net payout
+	Settled         float64                   `json:"settled"`            // Total paid out for the order
+	Difference      float64                   `json:"difference"`         // Settled less expected
+	Fees            []SettlementFeeDifference `json:"fees,omitempty"`
+	Currency        string                    `json:"currency"`
+	Date            time.Time                 `json:"date"` // Settlement time, or order creation time
+}
+
+// SettlementFeeDifference represents a fee charged on an order's payout lines
+// that differs from the fee in the order's financials
+type SettlementFeeDifference struct {
+	Fee        string  `json:"fee"`        // commission_fee, service_fee, transaction_fee or shipping_fee
+	Expected   float64 `json:"expected"`   // Fee in the order financials
+	Settled    float64 `json:"settled"`    // Fee charged on the payout lines
+	Difference float64 `json:"difference"` // Settled less expected
+}
+
+// SettlementOrderTotals represents the payout lines of an order totalled next
+// to the order's net payout and fees. Settled fees are nil when no line of the
+// order reports them.
+type SettlementOrderTotals struct {
+	ExternalOrderID       string
+	OrderID               uuid.UUID
+	OrderStatus           string
+	Currency              string
+	CreatedAt             time.Time
+	NetPayout             float64
+	Settled               float64
+	CommissionFee         float64
+	ServiceFee            float64
+	TransactionFee        float64
+	ShippingFee           float64
+	SettledCommissionFee  *float64
+	SettledServiceFee     *float64
+	SettledTransactionFee *float64
+	SettledShippingFee    *float64
+}
+
+// SettlementReport represents a reconciliation report of a connection
+type SettlementReport struct {
+	ConnectionID uuid.UUID               `json:"connection_id"`
+	Type         string                  `json:"type"`
+	StartDate    *time.Time              `json:"start_date,omitempty"`
+	EndDate      *time.Time              `json:"end_date,omitempty"`
+	Entries      []SettlementReportEntry `json:"entries"`
+	Total        int                     `json:"total"`
+	GeneratedAt  time.Time               `json:"generated_at"`
+}
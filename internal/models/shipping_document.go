@@ -0,0 +1,44 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ShippingDocument represents a shipping document (AWB or label) downloaded
+// from the marketplace and kept in the blob store. Regenerating a document
+// adds a new row, so earlier versions stay available.
+type ShippingDocument struct {
+	ID            uuid.UUID  `gorm:"type:uuid;primaryKey;default:gen_random_uuid()" json:"id"`
+	ConnectionID  uuid.UUID  `gorm:"type:uuid;not null" json:"connection_id"`
+	OrderID       uuid.UUID  `gorm:"type:uuid;not null" json:"order_id"`
+	DocumentType  string     `gorm:"type:varchar(50);not null" json:"document_type"` // e.g. NORMAL_AIR_WAYBILL, THERMAL_AIR_WAYBILL
+	StorageKey    string     `gorm:"type:varchar(500);not null" json:"-"`
+	ContentType   string     `gorm:"type:varchar(100);not null" json:"content_type"`
+	Size          int64      `gorm:"not null" json:"size"`
+	PrintCount    int        `gorm:"not null;default:0" json:"print_count"`
+	LastPrintedAt *time.Time `gorm:"type:timestamptz" json:"last_printed_at"`
+	GeneratedAt   time.Time  `gorm:"type:timestamptz;not null" json:"generated_at"`
+	CreatedAt     time.Time  `gorm:"autoCreateTime" json:"created_at"`
+
+	Prints []ShippingDocumentPrint `gorm:"foreignKey:DocumentID" json:"prints,omitempty"`
+}
+
+// TableName specifies the table name for ShippingDocument
+func (ShippingDocument) TableName() string {
+	return "marketplace.shipping_documents"
+}
+
+// ShippingDocumentPrint records a download of a stored shipping document
+type ShippingDocumentPrint struct {
+	ID         uuid.UUID `gorm:"type:uuid;primaryKey;default:gen_random_uuid()" json:"id"`
+	DocumentID uuid.UUID `gorm:"type:uuid;not null" json:"document_id"`
+	ClientIP   string    `gorm:"type:varchar(100)" json:"client_ip,omitempty"`
+	PrintedAt  time.Time `gorm:"type:timestamptz;not null" json:"printed_at"`
+}
+
+// TableName specifies the table name for ShippingDocumentPrint
+func (ShippingDocumentPrint) TableName() string {
+	return "marketplace.shipping_document_prints"
+}
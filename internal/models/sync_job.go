@@ -38,6 +38,9 @@ const (
 	JobTypeInventorySync = "inventory_sync"
 	JobTypeOrderSync     = "order_sync"
 	JobTypeTokenRefresh  = "token_refresh"
+
+	JobTypeInventoryReconcile = "inventory_reconcile"
+	JobTypeOrderBackfill      = "order_backfill"
 )
 
 // Job status constants
@@ -67,6 +70,31 @@ type OrderSyncPayload struct {
 	Action          string `json:"action"` // fetch, import, update_status
 }
 
+// OrderBackfillPayload represents the payload and progress of an order backfill job.
+// Orders created before Checkpoint have been imported or are listed in
+// FailedOrderIDs; a resumed job retries those and then starts at Checkpoint.
+type OrderBackfillPayload struct {
+	TimeFrom       time.Time  `json:"time_from"`
+	TimeTo         time.Time  `json:"time_to"`
+	SkipPush       bool       `json:"skip_push"` // Do not create the historical orders in service-order
+	Checkpoint     *time.Time `json:"checkpoint,omitempty"`
+	WindowsDone    int        `json:"windows_done"`
+	WindowsTotal   int        `json:"windows_total"`
+	OrdersSeen     int        `json:"orders_seen"`
+	OrdersImported int        `json:"orders_imported"`
+	OrdersFailed   int        `json:"orders_failed"`
+	FailedOrderIDs []string   `json:"failed_order_ids,omitempty"` // External IDs still to import
+}
+
+// OrderBackfillRequest represents a request to backfill historical orders.
+// TimeFrom and TimeTo take precedence over Days.
+type OrderBackfillRequest struct {
+	Days     int        `json:"days"` // Days back from now (default 90)
+	TimeFrom *time.Time `json:"time_from"`
+	TimeTo   *time.Time `json:"time_to"`
+	SkipPush bool       `json:"skip_push"`
+}
+
 // SyncJobFilter represents filter options for sync jobs
 type SyncJobFilter struct {
 	ConnectionID *uuid.UUID `json:"connection_id"`
@@ -0,0 +1,42 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// WarehouseMapping maps an internal warehouse to a marketplace warehouse/location
+type WarehouseMapping struct {
+	ID                  uuid.UUID `gorm:"type:uuid;primaryKey;default:gen_random_uuid()" json:"id"`
+	ConnectionID        uuid.UUID `gorm:"type:uuid;not null" json:"connection_id"`
+	InternalWarehouseID string    `gorm:"type:varchar(100);not null" json:"internal_warehouse_id"`
+	ExternalLocationID  string    `gorm:"type:varchar(100);not null;default:''" json:"external_location_id"` // Empty = platform default location
+	CreatedAt           time.Time `gorm:"autoCreateTime" json:"created_at"`
+	UpdatedAt           time.Time `gorm:"autoUpdateTime" json:"updated_at"`
+}
+
+// TableName specifies the table name for WarehouseMapping
+func (WarehouseMapping) TableName() string {
+	return "marketplace.warehouse_mappings"
+}
+
+// WarehouseStockLevel is the last known stock of a product in an internal warehouse
+type WarehouseStockLevel struct {
+	InternalProductID uuid.UUID `gorm:"type:uuid;primaryKey" json:"internal_product_id"`
+	WarehouseID       string    `gorm:"type:varchar(100);primaryKey" json:"warehouse_id"`
+	Quantity          int       `gorm:"not null;default:0" json:"quantity"`
+	ChangedAt         time.Time `gorm:"type:timestamptz;not null" json:"changed_at"` // Time of the stock change in the inventory service
+	UpdatedAt         time.Time `gorm:"autoUpdateTime" json:"updated_at"`
+}
+
+// TableName specifies the table name for WarehouseStockLevel
+func (WarehouseStockLevel) TableName() string {
+	return "marketplace.warehouse_stock_levels"
+}
+
+// SetWarehouseMappingRequest represents a request to map an internal warehouse
+type SetWarehouseMappingRequest struct {
+	InternalWarehouseID string `json:"internal_warehouse_id" binding:"required"`
+	ExternalLocationID  string `json:"external_location_id"` // Empty sums into the default location
+}
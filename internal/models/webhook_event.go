@@ -7,16 +7,23 @@ import (
 	"gorm.io/datatypes"
 )
 
-// WebhookEvent represents a webhook event received from a marketplace
+// WebhookEvent represents a webhook event received from a marketplace.
+// Events are stored raw before they are acknowledged and processed afterwards;
+// an event with no attempts has not been picked up yet.
 type WebhookEvent struct {
-	ID           uuid.UUID      `gorm:"type:uuid;primaryKey;default:gen_random_uuid()" json:"id"`
-	Platform     string         `gorm:"type:varchar(50);not null" json:"platform"`
-	EventType    string         `gorm:"type:varchar(100);not null" json:"event_type"`
-	Payload      datatypes.JSON `gorm:"type:jsonb;not null" json:"payload"`
-	Signature    string         `gorm:"type:varchar(255)" json:"signature"`
-	Processed    bool           `gorm:"default:false" json:"processed"`
-	ErrorMessage string         `gorm:"type:text" json:"error_message,omitempty"`
-	ReceivedAt   time.Time      `gorm:"autoCreateTime" json:"received_at"`
+	ID                 uuid.UUID      `gorm:"type:uuid;primaryKey;default:gen_random_uuid()" json:"id"`
+	Platform           string         `gorm:"type:varchar(50);not null" json:"platform"`
+	EventType          string         `gorm:"type:varchar(100);not null" json:"event_type"`
+	ShopID             string         `gorm:"type:varchar(100)" json:"shop_id,omitempty"`
+	Payload            datatypes.JSON `gorm:"type:jsonb;not null" json:"payload"`
+	Signature          string         `gorm:"type:text" json:"signature,omitempty"`
+	Fingerprint        *string        `gorm:"type:varchar(64)" json:"fingerprint,omitempty"` // Delivery fingerprint used to skip redeliveries
+	VerificationStatus string         `gorm:"type:varchar(20);not null;default:'skipped'" json:"verification_status"`
+	Processed          bool           `gorm:"default:false" json:"processed"`
+	ErrorMessage       string         `gorm:"type:text" json:"error_message,omitempty"`
+	Attempts           int            `gorm:"not null;default:0" json:"attempts"`
+	ProcessedAt        *time.Time     `gorm:"type:timestamptz" json:"processed_at,omitempty"`
+	ReceivedAt         time.Time      `gorm:"autoCreateTime" json:"received_at"`
 }
 
 // TableName specifies the table name for WebhookEvent
@@ -24,6 +31,13 @@ func (WebhookEvent) TableName() string {
 	return "marketplace.webhook_events"
 }
 
+// Webhook verification status constants
+const (
+	WebhookVerificationVerified = "verified"
+	WebhookVerificationFailed   = "failed"
+	WebhookVerificationSkipped  = "skipped" // No secret configured or no signature sent
+)
+
 // Event type constants for Shopee
 const (
 	// Shopee event types
@@ -52,10 +66,27 @@ const (
 	TikTokEventInventoryUpdated   = "tiktok.inventory.updated"
 )
 
+// WebhookEventReplay records the outcome of one manual replay of a webhook event
+type WebhookEventReplay struct {
+	ID             uuid.UUID `gorm:"type:uuid;primaryKey;default:gen_random_uuid()" json:"id"`
+	WebhookEventID uuid.UUID `gorm:"type:uuid;not null" json:"webhook_event_id"`
+	Success        bool      `gorm:"not null" json:"success"`
+	ErrorMessage   string    `gorm:"type:text" json:"error_message,omitempty"`
+	DurationMs     int64     `gorm:"not null;default:0" json:"duration_ms"`
+	ReplayedAt     time.Time `gorm:"autoCreateTime" json:"replayed_at"`
+}
+
+// TableName specifies the table name for WebhookEventReplay
+func (WebhookEventReplay) TableName() string {
+	return "marketplace.webhook_event_replays"
+}
+
 // WebhookEventFilter represents filter options for webhook events
 type WebhookEventFilter struct {
 	Platform  string     `json:"platform"`
 	EventType string     `json:"event_type"`
+	ShopID    string     `json:"shop_id"`
+	Search    string     `json:"search"` // Free text matched against the raw payload, e.g. an order SN
 	Processed *bool      `json:"processed"`
 	StartDate *time.Time `json:"start_date"`
 	EndDate   *time.Time `json:"end_date"`
@@ -68,3 +99,8 @@ type MarkProcessedRequest struct {
 	EventIDs     []uuid.UUID `json:"event_ids" binding:"required"`
 	ErrorMessage string      `json:"error_message,omitempty"`
 }
+
+// ReplayWebhookEventsRequest represents a request to re-run stored webhook events
+type ReplayWebhookEventsRequest struct {
+	EventIDs []uuid.UUID `json:"event_ids" binding:"required,min=1,max=100"`
+}
@@ -131,7 +131,9 @@ type ExternalCategory struct {
 // InventoryUpdate represents a stock update
 type InventoryUpdate struct {
 	ExternalProductID string `json:"external_product_id"`
+	ExternalVariantID string `json:"external_variant_id,omitempty"` // Marketplace model or SKU ID, empty for the whole listing
 	ExternalSKU       string `json:"external_sku,omitempty"`
+	LocationID        string `json:"location_id,omitempty"` // Marketplace warehouse/location, empty for the default
 	Quantity          int    `json:"quantity"`
 }
 
@@ -155,7 +157,8 @@ type OrderQueryParams struct {
 // ExternalOrder represents an order from marketplace
 type ExternalOrder struct {
 	ExternalOrderID string              `json:"external_order_id"`
-	Status          string              `json:"status"`
+	Status          string              `json:"status"`                    // Canonical shared.OrderStatus; empty when the platform status is unknown
+	PlatformStatus  string              `json:"platform_status,omitempty"` // Raw platform status
 	Items           []ExternalOrderItem `json:"items"`
 	BuyerName       string              `json:"buyer_name"`
 	BuyerID         string              `json:"buyer_id,omitempty"`
@@ -167,6 +170,10 @@ type ExternalOrder struct {
 	PaidAt          *time.Time          `json:"paid_at,omitempty"`
 	TrackingNumber  string              `json:"tracking_number,omitempty"`
 	Carrier         string              `json:"carrier,omitempty"`
+	PaymentMethod   string              `json:"payment_method,omitempty"`
+	SubtotalAmount  float64             `json:"subtotal_amount,omitempty"` // Item prices before discounts, when the order carries them
+	ShippingFee     float64             `json:"shipping_fee,omitempty"`    // Shipping paid by the buyer
+	DiscountAmount  float64             `json:"discount_amount,omitempty"`
 }
 
 // OrderItem represents an item in an order
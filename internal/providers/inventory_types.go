@@ -3,7 +3,16 @@ package providers
 // InventoryUpdateResult represents the result of an inventory update
 type InventoryUpdateResult struct {
 	ExternalProductID string `json:"external_product_id"`
+	ExternalVariantID string `json:"external_variant_id,omitempty"`
 	ExternalSKU       string `json:"external_sku,omitempty"`
+	LocationID        string `json:"location_id,omitempty"`
 	Success           bool   `json:"success"`
 	Error             string `json:"error,omitempty"`
 }
+
+// Warehouse represents a seller warehouse/location on a marketplace
+type Warehouse struct {
+	ID        string `json:"id"`
+	Name      string `json:"name"`
+	IsDefault bool   `json:"is_default,omitempty"`
+}
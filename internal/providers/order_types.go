@@ -4,22 +4,32 @@ import (
 	"time"
 )
 
+// Order list time fields
+const (
+	OrderTimeFieldCreate = "create_time"
+	OrderTimeFieldUpdate = "update_time"
+)
+
 // OrderListParams represents parameters for listing orders
 type OrderListParams struct {
-	TimeFrom time.Time `json:"time_from"`
-	TimeTo   time.Time `json:"time_to"`
-	Status   string    `json:"status,omitempty"`
-	PageSize int       `json:"page_size"`
-	Cursor   string    `json:"cursor,omitempty"`
+	TimeFrom  time.Time `json:"time_from"`
+	TimeTo    time.Time `json:"time_to"`
+	TimeField string    `json:"time_field,omitempty"` // OrderTimeField*; create time when empty
+	Status    string    `json:"status,omitempty"`
+	PageSize  int       `json:"page_size"`
+	Cursor    string    `json:"cursor,omitempty"`
 }
 
 // ExternalOrderItem represents an order line item
 type ExternalOrderItem struct {
 	ExternalProductID string  `json:"external_product_id"`
-	ExternalSKU       string  `json:"external_sku"`
+	ExternalVariantID string  `json:"external_variant_id,omitempty"` // Shopee model_id, TikTok sku_id
+	ExternalSKU       string  `json:"external_sku"`                  // Seller SKU
 	Name              string  `json:"name"`
+	VariantName       string  `json:"variant_name,omitempty"`
 	Quantity          int     `json:"quantity"`
 	UnitPrice         float64 `json:"unit_price"`
+	OriginalPrice     float64 `json:"original_price,omitempty"` // Unit price before discounts; 0 when unknown
 	TotalPrice        float64 `json:"total_price"`
 }
 
@@ -33,3 +43,21 @@ type ShippingAddress struct {
 	Country string `json:"country"`
 	ZipCode string `json:"zip_code"`
 }
+
+// OrderFinancials represents the amounts of an order as settled by the
+// marketplace (Shopee escrow, TikTok Shop settlements). Fees and discounts are
+// positive amounts; NetPayout is what the marketplace pays the seller.
+type OrderFinancials struct {
+	SubtotalAmount  float64 `json:"subtotal_amount"` // Item prices before discounts
+	ShippingFee     float64 `json:"shipping_fee"`    // Shipping paid by the buyer
+	DiscountAmount  float64 `json:"discount_amount"` // Item discounts by the seller and platform
+	SellerVoucher   float64 `json:"seller_voucher"`
+	PlatformVoucher float64 `json:"platform_voucher"`
+	Coins           float64 `json:"coins"` // Shopee coins redeemed by the buyer
+	CommissionFee   float64 `json:"commission_fee"`
+	TransactionFee  float64 `json:"transaction_fee"`
+	ServiceFee      float64 `json:"service_fee"`
+	NetPayout       float64 `json:"net_payout"`
+	PaymentMethod   string  `json:"payment_method,omitempty"`
+	Currency        string  `json:"currency,omitempty"`
+}
@@ -1,9 +1,25 @@
 package providers
 
 import (
+	"context"
 	"time"
 )
 
+// ReturnProvider is implemented by marketplaces that expose return, refund and
+// cancellation requests
+type ReturnProvider interface {
+	GetReturns(ctx context.Context, params *ReturnListParams) ([]ExternalReturn, string, error)
+	GetReturn(ctx context.Context, externalReturnID string) (*ExternalReturn, error)
+	ConfirmReturn(ctx context.Context, externalReturnID string) error
+	RejectReturn(ctx context.Context, req *DisputeReturnRequest) error
+}
+
+// ReturnReasonLister is implemented by marketplaces that require a seller to
+// pick a reason code when rejecting a return
+type ReturnReasonLister interface {
+	GetRejectReasons(ctx context.Context, externalReturnID string) ([]ReturnReason, error)
+}
+
 // ReturnListParams represents parameters for listing returns
 type ReturnListParams struct {
 	CreateTimeFrom time.Time `json:"create_time_from,omitempty"`
@@ -18,7 +34,9 @@ type ReturnListParams struct {
 type ExternalReturn struct {
 	ExternalReturnID string              `json:"external_return_id"`
 	ExternalOrderID  string              `json:"external_order_id"`
+	Type             string              `json:"type,omitempty"` // ReturnType* value
 	Status           string              `json:"status"`
+	PlatformStatus   string              `json:"platform_status,omitempty"` // Status as reported by the marketplace
 	Reason           string              `json:"reason"`
 	ReasonText       string              `json:"reason_text,omitempty"`
 	RefundAmount     float64             `json:"refund_amount"`
@@ -56,6 +74,13 @@ const (
 	ReturnStatusDisputed   = "disputed"
 )
 
+// Return type constants
+const (
+	ReturnTypeReturnRefund = "return_refund"
+	ReturnTypeRefundOnly   = "refund_only"
+	ReturnTypeCancellation = "cancellation"
+)
+
 // Return reason constants (Shopee standard reasons)
 const (
 	ReturnReasonDidNotReceive       = "DID_NOT_RECEIVE_GOODS"
@@ -73,5 +98,12 @@ type DisputeReturnRequest struct {
 	ReturnID      string   `json:"return_id"`
 	Email         string   `json:"email"`
 	DisputeReason string   `json:"dispute_reason"`
+	ReasonCode    string   `json:"reason_code,omitempty"` // Marketplace reject reason key (TikTok)
 	Images        []string `json:"images,omitempty"`
 }
+
+// ReturnReason is a reason code a seller may use to reject a return
+type ReturnReason struct {
+	Code string `json:"code"`
+	Name string `json:"name"`
+}
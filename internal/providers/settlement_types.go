@@ -0,0 +1,36 @@
+package providers
+
+import (
+	"context"
+	"time"
+)
+
+// SettlementLister is implemented by marketplaces that expose payout
+// statements (Shopee wallet transactions, TikTok Shop settlements)
+type SettlementLister interface {
+	GetSettlements(ctx context.Context, params *SettlementListParams) ([]ExternalSettlement, string, error)
+}
+
+// SettlementListParams represents parameters for listing settlement lines
+type SettlementListParams struct {
+	TimeFrom time.Time `json:"time_from"`
+	TimeTo   time.Time `json:"time_to"`
+	PageSize int       `json:"page_size"`
+	Cursor   string    `json:"cursor,omitempty"`
+}
+
+// ExternalSettlement represents a line of a marketplace payout statement
+type ExternalSettlement struct {
+	ExternalID      string    `json:"external_id"`                 // Wallet transaction ID or settlement key
+	ExternalOrderID string    `json:"external_order_id,omitempty"` // Empty for lines not tied to an order, such as withdrawals
+	Type            string    `json:"type"`                        // Transaction type as reported by the marketplace
+	Amount          float64   `json:"amount"`                      // Amount credited to the seller; negative when debited
+	FeeAmount       float64   `json:"fee_amount"`                  // Fees deducted from the line, when reported
+	CommissionFee   *float64  `json:"commission_fee,omitempty"`    // Fee breakdown as positive amounts; nil when not reported
+	ServiceFee      *float64  `json:"service_fee,omitempty"`
+	TransactionFee  *float64  `json:"transaction_fee,omitempty"`
+	ShippingFee     *float64  `json:"shipping_fee,omitempty"`
+	Currency        string    `json:"currency"`
+	Description     string    `json:"description,omitempty"`
+	SettledAt       time.Time `json:"settled_at"`
+}
@@ -0,0 +1,74 @@
+package providers
+
+import (
+	"time"
+)
+
+// Shipping methods
+const (
+	ShippingMethodDropoff       = "dropoff"        // Seller drops the parcel off at a courier branch
+	ShippingMethodPickup        = "pickup"         // Courier collects the parcel from a seller address
+	ShippingMethodNonIntegrated = "non_integrated" // Seller ships with its own courier and supplies the tracking number
+)
+
+// ShippingOptions represents the ways an order can be handed to the courier
+type ShippingOptions struct {
+	Methods           []string           `json:"methods"`                      // Available ShippingMethod* values
+	BranchRequired    bool               `json:"branch_required"`              // Dropoff needs a branch from DropoffBranches
+	DropoffBranches   []DropoffBranch    `json:"dropoff_branches,omitempty"`   // Courier branches accepting the parcel
+	PickupAddresses   []PickupAddress    `json:"pickup_addresses,omitempty"`   // Seller addresses the courier can collect from
+	ShippingProviders []ShippingProvider `json:"shipping_providers,omitempty"` // Couriers a non-integrated shipment must name (TikTok Shop)
+}
+
+// HasMethod reports whether a shipping method is available
+func (o *ShippingOptions) HasMethod(method string) bool {
+	for _, m := range o.Methods {
+		if m == method {
+			return true
+		}
+	}
+	return false
+}
+
+// DropoffBranch represents a courier branch accepting dropoffs
+type DropoffBranch struct {
+	BranchID int64  `json:"branch_id"`
+	Address  string `json:"address"`
+	City     string `json:"city,omitempty"`
+	State    string `json:"state,omitempty"`
+	ZipCode  string `json:"zip_code,omitempty"`
+}
+
+// PickupAddress represents a seller address with its pickup time slots
+type PickupAddress struct {
+	AddressID int64            `json:"address_id"`
+	Address   string           `json:"address"`
+	City      string           `json:"city,omitempty"`
+	State     string           `json:"state,omitempty"`
+	ZipCode   string           `json:"zip_code,omitempty"`
+	IsDefault bool             `json:"is_default"` // Seller's default pickup address on the marketplace
+	TimeSlots []PickupTimeSlot `json:"time_slots"`
+}
+
+// PickupTimeSlot represents a time slot the courier can collect in
+type PickupTimeSlot struct {
+	PickupTimeID string    `json:"pickup_time_id"`
+	Date         time.Time `json:"date"`
+	Text         string    `json:"text,omitempty"` // Marketplace label for the slot, e.g. "09:00-12:00"
+}
+
+// ShippingProvider represents a courier a seller-fulfilled shipment can be sent with
+type ShippingProvider struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// ShipmentSelection represents the shipping option chosen for an order
+type ShipmentSelection struct {
+	Method             string `json:"method"`
+	BranchID           int64  `json:"branch_id,omitempty"`            // Dropoff branch
+	AddressID          int64  `json:"address_id,omitempty"`           // Pickup address
+	PickupTimeID       string `json:"pickup_time_id,omitempty"`       // Pickup time slot
+	TrackingNumber     string `json:"tracking_number,omitempty"`      // Non-integrated shipments
+	ShippingProviderID string `json:"shipping_provider_id,omitempty"` // Courier of non-integrated shipments, from ShippingProviders
+}
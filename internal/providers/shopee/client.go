@@ -162,6 +162,13 @@ type Request struct {
 	NeedAuth bool
 }
 
+// RawResponse receives the body of an endpoint that answers with a file
+// instead of JSON. Pass it as the result of Do.
+type RawResponse struct {
+	Body        []byte
+	ContentType string
+}
+
 // Do performs an HTTP request to the Shopee API with automatic retry and token refresh.
 func (c *Client) Do(ctx context.Context, req *Request, result interface{}) error {
 	executor := shopeedomain.NewExecutor(c.retryPolicy)
@@ -369,6 +376,11 @@ func (c *Client) doRequest(ctx context.Context, req *Request, result interface{}
 	}
 
 	// Parse response
+	if raw, ok := result.(*RawResponse); ok {
+		raw.Body = respBody
+		raw.ContentType = resp.Header.Get("Content-Type")
+		return nil
+	}
 	if result != nil {
 		if err := json.Unmarshal(respBody, result); err != nil {
 			return fmt.Errorf("failed to parse response: %w", err)
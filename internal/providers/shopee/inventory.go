@@ -4,10 +4,16 @@ import (
 	"context"
 	"fmt"
 	"net/http"
+	"strconv"
 
 	"github.com/niaga-platform/service-marketplace/internal/providers"
 )
 
+const (
+	// Shop API paths
+	GetWarehouseDetailPath = "/api/v2/shop/get_warehouse_detail"
+)
+
 // InventoryProvider implements inventory operations for Shopee
 type InventoryProvider struct {
 	client *Client
@@ -47,24 +53,164 @@ func (p *InventoryProvider) UpdateStock(ctx context.Context, externalProductID s
 	return nil
 }
 
-// UpdateBatchStock updates stock for multiple products
+// UpdateLocationStock updates the seller stock of a product in one warehouse location
+func (p *InventoryProvider) UpdateLocationStock(ctx context.Context, externalProductID, locationID string, quantity int) error {
+	req := &Request{
+		Method: http.MethodPost,
+		Path:   UpdateStockPath,
+		Body: map[string]interface{}{
+			"item_id": externalProductID,
+			"stock_list": []map[string]interface{}{
+				{
+					"model_id": 0,
+					"seller_stock": []map[string]interface{}{
+						{
+							"location_id": locationID,
+							"stock":       quantity,
+						},
+					},
+				},
+			},
+		},
+		NeedAuth: true,
+	}
+
+	var resp BaseResponse
+	if err := p.client.Do(ctx, req, &resp); err != nil {
+		return fmt.Errorf("failed to update stock: %w", err)
+	}
+
+	if resp.HasError() {
+		return fmt.Errorf("shopee error: %s", resp.GetError())
+	}
+
+	return nil
+}
+
+// UpdateBatchStock updates stock for multiple products. Updates are grouped
+// into one update_stock call per item, with one stock_list entry per model.
+// The variant ID of an update is its Shopee model ID; updates without one go
+// to model 0, the only model of an item without variations.
 func (p *InventoryProvider) UpdateBatchStock(ctx context.Context, updates []providers.InventoryUpdate) ([]providers.InventoryUpdateResult, error) {
 	results := make([]providers.InventoryUpdateResult, len(updates))
 
+	itemOrder := make([]string, 0)
+	byItem := make(map[string][]int)
 	for i, update := range updates {
-		err := p.UpdateStock(ctx, update.ExternalProductID, update.Quantity)
 		results[i] = providers.InventoryUpdateResult{
 			ExternalProductID: update.ExternalProductID,
-			Success:           err == nil,
+			ExternalVariantID: update.ExternalVariantID,
+			ExternalSKU:       update.ExternalSKU,
+			LocationID:        update.LocationID,
 		}
-		if err != nil {
+		if _, err := parseModelID(update.ExternalVariantID); err != nil {
 			results[i].Error = err.Error()
+			continue
+		}
+		if _, ok := byItem[update.ExternalProductID]; !ok {
+			itemOrder = append(itemOrder, update.ExternalProductID)
+		}
+		byItem[update.ExternalProductID] = append(byItem[update.ExternalProductID], i)
+	}
+
+	for _, itemID := range itemOrder {
+		indexes := byItem[itemID]
+		failures, err := p.updateItemStock(ctx, itemID, updates, indexes)
+		for _, i := range indexes {
+			if err != nil {
+				results[i].Error = err.Error()
+				continue
+			}
+			modelID, _ := parseModelID(updates[i].ExternalVariantID)
+			if reason, failed := failures[modelID]; failed {
+				results[i].Error = reason
+				continue
+			}
+			results[i].Success = true
 		}
 	}
 
 	return results, nil
 }
 
+// updateItemStock sends the stock of several models of one item in a single
+// update_stock call. It returns the failure reason of each model that failed.
+func (p *InventoryProvider) updateItemStock(ctx context.Context, itemID string, updates []providers.InventoryUpdate, indexes []int) (map[int64]string, error) {
+	modelOrder := make([]int64, 0, len(indexes))
+	entries := make(map[int64]map[string]interface{}, len(indexes))
+	for _, i := range indexes {
+		update := updates[i]
+		modelID, _ := parseModelID(update.ExternalVariantID)
+		entry, ok := entries[modelID]
+		if !ok {
+			entry = map[string]interface{}{"model_id": modelID}
+			entries[modelID] = entry
+			modelOrder = append(modelOrder, modelID)
+		}
+		if update.LocationID == "" {
+			entry["normal_stock"] = update.Quantity
+			continue
+		}
+		sellerStock, _ := entry["seller_stock"].([]map[string]interface{})
+		entry["seller_stock"] = append(sellerStock, map[string]interface{}{
+			"location_id": update.LocationID,
+			"stock":       update.Quantity,
+		})
+	}
+
+	stockList := make([]map[string]interface{}, len(modelOrder))
+	for i, modelID := range modelOrder {
+		stockList[i] = entries[modelID]
+	}
+
+	req := &Request{
+		Method: http.MethodPost,
+		Path:   UpdateStockPath,
+		Body: map[string]interface{}{
+			"item_id":    itemID,
+			"stock_list": stockList,
+		},
+		NeedAuth: true,
+	}
+
+	var resp struct {
+		BaseResponse
+		Response struct {
+			FailureList []struct {
+				ModelID      int64  `json:"model_id"`
+				FailedReason string `json:"failed_reason"`
+			} `json:"failure_list"`
+		} `json:"response"`
+	}
+
+	if err := p.client.Do(ctx, req, &resp); err != nil {
+		return nil, fmt.Errorf("failed to update stock: %w", err)
+	}
+
+	if resp.HasError() {
+		return nil, fmt.Errorf("shopee error: %s", resp.GetError())
+	}
+
+	failures := make(map[int64]string, len(resp.Response.FailureList))
+	for _, failure := range resp.Response.FailureList {
+		failures[failure.ModelID] = failure.FailedReason
+	}
+	return failures, nil
+}
+
+// parseModelID parses the Shopee model ID held in an update's variant ID;
+// items without variations use model 0
+func parseModelID(externalVariantID string) (int64, error) {
+	if externalVariantID == "" {
+		return 0, nil
+	}
+	modelID, err := strconv.ParseInt(externalVariantID, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid Shopee model ID %q", externalVariantID)
+	}
+	return modelID, nil
+}
+
 // GetStock fetches current stock levels
 func (p *InventoryProvider) GetStock(ctx context.Context, externalProductIDs []string) ([]providers.InventoryItem, error) {
 	itemIDList := ""
@@ -118,3 +264,39 @@ func (p *InventoryProvider) GetStock(ctx context.Context, externalProductIDs []s
 
 	return items, nil
 }
+
+// GetWarehouses lists the shop's warehouse locations (multi-warehouse sellers only)
+func (p *InventoryProvider) GetWarehouses(ctx context.Context) ([]providers.Warehouse, error) {
+	req := &Request{
+		Method:   http.MethodGet,
+		Path:     GetWarehouseDetailPath,
+		NeedAuth: true,
+	}
+
+	var resp struct {
+		BaseResponse
+		Response []struct {
+			WarehouseID   int64  `json:"warehouse_id"`
+			WarehouseName string `json:"warehouse_name"`
+			LocationID    string `json:"location_id"`
+		} `json:"response"`
+	}
+
+	if err := p.client.Do(ctx, req, &resp); err != nil {
+		return nil, fmt.Errorf("failed to get warehouses: %w", err)
+	}
+
+	if resp.HasError() {
+		return nil, fmt.Errorf("shopee error: %s", resp.GetError())
+	}
+
+	warehouses := make([]providers.Warehouse, len(resp.Response))
+	for i, w := range resp.Response {
+		warehouses[i] = providers.Warehouse{
+			ID:   w.LocationID,
+			Name: w.WarehouseName,
+		}
+	}
+
+	return warehouses, nil
+}
@@ -6,6 +6,7 @@ import (
 	"net/http"
 	"time"
 
+	"github.com/niaga-platform/service-marketplace/internal/domain/shared"
 	"github.com/niaga-platform/service-marketplace/internal/providers"
 )
 
@@ -32,8 +33,13 @@ func NewOrderProvider(client *Client) *OrderProvider {
 
 // GetOrders fetches orders from Shopee
 func (p *OrderProvider) GetOrders(ctx context.Context, params *providers.OrderListParams) ([]providers.ExternalOrder, string, error) {
+	timeField := params.TimeField
+	if timeField == "" {
+		timeField = providers.OrderTimeFieldCreate
+	}
+
 	query := map[string]string{
-		"time_range_field": "create_time",
+		"time_range_field": timeField,
 		"time_from":        fmt.Sprintf("%d", params.TimeFrom.Unix()),
 		"time_to":          fmt.Sprintf("%d", params.TimeTo.Unix()),
 		"page_size":        fmt.Sprintf("%d", params.PageSize),
@@ -110,7 +116,7 @@ func (p *OrderProvider) getOrderDetails(ctx context.Context, orderSNs []string)
 		Path:   GetOrderDetailPath,
 		Query: map[string]string{
 			"order_sn_list":            orderSNList,
-			"response_optional_fields": "buyer_user_id,buyer_username,item_list,recipient_address",
+			"response_optional_fields": "buyer_user_id,buyer_username,item_list,recipient_address,payment_method",
 		},
 		NeedAuth: true,
 	}
@@ -130,6 +136,7 @@ func (p *OrderProvider) getOrderDetails(ctx context.Context, orderSNs []string)
 				BuyerUsername    string  `json:"buyer_username"`
 				ShippingCarrier  string  `json:"shipping_carrier"`
 				TrackingNumber   string  `json:"tracking_number"`
+				PaymentMethod    string  `json:"payment_method"`
 				RecipientAddress struct {
 					Name        string `json:"name"`
 					Phone       string `json:"phone"`
@@ -168,12 +175,23 @@ func (p *OrderProvider) getOrderDetails(ctx context.Context, orderSNs []string)
 	for i, o := range resp.Response.OrderList {
 		items := make([]providers.ExternalOrderItem, len(o.ItemList))
 		for j, item := range o.ItemList {
+			sku := item.ModelSKU
+			if sku == "" {
+				sku = item.ItemSKU
+			}
+			variantID := ""
+			if item.ModelID != 0 {
+				variantID = fmt.Sprintf("%d", item.ModelID)
+			}
 			items[j] = providers.ExternalOrderItem{
 				ExternalProductID: fmt.Sprintf("%d", item.ItemID),
-				ExternalSKU:       item.ModelSKU,
+				ExternalVariantID: variantID,
+				ExternalSKU:       sku,
 				Name:              item.ItemName,
+				VariantName:       item.ModelName,
 				Quantity:          item.ModelQuantity,
 				UnitPrice:         item.ModelDiscountedPrice,
+				OriginalPrice:     item.ModelOriginalPrice,
 				TotalPrice:        item.ModelDiscountedPrice * float64(item.ModelQuantity),
 			}
 		}
@@ -181,6 +199,7 @@ func (p *OrderProvider) getOrderDetails(ctx context.Context, orderSNs []string)
 		orders[i] = providers.ExternalOrder{
 			ExternalOrderID: o.OrderSN,
 			Status:          p.mapOrderStatus(o.OrderStatus),
+			PlatformStatus:  o.OrderStatus,
 			TotalAmount:     o.TotalAmount,
 			Currency:        o.Currency,
 			CreatedAt:       time.Unix(o.CreateTime, 0),
@@ -200,30 +219,33 @@ func (p *OrderProvider) getOrderDetails(ctx context.Context, orderSNs []string)
 			Items:          items,
 			TrackingNumber: o.TrackingNumber,
 			Carrier:        o.ShippingCarrier,
+			PaymentMethod:  o.PaymentMethod,
 		}
 	}
 
 	return orders, nil
 }
 
+// mapOrderStatus maps a Shopee order status to the canonical order status.
+// TO_CONFIRM_RECEIVE means the parcel was delivered and awaits buyer confirmation.
 func (p *OrderProvider) mapOrderStatus(status string) string {
 	switch status {
 	case "UNPAID":
-		return "pending_payment"
-	case "READY_TO_SHIP":
-		return "pending_shipment"
-	case "PROCESSED":
-		return "processing"
+		return shared.OrderUnpaid.String()
+	case "READY_TO_SHIP", "PROCESSED", "RETRY_SHIP", "IN_CANCEL":
+		return shared.OrderToShip.String()
 	case "SHIPPED":
-		return "shipped"
+		return shared.OrderShipped.String()
+	case "TO_CONFIRM_RECEIVE":
+		return shared.OrderDelivered.String()
 	case "COMPLETED":
-		return "completed"
+		return shared.OrderCompleted.String()
 	case "CANCELLED":
-		return "cancelled"
-	case "IN_CANCEL":
-		return "cancellation_requested"
+		return shared.OrderCancelled.String()
+	case "TO_RETURN":
+		return shared.OrderReturned.String()
 	default:
-		return status
+		return ""
 	}
 }
 
@@ -239,78 +261,27 @@ func (p *OrderProvider) GetOrder(ctx context.Context, orderID string) (*provider
 	return &orders[0], nil
 }
 
-// ShippingInfo contains information needed for arranging shipment
-type ShippingInfo struct {
-	Method          string // "dropoff" or "pickup"
-	TrackingNumber  string
-	DropoffBranchID int64
-	PickupTimeID    string
-	AddressID       int64
-}
-
-// ArrangeShipment arranges shipment for an order (calls ship_order API)
-func (p *OrderProvider) ArrangeShipment(ctx context.Context, orderSN string, info *ShippingInfo) error {
-	// First get shipping parameters to determine what's needed
-	params, err := p.GetShippingParameter(ctx, orderSN)
-	if err != nil {
-		return fmt.Errorf("failed to get shipping parameters: %w", err)
+// ArrangeShipment arranges shipment for an order (calls ship_order API). Without
+// a selection the first dropoff branch, or the first pickup address and time
+// slot, is used.
+func (p *OrderProvider) ArrangeShipment(ctx context.Context, orderSN string, selection *providers.ShipmentSelection) error {
+	if selection == nil {
+		options, err := p.GetShippingOptions(ctx, orderSN)
+		if err != nil {
+			return fmt.Errorf("failed to get shipping parameters: %w", err)
+		}
+		selection = firstShipment(options)
 	}
 
-	// Build ship order request based on available options
 	body := map[string]interface{}{
 		"order_sn": orderSN,
 	}
 
-	// Check what shipping method is available
-	infoNeeded := params["info_needed"].(map[string]interface{})
-	dropoffInfo := infoNeeded["dropoff"]
-	pickupInfo := infoNeeded["pickup"]
-
-	// Use dropoff if available (most common)
-	if dropoffInfo != nil {
-		dropoffList := params["dropoff"].([]map[string]interface{})
-		if len(dropoffList) > 0 {
-			// Use first available dropoff option
-			firstDropoff := dropoffList[0]
-			body["dropoff"] = map[string]interface{}{}
-
-			// Add branch ID if available
-			if branchList, ok := firstDropoff["branch_list"].([]interface{}); ok && len(branchList) > 0 {
-				if branch, ok := branchList[0].(map[string]interface{}); ok {
-					if branchID, ok := branch["branch_id"].(float64); ok {
-						body["dropoff"].(map[string]interface{})["branch_id"] = int64(branchID)
-					}
-				}
-			}
-		}
-	} else if pickupInfo != nil {
-		pickupList := params["pickup"].([]map[string]interface{})
-		if len(pickupList) > 0 {
-			// Use first available pickup option
-			firstPickup := pickupList[0]
-			pickupBody := map[string]interface{}{}
-
-			// Add address ID if available
-			if addressList, ok := firstPickup["address_list"].([]interface{}); ok && len(addressList) > 0 {
-				if addr, ok := addressList[0].(map[string]interface{}); ok {
-					if addrID, ok := addr["address_id"].(float64); ok {
-						pickupBody["address_id"] = int64(addrID)
-					}
-				}
-			}
-
-			// Add time slot if available
-			if timeSlotList, ok := firstPickup["time_slot_list"].([]interface{}); ok && len(timeSlotList) > 0 {
-				if slot, ok := timeSlotList[0].(map[string]interface{}); ok {
-					if pickupTimeID, ok := slot["pickup_time_id"].(string); ok {
-						pickupBody["pickup_time_id"] = pickupTimeID
-					}
-				}
-			}
-
-			body["pickup"] = pickupBody
-		}
+	method, params, err := shipmentParams(selection)
+	if err != nil {
+		return err
 	}
+	body[method] = params
 
 	req := &Request{
 		Method:   http.MethodPost,
@@ -331,6 +302,56 @@ func (p *OrderProvider) ArrangeShipment(ctx context.Context, orderSN string, inf
 	return nil
 }
 
+// shipmentParams builds the ship_order parameters of a shipping option
+func shipmentParams(selection *providers.ShipmentSelection) (string, map[string]interface{}, error) {
+	switch selection.Method {
+	case providers.ShippingMethodDropoff:
+		dropoff := map[string]interface{}{}
+		if selection.BranchID != 0 {
+			dropoff["branch_id"] = selection.BranchID
+		}
+		if selection.TrackingNumber != "" {
+			dropoff["tracking_number"] = selection.TrackingNumber
+		}
+		return "dropoff", dropoff, nil
+	case providers.ShippingMethodPickup:
+		return "pickup", map[string]interface{}{
+			"address_id":     selection.AddressID,
+			"pickup_time_id": selection.PickupTimeID,
+		}, nil
+	case providers.ShippingMethodNonIntegrated:
+		return "non_integrated", map[string]interface{}{
+			"tracking_number": selection.TrackingNumber,
+		}, nil
+	default:
+		return "", nil, fmt.Errorf("unsupported shipping method: %q", selection.Method)
+	}
+}
+
+// firstShipment picks the first available option, preferring dropoff
+func firstShipment(options *providers.ShippingOptions) *providers.ShipmentSelection {
+	switch {
+	case options.HasMethod(providers.ShippingMethodDropoff):
+		selection := &providers.ShipmentSelection{Method: providers.ShippingMethodDropoff}
+		if len(options.DropoffBranches) > 0 {
+			selection.BranchID = options.DropoffBranches[0].BranchID
+		}
+		return selection
+	case options.HasMethod(providers.ShippingMethodPickup):
+		selection := &providers.ShipmentSelection{Method: providers.ShippingMethodPickup}
+		if len(options.PickupAddresses) > 0 {
+			address := options.PickupAddresses[0]
+			selection.AddressID = address.AddressID
+			if len(address.TimeSlots) > 0 {
+				selection.PickupTimeID = address.TimeSlots[0].PickupTimeID
+			}
+		}
+		return selection
+	default:
+		return &providers.ShipmentSelection{Method: providers.ShippingMethodNonIntegrated}
+	}
+}
+
 // UpdateOrderStatus updates order status (e.g., ship order)
 func (p *OrderProvider) UpdateOrderStatus(ctx context.Context, orderID, status string) error {
 	if status == "shipped" {
@@ -348,8 +369,11 @@ type ShippingDocumentInfo struct {
 	ErrorMsg    string `json:"error_msg,omitempty"`
 }
 
-// GetShippingParameter gets shipping parameters for an order
-func (p *OrderProvider) GetShippingParameter(ctx context.Context, orderSN string) (map[string]interface{}, error) {
+// GetShippingOptions gets the shipping parameters of an order: the dropoff
+// branches, pickup addresses with their time slots, and whether the order can
+// be shipped non-integrated. A method is available when Shopee lists the
+// information it needs, even if that list is empty.
+func (p *OrderProvider) GetShippingOptions(ctx context.Context, orderSN string) (*providers.ShippingOptions, error) {
 	req := &Request{
 		Method: http.MethodGet,
 		Path:   GetShippingParameterPath,
@@ -363,12 +387,34 @@ func (p *OrderProvider) GetShippingParameter(ctx context.Context, orderSN string
 		BaseResponse
 		Response struct {
 			InfoNeeded struct {
-				Dropoff   []string `json:"dropoff"`
-				Pickup    []string `json:"pickup"`
+				Dropoff       []string `json:"dropoff"`
+				Pickup        []string `json:"pickup"`
 				NonIntegrated []string `json:"non_integrated"`
 			} `json:"info_needed"`
-			Dropoff   []map[string]interface{} `json:"dropoff"`
-			Pickup    []map[string]interface{} `json:"pickup"`
+			Dropoff struct {
+				BranchList []struct {
+					BranchID int64  `json:"branch_id"`
+					State    string `json:"state"`
+					City     string `json:"city"`
+					Address  string `json:"address"`
+					Zipcode  string `json:"zipcode"`
+				} `json:"branch_list"`
+			} `json:"dropoff"`
+			Pickup struct {
+				AddressList []struct {
+					AddressID    int64    `json:"address_id"`
+					State        string   `json:"state"`
+					City         string   `json:"city"`
+					Address      string   `json:"address"`
+					Zipcode      string   `json:"zipcode"`
+					AddressFlag  []string `json:"address_flag"`
+					TimeSlotList []struct {
+						Date         int64  `json:"date"`
+						TimeText     string `json:"time_text"`
+						PickupTimeID string `json:"pickup_time_id"`
+					} `json:"time_slot_list"`
+				} `json:"address_list"`
+			} `json:"pickup"`
 		} `json:"response"`
 	}
 
@@ -380,11 +426,58 @@ func (p *OrderProvider) GetShippingParameter(ctx context.Context, orderSN string
 		return nil, fmt.Errorf("shopee error: %s", resp.GetError())
 	}
 
-	return map[string]interface{}{
-		"info_needed": resp.Response.InfoNeeded,
-		"dropoff":     resp.Response.Dropoff,
-		"pickup":      resp.Response.Pickup,
-	}, nil
+	infoNeeded := resp.Response.InfoNeeded
+	options := &providers.ShippingOptions{}
+	if infoNeeded.Dropoff != nil {
+		options.Methods = append(options.Methods, providers.ShippingMethodDropoff)
+		for _, field := range infoNeeded.Dropoff {
+			if field == "branch_id" {
+				options.BranchRequired = true
+			}
+		}
+	}
+	if infoNeeded.Pickup != nil {
+		options.Methods = append(options.Methods, providers.ShippingMethodPickup)
+	}
+	if infoNeeded.NonIntegrated != nil {
+		options.Methods = append(options.Methods, providers.ShippingMethodNonIntegrated)
+	}
+
+	for _, branch := range resp.Response.Dropoff.BranchList {
+		options.DropoffBranches = append(options.DropoffBranches, providers.DropoffBranch{
+			BranchID: branch.BranchID,
+			Address:  branch.Address,
+			City:     branch.City,
+			State:    branch.State,
+			ZipCode:  branch.Zipcode,
+		})
+	}
+
+	for _, addr := range resp.Response.Pickup.AddressList {
+		address := providers.PickupAddress{
+			AddressID: addr.AddressID,
+			Address:   addr.Address,
+			City:      addr.City,
+			State:     addr.State,
+			ZipCode:   addr.Zipcode,
+			TimeSlots: make([]providers.PickupTimeSlot, 0, len(addr.TimeSlotList)),
+		}
+		for _, flag := range addr.AddressFlag {
+			if flag == "default_address" {
+				address.IsDefault = true
+			}
+		}
+		for _, slot := range addr.TimeSlotList {
+			address.TimeSlots = append(address.TimeSlots, providers.PickupTimeSlot{
+				PickupTimeID: slot.PickupTimeID,
+				Date:         time.Unix(slot.Date, 0),
+				Text:         slot.TimeText,
+			})
+		}
+		options.PickupAddresses = append(options.PickupAddresses, address)
+	}
+
+	return options, nil
 }
 
 // CreateShippingDocument creates AWB document for an order
@@ -486,56 +579,3 @@ func (p *OrderProvider) GetShippingDocumentResult(ctx context.Context, orderSN s
 
 	return nil, fmt.Errorf("order not found in result")
 }
-
-// DownloadShippingDocument downloads AWB PDF for an order
-func (p *OrderProvider) DownloadShippingDocument(ctx context.Context, orderSN string, documentType string) (string, error) {
-	if documentType == "" {
-		documentType = "NORMAL_AIR_WAYBILL"
-	}
-
-	req := &Request{
-		Method: http.MethodPost,
-		Path:   DownloadShippingDocumentPath,
-		Body: map[string]interface{}{
-			"order_list": []map[string]interface{}{
-				{
-					"order_sn":               orderSN,
-					"shipping_document_type": documentType,
-				},
-			},
-		},
-		NeedAuth: true,
-	}
-
-	var resp struct {
-		BaseResponse
-		Response struct {
-			ResultList []struct {
-				OrderSN            string `json:"order_sn"`
-				ShippingDocumentInfo struct {
-					ShippingDocumentURL string `json:"shipping_document_url"`
-				} `json:"shipping_document_info"`
-				FailError string `json:"fail_error"`
-			} `json:"result_list"`
-		} `json:"response"`
-	}
-
-	if err := p.client.Do(ctx, req, &resp); err != nil {
-		return "", fmt.Errorf("failed to download shipping document: %w", err)
-	}
-
-	if resp.HasError() {
-		return "", fmt.Errorf("shopee error: %s", resp.GetError())
-	}
-
-	for _, result := range resp.Response.ResultList {
-		if result.OrderSN == orderSN {
-			if result.FailError != "" {
-				return "", fmt.Errorf("download failed: %s", result.FailError)
-			}
-			return result.ShippingDocumentInfo.ShippingDocumentURL, nil
-		}
-	}
-
-	return "", fmt.Errorf("order not found in result")
-}
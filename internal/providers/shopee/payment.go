@@ -0,0 +1,145 @@
+package shopee
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/niaga-platform/service-marketplace/internal/providers"
+)
+
+const (
+	GetEscrowDetailPath          = "/api/v2/payment/get_escrow_detail"
+	GetWalletTransactionListPath = "/api/v2/payment/get_wallet_transaction_list"
+)
+
+// GetOrderFinancials fetches the escrow breakdown of an order. Amounts are
+// estimates until the order is completed and the escrow is released.
+func (p *OrderProvider) GetOrderFinancials(ctx context.Context, orderSN string) (*providers.OrderFinancials, error) {
+	req := &Request{
+		Method: http.MethodGet,
+		Path:   GetEscrowDetailPath,
+		Query: map[string]string{
+			"order_sn": orderSN,
+		},
+		NeedAuth: true,
+	}
+
+	var resp struct {
+		BaseResponse
+		Response struct {
+			OrderSN          string `json:"order_sn"`
+			BuyerPaymentInfo struct {
+				BuyerPaymentMethod string `json:"buyer_payment_method"`
+			} `json:"buyer_payment_info"`
+			OrderIncome struct {
+				EscrowAmount         float64 `json:"escrow_amount"`
+				OriginalPrice        float64 `json:"original_price"`
+				SellerDiscount       float64 `json:"seller_discount"`
+				ShopeeDiscount       float64 `json:"shopee_discount"`
+				VoucherFromSeller    float64 `json:"voucher_from_seller"`
+				VoucherFromShopee    float64 `json:"voucher_from_shopee"`
+				Coins                float64 `json:"coins"`
+				BuyerPaidShippingFee float64 `json:"buyer_paid_shipping_fee"`
+				CommissionFee        float64 `json:"commission_fee"`
+				ServiceFee           float64 `json:"service_fee"`
+				SellerTransactionFee float64 `json:"seller_transaction_fee"`
+			} `json:"order_income"`
+		} `json:"response"`
+	}
+
+	if err := p.client.Do(ctx, req, &resp); err != nil {
+		return nil, fmt.Errorf("failed to get escrow detail: %w", err)
+	}
+
+	if resp.HasError() {
+		return nil, fmt.Errorf("shopee error: %s", resp.GetError())
+	}
+
+	income := resp.Response.OrderIncome
+	return &providers.OrderFinancials{
+		SubtotalAmount:  income.OriginalPrice,
+		ShippingFee:     income.BuyerPaidShippingFee,
+		DiscountAmount:  income.SellerDiscount + income.ShopeeDiscount,
+		SellerVoucher:   income.VoucherFromSeller,
+		PlatformVoucher: income.VoucherFromShopee,
+		Coins:           income.Coins,
+		CommissionFee:   income.CommissionFee,
+		TransactionFee:  income.SellerTransactionFee,
+		ServiceFee:      income.ServiceFee,
+		NetPayout:       income.EscrowAmount,
+		PaymentMethod:   resp.Response.BuyerPaymentInfo.BuyerPaymentMethod,
+	}, nil
+}
+
+// GetSettlements lists the seller wallet transactions created in a time range.
+// Escrow releases carry the order they pay out but no fee breakdown, which stays
+// nil. The cursor is the next page number.
+func (p *OrderProvider) GetSettlements(ctx context.Context, params *providers.SettlementListParams) ([]providers.ExternalSettlement, string, error) {
+	pageSize := params.PageSize
+	if pageSize == 0 {
+		pageSize = 50
+	}
+	pageNo := 0
+	if params.Cursor != "" {
+		pageNo, _ = strconv.Atoi(params.Cursor)
+	}
+
+	req := &Request{
+		Method: http.MethodGet,
+		Path:   GetWalletTransactionListPath,
+		Query: map[string]string{
+			"page_no":          fmt.Sprintf("%d", pageNo),
+			"page_size":        fmt.Sprintf("%d", pageSize),
+			"create_time_from": fmt.Sprintf("%d", params.TimeFrom.Unix()),
+			"create_time_to":   fmt.Sprintf("%d", params.TimeTo.Unix()),
+		},
+		NeedAuth: true,
+	}
+
+	var resp struct {
+		BaseResponse
+		Response struct {
+			More            bool `json:"more"`
+			TransactionList []struct {
+				TransactionID   int64   `json:"transaction_id"`
+				TransactionType string  `json:"transaction_type"`
+				Amount          float64 `json:"amount"`
+				TransactionFee  float64 `json:"transaction_fee"`
+				OrderSN         string  `json:"order_sn"`
+				Description     string  `json:"description"`
+				CreateTime      int64   `json:"create_time"`
+			} `json:"transaction_list"`
+		} `json:"response"`
+	}
+
+	if err := p.client.Do(ctx, req, &resp); err != nil {
+		return nil, "", fmt.Errorf("failed to get wallet transactions: %w", err)
+	}
+
+	if resp.HasError() {
+		return nil, "", fmt.Errorf("shopee error: %s", resp.GetError())
+	}
+
+	lines := make([]providers.ExternalSettlement, len(resp.Response.TransactionList))
+	for i, t := range resp.Response.TransactionList {
+		lines[i] = providers.ExternalSettlement{
+			ExternalID:      strconv.FormatInt(t.TransactionID, 10),
+			ExternalOrderID: t.OrderSN,
+			Type:            t.TransactionType,
+			Amount:          t.Amount,
+			FeeAmount:       t.TransactionFee,
+			Description:     t.Description,
+			SettledAt:       time.Unix(t.CreateTime, 0),
+		}
+	}
+
+	nextCursor := ""
+	if resp.Response.More {
+		nextCursor = strconv.Itoa(pageNo + 1)
+	}
+
+	return lines, nextCursor, nil
+}
@@ -24,6 +24,7 @@ const (
 	GetItemInfoPath     = "/api/v2/product/get_item_base_info"
 	GetCategoryPath     = "/api/v2/product/get_category"
 	UpdateStockPath     = "/api/v2/product/update_stock"
+	GetModelListPath    = "/api/v2/product/get_model_list"
 	UploadImagePath     = "/api/v2/media_space/upload_image"
 	InitVideoUploadPath = "/api/v2/media_space/init_video_upload"
 
@@ -645,7 +646,8 @@ func (p *ProductProvider) GetItemBaseInfo(ctx context.Context, itemIDs []int64)
 	return items, nil
 }
 
-// GetInventory fetches inventory levels for products
+// GetInventory fetches inventory levels for products. Items with variations
+// are reported per model, with the model ID as the SKU.
 func (p *ProductProvider) GetInventory(ctx context.Context, externalProductIDs []string) ([]providers.InventoryItem, error) {
 	// Build comma-separated item IDs
 	itemIDList := ""
@@ -670,6 +672,7 @@ func (p *ProductProvider) GetInventory(ctx context.Context, externalProductIDs [
 		Response struct {
 			ItemList []struct {
 				ItemID      int64 `json:"item_id"`
+				HasModel    bool  `json:"has_model"`
 				StockInfoV2 struct {
 					SummaryInfo struct {
 						TotalAvailableStock int `json:"total_available_stock"`
@@ -688,12 +691,68 @@ func (p *ProductProvider) GetInventory(ctx context.Context, externalProductIDs [
 		return nil, fmt.Errorf("shopee error: %s", resp.GetError())
 	}
 
-	items := make([]providers.InventoryItem, len(resp.Response.ItemList))
-	for i, item := range resp.Response.ItemList {
-		items[i] = providers.InventoryItem{
-			ExternalProductID: fmt.Sprintf("%d", item.ItemID),
+	items := make([]providers.InventoryItem, 0, len(resp.Response.ItemList))
+	for _, item := range resp.Response.ItemList {
+		externalProductID := fmt.Sprintf("%d", item.ItemID)
+		if item.HasModel {
+			modelItems, err := p.GetModelInventory(ctx, externalProductID)
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, modelItems...)
+			continue
+		}
+		items = append(items, providers.InventoryItem{
+			ExternalProductID: externalProductID,
 			Quantity:          item.StockInfoV2.SummaryInfo.TotalAvailableStock,
 			Reserved:          item.StockInfoV2.SummaryInfo.TotalReservedStock,
+		})
+	}
+
+	return items, nil
+}
+
+// GetModelInventory fetches the stock of every model of an item
+func (p *ProductProvider) GetModelInventory(ctx context.Context, externalProductID string) ([]providers.InventoryItem, error) {
+	req := &Request{
+		Method: http.MethodGet,
+		Path:   GetModelListPath,
+		Query: map[string]string{
+			"item_id": externalProductID,
+		},
+		NeedAuth: true,
+	}
+
+	var resp struct {
+		BaseResponse
+		Response struct {
+			Model []struct {
+				ModelID     int64 `json:"model_id"`
+				StockInfoV2 struct {
+					SummaryInfo struct {
+						TotalAvailableStock int `json:"total_available_stock"`
+						TotalReservedStock  int `json:"total_reserved_stock"`
+					} `json:"summary_info"`
+				} `json:"stock_info_v2"`
+			} `json:"model"`
+		} `json:"response"`
+	}
+
+	if err := p.client.Do(ctx, req, &resp); err != nil {
+		return nil, fmt.Errorf("failed to get model inventory: %w", err)
+	}
+
+	if resp.HasError() {
+		return nil, fmt.Errorf("shopee error: %s", resp.GetError())
+	}
+
+	items := make([]providers.InventoryItem, len(resp.Response.Model))
+	for i, model := range resp.Response.Model {
+		items[i] = providers.InventoryItem{
+			ExternalProductID: externalProductID,
+			ExternalSKU:       fmt.Sprintf("%d", model.ModelID),
+			Quantity:          model.StockInfoV2.SummaryInfo.TotalAvailableStock,
+			Reserved:          model.StockInfoV2.SummaryInfo.TotalReservedStock,
 		}
 	}
 
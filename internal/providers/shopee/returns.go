@@ -214,10 +214,24 @@ func (p *ReturnProvider) GetReturn(ctx context.Context, returnSN string) (*provi
 		}
 	}
 
+	// Returns that need no logistics are refunded without the goods coming back
+	returnType := providers.ReturnTypeReturnRefund
+	if !r.NeedsLogistics {
+		returnType = providers.ReturnTypeRefundOnly
+	}
+
+	var shipDueDate *time.Time
+	if r.ReturnShipDue > 0 {
+		t := time.Unix(r.ReturnShipDue, 0)
+		shipDueDate = &t
+	}
+
 	return &providers.ExternalReturn{
 		ExternalReturnID: r.ReturnSN,
 		ExternalOrderID:  r.OrderSN,
+		Type:             returnType,
 		Status:           p.mapReturnStatus(r.Status),
+		PlatformStatus:   r.Status,
 		Reason:           r.Reason,
 		ReasonText:       r.TextReason,
 		RefundAmount:     r.RefundAmount,
@@ -230,7 +244,7 @@ func (p *ReturnProvider) GetReturn(ctx context.Context, returnSN string) (*provi
 		Images:           r.Images,
 		CreatedAt:        time.Unix(r.CreateTime, 0),
 		UpdatedAt:        time.Unix(r.UpdateTime, 0),
-		ShipDueDate:      func() *time.Time { t := time.Unix(r.ReturnShipDue, 0); return &t }(),
+		ShipDueDate:      shipDueDate,
 	}, nil
 }
 
@@ -288,6 +302,12 @@ func (p *ReturnProvider) DisputeReturn(ctx context.Context, returnSN string, ema
 	return nil
 }
 
+// RejectReturn disputes a return request; Shopee has no reason codes, so the
+// dispute is raised with the email, reason and evidence images
+func (p *ReturnProvider) RejectReturn(ctx context.Context, req *providers.DisputeReturnRequest) error {
+	return p.DisputeReturn(ctx, req.ReturnID, req.Email, req.DisputeReason, req.Images)
+}
+
 // mapReturnStatus maps Shopee return status to internal status
 func (p *ReturnProvider) mapReturnStatus(status string) string {
 	switch status {
@@ -0,0 +1,233 @@
+package shopee
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/niaga-platform/service-marketplace/internal/providers"
+)
+
+const (
+	BatchShipOrderPath = "/api/v2/logistics/batch_ship_order"
+
+	// MaxBatchOrders is the most orders Shopee accepts in one batch logistics call
+	MaxBatchOrders = 50
+)
+
+// batchResult is a per-order result of a batch logistics call
+type batchResult struct {
+	OrderSN     string `json:"order_sn"`
+	Status      string `json:"status"`
+	FailError   string `json:"fail_error"`
+	FailMessage string `json:"fail_message"`
+}
+
+// failure returns the error of a batch result, or an empty string on success
+func (r *batchResult) failure() string {
+	if r.FailError == "" {
+		return ""
+	}
+	if r.FailMessage != "" {
+		return fmt.Sprintf("%s: %s", r.FailError, r.FailMessage)
+	}
+	return r.FailError
+}
+
+// BatchArrangeShipment arranges shipment for up to MaxBatchOrders orders
+// sharing one shipping option (calls batch_ship_order API). It returns the
+// error of each order that failed, keyed by order SN.
+func (p *OrderProvider) BatchArrangeShipment(ctx context.Context, orderSNs []string, selection *providers.ShipmentSelection) (map[string]string, error) {
+	if len(orderSNs) > MaxBatchOrders {
+		return nil, fmt.Errorf("too many orders: %d, at most %d per batch", len(orderSNs), MaxBatchOrders)
+	}
+
+	method, params, err := shipmentParams(selection)
+	if err != nil {
+		return nil, err
+	}
+
+	req := &Request{
+		Method: http.MethodPost,
+		Path:   BatchShipOrderPath,
+		Body: map[string]interface{}{
+			"order_list": orderList(orderSNs, ""),
+			method:       params,
+		},
+		NeedAuth: true,
+	}
+
+	var resp struct {
+		BaseResponse
+		Response struct {
+			ResultList []batchResult `json:"result_list"`
+		} `json:"response"`
+	}
+
+	if err := p.client.Do(ctx, req, &resp); err != nil {
+		return nil, fmt.Errorf("failed to batch ship orders: %w", err)
+	}
+
+	if resp.HasError() {
+		return nil, fmt.Errorf("shopee error: %s", resp.GetError())
+	}
+
+	failures := make(map[string]string)
+	for _, result := range resp.Response.ResultList {
+		if failure := result.failure(); failure != "" {
+			failures[result.OrderSN] = failure
+		}
+	}
+	return failures, nil
+}
+
+// CreateShippingDocuments creates the AWB documents of up to MaxBatchOrders
+// orders. It returns the error of each order that failed, keyed by order SN.
+func (p *OrderProvider) CreateShippingDocuments(ctx context.Context, orderSNs []string, documentType string) (map[string]string, error) {
+	if len(orderSNs) > MaxBatchOrders {
+		return nil, fmt.Errorf("too many orders: %d, at most %d per batch", len(orderSNs), MaxBatchOrders)
+	}
+	if documentType == "" {
+		documentType = "NORMAL_AIR_WAYBILL"
+	}
+
+	req := &Request{
+		Method: http.MethodPost,
+		Path:   CreateShippingDocumentPath,
+		Body: map[string]interface{}{
+			"order_list": orderList(orderSNs, documentType),
+		},
+		NeedAuth: true,
+	}
+
+	var resp struct {
+		BaseResponse
+		Response struct {
+			ResultList []batchResult `json:"result_list"`
+		} `json:"response"`
+	}
+
+	if err := p.client.Do(ctx, req, &resp); err != nil {
+		return nil, fmt.Errorf("failed to create shipping documents: %w", err)
+	}
+
+	if resp.HasError() {
+		return nil, fmt.Errorf("shopee error: %s", resp.GetError())
+	}
+
+	failures := make(map[string]string)
+	for _, result := range resp.Response.ResultList {
+		if failure := result.failure(); failure != "" {
+			failures[result.OrderSN] = failure
+		}
+	}
+	return failures, nil
+}
+
+// GetShippingDocumentResults checks whether the AWB documents of up to
+// MaxBatchOrders orders are ready, keyed by order SN
+func (p *OrderProvider) GetShippingDocumentResults(ctx context.Context, orderSNs []string, documentType string) (map[string]*ShippingDocumentInfo, error) {
+	if len(orderSNs) > MaxBatchOrders {
+		return nil, fmt.Errorf("too many orders: %d, at most %d per batch", len(orderSNs), MaxBatchOrders)
+	}
+	if documentType == "" {
+		documentType = "NORMAL_AIR_WAYBILL"
+	}
+
+	req := &Request{
+		Method: http.MethodPost,
+		Path:   GetShippingDocumentResultPath,
+		Body: map[string]interface{}{
+			"order_list": orderList(orderSNs, documentType),
+		},
+		NeedAuth: true,
+	}
+
+	var resp struct {
+		BaseResponse
+		Response struct {
+			ResultList []batchResult `json:"result_list"`
+		} `json:"response"`
+	}
+
+	if err := p.client.Do(ctx, req, &resp); err != nil {
+		return nil, fmt.Errorf("failed to get shipping document results: %w", err)
+	}
+
+	if resp.HasError() {
+		return nil, fmt.Errorf("shopee error: %s", resp.GetError())
+	}
+
+	results := make(map[string]*ShippingDocumentInfo, len(resp.Response.ResultList))
+	for _, result := range resp.Response.ResultList {
+		results[result.OrderSN] = &ShippingDocumentInfo{
+			Status:   result.Status, // READY, FAILED, PROCESSING
+			ErrorMsg: result.failure(),
+		}
+	}
+	return results, nil
+}
+
+// FetchShippingDocument downloads the AWB of an order whose document is ready.
+// download_shipping_document answers with the file itself; a JSON body
+// carries an error instead.
+func (p *OrderProvider) FetchShippingDocument(ctx context.Context, orderSN string, documentType string) ([]byte, error) {
+	if documentType == "" {
+		documentType = "NORMAL_AIR_WAYBILL"
+	}
+
+	req := &Request{
+		Method: http.MethodPost,
+		Path:   DownloadShippingDocumentPath,
+		Body: map[string]interface{}{
+			"order_list": orderList([]string{orderSN}, documentType),
+		},
+		NeedAuth: true,
+	}
+
+	var resp RawResponse
+	if err := p.client.Do(ctx, req, &resp); err != nil {
+		return nil, fmt.Errorf("failed to download shipping document: %w", err)
+	}
+
+	contentType := resp.ContentType
+	if contentType == "" {
+		contentType = http.DetectContentType(resp.Body)
+	}
+	if strings.HasPrefix(contentType, "application/json") || strings.HasPrefix(contentType, "text/") {
+		var result struct {
+			BaseResponse
+			Response struct {
+				ResultList []batchResult `json:"result_list"`
+			} `json:"response"`
+		}
+		if err := json.Unmarshal(resp.Body, &result); err == nil {
+			for _, r := range result.Response.ResultList {
+				if failure := r.failure(); failure != "" {
+					return nil, fmt.Errorf("download failed: %s", failure)
+				}
+			}
+		}
+		return nil, fmt.Errorf("download failed: unexpected %s response", contentType)
+	}
+	if len(resp.Body) == 0 {
+		return nil, fmt.Errorf("download failed: empty document")
+	}
+	return resp.Body, nil
+}
+
+// orderList builds the order_list of a batch logistics call
+func orderList(orderSNs []string, documentType string) []map[string]interface{} {
+	list := make([]map[string]interface{}, len(orderSNs))
+	for i, orderSN := range orderSNs {
+		list[i] = map[string]interface{}{
+			"order_sn": orderSN,
+		}
+		if documentType != "" {
+			list[i]["shipping_document_type"] = documentType
+		}
+	}
+	return list
+}
@@ -32,6 +32,7 @@ const (
 // Webhook push codes from Shopee.
 const (
 	PushCodeShopAuthorization   = 1
+	PushCodeShopDeauthorization = 2
 	PushCodeOrderStatusUpdate   = 3
 	PushCodeTrackingUpdate      = 4
 	PushCodeItemPromotion       = 5
@@ -40,6 +41,7 @@ const (
 	PushCodeOpenApi             = 8
 	PushCodeReturnStatusUpdate  = 9
 	PushCodeWebhookTest         = 10
+	PushCodeViolationItem       = 16
 )
 
 // WebhookPayload represents the raw webhook payload from Shopee.
@@ -74,6 +76,59 @@ type TrackingUpdateData struct {
 	UpdateTime     int64  `json:"update_time"`
 }
 
+// ReservedStockChangeData represents reserved stock change webhook data.
+// Shopee pushes it when stock of an item is locked or released by a promotion.
+type ReservedStockChangeData struct {
+	ShopID        int64                      `json:"shop_id"`
+	ItemID        int64                      `json:"item_id"`
+	ModelID       int64                      `json:"model_id"`
+	Action        string                     `json:"action"`
+	PromotionType string                     `json:"promotion_type"`
+	PromotionID   int64                      `json:"promotion_id"`
+	ChangedValues []ReservedStockChangeValue `json:"changed_values"`
+	UpdateTime    int64                      `json:"update_time"`
+}
+
+// ReservedStockChangeValue represents a single changed field in a reserved stock push.
+type ReservedStockChangeValue struct {
+	Name string `json:"name"`
+	Old  int    `json:"old"`
+	New  int    `json:"new"`
+}
+
+// ReservedStock returns the previous and new reserved stock carried by the push.
+func (d *ReservedStockChangeData) ReservedStock() (previous, current int, ok bool) {
+	for _, v := range d.ChangedValues {
+		if v.Name == "reserved_stock" {
+			return v.Old, v.New, true
+		}
+	}
+	return 0, 0, false
+}
+
+// ItemPromotionData represents item promotion webhook data.
+type ItemPromotionData struct {
+	ItemID        int64  `json:"item_id"`
+	PromotionType string `json:"promotion_type"`
+	PromotionID   int64  `json:"promotion_id"`
+	Action        string `json:"action"`
+	ShopID        int64  `json:"shop_id"`
+	UpdateTime    int64  `json:"update_time"`
+}
+
+// ViolationItemData represents item violation webhook data.
+// Shopee pushes it when a listing is banned, deleted or unlisted for breaking its policies.
+type ViolationItemData struct {
+	ItemID            int64  `json:"item_id"`
+	ItemName          string `json:"item_name"`
+	ItemStatus        string `json:"item_status"` // NORMAL, BANNED, SHOPEE_DELETE, UNLIST
+	ViolationType     string `json:"violation_type"`
+	ViolationReason   string `json:"violation_reason"`
+	SuggestionMessage string `json:"suggestion"`
+	ShopID            int64  `json:"shop_id"`
+	UpdateTime        int64  `json:"update_time"`
+}
+
 // ReturnStatusData represents return/refund status update webhook data.
 type ReturnStatusData struct {
 	ReturnSN   string `json:"returnsn"`
@@ -141,7 +196,7 @@ func (h *WebhookHandler) ParseWebhookEvent(body []byte) (*providers.WebhookEvent
 
 	// Parse event-specific data
 	switch payload.Code {
-	case PushCodeShopAuthorization:
+	case PushCodeShopAuthorization, PushCodeShopDeauthorization:
 		var data ShopAuthorizationData
 		if err := json.Unmarshal(payload.Data, &data); err == nil {
 			event.Payload = data
@@ -159,12 +214,30 @@ func (h *WebhookHandler) ParseWebhookEvent(body []byte) (*providers.WebhookEvent
 			event.Payload = data
 		}
 
+	case PushCodeItemPromotion:
+		var data ItemPromotionData
+		if err := json.Unmarshal(payload.Data, &data); err == nil {
+			event.Payload = data
+		}
+
+	case PushCodeReservedStockChange:
+		var data ReservedStockChangeData
+		if err := json.Unmarshal(payload.Data, &data); err == nil {
+			event.Payload = data
+		}
+
 	case PushCodeReturnStatusUpdate:
 		var data ReturnStatusData
 		if err := json.Unmarshal(payload.Data, &data); err == nil {
 			event.Payload = data
 		}
 
+	case PushCodeViolationItem:
+		var data ViolationItemData
+		if err := json.Unmarshal(payload.Data, &data); err == nil {
+			event.Payload = data
+		}
+
 	default:
 		// Store raw data for unknown event types
 		var rawData map[string]interface{}
@@ -186,6 +259,8 @@ func (h *WebhookHandler) mapEventType(code int) string {
 	switch code {
 	case PushCodeShopAuthorization:
 		return "shop.authorization"
+	case PushCodeShopDeauthorization:
+		return "shop.deauthorization"
 	case PushCodeOrderStatusUpdate:
 		return "order.status_changed"
 	case PushCodeTrackingUpdate:
@@ -202,6 +277,8 @@ func (h *WebhookHandler) mapEventType(code int) string {
 		return "return.status_changed"
 	case PushCodeWebhookTest:
 		return "webhook.test"
+	case PushCodeViolationItem:
+		return "item.violation"
 	default:
 		return fmt.Sprintf("unknown.%d", code)
 	}
@@ -233,6 +310,12 @@ func ExtractShopID(event *providers.WebhookEvent) (int64, bool) {
 		return data.ShopID, true
 	case ReturnStatusData:
 		return data.ShopID, true
+	case ReservedStockChangeData:
+		return data.ShopID, true
+	case ItemPromotionData:
+		return data.ShopID, true
+	case ViolationItemData:
+		return data.ShopID, true
 	case map[string]interface{}:
 		if shopID, ok := data["shop_id"].(float64); ok {
 			return int64(shopID), true
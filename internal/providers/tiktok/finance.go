@@ -0,0 +1,166 @@
+package tiktok
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/http"
+	"time"
+
+	"github.com/niaga-platform/service-marketplace/internal/providers"
+)
+
+const (
+	GetOrderSettlementsPath = "/api/finance/order/settlements"
+	SearchSettlementsPath   = "/api/finance/settlements/search"
+)
+
+// GetOrderFinancials fetches the settlements of an order and totals them over
+// its SKUs. TikTok Shop reports fees as negative amounts; they are returned as
+// positive amounts. Orders not settled yet return an error.
+func (p *OrderProvider) GetOrderFinancials(ctx context.Context, orderID string) (*providers.OrderFinancials, error) {
+	req := &Request{
+		Method: http.MethodGet,
+		Path:   GetOrderSettlementsPath,
+		Query: map[string]string{
+			"order_id": orderID,
+		},
+		NeedAuth: true,
+	}
+
+	var resp struct {
+		BaseResponse
+		Data struct {
+			SettlementList []struct {
+				SettlementInfo struct {
+					Currency            string `json:"currency"`
+					SettlementAmount    string `json:"settlement_amount"`
+					PlatformPromotion   string `json:"platform_promotion"`
+					FlatFee             string `json:"flat_fee"`
+					SalesFee            string `json:"sales_fee"`
+					AffiliateCommission string `json:"affiliate_commission"`
+					TransactionFee      string `json:"transaction_fee"`
+					SfpServiceFee       string `json:"sfp_service_fee"`
+					ShippingFee         string `json:"shipping_fee"`
+				} `json:"settlement_info"`
+			} `json:"settlement_list"`
+		} `json:"data"`
+	}
+
+	if err := p.client.Do(ctx, req, &resp); err != nil {
+		return nil, fmt.Errorf("failed to get order settlements: %w", err)
+	}
+
+	if resp.HasError() {
+		return nil, fmt.Errorf("tiktok error: %s", resp.GetError())
+	}
+
+	if len(resp.Data.SettlementList) == 0 {
+		return nil, fmt.Errorf("order not settled: %s", orderID)
+	}
+
+	financials := &providers.OrderFinancials{}
+	for _, settlement := range resp.Data.SettlementList {
+		info := settlement.SettlementInfo
+		financials.PlatformVoucher += math.Abs(parseFloat(info.PlatformPromotion))
+		financials.CommissionFee += math.Abs(parseFloat(info.FlatFee)) + math.Abs(parseFloat(info.SalesFee)) + math.Abs(parseFloat(info.AffiliateCommission))
+		financials.TransactionFee += math.Abs(parseFloat(info.TransactionFee))
+		financials.ServiceFee += math.Abs(parseFloat(info.SfpServiceFee))
+		financials.ShippingFee += math.Abs(parseFloat(info.ShippingFee))
+		financials.NetPayout += parseFloat(info.SettlementAmount)
+		if info.Currency != "" {
+			financials.Currency = info.Currency
+		}
+	}
+
+	return financials, nil
+}
+
+// GetSettlements lists the settlements made in a time range. TikTok Shop
+// settles orders per SKU, so an order can have several lines.
+func (p *OrderProvider) GetSettlements(ctx context.Context, params *providers.SettlementListParams) ([]providers.ExternalSettlement, string, error) {
+	pageSize := params.PageSize
+	if pageSize == 0 {
+		pageSize = 50
+	}
+
+	body := map[string]interface{}{
+		"request_time_from": params.TimeFrom.Unix(),
+		"request_time_to":   params.TimeTo.Unix(),
+		"page_size":         pageSize,
+		"sort_type":         1,
+	}
+	if params.Cursor != "" {
+		body["cursor"] = params.Cursor
+	}
+
+	req := &Request{
+		Method:   http.MethodPost,
+		Path:     SearchSettlementsPath,
+		Body:     body,
+		NeedAuth: true,
+	}
+
+	var resp struct {
+		BaseResponse
+		Data struct {
+			More           bool   `json:"more"`
+			NextCursor     string `json:"next_cursor"`
+			SettlementList []struct {
+				UniqueKey      string `json:"unique_key"`
+				OrderID        string `json:"order_id"`
+				SkuName        string `json:"sku_name"`
+				SettlementInfo struct {
+					Currency            string `json:"currency"`
+					SettlementAmount    string `json:"settlement_amount"`
+					SettleTime          int64  `json:"settle_time"`
+					FeeType             string `json:"fee_type"`
+					FlatFee             string `json:"flat_fee"`
+					SalesFee            string `json:"sales_fee"`
+					AffiliateCommission string `json:"affiliate_commission"`
+					TransactionFee      string `json:"transaction_fee"`
+					SfpServiceFee       string `json:"sfp_service_fee"`
+					ShippingFee         string `json:"shipping_fee"`
+				} `json:"settlement_info"`
+			} `json:"settlement_list"`
+		} `json:"data"`
+	}
+
+	if err := p.client.Do(ctx, req, &resp); err != nil {
+		return nil, "", fmt.Errorf("failed to search settlements: %w", err)
+	}
+
+	if resp.HasError() {
+		return nil, "", fmt.Errorf("tiktok error: %s", resp.GetError())
+	}
+
+	lines := make([]providers.ExternalSettlement, len(resp.Data.SettlementList))
+	for i, settlement := range resp.Data.SettlementList {
+		info := settlement.SettlementInfo
+		commissionFee := math.Abs(parseFloat(info.FlatFee)) + math.Abs(parseFloat(info.SalesFee)) + math.Abs(parseFloat(info.AffiliateCommission))
+		serviceFee := math.Abs(parseFloat(info.SfpServiceFee))
+		transactionFee := math.Abs(parseFloat(info.TransactionFee))
+		shippingFee := math.Abs(parseFloat(info.ShippingFee))
+		lines[i] = providers.ExternalSettlement{
+			ExternalID:      settlement.UniqueKey,
+			ExternalOrderID: settlement.OrderID,
+			Type:            info.FeeType,
+			Amount:          parseFloat(info.SettlementAmount),
+			FeeAmount:       commissionFee + serviceFee + transactionFee,
+			CommissionFee:   &commissionFee,
+			ServiceFee:      &serviceFee,
+			TransactionFee:  &transactionFee,
+			ShippingFee:     &shippingFee,
+			Currency:        info.Currency,
+			Description:     settlement.SkuName,
+			SettledAt:       time.Unix(info.SettleTime, 0),
+		}
+	}
+
+	nextCursor := ""
+	if resp.Data.More {
+		nextCursor = resp.Data.NextCursor
+	}
+
+	return lines, nextCursor, nil
+}
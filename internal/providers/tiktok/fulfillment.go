@@ -0,0 +1,427 @@
+package tiktok
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/niaga-platform/service-marketplace/internal/providers"
+)
+
+const (
+	CreatePackagePath        = "/api/fulfillment/package/create"
+	GetPackagePickupPath     = "/api/fulfillment/package_pickup_config/list"
+	GetShippingDocumentPath  = "/api/fulfillment/shipping_document"
+	GetShippingProvidersPath = "/api/logistics/shipping_providers"
+)
+
+// TikTok Shop shipping types of an order
+const (
+	shippingTypeTikTok = "TIKTOK" // Platform logistics
+	shippingTypeSeller = "SELLER" // Seller ships with its own courier
+)
+
+// TikTok Shop pick up types of a package
+const (
+	pickUpTypePickup  = 1
+	pickUpTypeDropoff = 2
+)
+
+// ErrMultiplePackages is returned for orders TikTok Shop split into several
+// packages; those are shipped and labelled in Seller Center
+var ErrMultiplePackages = errors.New("order is split into several packages")
+
+// Shipping document types
+const (
+	DocumentShippingLabel     = "SHIPPING_LABEL"
+	DocumentPickList          = "PICK_LIST"
+	DocumentShippingLabelList = "SL_PL" // Shipping label and pick list
+)
+
+// fulfillmentInfo is the part of an order needed to ship it
+type fulfillmentInfo struct {
+	ShippingType     string `json:"shipping_type"`
+	DeliveryOptionID string `json:"delivery_option_id"`
+	PackageList      []struct {
+		PackageID string `json:"package_id"`
+	} `json:"package_list"`
+}
+
+// getFulfillmentInfo fetches the shipping type and packages of an order
+func (p *OrderProvider) getFulfillmentInfo(ctx context.Context, orderID string) (*fulfillmentInfo, error) {
+	req := &Request{
+		Method: http.MethodPost,
+		Path:   GetOrderDetailPath,
+		Body: map[string]interface{}{
+			"order_id": orderID,
+		},
+		NeedAuth: true,
+	}
+
+	var resp struct {
+		BaseResponse
+		Data fulfillmentInfo `json:"data"`
+	}
+
+	if err := p.client.Do(ctx, req, &resp); err != nil {
+		return nil, fmt.Errorf("failed to get order: %w", err)
+	}
+
+	if resp.HasError() {
+		return nil, fmt.Errorf("tiktok error: %s", resp.GetError())
+	}
+
+	return &resp.Data, nil
+}
+
+// CreatePackage creates the package of an order and returns its ID. Orders
+// are shipped per package; TikTok Shop creates most packages on payment, so
+// this is only needed for orders without one.
+func (p *OrderProvider) CreatePackage(ctx context.Context, orderID string) (string, error) {
+	req := &Request{
+		Method: http.MethodPost,
+		Path:   CreatePackagePath,
+		Body: map[string]interface{}{
+			"order_id": orderID,
+		},
+		NeedAuth: true,
+	}
+
+	var resp struct {
+		BaseResponse
+		Data struct {
+			PackageID string `json:"package_id"`
+		} `json:"data"`
+	}
+
+	if err := p.client.Do(ctx, req, &resp); err != nil {
+		return "", fmt.Errorf("failed to create package: %w", err)
+	}
+
+	if resp.HasError() {
+		return "", fmt.Errorf("tiktok error: %s", resp.GetError())
+	}
+
+	return resp.Data.PackageID, nil
+}
+
+// singlePackage returns the package of an order, or an empty string if it has
+// none yet. Orders split into several packages are rejected.
+func singlePackage(orderID string, info *fulfillmentInfo) (string, error) {
+	switch len(info.PackageList) {
+	case 0:
+		return "", nil
+	case 1:
+		return info.PackageList[0].PackageID, nil
+	default:
+		return "", fmt.Errorf("%w: order %s has %d packages", ErrMultiplePackages, orderID, len(info.PackageList))
+	}
+}
+
+// GetShippingOptions gets how an order can be shipped. Platform logistics
+// orders offer pickup with time slots and dropoff at any courier point;
+// seller-fulfilled orders are shipped non-integrated with one of the shop's
+// shipping providers and a tracking number. Nothing is created on TikTok
+// Shop: an order without a package yet offers dropoff only, since pickup
+// slots belong to a package.
+func (p *OrderProvider) GetShippingOptions(ctx context.Context, orderID string) (*providers.ShippingOptions, error) {
+	info, err := p.getFulfillmentInfo(ctx, orderID)
+	if err != nil {
+		return nil, err
+	}
+	packageID, err := singlePackage(orderID, info)
+	if err != nil {
+		return nil, err
+	}
+
+	if info.ShippingType == shippingTypeSeller {
+		shippingProviders, err := p.GetShippingProviders(ctx, info.DeliveryOptionID)
+		if err != nil {
+			return nil, err
+		}
+		return &providers.ShippingOptions{
+			Methods:           []string{providers.ShippingMethodNonIntegrated},
+			ShippingProviders: shippingProviders,
+		}, nil
+	}
+
+	if packageID == "" {
+		return &providers.ShippingOptions{
+			Methods: []string{providers.ShippingMethodDropoff},
+		}, nil
+	}
+
+	req := &Request{
+		Method: http.MethodGet,
+		Path:   GetPackagePickupPath,
+		Query: map[string]string{
+			"package_id": packageID,
+		},
+		NeedAuth: true,
+	}
+
+	var resp struct {
+		BaseResponse
+		Data struct {
+			IsPickUp       bool `json:"is_pick_up"`
+			IsDropOff      bool `json:"is_drop_off"`
+			PickUpTimeList []struct {
+				StartTime string `json:"start_time"`
+				EndTime   string `json:"end_time"`
+				Avaliable bool   `json:"avaliable"` // Spelled this way by TikTok Shop
+			} `json:"pick_up_time_list"`
+		} `json:"data"`
+	}
+
+	if err := p.client.Do(ctx, req, &resp); err != nil {
+		return nil, fmt.Errorf("failed to get pickup config: %w", err)
+	}
+
+	if resp.HasError() {
+		return nil, fmt.Errorf("tiktok error: %s", resp.GetError())
+	}
+
+	options := &providers.ShippingOptions{}
+	if resp.Data.IsDropOff {
+		options.Methods = append(options.Methods, providers.ShippingMethodDropoff)
+	}
+	if resp.Data.IsPickUp {
+		options.Methods = append(options.Methods, providers.ShippingMethodPickup)
+
+		// Couriers collect from the shop's warehouse address set in Seller
+		// Center, which the API does not return; it is the only address
+		address := providers.PickupAddress{
+			IsDefault: true,
+			TimeSlots: []providers.PickupTimeSlot{},
+		}
+		for _, slot := range resp.Data.PickUpTimeList {
+			if !slot.Avaliable {
+				continue
+			}
+			start, _ := strconv.ParseInt(slot.StartTime, 10, 64)
+			end, _ := strconv.ParseInt(slot.EndTime, 10, 64)
+			address.TimeSlots = append(address.TimeSlots, providers.PickupTimeSlot{
+				PickupTimeID: slot.StartTime + "-" + slot.EndTime,
+				Date:         time.Unix(start, 0),
+				Text:         time.Unix(start, 0).Format("15:04") + "-" + time.Unix(end, 0).Format("15:04"),
+			})
+		}
+		options.PickupAddresses = []providers.PickupAddress{address}
+	}
+
+	return options, nil
+}
+
+// GetShippingProviders lists the couriers seller-fulfilled orders of a
+// delivery option can be shipped with
+func (p *OrderProvider) GetShippingProviders(ctx context.Context, deliveryOptionID string) ([]providers.ShippingProvider, error) {
+	req := &Request{
+		Method: http.MethodGet,
+		Path:   GetShippingProvidersPath,
+		Query: map[string]string{
+			"delivery_option_id": deliveryOptionID,
+		},
+		NeedAuth: true,
+	}
+
+	var resp struct {
+		BaseResponse
+		Data struct {
+			ShippingProviderList []struct {
+				ShippingProviderID   string `json:"shipping_provider_id"`
+				ShippingProviderName string `json:"shipping_provider_name"`
+			} `json:"shipping_provider_list"`
+		} `json:"data"`
+	}
+
+	if err := p.client.Do(ctx, req, &resp); err != nil {
+		return nil, fmt.Errorf("failed to get shipping providers: %w", err)
+	}
+
+	if resp.HasError() {
+		return nil, fmt.Errorf("tiktok error: %s", resp.GetError())
+	}
+
+	shippingProviders := make([]providers.ShippingProvider, len(resp.Data.ShippingProviderList))
+	for i, provider := range resp.Data.ShippingProviderList {
+		shippingProviders[i] = providers.ShippingProvider{
+			ID:   provider.ShippingProviderID,
+			Name: provider.ShippingProviderName,
+		}
+	}
+	return shippingProviders, nil
+}
+
+// ArrangeShipment ships the package of an order, creating the package if the
+// order has none yet. Without a selection the package is dropped off, or
+// picked up in the first time slot. Orders split into several packages are
+// rejected.
+func (p *OrderProvider) ArrangeShipment(ctx context.Context, orderID string, selection *providers.ShipmentSelection) error {
+	info, err := p.getFulfillmentInfo(ctx, orderID)
+	if err != nil {
+		return err
+	}
+	packageID, err := singlePackage(orderID, info)
+	if err != nil {
+		return err
+	}
+	if packageID == "" {
+		if packageID, err = p.CreatePackage(ctx, orderID); err != nil {
+			return err
+		}
+	}
+
+	if selection == nil {
+		options, err := p.GetShippingOptions(ctx, orderID)
+		if err != nil {
+			return fmt.Errorf("failed to get shipping options: %w", err)
+		}
+		selection = firstShipment(options)
+	}
+
+	body := map[string]interface{}{
+		"package_id": packageID,
+	}
+
+	switch selection.Method {
+	case providers.ShippingMethodDropoff:
+		body["pick_up_type"] = pickUpTypeDropoff
+	case providers.ShippingMethodPickup:
+		start, end, ok := strings.Cut(selection.PickupTimeID, "-")
+		if !ok {
+			return fmt.Errorf("invalid pickup time: %q", selection.PickupTimeID)
+		}
+		body["pick_up_type"] = pickUpTypePickup
+		body["pick_up"] = map[string]interface{}{
+			"pick_up_start_time": start,
+			"pick_up_end_time":   end,
+		}
+	case providers.ShippingMethodNonIntegrated:
+		body["self_shipment"] = map[string]interface{}{
+			"tracking_number":      selection.TrackingNumber,
+			"shipping_provider_id": selection.ShippingProviderID,
+		}
+	default:
+		return fmt.Errorf("unsupported shipping method: %q", selection.Method)
+	}
+
+	req := &Request{
+		Method:   http.MethodPost,
+		Path:     ShipOrderPath,
+		Body:     body,
+		NeedAuth: true,
+	}
+
+	var resp BaseResponse
+	if err := p.client.Do(ctx, req, &resp); err != nil {
+		return fmt.Errorf("failed to ship package: %w", err)
+	}
+
+	if resp.HasError() {
+		return fmt.Errorf("tiktok error: %s", resp.GetError())
+	}
+
+	return nil
+}
+
+// firstShipment picks the first available option, preferring dropoff
+func firstShipment(options *providers.ShippingOptions) *providers.ShipmentSelection {
+	switch {
+	case options.HasMethod(providers.ShippingMethodDropoff):
+		return &providers.ShipmentSelection{Method: providers.ShippingMethodDropoff}
+	case options.HasMethod(providers.ShippingMethodPickup):
+		selection := &providers.ShipmentSelection{Method: providers.ShippingMethodPickup}
+		if len(options.PickupAddresses) > 0 && len(options.PickupAddresses[0].TimeSlots) > 0 {
+			selection.PickupTimeID = options.PickupAddresses[0].TimeSlots[0].PickupTimeID
+		}
+		return selection
+	default:
+		return &providers.ShipmentSelection{Method: providers.ShippingMethodNonIntegrated}
+	}
+}
+
+// GenerateShippingDocument downloads a shipping document of a shipped order.
+// The document type is one of the Document* types, the shipping label if empty.
+// Orders split into several packages are rejected.
+func (p *OrderProvider) GenerateShippingDocument(ctx context.Context, orderID string, documentType string) ([]byte, error) {
+	switch documentType {
+	case "":
+		documentType = DocumentShippingLabel
+	case DocumentShippingLabel, DocumentPickList, DocumentShippingLabelList:
+	default:
+		return nil, fmt.Errorf("unsupported document type: %q", documentType)
+	}
+
+	info, err := p.getFulfillmentInfo(ctx, orderID)
+	if err != nil {
+		return nil, err
+	}
+	packageID, err := singlePackage(orderID, info)
+	if err != nil {
+		return nil, err
+	}
+	if packageID == "" {
+		return nil, fmt.Errorf("order %s has no package, arrange shipment first", orderID)
+	}
+
+	req := &Request{
+		Method: http.MethodGet,
+		Path:   GetShippingDocumentPath,
+		Query: map[string]string{
+			"package_id":    packageID,
+			"document_type": documentType,
+			"document_size": "A6",
+		},
+		NeedAuth: true,
+	}
+
+	var resp struct {
+		BaseResponse
+		Data struct {
+			DocURL string `json:"doc_url"`
+		} `json:"data"`
+	}
+
+	if err := p.client.Do(ctx, req, &resp); err != nil {
+		return nil, fmt.Errorf("failed to get shipping document: %w", err)
+	}
+
+	if resp.HasError() {
+		return nil, fmt.Errorf("tiktok error: %s", resp.GetError())
+	}
+
+	if resp.Data.DocURL == "" {
+		return nil, fmt.Errorf("shipping document not available for order %s", orderID)
+	}
+
+	return p.client.fetchFile(ctx, resp.Data.DocURL)
+}
+
+// fetchFile downloads a file from a TikTok Shop download URL
+func (c *Client) fetchFile(ctx context.Context, url string) ([]byte, error) {
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create download request: %w", err)
+	}
+
+	resp, err := c.httpClient.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("download failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("download failed with status %d", resp.StatusCode)
+	}
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read download: %w", err)
+	}
+	return data, nil
+}
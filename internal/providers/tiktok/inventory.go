@@ -8,6 +8,11 @@ import (
 	"github.com/niaga-platform/service-marketplace/internal/providers"
 )
 
+const (
+	// Logistics API paths
+	GetWarehouseListPath = "/api/logistics/get_warehouse_list"
+)
+
 // InventoryProvider implements inventory operations for TikTok Shop
 type InventoryProvider struct {
 	client *Client
@@ -49,24 +54,93 @@ func (p *InventoryProvider) UpdateStock(ctx context.Context, productID, skuID st
 	return nil
 }
 
-// UpdateBatchStock updates stock for multiple products
+// UpdateBatchStock updates stock for multiple products. Updates for SKUs of
+// the same product are sent in a single request. The variant ID of an update
+// is its TikTok SKU ID, falling back to the update's SKU.
 func (p *InventoryProvider) UpdateBatchStock(ctx context.Context, updates []providers.InventoryUpdate) ([]providers.InventoryUpdateResult, error) {
 	results := make([]providers.InventoryUpdateResult, len(updates))
 
+	// Group update indexes by product, keeping first-seen order
+	order := make([]string, 0)
+	groups := make(map[string][]int)
 	for i, update := range updates {
-		err := p.UpdateStock(ctx, update.ExternalProductID, update.ExternalSKU, update.Quantity)
-		results[i] = providers.InventoryUpdateResult{
-			ExternalProductID: update.ExternalProductID,
-			Success:           err == nil,
+		if _, ok := groups[update.ExternalProductID]; !ok {
+			order = append(order, update.ExternalProductID)
 		}
-		if err != nil {
-			results[i].Error = err.Error()
+		groups[update.ExternalProductID] = append(groups[update.ExternalProductID], i)
+	}
+
+	for _, productID := range order {
+		indexes := groups[productID]
+
+		// Each SKU carries one stock entry per warehouse being updated
+		skuOrder := make([]string, 0)
+		stockInfos := make(map[string][]map[string]interface{})
+		for _, idx := range indexes {
+			skuID := updates[idx].ExternalVariantID
+			if skuID == "" {
+				skuID = updates[idx].ExternalSKU
+			}
+			if _, ok := stockInfos[skuID]; !ok {
+				skuOrder = append(skuOrder, skuID)
+			}
+			info := map[string]interface{}{"available_stock": updates[idx].Quantity}
+			if updates[idx].LocationID != "" {
+				info["warehouse_id"] = updates[idx].LocationID
+			}
+			stockInfos[skuID] = append(stockInfos[skuID], info)
+		}
+
+		skus := make([]map[string]interface{}, len(skuOrder))
+		for j, skuID := range skuOrder {
+			skus[j] = map[string]interface{}{
+				"id":          skuID,
+				"stock_infos": stockInfos[skuID],
+			}
+		}
+
+		err := p.updateProductStock(ctx, productID, skus)
+		for _, idx := range indexes {
+			results[idx] = providers.InventoryUpdateResult{
+				ExternalProductID: updates[idx].ExternalProductID,
+				ExternalVariantID: updates[idx].ExternalVariantID,
+				ExternalSKU:       updates[idx].ExternalSKU,
+				LocationID:        updates[idx].LocationID,
+				Success:           err == nil,
+			}
+			if err != nil {
+				results[idx].Error = err.Error()
+			}
 		}
 	}
 
 	return results, nil
 }
 
+// updateProductStock sends one stock update request for several SKUs of a product
+func (p *InventoryProvider) updateProductStock(ctx context.Context, productID string, skus []map[string]interface{}) error {
+	req := &Request{
+		Method: http.MethodPut,
+		Path:   UpdateInventoryPath,
+		Body: map[string]interface{}{
+			"product_id": productID,
+			"skus":       skus,
+		},
+		NeedAuth: true,
+	}
+
+	var resp BaseResponse
+	if err := p.client.Do(ctx, req, &resp); err != nil {
+		return fmt.Errorf("failed to update stock: %w", err)
+	}
+
+	if resp.HasError() {
+		return fmt.Errorf("tiktok error: %s", resp.GetError())
+	}
+
+	return nil
+}
+
 // GetStock fetches current stock levels
 func (p *InventoryProvider) GetStock(ctx context.Context, productIDs []string) ([]providers.InventoryItem, error) {
 	req := &Request{
@@ -119,3 +193,46 @@ func (p *InventoryProvider) GetStock(ctx context.Context, productIDs []string) (
 
 	return items, nil
 }
+
+// GetWarehouses lists the shop's sales warehouses
+func (p *InventoryProvider) GetWarehouses(ctx context.Context) ([]providers.Warehouse, error) {
+	req := &Request{
+		Method:   http.MethodGet,
+		Path:     GetWarehouseListPath,
+		NeedAuth: true,
+	}
+
+	var resp struct {
+		BaseResponse
+		Data struct {
+			WarehouseList []struct {
+				WarehouseID   string `json:"warehouse_id"`
+				WarehouseName string `json:"warehouse_name"`
+				WarehouseType int    `json:"warehouse_type"` // 1 = sales, 2 = return
+				IsDefault     bool   `json:"is_default"`
+			} `json:"warehouse_list"`
+		} `json:"data"`
+	}
+
+	if err := p.client.Do(ctx, req, &resp); err != nil {
+		return nil, fmt.Errorf("failed to get warehouses: %w", err)
+	}
+
+	if resp.HasError() {
+		return nil, fmt.Errorf("tiktok error: %s", resp.GetError())
+	}
+
+	warehouses := make([]providers.Warehouse, 0, len(resp.Data.WarehouseList))
+	for _, w := range resp.Data.WarehouseList {
+		if w.WarehouseType != 1 {
+			continue
+		}
+		warehouses = append(warehouses, providers.Warehouse{
+			ID:        w.WarehouseID,
+			Name:      w.WarehouseName,
+			IsDefault: w.IsDefault,
+		})
+	}
+
+	return warehouses, nil
+}
@@ -4,8 +4,10 @@ import (
 	"context"
 	"fmt"
 	"net/http"
+	"strconv"
 	"time"
 
+	"github.com/niaga-platform/service-marketplace/internal/domain/shared"
 	"github.com/niaga-platform/service-marketplace/internal/providers"
 )
 
@@ -28,9 +30,14 @@ func NewOrderProvider(client *Client) *OrderProvider {
 // GetOrders fetches orders from TikTok
 func (p *OrderProvider) GetOrders(ctx context.Context, params *providers.OrderListParams) ([]providers.ExternalOrder, string, error) {
 	body := map[string]interface{}{
-		"create_time_ge": params.TimeFrom.Unix(),
-		"create_time_lt": params.TimeTo.Unix(),
-		"page_size":      params.PageSize,
+		"page_size": params.PageSize,
+	}
+	if params.TimeField == providers.OrderTimeFieldUpdate {
+		body["update_time_ge"] = params.TimeFrom.Unix()
+		body["update_time_lt"] = params.TimeTo.Unix()
+	} else {
+		body["create_time_ge"] = params.TimeFrom.Unix()
+		body["create_time_lt"] = params.TimeTo.Unix()
 	}
 
 	if params.Cursor != "" {
@@ -69,15 +76,18 @@ func (p *OrderProvider) GetOrders(ctx context.Context, params *providers.OrderLi
 					Region       string `json:"region_code"`
 				} `json:"recipient_address"`
 				LineItems []struct {
-					SkuID       string `json:"sku_id"`
-					ProductID   string `json:"product_id"`
-					ProductName string `json:"product_name"`
-					SkuName     string `json:"sku_name"`
-					Quantity    int    `json:"quantity"`
-					SalePrice   string `json:"sale_price"`
+					SkuID         string `json:"sku_id"`
+					SellerSKU     string `json:"seller_sku"`
+					ProductID     string `json:"product_id"`
+					ProductName   string `json:"product_name"`
+					SkuName       string `json:"sku_name"`
+					Quantity      int    `json:"quantity"`
+					SalePrice     string `json:"sale_price"`
+					OriginalPrice string `json:"original_price"`
 				} `json:"line_items"`
-				TrackingNumber   string `json:"tracking_number"`
-				ShippingProvider string `json:"shipping_provider"`
+				TrackingNumber   string      `json:"tracking_number"`
+				ShippingProvider string      `json:"shipping_provider"`
+				PaymentInfo      paymentInfo `json:"payment_info"`
 			} `json:"orders"`
 		} `json:"data"`
 	}
@@ -97,10 +107,13 @@ func (p *OrderProvider) GetOrders(ctx context.Context, params *providers.OrderLi
 			price := parseFloat(item.SalePrice)
 			items[j] = providers.ExternalOrderItem{
 				ExternalProductID: item.ProductID,
-				ExternalSKU:       item.SkuID,
+				ExternalVariantID: item.SkuID,
+				ExternalSKU:       item.SellerSKU,
 				Name:              item.ProductName,
+				VariantName:       item.SkuName,
 				Quantity:          item.Quantity,
 				UnitPrice:         price,
+				OriginalPrice:     parseFloat(item.OriginalPrice),
 				TotalPrice:        price * float64(item.Quantity),
 			}
 		}
@@ -108,6 +121,7 @@ func (p *OrderProvider) GetOrders(ctx context.Context, params *providers.OrderLi
 		orders[i] = providers.ExternalOrder{
 			ExternalOrderID: o.OrderID,
 			Status:          p.mapOrderStatus(o.OrderStatus),
+			PlatformStatus:  strconv.Itoa(o.OrderStatus),
 			TotalAmount:     parseFloat(o.TotalAmount),
 			Currency:        o.Currency,
 			CreatedAt:       time.Unix(o.CreateTime, 0),
@@ -124,46 +138,69 @@ func (p *OrderProvider) GetOrders(ctx context.Context, params *providers.OrderLi
 			Items:          items,
 			TrackingNumber: o.TrackingNumber,
 			Carrier:        o.ShippingProvider,
+			PaymentMethod:  o.PaymentMethod,
+			SubtotalAmount: parseFloat(o.PaymentInfo.OriginalTotalProductPrice),
+			ShippingFee:    parseFloat(o.PaymentInfo.ShippingFee),
+			DiscountAmount: o.PaymentInfo.discount(),
 		}
 	}
 
 	return orders, resp.Data.NextCursor, nil
 }
 
+// paymentInfo is the price breakdown of a TikTok order
+type paymentInfo struct {
+	OriginalTotalProductPrice string `json:"original_total_product_price"`
+	ShippingFee               string `json:"shipping_fee"`
+	SellerDiscount            string `json:"seller_discount"`
+	PlatformDiscount          string `json:"platform_discount"`
+}
+
+// discount returns the seller and platform discounts on the order
+func (i paymentInfo) discount() float64 {
+	return parseFloat(i.SellerDiscount) + parseFloat(i.PlatformDiscount)
+}
+
 func parseFloat(s string) float64 {
 	var f float64
 	fmt.Sscanf(s, "%f", &f)
 	return f
 }
 
+// mapOrderStatus maps a TikTok order status code to the canonical order status
 func (p *OrderProvider) mapOrderStatus(status int) string {
 	switch status {
 	case 100:
-		return "pending_payment"
-	case 111, 112:
-		return "pending_shipment"
+		return shared.OrderUnpaid.String()
+	case 105, 111, 112, 114: // On hold, awaiting shipment, awaiting collection, partially shipping
+		return shared.OrderToShip.String()
 	case 121:
-		return "shipped"
+		return shared.OrderShipped.String()
+	case 122:
+		return shared.OrderDelivered.String()
 	case 130:
-		return "completed"
+		return shared.OrderCompleted.String()
 	case 140:
-		return "cancelled"
+		return shared.OrderCancelled.String()
 	default:
-		return fmt.Sprintf("unknown_%d", status)
+		return ""
 	}
 }
 
+// mapStatusToAPI maps a canonical order status to the TikTok status code used to filter order lists
 func (p *OrderProvider) mapStatusToAPI(status string) int {
-	switch status {
-	case "pending_payment":
+	switch shared.OrderStatus(status) {
+	case shared.OrderUnpaid:
 		return 100
-	case "pending_shipment":
+	case shared.OrderToShip:
 		return 111
-	case "shipped":
+	case shared.OrderShipped:
 		return 121
-	case "completed":
+	case shared.OrderDelivered:
+		return 122
+	case shared.OrderCompleted:
 		return 130
-	case "cancelled":
+	case shared.OrderCancelled:
 		return 140
 	default:
 		return 0
@@ -188,6 +225,7 @@ func (p *OrderProvider) GetOrder(ctx context.Context, orderID string) (*provider
 			OrderStatus      int    `json:"order_status"`
 			CreateTime       int64  `json:"create_time"`
 			UpdateTime       int64  `json:"update_time"`
+			PaymentMethod    string `json:"payment_method_name"`
 			TotalAmount      string `json:"total_amount"`
 			Currency         string `json:"currency"`
 			RecipientAddress struct {
@@ -200,14 +238,18 @@ func (p *OrderProvider) GetOrder(ctx context.Context, orderID string) (*provider
 				Region       string `json:"region_code"`
 			} `json:"recipient_address"`
 			LineItems []struct {
-				SkuID       string `json:"sku_id"`
-				ProductID   string `json:"product_id"`
-				ProductName string `json:"product_name"`
-				Quantity    int    `json:"quantity"`
-				SalePrice   string `json:"sale_price"`
+				SkuID         string `json:"sku_id"`
+				SellerSKU     string `json:"seller_sku"`
+				ProductID     string `json:"product_id"`
+				ProductName   string `json:"product_name"`
+				SkuName       string `json:"sku_name"`
+				Quantity      int    `json:"quantity"`
+				SalePrice     string `json:"sale_price"`
+				OriginalPrice string `json:"original_price"`
 			} `json:"line_items"`
-			TrackingNumber   string `json:"tracking_number"`
-			ShippingProvider string `json:"shipping_provider"`
+			TrackingNumber   string      `json:"tracking_number"`
+			ShippingProvider string      `json:"shipping_provider"`
+			PaymentInfo      paymentInfo `json:"payment_info"`
 		} `json:"data"`
 	}
 
@@ -225,10 +267,13 @@ func (p *OrderProvider) GetOrder(ctx context.Context, orderID string) (*provider
 		price := parseFloat(item.SalePrice)
 		items[j] = providers.ExternalOrderItem{
 			ExternalProductID: item.ProductID,
-			ExternalSKU:       item.SkuID,
+			ExternalVariantID: item.SkuID,
+			ExternalSKU:       item.SellerSKU,
 			Name:              item.ProductName,
+			VariantName:       item.SkuName,
 			Quantity:          item.Quantity,
 			UnitPrice:         price,
+			OriginalPrice:     parseFloat(item.OriginalPrice),
 			TotalPrice:        price * float64(item.Quantity),
 		}
 	}
@@ -236,6 +281,7 @@ func (p *OrderProvider) GetOrder(ctx context.Context, orderID string) (*provider
 	return &providers.ExternalOrder{
 		ExternalOrderID: o.OrderID,
 		Status:          p.mapOrderStatus(o.OrderStatus),
+		PlatformStatus:  strconv.Itoa(o.OrderStatus),
 		TotalAmount:     parseFloat(o.TotalAmount),
 		Currency:        o.Currency,
 		CreatedAt:       time.Unix(o.CreateTime, 0),
@@ -252,29 +298,18 @@ func (p *OrderProvider) GetOrder(ctx context.Context, orderID string) (*provider
 		Items:          items,
 		TrackingNumber: o.TrackingNumber,
 		Carrier:        o.ShippingProvider,
+		PaymentMethod:  o.PaymentMethod,
+		SubtotalAmount: parseFloat(o.PaymentInfo.OriginalTotalProductPrice),
+		ShippingFee:    parseFloat(o.PaymentInfo.ShippingFee),
+		DiscountAmount: o.PaymentInfo.discount(),
 	}, nil
 }
 
-// UpdateOrderStatus updates order status
+// UpdateOrderStatus updates order status. Shipping an order ships its
+// package with the first available option.
 func (p *OrderProvider) UpdateOrderStatus(ctx context.Context, orderID, status string) error {
 	if status == "shipped" {
-		req := &Request{
-			Method: http.MethodPost,
-			Path:   ShipOrderPath,
-			Body: map[string]interface{}{
-				"order_id": orderID,
-			},
-			NeedAuth: true,
-		}
-
-		var resp BaseResponse
-		if err := p.client.Do(ctx, req, &resp); err != nil {
-			return fmt.Errorf("failed to ship order: %w", err)
-		}
-
-		if resp.HasError() {
-			return fmt.Errorf("tiktok error: %s", resp.GetError())
-		}
+		return p.ArrangeShipment(ctx, orderID, nil)
 	}
 
 	return nil
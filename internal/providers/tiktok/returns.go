@@ -0,0 +1,330 @@
+package tiktok
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/niaga-platform/service-marketplace/internal/providers"
+)
+
+const (
+	// Reverse (return/refund/cancel) API paths
+	GetReverseOrderListPath   = "/api/reverse/reverse_order/list"
+	ConfirmReverseRequestPath = "/api/reverse/reverse_request/confirm"
+	RejectReverseRequestPath  = "/api/reverse/reverse_request/reject"
+	GetReverseReasonListPath  = "/api/reverse/reverse_reason/list"
+)
+
+// Reverse order types
+const (
+	ReverseTypeCancel          = 1
+	ReverseTypeRefundOnly      = 2
+	ReverseTypeReturnAndRefund = 3
+	ReverseTypeRequestCancel   = 4
+)
+
+// Reverse order statuses
+const (
+	ReverseStatusApplying            = 1
+	ReverseStatusRejectApplication   = 2
+	ReverseStatusReturning           = 3
+	ReverseStatusBuyerShipped        = 4
+	ReverseStatusSellerRejectReceive = 5
+	ReverseStatusSuccess             = 50
+	ReverseStatusCancelSuccess       = 51
+	ReverseStatusClosed              = 99
+	ReverseStatusComplete            = 100
+)
+
+// ReturnProvider implements return, refund and cancellation operations for
+// TikTok Shop, which models them all as reverse orders
+type ReturnProvider struct {
+	client *Client
+}
+
+// NewReturnProvider creates a new TikTok return provider
+func NewReturnProvider(client *Client) *ReturnProvider {
+	return &ReturnProvider{client: client}
+}
+
+// reverseOrder is a reverse order as returned by the reverse order list API
+type reverseOrder struct {
+	ReverseOrderID     string `json:"reverse_order_id"`
+	OrderID            string `json:"order_id"`
+	ReverseType        int    `json:"reverse_type"`
+	ReverseStatusValue int    `json:"reverse_status_value"`
+	ReturnReason       string `json:"return_reason"`
+	RefundTotal        string `json:"refund_total"`
+	Currency           string `json:"currency"`
+	ReturnTrackingID   string `json:"return_tracking_id"`
+	RequestTime        int64  `json:"reverse_request_time"`
+	UpdateTime         int64  `json:"reverse_update_time"`
+	ReturnItemList     []struct {
+		ProductID     string `json:"return_product_id"`
+		ProductName   string `json:"return_product_name"`
+		SkuID         string `json:"sku_id"`
+		SkuName       string `json:"sku_name"`
+		Quantity      int    `json:"return_quantity"`
+		ProductImages string `json:"product_images"`
+	} `json:"return_item_list"`
+}
+
+// GetReturns fetches reverse orders updated in the given window. TikTok only
+// filters reverse orders by update time, so the create time bounds are applied
+// to the update time.
+func (p *ReturnProvider) GetReturns(ctx context.Context, params *providers.ReturnListParams) ([]providers.ExternalReturn, string, error) {
+	pageSize := params.PageSize
+	if pageSize == 0 {
+		pageSize = 50
+	}
+	offset := 0
+	if params.Cursor != "" {
+		offset, _ = strconv.Atoi(params.Cursor)
+	}
+
+	body := map[string]interface{}{
+		"offset": offset,
+		"size":   pageSize,
+	}
+	if !params.CreateTimeFrom.IsZero() {
+		body["update_time_from"] = params.CreateTimeFrom.Unix()
+	}
+	if !params.CreateTimeTo.IsZero() {
+		body["update_time_to"] = params.CreateTimeTo.Unix()
+	}
+
+	reverseOrders, more, err := p.listReverseOrders(ctx, body)
+	if err != nil {
+		return nil, "", err
+	}
+
+	returns := make([]providers.ExternalReturn, len(reverseOrders))
+	for i := range reverseOrders {
+		returns[i] = p.mapReverseOrder(&reverseOrders[i])
+	}
+
+	nextCursor := ""
+	if more {
+		nextCursor = strconv.Itoa(offset + len(reverseOrders))
+	}
+
+	return returns, nextCursor, nil
+}
+
+// GetReturn fetches a single reverse order
+func (p *ReturnProvider) GetReturn(ctx context.Context, reverseOrderID string) (*providers.ExternalReturn, error) {
+	reverseOrders, _, err := p.listReverseOrders(ctx, map[string]interface{}{
+		"reverse_order_id": reverseOrderID,
+		"offset":           0,
+		"size":             1,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(reverseOrders) == 0 {
+		return nil, fmt.Errorf("reverse order %s not found", reverseOrderID)
+	}
+
+	ret := p.mapReverseOrder(&reverseOrders[0])
+	return &ret, nil
+}
+
+// ConfirmReturn approves a buyer's return, refund or cancellation request
+func (p *ReturnProvider) ConfirmReturn(ctx context.Context, reverseOrderID string) error {
+	req := &Request{
+		Method: http.MethodPost,
+		Path:   ConfirmReverseRequestPath,
+		Body: map[string]interface{}{
+			"reverse_order_id": reverseOrderID,
+		},
+		NeedAuth: true,
+	}
+
+	var resp BaseResponse
+	if err := p.client.Do(ctx, req, &resp); err != nil {
+		return fmt.Errorf("failed to approve reverse request: %w", err)
+	}
+
+	if resp.HasError() {
+		return fmt.Errorf("tiktok error: %s", resp.GetError())
+	}
+
+	return nil
+}
+
+// RejectReturn rejects a buyer's request. TikTok requires one of the reason
+// keys returned by GetRejectReasons; the dispute reason is sent as the comment.
+func (p *ReturnProvider) RejectReturn(ctx context.Context, req *providers.DisputeReturnRequest) error {
+	if req.ReasonCode == "" {
+		return errors.New("reason code is required to reject a TikTok return")
+	}
+
+	body := map[string]interface{}{
+		"reverse_order_id":          req.ReturnID,
+		"reverse_reject_reason_key": req.ReasonCode,
+	}
+	if req.DisputeReason != "" {
+		body["reverse_reject_comments"] = req.DisputeReason
+	}
+
+	apiReq := &Request{
+		Method:   http.MethodPost,
+		Path:     RejectReverseRequestPath,
+		Body:     body,
+		NeedAuth: true,
+	}
+
+	var resp BaseResponse
+	if err := p.client.Do(ctx, apiReq, &resp); err != nil {
+		return fmt.Errorf("failed to reject reverse request: %w", err)
+	}
+
+	if resp.HasError() {
+		return fmt.Errorf("tiktok error: %s", resp.GetError())
+	}
+
+	return nil
+}
+
+// GetRejectReasons lists the reason codes a seller may use to reject a reverse order
+func (p *ReturnProvider) GetRejectReasons(ctx context.Context, reverseOrderID string) ([]providers.ReturnReason, error) {
+	req := &Request{
+		Method: http.MethodGet,
+		Path:   GetReverseReasonListPath,
+		Query: map[string]string{
+			"reverse_order_id":    reverseOrderID,
+			"reverse_action_type": "2", // Seller reject
+		},
+		NeedAuth: true,
+	}
+
+	var resp struct {
+		BaseResponse
+		Data struct {
+			ReverseReasonList []struct {
+				ReverseReasonKey string `json:"reverse_reason_key"`
+				ReverseReason    string `json:"reverse_reason"`
+			} `json:"reverse_reason_list"`
+		} `json:"data"`
+	}
+
+	if err := p.client.Do(ctx, req, &resp); err != nil {
+		return nil, fmt.Errorf("failed to get reject reasons: %w", err)
+	}
+
+	if resp.HasError() {
+		return nil, fmt.Errorf("tiktok error: %s", resp.GetError())
+	}
+
+	reasons := make([]providers.ReturnReason, len(resp.Data.ReverseReasonList))
+	for i, r := range resp.Data.ReverseReasonList {
+		reasons[i] = providers.ReturnReason{
+			Code: r.ReverseReasonKey,
+			Name: r.ReverseReason,
+		}
+	}
+
+	return reasons, nil
+}
+
+// listReverseOrders calls the reverse order list API
+func (p *ReturnProvider) listReverseOrders(ctx context.Context, body map[string]interface{}) ([]reverseOrder, bool, error) {
+	req := &Request{
+		Method:   http.MethodPost,
+		Path:     GetReverseOrderListPath,
+		Body:     body,
+		NeedAuth: true,
+	}
+
+	var resp struct {
+		BaseResponse
+		Data struct {
+			More        bool           `json:"more"`
+			ReverseList []reverseOrder `json:"reverse_list"`
+		} `json:"data"`
+	}
+
+	if err := p.client.Do(ctx, req, &resp); err != nil {
+		return nil, false, fmt.Errorf("failed to get reverse orders: %w", err)
+	}
+
+	if resp.HasError() {
+		return nil, false, fmt.Errorf("tiktok error: %s", resp.GetError())
+	}
+
+	return resp.Data.ReverseList, resp.Data.More, nil
+}
+
+// mapReverseOrder maps a TikTok reverse order to the common return shape
+func (p *ReturnProvider) mapReverseOrder(r *reverseOrder) providers.ExternalReturn {
+	items := make([]providers.ExternalReturnItem, len(r.ReturnItemList))
+	for i, item := range r.ReturnItemList {
+		items[i] = providers.ExternalReturnItem{
+			ExternalProductID: item.ProductID,
+			ExternalVariantID: item.SkuID,
+			Name:              item.ProductName,
+			Quantity:          item.Quantity,
+		}
+		if item.ProductImages != "" {
+			items[i].Images = []string{item.ProductImages}
+		}
+	}
+
+	return providers.ExternalReturn{
+		ExternalReturnID: r.ReverseOrderID,
+		ExternalOrderID:  r.OrderID,
+		Type:             MapReverseType(r.ReverseType),
+		Status:           MapReverseStatus(r.ReverseType, r.ReverseStatusValue),
+		PlatformStatus:   strconv.Itoa(r.ReverseStatusValue),
+		Reason:           r.ReturnReason,
+		RefundAmount:     parseFloat(r.RefundTotal),
+		Currency:         r.Currency,
+		NeedsLogistics:   r.ReverseType == ReverseTypeReturnAndRefund,
+		TrackingNumber:   r.ReturnTrackingID,
+		Items:            items,
+		CreatedAt:        time.Unix(r.RequestTime, 0),
+		UpdatedAt:        time.Unix(r.UpdateTime, 0),
+	}
+}
+
+// MapReverseType maps a TikTok reverse type to the common return type
+func MapReverseType(reverseType int) string {
+	switch reverseType {
+	case ReverseTypeCancel, ReverseTypeRequestCancel:
+		return providers.ReturnTypeCancellation
+	case ReverseTypeRefundOnly:
+		return providers.ReturnTypeRefundOnly
+	default:
+		return providers.ReturnTypeReturnRefund
+	}
+}
+
+// MapReverseStatus maps a TikTok reverse status to the common return status. A
+// cancellation that goes through cancels the order instead of refunding a return.
+func MapReverseStatus(reverseType, status int) string {
+	switch status {
+	case ReverseStatusApplying:
+		return providers.ReturnStatusRequested
+	case ReverseStatusReturning:
+		return providers.ReturnStatusAccepted
+	case ReverseStatusBuyerShipped:
+		return providers.ReturnStatusProcessing
+	case ReverseStatusRejectApplication, ReverseStatusSellerRejectReceive:
+		return providers.ReturnStatusDisputed
+	case ReverseStatusCancelSuccess:
+		return providers.ReturnStatusCancelled
+	case ReverseStatusSuccess, ReverseStatusComplete:
+		if reverseType == ReverseTypeCancel || reverseType == ReverseTypeRequestCancel {
+			return providers.ReturnStatusCancelled
+		}
+		return providers.ReturnStatusRefunded
+	case ReverseStatusClosed:
+		return providers.ReturnStatusClosed
+	default:
+		return providers.ReturnStatusProcessing
+	}
+}
@@ -0,0 +1,288 @@
+package tiktok
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/niaga-platform/service-marketplace/internal/providers"
+)
+
+// Webhook types from TikTok Shop. Current API versions send the numeric type,
+// older integrations the upper-case name.
+const (
+	WebhookTypeOrderStatusChange        = 1
+	WebhookTypeReverseOrderStatusChange = 2
+	WebhookTypeRecipientAddressUpdate   = 3
+	WebhookTypePackageUpdate            = 4
+	WebhookTypeProductStatusChange      = 5
+	WebhookTypeSellerDeauthorization    = 6
+	WebhookTypeCancellationStatusChange = 11
+	WebhookTypeReturnStatusChange       = 12
+)
+
+// webhookTypeNames maps legacy type names to webhook types
+var webhookTypeNames = map[string]int{
+	"ORDER_STATUS_CHANGE":         WebhookTypeOrderStatusChange,
+	"REVERSE_ORDER_STATUS_CHANGE": WebhookTypeReverseOrderStatusChange,
+	"RECIPIENT_ADDRESS_UPDATE":    WebhookTypeRecipientAddressUpdate,
+	"PACKAGE_UPDATE":              WebhookTypePackageUpdate,
+	"PRODUCT_STATUS_CHANGE":       WebhookTypeProductStatusChange,
+	"SELLER_DEAUTHORIZATION":      WebhookTypeSellerDeauthorization,
+	"CANCELLATION_STATUS_CHANGE":  WebhookTypeCancellationStatusChange,
+	"RETURN_STATUS_CHANGE":        WebhookTypeReturnStatusChange,
+}
+
+// FlexString is a JSON value TikTok sends either as a string or as a number
+type FlexString string
+
+// UnmarshalJSON accepts a JSON string or number
+func (f *FlexString) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err == nil {
+		*f = FlexString(s)
+		return nil
+	}
+	var n json.Number
+	if err := json.Unmarshal(data, &n); err != nil {
+		return err
+	}
+	*f = FlexString(n.String())
+	return nil
+}
+
+// WebhookPayload represents the raw webhook payload from TikTok Shop
+type WebhookPayload struct {
+	Type           FlexString      `json:"type"`
+	NotificationID string          `json:"tts_notification_id"`
+	ShopID         string          `json:"shop_id"`
+	Timestamp      int64           `json:"timestamp"`
+	Data           json.RawMessage `json:"data"`
+}
+
+// TypeCode resolves the numeric webhook type, or 0 if it is not recognised
+func (p *WebhookPayload) TypeCode() int {
+	if code, err := strconv.Atoi(string(p.Type)); err == nil {
+		return code
+	}
+	return webhookTypeNames[strings.ToUpper(string(p.Type))]
+}
+
+// OrderStatusData represents order status change webhook data
+type OrderStatusData struct {
+	OrderID     string     `json:"order_id"`
+	OrderStatus FlexString `json:"order_status"`
+	UpdateTime  int64      `json:"update_time"`
+}
+
+// ReturnStatusData represents return or refund status webhook data
+type ReturnStatusData struct {
+	OrderID      string     `json:"order_id"`
+	ReturnID     string     `json:"return_id"`
+	ReturnStatus FlexString `json:"return_status"`
+	ReturnType   FlexString `json:"return_type"`
+	UpdateTime   int64      `json:"update_time"`
+}
+
+// reverseOrderData is the legacy reverse order webhook, mapped onto ReturnStatusData
+type reverseOrderData struct {
+	OrderID            string     `json:"order_id"`
+	ReverseOrderID     string     `json:"reverse_order_id"`
+	ReverseOrderStatus FlexString `json:"reverse_order_status"`
+	ReverseType        FlexString `json:"reverse_type"`
+	UpdateTime         int64      `json:"update_time"`
+}
+
+// CancellationStatusData represents order cancellation webhook data
+type CancellationStatusData struct {
+	OrderID           string     `json:"order_id"`
+	CancelID          string     `json:"cancel_id"`
+	CancelStatus      FlexString `json:"cancel_status"`
+	CancellationsRole string     `json:"cancellations_role"`
+	UpdateTime        int64      `json:"update_time"`
+}
+
+// PackageUpdateData represents package (shipment) update webhook data
+type PackageUpdateData struct {
+	OrderID    string `json:"order_id"`
+	PackageID  string `json:"package_id"`
+	UpdateTime int64  `json:"update_time"`
+}
+
+// AddressUpdateData represents recipient address update webhook data
+type AddressUpdateData struct {
+	OrderID    string `json:"order_id"`
+	Message    string `json:"message"`
+	UpdateTime int64  `json:"update_time"`
+}
+
+// ProductStatusData represents product audit status webhook data
+type ProductStatusData struct {
+	ProductID       string `json:"product_id"`
+	Status          string `json:"status"` // e.g. ACTIVATE, FAILED, FREEZE, SELLER_DEACTIVATED, PLATFORM_DEACTIVATED, DELETED
+	SuspendedReason string `json:"suspended_reason"`
+	UpdateTime      int64  `json:"update_time"`
+}
+
+// DeauthorizationData represents seller deauthorisation webhook data
+type DeauthorizationData struct {
+	Message    string `json:"message"`
+	UpdateTime int64  `json:"update_time"`
+}
+
+// WebhookHandler handles incoming TikTok Shop webhooks
+type WebhookHandler struct {
+	appKey    string
+	appSecret string
+	logger    *zap.Logger
+}
+
+// NewWebhookHandler creates a new webhook handler
+func NewWebhookHandler(appKey, appSecret string, logger *zap.Logger) *WebhookHandler {
+	return &WebhookHandler{
+		appKey:    appKey,
+		appSecret: appSecret,
+		logger:    logger,
+	}
+}
+
+// VerifyWebhook verifies the signature of an incoming webhook.
+// TikTok Shop signs HMAC-SHA256(app_key + body) with the app secret and sends
+// it in the Authorization header. Older integrations sent HMAC-SHA256(body) in
+// X-Tts-Signature, which is still accepted.
+func (h *WebhookHandler) VerifyWebhook(ctx context.Context, body []byte, headers map[string]string) (bool, error) {
+	if authorization := headers["Authorization"]; authorization != "" {
+		isValid := hmac.Equal([]byte(h.sign(h.appKey+string(body))), []byte(authorization))
+		if !isValid {
+			h.logger.Warn("webhook signature verification failed",
+				zap.String("provided_signature", authorization),
+			)
+		}
+		return isValid, nil
+	}
+
+	if legacy := headers["X-Tts-Signature"]; legacy != "" {
+		isValid := hmac.Equal([]byte(h.sign(string(body))), []byte(legacy))
+		if !isValid {
+			h.logger.Warn("webhook signature verification failed",
+				zap.String("provided_signature", legacy),
+			)
+		}
+		return isValid, nil
+	}
+
+	h.logger.Warn("webhook missing signature header")
+	return false, nil
+}
+
+// sign computes the hex HMAC-SHA256 of a string with the app secret
+func (h *WebhookHandler) sign(base string) string {
+	mac := hmac.New(sha256.New, []byte(h.appSecret))
+	mac.Write([]byte(base))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// ParseWebhookEvent parses a raw webhook body into a structured event
+func (h *WebhookHandler) ParseWebhookEvent(body []byte) (*providers.WebhookEvent, error) {
+	var payload WebhookPayload
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return nil, fmt.Errorf("failed to parse webhook payload: %w", err)
+	}
+
+	code := payload.TypeCode()
+	event := &providers.WebhookEvent{
+		Type:      h.mapEventType(code, string(payload.Type)),
+		ShopID:    payload.ShopID,
+		Timestamp: time.Unix(payload.Timestamp, 0),
+	}
+
+	var err error
+	switch code {
+	case WebhookTypeOrderStatusChange:
+		var data OrderStatusData
+		err = json.Unmarshal(payload.Data, &data)
+		event.Payload = data
+
+	case WebhookTypeReverseOrderStatusChange:
+		var legacy reverseOrderData
+		err = json.Unmarshal(payload.Data, &legacy)
+		event.Payload = ReturnStatusData{
+			OrderID:      legacy.OrderID,
+			ReturnID:     legacy.ReverseOrderID,
+			ReturnStatus: legacy.ReverseOrderStatus,
+			ReturnType:   legacy.ReverseType,
+			UpdateTime:   legacy.UpdateTime,
+		}
+
+	case WebhookTypeReturnStatusChange:
+		var data ReturnStatusData
+		err = json.Unmarshal(payload.Data, &data)
+		event.Payload = data
+
+	case WebhookTypeCancellationStatusChange:
+		var data CancellationStatusData
+		err = json.Unmarshal(payload.Data, &data)
+		event.Payload = data
+
+	case WebhookTypePackageUpdate:
+		var data PackageUpdateData
+		err = json.Unmarshal(payload.Data, &data)
+		event.Payload = data
+
+	case WebhookTypeRecipientAddressUpdate:
+		var data AddressUpdateData
+		err = json.Unmarshal(payload.Data, &data)
+		event.Payload = data
+
+	case WebhookTypeProductStatusChange:
+		var data ProductStatusData
+		err = json.Unmarshal(payload.Data, &data)
+		event.Payload = data
+
+	case WebhookTypeSellerDeauthorization:
+		var data DeauthorizationData
+		err = json.Unmarshal(payload.Data, &data)
+		event.Payload = data
+
+	default:
+		// Store raw data for unknown event types
+		var rawData map[string]interface{}
+		json.Unmarshal(payload.Data, &rawData)
+		event.Payload = rawData
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s webhook data: %w", event.Type, err)
+	}
+
+	return event, nil
+}
+
+// mapEventType maps TikTok webhook types to event type strings
+func (h *WebhookHandler) mapEventType(code int, raw string) string {
+	switch code {
+	case WebhookTypeOrderStatusChange:
+		return "order.status_changed"
+	case WebhookTypeReverseOrderStatusChange, WebhookTypeReturnStatusChange:
+		return "return.status_changed"
+	case WebhookTypeCancellationStatusChange:
+		return "order.cancellation_changed"
+	case WebhookTypePackageUpdate:
+		return "order.package_update"
+	case WebhookTypeRecipientAddressUpdate:
+		return "order.address_update"
+	case WebhookTypeProductStatusChange:
+		return "product.status_changed"
+	case WebhookTypeSellerDeauthorization:
+		return "shop.deauthorization"
+	default:
+		return fmt.Sprintf("unknown.%s", raw)
+	}
+}
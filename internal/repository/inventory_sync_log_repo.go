@@ -0,0 +1,99 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/niaga-platform/service-marketplace/internal/models"
+	"gorm.io/gorm"
+)
+
+// InventorySyncLogRepository handles database operations for inventory sync logs
+type InventorySyncLogRepository struct {
+	db *gorm.DB
+}
+
+// NewInventorySyncLogRepository creates a new InventorySyncLogRepository
+func NewInventorySyncLogRepository(db *gorm.DB) *InventorySyncLogRepository {
+	return &InventorySyncLogRepository{db: db}
+}
+
+// Create creates a new inventory sync log entry
+func (r *InventorySyncLogRepository) Create(ctx context.Context, log *models.InventorySyncLog) error {
+	return r.db.WithContext(ctx).Create(log).Error
+}
+
+// CreateBatch creates multiple inventory sync log entries
+func (r *InventorySyncLogRepository) CreateBatch(ctx context.Context, logs []models.InventorySyncLog) error {
+	if len(logs) == 0 {
+		return nil
+	}
+	return r.db.WithContext(ctx).Create(&logs).Error
+}
+
+// GetLatestSuccessful retrieves the most recent successful push for a listing
+func (r *InventorySyncLogRepository) GetLatestSuccessful(ctx context.Context, connectionID uuid.UUID, externalProductID, externalSKU, locationID string) (*models.InventorySyncLog, error) {
+	var log models.InventorySyncLog
+	err := r.db.WithContext(ctx).
+		Where("connection_id = ? AND external_product_id = ? AND COALESCE(external_sku, '') = ? AND COALESCE(external_location_id, '') = ? AND sync_status = ?",
+			connectionID, externalProductID, externalSKU, locationID, models.InventorySyncStatusSuccess).
+		Order("created_at DESC").
+		First(&log).Error
+	if err != nil {
+		return nil, err
+	}
+	return &log, nil
+}
+
+// GetByConnectionID retrieves inventory sync logs for a connection with filters
+func (r *InventorySyncLogRepository) GetByConnectionID(ctx context.Context, connectionID uuid.UUID, filter *models.InventorySyncLogFilter) ([]models.InventorySyncLog, int64, error) {
+	var logs []models.InventorySyncLog
+	var total int64
+
+	query := r.db.WithContext(ctx).Model(&models.InventorySyncLog{}).Where("connection_id = ?", connectionID)
+
+	if filter != nil {
+		if filter.InternalProductID != nil {
+			query = query.Where("internal_product_id = ?", *filter.InternalProductID)
+		}
+		if filter.ExternalProductID != "" {
+			query = query.Where("external_product_id = ?", filter.ExternalProductID)
+		}
+		if filter.Source != "" {
+			query = query.Where("source = ?", filter.Source)
+		}
+		if filter.SyncStatus != "" {
+			query = query.Where("sync_status = ?", filter.SyncStatus)
+		}
+		if filter.StartDate != nil {
+			query = query.Where("created_at >= ?", *filter.StartDate)
+		}
+		if filter.EndDate != nil {
+			query = query.Where("created_at <= ?", *filter.EndDate)
+		}
+	}
+
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	page := 1
+	pageSize := 20
+	if filter != nil {
+		if filter.Page > 0 {
+			page = filter.Page
+		}
+		if filter.PageSize > 0 {
+			pageSize = filter.PageSize
+		}
+	}
+	offset := (page - 1) * pageSize
+
+	err := query.
+		Offset(offset).
+		Limit(pageSize).
+		Order("created_at DESC").
+		Find(&logs).Error
+
+	return logs, total, err
+}
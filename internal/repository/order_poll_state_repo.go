@@ -0,0 +1,71 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/niaga-platform/service-marketplace/internal/models"
+)
+
+// OrderPollStateRepository handles database operations for order poll states
+type OrderPollStateRepository struct {
+	db *gorm.DB
+}
+
+// NewOrderPollStateRepository creates a new OrderPollStateRepository
+func NewOrderPollStateRepository(db *gorm.DB) *OrderPollStateRepository {
+	return &OrderPollStateRepository{db: db}
+}
+
+// GetByConnectionID retrieves the poll state of a connection
+func (r *OrderPollStateRepository) GetByConnectionID(ctx context.Context, connectionID uuid.UUID) (*models.OrderPollState, error) {
+	var state models.OrderPollState
+	err := r.db.WithContext(ctx).First(&state, "connection_id = ?", connectionID).Error
+	if err != nil {
+		return nil, err
+	}
+	return &state, nil
+}
+
+// Save creates or replaces the poll state of a connection. The poll lock is
+// left as it is; see Claim and Release.
+func (r *OrderPollStateRepository) Save(ctx context.Context, state *models.OrderPollState) error {
+	return r.db.WithContext(ctx).
+		Clauses(clause.OnConflict{
+			Columns: []clause.Column{{Name: "connection_id"}},
+			DoUpdates: clause.AssignmentColumns([]string{
+				"high_water_mark", "last_run_at", "last_success_at", "last_error",
+				"last_orders_seen", "consecutive_failures", "import_failures", "updated_at",
+			}),
+		}).
+		Omit("locked_until").
+		Create(state).Error
+}
+
+// Claim locks the poll of a connection until the given time unless another
+// replica holds an unexpired lock, creating the state on the first poll. It
+// reports whether this call made the claim.
+func (r *OrderPollStateRepository) Claim(ctx context.Context, connectionID uuid.UUID, until time.Time) (bool, error) {
+	result := r.db.WithContext(ctx).
+		Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "connection_id"}},
+			DoUpdates: clause.Assignments(map[string]interface{}{"locked_until": until}),
+			Where: clause.Where{Exprs: []clause.Expression{
+				clause.Expr{SQL: "order_poll_states.locked_until IS NULL OR order_poll_states.locked_until < ?", Vars: []interface{}{time.Now()}},
+			}},
+		}).
+		Create(&models.OrderPollState{ConnectionID: connectionID, LockedUntil: &until})
+	return result.RowsAffected == 1, result.Error
+}
+
+// Release clears the poll lock of a connection
+func (r *OrderPollStateRepository) Release(ctx context.Context, connectionID uuid.UUID) error {
+	return r.db.WithContext(ctx).
+		Model(&models.OrderPollState{}).
+		Where("connection_id = ?", connectionID).
+		Update("locked_until", nil).Error
+}
@@ -6,6 +6,7 @@ import (
 
 	"github.com/google/uuid"
 	"github.com/niaga-platform/service-marketplace/internal/models"
+	"gorm.io/datatypes"
 	"gorm.io/gorm"
 )
 
@@ -36,6 +37,15 @@ func (r *MarketplaceOrderRepository) GetByID(ctx context.Context, id uuid.UUID)
 	return &order, nil
 }
 
+// GetByIDs retrieves the orders of a connection with the given IDs
+func (r *MarketplaceOrderRepository) GetByIDs(ctx context.Context, connectionID uuid.UUID, ids []uuid.UUID) ([]models.MarketplaceOrder, error) {
+	var orders []models.MarketplaceOrder
+	err := r.db.WithContext(ctx).
+		Where("connection_id = ? AND id IN ?", connectionID, ids).
+		Find(&orders).Error
+	return orders, err
+}
+
 // GetByExternalOrderID retrieves an order by external order ID and connection
 func (r *MarketplaceOrderRepository) GetByExternalOrderID(ctx context.Context, connectionID uuid.UUID, externalOrderID string) (*models.MarketplaceOrder, error) {
 	var order models.MarketplaceOrder
@@ -78,6 +88,12 @@ func (r *MarketplaceOrderRepository) GetByConnectionID(ctx context.Context, conn
 		if filter.ImportedOnly != nil && *filter.ImportedOnly {
 			query = query.Where("internal_order_id IS NOT NULL")
 		}
+		if filter.Unresolved != nil && *filter.Unresolved {
+			query = query.Where("unresolved_items > 0")
+		}
+		if filter.FulfillmentSync != "" {
+			query = query.Where("fulfillment_sync = ?", filter.FulfillmentSync)
+		}
 		if filter.StartDate != nil {
 			query = query.Where("created_at >= ?", *filter.StartDate)
 		}
@@ -160,8 +176,40 @@ func (r *MarketplaceOrderRepository) GetAllByPlatform(ctx context.Context, platf
 }
 
 // Update updates a marketplace order
+// The service-order link is left out; it is only written through
+// ClaimServiceOrder, ReleaseServiceOrder and LinkServiceOrder.
 func (r *MarketplaceOrderRepository) Update(ctx context.Context, order *models.MarketplaceOrder) error {
-	return r.db.WithContext(ctx).Save(order).Error
+	return r.db.WithContext(ctx).Omit("internal_order_id", "service_order_pending").Save(order).Error
+}
+
+// ClaimServiceOrder clears the pending flag of an order waiting to be created in
+// service-order. It reports whether this call made the claim, so only one
+// caller creates the order.
+func (r *MarketplaceOrderRepository) ClaimServiceOrder(ctx context.Context, id uuid.UUID) (bool, error) {
+	result := r.db.WithContext(ctx).
+		Model(&models.MarketplaceOrder{}).
+		Where("id = ? AND service_order_pending AND internal_order_id IS NULL", id).
+		Update("service_order_pending", false)
+	return result.RowsAffected == 1, result.Error
+}
+
+// ReleaseServiceOrder sets an order pending again after it could not be created in service-order
+func (r *MarketplaceOrderRepository) ReleaseServiceOrder(ctx context.Context, id uuid.UUID) error {
+	return r.db.WithContext(ctx).
+		Model(&models.MarketplaceOrder{}).
+		Where("id = ? AND internal_order_id IS NULL", id).
+		Update("service_order_pending", true).Error
+}
+
+// LinkServiceOrder links an order to the order created for it in service-order
+func (r *MarketplaceOrderRepository) LinkServiceOrder(ctx context.Context, id, internalOrderID uuid.UUID) error {
+	return r.db.WithContext(ctx).
+		Model(&models.MarketplaceOrder{}).
+		Where("id = ?", id).
+		Updates(map[string]interface{}{
+			"internal_order_id":     internalOrderID,
+			"service_order_pending": false,
+		}).Error
 }
 
 // UpdateStatus updates the status of an order
@@ -184,6 +232,37 @@ func (r *MarketplaceOrderRepository) LinkToInternalOrder(ctx context.Context, id
 		}).Error
 }
 
+// UpdateFulfillmentSync records the result of shipping an order from a fulfillment
+// event with the tracking used. A failure counts an attempt; a sync resets the count.
+func (r *MarketplaceOrderRepository) UpdateFulfillmentSync(ctx context.Context, id uuid.UUID, result, syncError string, tracking datatypes.JSON) error {
+	attempts := gorm.Expr("0")
+	if result == models.FulfillmentSyncFailed {
+		attempts = gorm.Expr("fulfillment_attempts + 1")
+	}
+	return r.db.WithContext(ctx).
+		Model(&models.MarketplaceOrder{}).
+		Where("id = ?", id).
+		Updates(map[string]interface{}{
+			"fulfillment_sync":       result,
+			"fulfillment_sync_error": syncError,
+			"fulfillment_synced_at":  time.Now(),
+			"fulfillment_tracking":   tracking,
+			"fulfillment_attempts":   attempts,
+		}).Error
+}
+
+// GetFailedFulfillments retrieves orders of a connection whose fulfillment sync
+// failed fewer than maxAttempts times, oldest attempt first
+func (r *MarketplaceOrderRepository) GetFailedFulfillments(ctx context.Context, connectionID uuid.UUID, maxAttempts, limit int) ([]models.MarketplaceOrder, error) {
+	var orders []models.MarketplaceOrder
+	err := r.db.WithContext(ctx).
+		Where("connection_id = ? AND fulfillment_sync = ? AND fulfillment_attempts < ?", connectionID, models.FulfillmentSyncFailed, maxAttempts).
+		Order("fulfillment_synced_at ASC").
+		Limit(limit).
+		Find(&orders).Error
+	return orders, err
+}
+
 // Delete deletes a marketplace order
 func (r *MarketplaceOrderRepository) Delete(ctx context.Context, id uuid.UUID) error {
 	return r.db.WithContext(ctx).Delete(&models.MarketplaceOrder{}, "id = ?", id).Error
@@ -0,0 +1,48 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"github.com/niaga-platform/service-marketplace/internal/models"
+)
+
+// OrderStatusHistoryRepository handles database operations for order status history
+type OrderStatusHistoryRepository struct {
+	db *gorm.DB
+}
+
+// NewOrderStatusHistoryRepository creates a new OrderStatusHistoryRepository
+func NewOrderStatusHistoryRepository(db *gorm.DB) *OrderStatusHistoryRepository {
+	return &OrderStatusHistoryRepository{db: db}
+}
+
+// Create records a status change
+func (r *OrderStatusHistoryRepository) Create(ctx context.Context, entry *models.OrderStatusHistory) error {
+	return r.db.WithContext(ctx).Create(entry).Error
+}
+
+// GetByOrderID retrieves the status changes of an order, oldest first
+func (r *OrderStatusHistoryRepository) GetByOrderID(ctx context.Context, orderID uuid.UUID) ([]models.OrderStatusHistory, error) {
+	var entries []models.OrderStatusHistory
+	err := r.db.WithContext(ctx).
+		Where("order_id = ?", orderID).
+		Order("created_at ASC").
+		Find(&entries).Error
+	return entries, err
+}
+
+// GetFirstReached retrieves the earliest change of an order into one of the given statuses
+func (r *OrderStatusHistoryRepository) GetFirstReached(ctx context.Context, orderID uuid.UUID, statuses []string) (*models.OrderStatusHistory, error) {
+	var entry models.OrderStatusHistory
+	err := r.db.WithContext(ctx).
+		Where("order_id = ? AND to_status IN ?", orderID, statuses).
+		Order("created_at ASC").
+		First(&entry).Error
+	if err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
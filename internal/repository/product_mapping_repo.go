@@ -96,6 +96,10 @@ func (r *ProductMappingRepository) GetByConnectionID(ctx context.Context, connec
 	}
 	offset := (page - 1) * pageSize
 
+	if filter != nil && filter.WithVariants {
+		query = query.Preload("VariantMappings")
+	}
+
 	err := query.
 		Offset(offset).
 		Limit(pageSize).
@@ -115,6 +119,69 @@ func (r *ProductMappingRepository) GetByInternalProductID(ctx context.Context, i
 	return mappings, err
 }
 
+// GetVariantMapping retrieves the variant mapping of an external variant within a product mapping
+func (r *ProductMappingRepository) GetVariantMapping(ctx context.Context, productMappingID uuid.UUID, externalVariantID string) (*models.VariantMapping, error) {
+	var variant models.VariantMapping
+	err := r.db.WithContext(ctx).
+		Where("product_mapping_id = ? AND external_variant_id = ?", productMappingID, externalVariantID).
+		First(&variant).Error
+	if err != nil {
+		return nil, err
+	}
+	return &variant, nil
+}
+
+// GetVariantMappingByInternalVariant retrieves the variant mapping of an internal variant within a product mapping
+func (r *ProductMappingRepository) GetVariantMappingByInternalVariant(ctx context.Context, productMappingID, internalVariantID uuid.UUID) (*models.VariantMapping, error) {
+	var variant models.VariantMapping
+	err := r.db.WithContext(ctx).
+		Where("product_mapping_id = ? AND internal_variant_id = ?", productMappingID, internalVariantID).
+		First(&variant).Error
+	if err != nil {
+		return nil, err
+	}
+	return &variant, nil
+}
+
+// GetByConnectionAndExternalProductWithVariants retrieves a mapping by connection and external product ID with its variant mappings
+func (r *ProductMappingRepository) GetByConnectionAndExternalProductWithVariants(ctx context.Context, connectionID uuid.UUID, externalProductID string) (*models.ProductMapping, error) {
+	var mapping models.ProductMapping
+	err := r.db.WithContext(ctx).
+		Preload("VariantMappings").
+		Where("connection_id = ? AND external_product_id = ?", connectionID, externalProductID).
+		First(&mapping).Error
+	if err != nil {
+		return nil, err
+	}
+	return &mapping, nil
+}
+
+// GetVariantMappingBySKU retrieves a variant mapping of a connection by its external SKU, with its product mapping
+func (r *ProductMappingRepository) GetVariantMappingBySKU(ctx context.Context, connectionID uuid.UUID, sku string) (*models.VariantMapping, error) {
+	var variant models.VariantMapping
+	err := r.db.WithContext(ctx).
+		Preload("ProductMapping").
+		Joins("JOIN marketplace.product_mappings pm ON pm.id = variant_mappings.product_mapping_id").
+		Where("pm.connection_id = ? AND variant_mappings.external_sku = ?", connectionID, sku).
+		First(&variant).Error
+	if err != nil {
+		return nil, err
+	}
+	return &variant, nil
+}
+
+// GetByConnectionAndSKU retrieves a mapping by connection and external SKU
+func (r *ProductMappingRepository) GetByConnectionAndSKU(ctx context.Context, connectionID uuid.UUID, sku string) (*models.ProductMapping, error) {
+	var mapping models.ProductMapping
+	err := r.db.WithContext(ctx).
+		Where("connection_id = ? AND external_sku = ?", connectionID, sku).
+		First(&mapping).Error
+	if err != nil {
+		return nil, err
+	}
+	return &mapping, nil
+}
+
 // Update updates a product mapping
 func (r *ProductMappingRepository) Update(ctx context.Context, mapping *models.ProductMapping) error {
 	return r.db.WithContext(ctx).Save(mapping).Error
@@ -136,6 +203,18 @@ func (r *ProductMappingRepository) UpdateSyncStatus(ctx context.Context, id uuid
 		Updates(updates).Error
 }
 
+// UpdateListingStatus records the marketplace listing status of a mapping
+func (r *ProductMappingRepository) UpdateListingStatus(ctx context.Context, id uuid.UUID, status, reason string) error {
+	return r.db.WithContext(ctx).
+		Model(&models.ProductMapping{}).
+		Where("id = ?", id).
+		Updates(map[string]interface{}{
+			"listing_status":            status,
+			"listing_status_reason":     reason,
+			"listing_status_updated_at": gorm.Expr("NOW()"),
+		}).Error
+}
+
 // Delete deletes a product mapping
 func (r *ProductMappingRepository) Delete(ctx context.Context, id uuid.UUID) error {
 	return r.db.WithContext(ctx).Delete(&models.ProductMapping{}, "id = ?", id).Error
@@ -0,0 +1,83 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/niaga-platform/service-marketplace/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ReservedStockRepository handles database operations for promotion reserved stock
+type ReservedStockRepository struct {
+	db *gorm.DB
+}
+
+// NewReservedStockRepository creates a new ReservedStockRepository
+func NewReservedStockRepository(db *gorm.DB) *ReservedStockRepository {
+	return &ReservedStockRepository{db: db}
+}
+
+// GetByListing retrieves the reserved stock of one listing model
+func (r *ReservedStockRepository) GetByListing(ctx context.Context, connectionID uuid.UUID, externalProductID, externalModelID string) (*models.ReservedStock, error) {
+	var reserved models.ReservedStock
+	err := r.db.WithContext(ctx).
+		Where("connection_id = ? AND external_product_id = ? AND external_model_id = ?", connectionID, externalProductID, externalModelID).
+		First(&reserved).Error
+	if err != nil {
+		return nil, err
+	}
+	return &reserved, nil
+}
+
+// Upsert stores the latest reserved quantity of a listing model. Changes older
+// than the stored one are ignored.
+func (r *ReservedStockRepository) Upsert(ctx context.Context, reserved *models.ReservedStock) error {
+	return r.db.WithContext(ctx).
+		Clauses(clause.OnConflict{
+			Columns: []clause.Column{{Name: "connection_id"}, {Name: "external_product_id"}, {Name: "external_model_id"}},
+			DoUpdates: clause.AssignmentColumns([]string{
+				"reserved_quantity", "action", "promotion_type", "promotion_id", "changed_at", "updated_at",
+			}),
+			Where: clause.Where{Exprs: []clause.Expression{
+				clause.Expr{SQL: "marketplace.reserved_stocks.changed_at <= excluded.changed_at"},
+			}},
+		}).
+		Create(reserved).Error
+}
+
+// GetTotalForProduct sums the reserved stock across all models of a listing
+func (r *ReservedStockRepository) GetTotalForProduct(ctx context.Context, connectionID uuid.UUID, externalProductID string) (int, error) {
+	var total int
+	err := r.db.WithContext(ctx).
+		Model(&models.ReservedStock{}).
+		Select("COALESCE(SUM(reserved_quantity), 0)").
+		Where("connection_id = ? AND external_product_id = ?", connectionID, externalProductID).
+		Scan(&total).Error
+	return total, err
+}
+
+// GetByConnectionID retrieves listings with reserved stock for a connection
+func (r *ReservedStockRepository) GetByConnectionID(ctx context.Context, connectionID uuid.UUID, page, pageSize int) ([]models.ReservedStock, int64, error) {
+	var reserved []models.ReservedStock
+	var total int64
+
+	query := r.db.WithContext(ctx).Model(&models.ReservedStock{}).
+		Where("connection_id = ? AND reserved_quantity > 0", connectionID)
+
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 {
+		pageSize = 20
+	}
+	offset := (page - 1) * pageSize
+
+	err := query.Order("changed_at DESC").Offset(offset).Limit(pageSize).Find(&reserved).Error
+	return reserved, total, err
+}
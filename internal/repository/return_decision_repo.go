@@ -0,0 +1,147 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/niaga-platform/service-marketplace/internal/models"
+)
+
+// ReturnDecisionRepository handles database operations for automatic return decisions
+type ReturnDecisionRepository struct {
+	db *gorm.DB
+}
+
+// NewReturnDecisionRepository creates a new ReturnDecisionRepository
+func NewReturnDecisionRepository(db *gorm.DB) *ReturnDecisionRepository {
+	return &ReturnDecisionRepository{db: db}
+}
+
+// Create records a decision unless the return already has one. It reports
+// whether the decision was recorded.
+func (r *ReturnDecisionRepository) Create(ctx context.Context, decision *models.ReturnDecision) (bool, error) {
+	result := r.db.WithContext(ctx).
+		Omit("Return").
+		Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "return_id"}},
+			DoNothing: true,
+		}).
+		Create(decision)
+	return result.RowsAffected == 1, result.Error
+}
+
+// GetByID retrieves a decision by ID
+func (r *ReturnDecisionRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.ReturnDecision, error) {
+	var decision models.ReturnDecision
+	err := r.db.WithContext(ctx).First(&decision, "id = ?", id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &decision, nil
+}
+
+// GetByReturnID retrieves the decision taken on a return
+func (r *ReturnDecisionRepository) GetByReturnID(ctx context.Context, returnID uuid.UUID) (*models.ReturnDecision, error) {
+	var decision models.ReturnDecision
+	err := r.db.WithContext(ctx).First(&decision, "return_id = ?", returnID).Error
+	if err != nil {
+		return nil, err
+	}
+	return &decision, nil
+}
+
+// GetByConnectionID retrieves decisions for a connection with filtering, newest first
+func (r *ReturnDecisionRepository) GetByConnectionID(ctx context.Context, connectionID uuid.UUID, filter *models.ReturnDecisionFilter) ([]models.ReturnDecision, int64, error) {
+	var decisions []models.ReturnDecision
+	var total int64
+
+	query := r.db.WithContext(ctx).Model(&models.ReturnDecision{}).
+		Where("connection_id = ?", connectionID)
+
+	if filter.Status != "" {
+		query = query.Where("status = ?", filter.Status)
+	}
+	if filter.Action != "" {
+		query = query.Where("action = ?", filter.Action)
+	}
+
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	page := filter.Page
+	if page < 1 {
+		page = 1
+	}
+	pageSize := filter.PageSize
+	if pageSize < 1 {
+		pageSize = 20
+	}
+	offset := (page - 1) * pageSize
+
+	err := query.Preload("Return").Order("created_at DESC").Offset(offset).Limit(pageSize).Find(&decisions).Error
+	return decisions, total, err
+}
+
+// GetDue retrieves scheduled decisions whose execution time has passed
+func (r *ReturnDecisionRepository) GetDue(ctx context.Context, limit int) ([]models.ReturnDecision, error) {
+	var decisions []models.ReturnDecision
+	err := r.db.WithContext(ctx).
+		Where("status = ? AND execute_at <= ?", models.ReturnDecisionScheduled, time.Now()).
+		Order("execute_at ASC").
+		Limit(limit).
+		Find(&decisions).Error
+	return decisions, err
+}
+
+// Claim moves a scheduled decision to executing. It reports whether this call
+// made the claim, so a decision reverted in the meantime is never executed.
+func (r *ReturnDecisionRepository) Claim(ctx context.Context, id uuid.UUID) (bool, error) {
+	result := r.db.WithContext(ctx).
+		Model(&models.ReturnDecision{}).
+		Where("id = ? AND status = ?", id, models.ReturnDecisionScheduled).
+		Update("status", models.ReturnDecisionExecuting)
+	return result.RowsAffected == 1, result.Error
+}
+
+// Revert cancels a scheduled decision. It reports whether the decision was
+// still scheduled.
+func (r *ReturnDecisionRepository) Revert(ctx context.Context, id uuid.UUID, reason string) (bool, error) {
+	result := r.db.WithContext(ctx).
+		Model(&models.ReturnDecision{}).
+		Where("id = ? AND status = ?", id, models.ReturnDecisionScheduled).
+		Updates(map[string]interface{}{
+			"status":        models.ReturnDecisionReverted,
+			"reverted_at":   time.Now(),
+			"revert_reason": reason,
+		})
+	return result.RowsAffected == 1, result.Error
+}
+
+// Finish records the outcome of executing a decision
+func (r *ReturnDecisionRepository) Finish(ctx context.Context, id uuid.UUID, status, errorMessage string) error {
+	updates := map[string]interface{}{
+		"status":        status,
+		"error_message": errorMessage,
+	}
+	if status == models.ReturnDecisionExecuted {
+		updates["executed_at"] = time.Now()
+	}
+	return r.db.WithContext(ctx).
+		Model(&models.ReturnDecision{}).
+		Where("id = ?", id).
+		Updates(updates).Error
+}
+
+// ResetExecuting puts decisions left executing by a stopped process back on the schedule
+func (r *ReturnDecisionRepository) ResetExecuting(ctx context.Context) (int64, error) {
+	result := r.db.WithContext(ctx).
+		Model(&models.ReturnDecision{}).
+		Where("status = ?", models.ReturnDecisionExecuting).
+		Update("status", models.ReturnDecisionScheduled)
+	return result.RowsAffected, result.Error
+}
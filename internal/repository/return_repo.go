@@ -0,0 +1,191 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/niaga-platform/service-marketplace/internal/models"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ReturnRepository handles database operations for marketplace returns
+type ReturnRepository struct {
+	db *gorm.DB
+}
+
+// NewReturnRepository creates a new ReturnRepository
+func NewReturnRepository(db *gorm.DB) *ReturnRepository {
+	return &ReturnRepository{db: db}
+}
+
+// GetByID retrieves a return by ID
+func (r *ReturnRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.MarketplaceReturn, error) {
+	var ret models.MarketplaceReturn
+	err := r.db.WithContext(ctx).
+		Preload("Order").
+		First(&ret, "id = ?", id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &ret, nil
+}
+
+// GetByExternalReturnID retrieves a return by its marketplace ID
+func (r *ReturnRepository) GetByExternalReturnID(ctx context.Context, connectionID uuid.UUID, externalReturnID string) (*models.MarketplaceReturn, error) {
+	var ret models.MarketplaceReturn
+	err := r.db.WithContext(ctx).
+		Where("connection_id = ? AND external_return_id = ?", connectionID, externalReturnID).
+		First(&ret).Error
+	if err != nil {
+		return nil, err
+	}
+	return &ret, nil
+}
+
+// GetByConnectionID retrieves returns for a connection with filtering
+func (r *ReturnRepository) GetByConnectionID(ctx context.Context, connectionID uuid.UUID, filter *models.MarketplaceReturnFilter) ([]models.MarketplaceReturn, int64, error) {
+	var returns []models.MarketplaceReturn
+	var total int64
+
+	query := r.db.WithContext(ctx).Model(&models.MarketplaceReturn{}).
+		Where("connection_id = ?", connectionID)
+
+	if filter.Status != "" {
+		query = query.Where("status = ?", filter.Status)
+	}
+	if filter.Type != "" {
+		query = query.Where("return_type = ?", filter.Type)
+	}
+	if filter.ExternalOrderID != "" {
+		query = query.Where("external_order_id = ?", filter.ExternalOrderID)
+	}
+	if filter.StartDate != nil {
+		query = query.Where("external_created_at >= ?", filter.StartDate)
+	}
+	if filter.EndDate != nil {
+		query = query.Where("external_created_at <= ?", filter.EndDate)
+	}
+
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	page := filter.Page
+	if page < 1 {
+		page = 1
+	}
+	pageSize := filter.PageSize
+	if pageSize < 1 {
+		pageSize = 20
+	}
+	offset := (page - 1) * pageSize
+
+	err := query.Order("external_created_at DESC NULLS LAST").Offset(offset).Limit(pageSize).Find(&returns).Error
+	return returns, total, err
+}
+
+// CountByBuyer counts a buyer's returns on a connection, excluding the given return
+func (r *ReturnRepository) CountByBuyer(ctx context.Context, connectionID uuid.UUID, buyerID string, excludeID uuid.UUID) (int64, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&models.MarketplaceReturn{}).
+		Where("connection_id = ? AND buyer_id = ? AND id <> ?", connectionID, buyerID, excludeID).
+		Count(&count).Error
+	return count, err
+}
+
+// Upsert stores the latest marketplace state of a return. States older than the
+// stored one are ignored, and local fields such as dispute details are left untouched.
+func (r *ReturnRepository) Upsert(ctx context.Context, ret *models.MarketplaceReturn) error {
+	return r.db.WithContext(ctx).
+		Clauses(clause.OnConflict{
+			Columns: []clause.Column{{Name: "connection_id"}, {Name: "external_return_id"}},
+			DoUpdates: clause.AssignmentColumns([]string{
+				"order_id", "external_order_id", "return_type", "status", "platform_status", "reason", "reason_text",
+				"refund_amount", "currency", "needs_logistics", "tracking_number", "buyer_id", "buyer_name",
+				"items", "images", "ship_due_date", "external_created_at", "external_updated_at",
+				"synced_at", "updated_at",
+			}),
+			Where: clause.Where{Exprs: []clause.Expression{
+				clause.Expr{SQL: "marketplace.returns.external_updated_at IS NULL OR marketplace.returns.external_updated_at <= excluded.external_updated_at"},
+			}},
+		}).
+		Create(ret).Error
+}
+
+// MarkAccepted records that a return was accepted on the marketplace. Only the
+// decision column is written so concurrent restock and refund claims are kept.
+func (r *ReturnRepository) MarkAccepted(ctx context.Context, id uuid.UUID, acceptedAt time.Time) error {
+	return r.db.WithContext(ctx).
+		Model(&models.MarketplaceReturn{}).
+		Where("id = ?", id).
+		Updates(map[string]interface{}{
+			"accepted_at": acceptedAt,
+			"updated_at":  time.Now(),
+		}).Error
+}
+
+// MarkDisputed records a dispute raised on the marketplace with its evidence
+func (r *ReturnRepository) MarkDisputed(ctx context.Context, id uuid.UUID, reason string, images datatypes.JSON, disputedAt time.Time) error {
+	return r.db.WithContext(ctx).
+		Model(&models.MarketplaceReturn{}).
+		Where("id = ?", id).
+		Updates(map[string]interface{}{
+			"dispute_reason": reason,
+			"dispute_images": images,
+			"disputed_at":    disputedAt,
+			"updated_at":     time.Now(),
+		}).Error
+}
+
+// ClaimRestock marks a return restocked unless it already was. It reports whether
+// this call made the claim, so only one caller publishes the restock.
+func (r *ReturnRepository) ClaimRestock(ctx context.Context, id uuid.UUID) (bool, error) {
+	result := r.db.WithContext(ctx).
+		Model(&models.MarketplaceReturn{}).
+		Where("id = ? AND restocked_at IS NULL", id).
+		Update("restocked_at", time.Now())
+	return result.RowsAffected == 1, result.Error
+}
+
+// ClaimRefund marks a return's refund propagated unless it already was. It reports
+// whether this call made the claim.
+func (r *ReturnRepository) ClaimRefund(ctx context.Context, id uuid.UUID) (bool, error) {
+	result := r.db.WithContext(ctx).
+		Model(&models.MarketplaceReturn{}).
+		Where("id = ? AND refund_propagated_at IS NULL", id).
+		Update("refund_propagated_at", time.Now())
+	return result.RowsAffected == 1, result.Error
+}
+
+// ReleaseRestock clears a restock claim after the restock could not be published
+func (r *ReturnRepository) ReleaseRestock(ctx context.Context, id uuid.UUID, errorMessage string) error {
+	return r.db.WithContext(ctx).
+		Model(&models.MarketplaceReturn{}).
+		Where("id = ?", id).
+		Updates(map[string]interface{}{
+			"restocked_at":      nil,
+			"propagation_error": errorMessage,
+		}).Error
+}
+
+// SetPropagationError records why refund propagation could not complete
+func (r *ReturnRepository) SetPropagationError(ctx context.Context, id uuid.UUID, errorMessage string) error {
+	return r.db.WithContext(ctx).
+		Model(&models.MarketplaceReturn{}).
+		Where("id = ?", id).
+		Update("propagation_error", errorMessage).Error
+}
+
+// ReleaseRefund clears a refund claim after the internal order could not be refunded
+func (r *ReturnRepository) ReleaseRefund(ctx context.Context, id uuid.UUID, errorMessage string) error {
+	return r.db.WithContext(ctx).
+		Model(&models.MarketplaceReturn{}).
+		Where("id = ?", id).
+		Updates(map[string]interface{}{
+			"refund_propagated_at": nil,
+			"propagation_error":    errorMessage,
+		}).Error
+}
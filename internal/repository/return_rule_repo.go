@@ -0,0 +1,65 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"github.com/niaga-platform/service-marketplace/internal/models"
+)
+
+// ReturnRuleRepository handles database operations for return rules
+type ReturnRuleRepository struct {
+	db *gorm.DB
+}
+
+// NewReturnRuleRepository creates a new ReturnRuleRepository
+func NewReturnRuleRepository(db *gorm.DB) *ReturnRuleRepository {
+	return &ReturnRuleRepository{db: db}
+}
+
+// Create creates a new return rule
+func (r *ReturnRuleRepository) Create(ctx context.Context, rule *models.ReturnRule) error {
+	return r.db.WithContext(ctx).Create(rule).Error
+}
+
+// GetByID retrieves a return rule by ID
+func (r *ReturnRuleRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.ReturnRule, error) {
+	var rule models.ReturnRule
+	err := r.db.WithContext(ctx).First(&rule, "id = ?", id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &rule, nil
+}
+
+// GetByConnectionID retrieves all rules of a connection in evaluation order
+func (r *ReturnRuleRepository) GetByConnectionID(ctx context.Context, connectionID uuid.UUID) ([]models.ReturnRule, error) {
+	var rules []models.ReturnRule
+	err := r.db.WithContext(ctx).
+		Where("connection_id = ?", connectionID).
+		Order("priority ASC, created_at ASC").
+		Find(&rules).Error
+	return rules, err
+}
+
+// GetEnabledByConnectionID retrieves the enabled rules of a connection in evaluation order
+func (r *ReturnRuleRepository) GetEnabledByConnectionID(ctx context.Context, connectionID uuid.UUID) ([]models.ReturnRule, error) {
+	var rules []models.ReturnRule
+	err := r.db.WithContext(ctx).
+		Where("connection_id = ? AND enabled = ?", connectionID, true).
+		Order("priority ASC, created_at ASC").
+		Find(&rules).Error
+	return rules, err
+}
+
+// Update updates a return rule
+func (r *ReturnRuleRepository) Update(ctx context.Context, rule *models.ReturnRule) error {
+	return r.db.WithContext(ctx).Save(rule).Error
+}
+
+// Delete deletes a return rule
+func (r *ReturnRuleRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	return r.db.WithContext(ctx).Delete(&models.ReturnRule{}, "id = ?", id).Error
+}
@@ -0,0 +1,144 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/niaga-platform/service-marketplace/internal/models"
+)
+
+// SettlementRepository handles database operations for settlement lines
+type SettlementRepository struct {
+	db *gorm.DB
+}
+
+// NewSettlementRepository creates a new SettlementRepository
+func NewSettlementRepository(db *gorm.DB) *SettlementRepository {
+	return &SettlementRepository{db: db}
+}
+
+// UpsertBatch creates or updates settlement lines. Lines already matched keep their order.
+func (r *SettlementRepository) UpsertBatch(ctx context.Context, lines []models.SettlementLine) error {
+	if len(lines) == 0 {
+		return nil
+	}
+	return r.db.WithContext(ctx).
+		Clauses(clause.OnConflict{
+			Columns: []clause.Column{{Name: "connection_id"}, {Name: "external_id"}},
+			DoUpdates: clause.AssignmentColumns([]string{
+				"external_order_id", "transaction_type", "amount", "fee_amount",
+				"commission_fee", "service_fee", "transaction_fee", "shipping_fee",
+				"currency", "description", "settled_at", "updated_at",
+			}),
+		}).
+		CreateInBatches(lines, 50).Error
+}
+
+// MatchOrders links the unmatched settlement lines of a connection to the
+// orders they pay out and returns the number of lines matched
+func (r *SettlementRepository) MatchOrders(ctx context.Context, connectionID uuid.UUID) (int64, error) {
+	result := r.db.WithContext(ctx).Exec(`
+		UPDATE marketplace.settlement_lines sl
+		SET order_id = o.id, updated_at = NOW()
+		FROM marketplace.orders o
+		WHERE sl.connection_id = ?
+		  AND sl.order_id IS NULL
+		  AND sl.external_order_id <> ''
+		  AND o.connection_id = sl.connection_id
+		  AND o.external_order_id = sl.external_order_id`, connectionID)
+	return result.RowsAffected, result.Error
+}
+
+// GetByOrderID retrieves the settlement lines of an order, oldest first
+func (r *SettlementRepository) GetByOrderID(ctx context.Context, orderID uuid.UUID) ([]models.SettlementLine, error) {
+	var lines []models.SettlementLine
+	err := r.db.WithContext(ctx).
+		Where("order_id = ?", orderID).
+		Order("settled_at ASC").
+		Find(&lines).Error
+	return lines, err
+}
+
+// GetUnmatchedPayouts retrieves order payouts of a connection that match no imported order
+func (r *SettlementRepository) GetUnmatchedPayouts(ctx context.Context, connectionID uuid.UUID, filter *models.SettlementReportFilter) ([]models.SettlementLine, error) {
+	var lines []models.SettlementLine
+	query := r.db.WithContext(ctx).
+		Where("connection_id = ? AND order_id IS NULL AND external_order_id <> ''", connectionID)
+	if filter.StartDate != nil {
+		query = query.Where("settled_at >= ?", filter.StartDate)
+	}
+	if filter.EndDate != nil {
+		query = query.Where("settled_at <= ?", filter.EndDate)
+	}
+	err := query.Order("settled_at ASC").Find(&lines).Error
+	return lines, err
+}
+
+// GetUnpaidOrders retrieves completed orders of a connection without any settlement line
+func (r *SettlementRepository) GetUnpaidOrders(ctx context.Context, connectionID uuid.UUID, filter *models.SettlementReportFilter) ([]models.MarketplaceOrder, error) {
+	var orders []models.MarketplaceOrder
+	query := r.db.WithContext(ctx).
+		Where("connection_id = ? AND status = ?", connectionID, models.OrderStatusCompleted).
+		Where("NOT EXISTS (SELECT 1 FROM marketplace.settlement_lines sl WHERE sl.order_id = marketplace.orders.id)")
+	if filter.StartDate != nil {
+		query = query.Where("created_at >= ?", filter.StartDate)
+	}
+	if filter.EndDate != nil {
+		query = query.Where("created_at <= ?", filter.EndDate)
+	}
+	err := query.Order("created_at ASC").Find(&orders).Error
+	return orders, err
+}
+
+// settlementFees are the fees stored both on settlement lines and in the
+// order_data financials
+var settlementFees = []string{
+	models.SettlementFeeCommission,
+	models.SettlementFeeService,
+	models.SettlementFeeTransaction,
+	models.SettlementFeeShipping,
+}
+
+// GetPayoutMismatches retrieves orders of a connection whose settlement lines
+// total differs from the order's net payout, or whose lines charge a fee that
+// differs from the order's financials, by more than the filter's tolerance.
+// Fees no line of an order reports are not compared.
+func (r *SettlementRepository) GetPayoutMismatches(ctx context.Context, connectionID uuid.UUID, filter *models.SettlementReportFilter) ([]models.SettlementOrderTotals, error) {
+	columns := []string{
+		"o.external_order_id", "o.id AS order_id", "o.status AS order_status", "o.currency", "o.created_at",
+		"o.net_payout", "SUM(sl.amount) AS settled",
+	}
+	having := []string{"ABS(SUM(sl.amount) - o.net_payout) > @tolerance"}
+	for _, fee := range settlementFees {
+		expected := fmt.Sprintf("COALESCE((o.order_data->>'%s')::numeric, 0)", fee)
+		columns = append(columns,
+			fmt.Sprintf("%s AS %s", expected, fee),
+			fmt.Sprintf("SUM(sl.%s) AS settled_%s", fee, fee),
+		)
+		having = append(having, fmt.Sprintf("ABS(SUM(sl.%s) - %s) > @tolerance", fee, expected))
+	}
+
+	var totals []models.SettlementOrderTotals
+	query := r.db.WithContext(ctx).
+		Table("marketplace.orders o").
+		Select(strings.Join(columns, ", ")).
+		Joins("JOIN marketplace.settlement_lines sl ON sl.order_id = o.id").
+		Where("o.connection_id = ? AND o.net_payout IS NOT NULL", connectionID)
+	if filter.StartDate != nil {
+		query = query.Where("o.created_at >= ?", filter.StartDate)
+	}
+	if filter.EndDate != nil {
+		query = query.Where("o.created_at <= ?", filter.EndDate)
+	}
+	err := query.
+		Group("o.id").
+		Having(strings.Join(having, " OR "), map[string]interface{}{"tolerance": filter.Tolerance}).
+		Order("o.created_at ASC").
+		Scan(&totals).Error
+	return totals, err
+}
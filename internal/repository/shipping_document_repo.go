@@ -0,0 +1,84 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"github.com/niaga-platform/service-marketplace/internal/models"
+)
+
+// ShippingDocumentRepository handles database operations for stored shipping documents
+type ShippingDocumentRepository struct {
+	db *gorm.DB
+}
+
+// NewShippingDocumentRepository creates a new ShippingDocumentRepository
+func NewShippingDocumentRepository(db *gorm.DB) *ShippingDocumentRepository {
+	return &ShippingDocumentRepository{db: db}
+}
+
+// Create creates a shipping document record
+func (r *ShippingDocumentRepository) Create(ctx context.Context, document *models.ShippingDocument) error {
+	return r.db.WithContext(ctx).Create(document).Error
+}
+
+// GetByID retrieves a shipping document of an order
+func (r *ShippingDocumentRepository) GetByID(ctx context.Context, orderID, id uuid.UUID) (*models.ShippingDocument, error) {
+	var document models.ShippingDocument
+	err := r.db.WithContext(ctx).
+		Where("order_id = ?", orderID).
+		First(&document, "id = ?", id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &document, nil
+}
+
+// GetLatest retrieves the most recently generated document of a type for an order
+func (r *ShippingDocumentRepository) GetLatest(ctx context.Context, orderID uuid.UUID, documentType string) (*models.ShippingDocument, error) {
+	var document models.ShippingDocument
+	err := r.db.WithContext(ctx).
+		Where("order_id = ? AND document_type = ?", orderID, documentType).
+		Order("generated_at DESC").
+		First(&document).Error
+	if err != nil {
+		return nil, err
+	}
+	return &document, nil
+}
+
+// GetByOrderID retrieves the documents of an order with their print history, newest first
+func (r *ShippingDocumentRepository) GetByOrderID(ctx context.Context, orderID uuid.UUID) ([]models.ShippingDocument, error) {
+	var documents []models.ShippingDocument
+	err := r.db.WithContext(ctx).
+		Preload("Prints", func(db *gorm.DB) *gorm.DB {
+			return db.Order("printed_at ASC")
+		}).
+		Where("order_id = ?", orderID).
+		Order("generated_at DESC").
+		Find(&documents).Error
+	return documents, err
+}
+
+// RecordPrint records a download of a document and bumps its print count
+func (r *ShippingDocumentRepository) RecordPrint(ctx context.Context, documentID uuid.UUID, clientIP string) error {
+	now := time.Now()
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&models.ShippingDocumentPrint{
+			DocumentID: documentID,
+			ClientIP:   clientIP,
+			PrintedAt:  now,
+		}).Error; err != nil {
+			return err
+		}
+		return tx.Model(&models.ShippingDocument{}).
+			Where("id = ?", documentID).
+			Updates(map[string]interface{}{
+				"print_count":     gorm.Expr("print_count + 1"),
+				"last_printed_at": now,
+			}).Error
+	})
+}
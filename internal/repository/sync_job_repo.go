@@ -6,6 +6,7 @@ import (
 
 	"github.com/google/uuid"
 	"github.com/niaga-platform/service-marketplace/internal/models"
+	"gorm.io/datatypes"
 	"gorm.io/gorm"
 )
 
@@ -102,6 +103,14 @@ func (r *SyncJobRepository) Update(ctx context.Context, job *models.SyncJob) err
 	return r.db.WithContext(ctx).Save(job).Error
 }
 
+// UpdatePayload replaces the payload of a job, e.g. to store a report or checkpoint
+func (r *SyncJobRepository) UpdatePayload(ctx context.Context, id uuid.UUID, payload datatypes.JSON) error {
+	return r.db.WithContext(ctx).
+		Model(&models.SyncJob{}).
+		Where("id = ?", id).
+		Update("payload", payload).Error
+}
+
 // MarkProcessing marks a job as processing
 func (r *SyncJobRepository) MarkProcessing(ctx context.Context, id uuid.UUID) error {
 	now := time.Now()
@@ -115,6 +124,25 @@ func (r *SyncJobRepository) MarkProcessing(ctx context.Context, id uuid.UUID) er
 		}).Error
 }
 
+// ClaimProcessing marks a job as processing unless another worker holds it.
+// A processing job not updated since staleBefore is taken over, since the
+// worker that held it has stopped. It returns false if the job is held.
+func (r *SyncJobRepository) ClaimProcessing(ctx context.Context, id uuid.UUID, staleBefore time.Time) (bool, error) {
+	now := time.Now()
+	result := r.db.WithContext(ctx).
+		Model(&models.SyncJob{}).
+		Where("id = ? AND (status <> ? OR updated_at < ?)", id, models.JobStatusProcessing, staleBefore).
+		Updates(map[string]interface{}{
+			"status":     models.JobStatusProcessing,
+			"started_at": now,
+			"attempts":   gorm.Expr("attempts + 1"),
+		})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
 // MarkCompleted marks a job as completed
 func (r *SyncJobRepository) MarkCompleted(ctx context.Context, id uuid.UUID) error {
 	now := time.Now()
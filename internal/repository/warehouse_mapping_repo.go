@@ -0,0 +1,71 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/niaga-platform/service-marketplace/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// WarehouseMappingRepository handles database operations for warehouse mappings
+// and the per-warehouse stock snapshot used to aggregate them
+type WarehouseMappingRepository struct {
+	db *gorm.DB
+}
+
+// NewWarehouseMappingRepository creates a new WarehouseMappingRepository
+func NewWarehouseMappingRepository(db *gorm.DB) *WarehouseMappingRepository {
+	return &WarehouseMappingRepository{db: db}
+}
+
+// GetByConnectionID retrieves all warehouse mappings for a connection
+func (r *WarehouseMappingRepository) GetByConnectionID(ctx context.Context, connectionID uuid.UUID) ([]models.WarehouseMapping, error) {
+	var mappings []models.WarehouseMapping
+	err := r.db.WithContext(ctx).
+		Where("connection_id = ?", connectionID).
+		Order("internal_warehouse_id ASC").
+		Find(&mappings).Error
+	return mappings, err
+}
+
+// Upsert creates or updates the mapping for an internal warehouse
+func (r *WarehouseMappingRepository) Upsert(ctx context.Context, mapping *models.WarehouseMapping) error {
+	return r.db.WithContext(ctx).
+		Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "connection_id"}, {Name: "internal_warehouse_id"}},
+			DoUpdates: clause.AssignmentColumns([]string{"external_location_id", "updated_at"}),
+		}).
+		Create(mapping).Error
+}
+
+// Delete removes the mapping for an internal warehouse
+func (r *WarehouseMappingRepository) Delete(ctx context.Context, connectionID uuid.UUID, internalWarehouseID string) error {
+	return r.db.WithContext(ctx).
+		Where("connection_id = ? AND internal_warehouse_id = ?", connectionID, internalWarehouseID).
+		Delete(&models.WarehouseMapping{}).Error
+}
+
+// UpsertStockLevel records the latest stock of a product in a warehouse.
+// Changes older than the stored one are ignored.
+func (r *WarehouseMappingRepository) UpsertStockLevel(ctx context.Context, level *models.WarehouseStockLevel) error {
+	return r.db.WithContext(ctx).
+		Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "internal_product_id"}, {Name: "warehouse_id"}},
+			DoUpdates: clause.AssignmentColumns([]string{"quantity", "changed_at", "updated_at"}),
+			Where: clause.Where{Exprs: []clause.Expression{
+				clause.Expr{SQL: "marketplace.warehouse_stock_levels.changed_at <= excluded.changed_at"},
+			}},
+		}).
+		Create(level).Error
+}
+
+// GetStockLevels retrieves the known per-warehouse stock of a product
+func (r *WarehouseMappingRepository) GetStockLevels(ctx context.Context, internalProductID uuid.UUID) ([]models.WarehouseStockLevel, error) {
+	var levels []models.WarehouseStockLevel
+	err := r.db.WithContext(ctx).
+		Where("internal_product_id = ?", internalProductID).
+		Find(&levels).Error
+	return levels, err
+}
@@ -0,0 +1,190 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/niaga-platform/service-marketplace/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// WebhookEventRepository handles database operations for stored webhook events
+type WebhookEventRepository struct {
+	db *gorm.DB
+}
+
+// NewWebhookEventRepository creates a new WebhookEventRepository
+func NewWebhookEventRepository(db *gorm.DB) *WebhookEventRepository {
+	return &WebhookEventRepository{db: db}
+}
+
+// Create stores a new webhook event
+func (r *WebhookEventRepository) Create(ctx context.Context, event *models.WebhookEvent) error {
+	return r.db.WithContext(ctx).Create(event).Error
+}
+
+// CreateIfNew stores a webhook event unless one with the same fingerprint
+// exists. It reports whether the event was stored.
+func (r *WebhookEventRepository) CreateIfNew(ctx context.Context, event *models.WebhookEvent) (bool, error) {
+	result := r.db.WithContext(ctx).
+		Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "fingerprint"}},
+			DoNothing: true,
+		}).
+		Create(event)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// GetByFingerprint retrieves the webhook event stored for a delivery fingerprint
+func (r *WebhookEventRepository) GetByFingerprint(ctx context.Context, fingerprint string) (*models.WebhookEvent, error) {
+	var event models.WebhookEvent
+	err := r.db.WithContext(ctx).First(&event, "fingerprint = ?", fingerprint).Error
+	if err != nil {
+		return nil, err
+	}
+	return &event, nil
+}
+
+// GetByID retrieves a webhook event by ID
+func (r *WebhookEventRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.WebhookEvent, error) {
+	var event models.WebhookEvent
+	err := r.db.WithContext(ctx).First(&event, "id = ?", id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &event, nil
+}
+
+// MarkProcessed records a successful processing attempt
+func (r *WebhookEventRepository) MarkProcessed(ctx context.Context, id uuid.UUID) error {
+	return r.db.WithContext(ctx).
+		Model(&models.WebhookEvent{}).
+		Where("id = ?", id).
+		Updates(map[string]interface{}{
+			"processed":     true,
+			"error_message": "",
+			"attempts":      gorm.Expr("attempts + 1"),
+			"processed_at":  time.Now(),
+		}).Error
+}
+
+// MarkFailed records a failed processing attempt
+func (r *WebhookEventRepository) MarkFailed(ctx context.Context, id uuid.UUID, errorMessage string) error {
+	return r.db.WithContext(ctx).
+		Model(&models.WebhookEvent{}).
+		Where("id = ?", id).
+		Updates(map[string]interface{}{
+			"processed":     false,
+			"error_message": errorMessage,
+			"attempts":      gorm.Expr("attempts + 1"),
+			"processed_at":  time.Now(),
+		}).Error
+}
+
+// GetPending retrieves events received before the given time that were stored
+// but never processed, oldest first
+func (r *WebhookEventRepository) GetPending(ctx context.Context, receivedBefore time.Time, limit int) ([]models.WebhookEvent, error) {
+	var events []models.WebhookEvent
+	err := r.db.WithContext(ctx).
+		Where("processed = ? AND attempts = 0 AND received_at < ?", false, receivedBefore).
+		Order("received_at ASC").
+		Limit(limit).
+		Find(&events).Error
+	return events, err
+}
+
+// GetAll retrieves webhook events with optional filters
+func (r *WebhookEventRepository) GetAll(ctx context.Context, filter *models.WebhookEventFilter) ([]models.WebhookEvent, int64, error) {
+	var events []models.WebhookEvent
+	var total int64
+
+	query := r.db.WithContext(ctx).Model(&models.WebhookEvent{})
+
+	if filter != nil {
+		if filter.Platform != "" {
+			query = query.Where("platform = ?", filter.Platform)
+		}
+		if filter.EventType != "" {
+			query = query.Where("event_type = ?", filter.EventType)
+		}
+		if filter.ShopID != "" {
+			query = query.Where("shop_id = ?", filter.ShopID)
+		}
+		if filter.Search != "" {
+			query = query.Where("payload::text ILIKE ?", "%"+filter.Search+"%")
+		}
+		if filter.Processed != nil {
+			query = query.Where("processed = ?", *filter.Processed)
+		}
+		if filter.StartDate != nil {
+			query = query.Where("received_at >= ?", *filter.StartDate)
+		}
+		if filter.EndDate != nil {
+			query = query.Where("received_at <= ?", *filter.EndDate)
+		}
+	}
+
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	page := 1
+	pageSize := 20
+	if filter != nil {
+		if filter.Page > 0 {
+			page = filter.Page
+		}
+		if filter.PageSize > 0 {
+			pageSize = filter.PageSize
+		}
+	}
+	offset := (page - 1) * pageSize
+
+	err := query.
+		Offset(offset).
+		Limit(pageSize).
+		Order("received_at DESC").
+		Find(&events).Error
+
+	return events, total, err
+}
+
+// GetByIDs retrieves webhook events by ID
+func (r *WebhookEventRepository) GetByIDs(ctx context.Context, ids []uuid.UUID) ([]models.WebhookEvent, error) {
+	var events []models.WebhookEvent
+	err := r.db.WithContext(ctx).Where("id IN ?", ids).Find(&events).Error
+	return events, err
+}
+
+// MarkResolved flags events as processed without running them, keeping an optional note
+func (r *WebhookEventRepository) MarkResolved(ctx context.Context, ids []uuid.UUID, note string) (int64, error) {
+	result := r.db.WithContext(ctx).
+		Model(&models.WebhookEvent{}).
+		Where("id IN ?", ids).
+		Updates(map[string]interface{}{
+			"processed":     true,
+			"error_message": note,
+			"processed_at":  time.Now(),
+		})
+	return result.RowsAffected, result.Error
+}
+
+// CreateReplay records the outcome of a replay
+func (r *WebhookEventRepository) CreateReplay(ctx context.Context, replay *models.WebhookEventReplay) error {
+	return r.db.WithContext(ctx).Create(replay).Error
+}
+
+// GetReplays retrieves the replay history of an event, newest first
+func (r *WebhookEventRepository) GetReplays(ctx context.Context, eventID uuid.UUID) ([]models.WebhookEventReplay, error) {
+	var replays []models.WebhookEventReplay
+	err := r.db.WithContext(ctx).
+		Where("webhook_event_id = ?", eventID).
+		Order("replayed_at DESC").
+		Find(&replays).Error
+	return replays, err
+}
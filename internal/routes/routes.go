@@ -15,6 +15,10 @@ type RouteConfig struct {
 	CategoryHandler   *handlers.CategoryHandler
 	InventoryHandler  *handlers.InventoryHandler
 	OrderHandler      *handlers.OrderHandler
+	ReturnHandler     *handlers.ReturnHandler
+	ReturnRuleHandler *handlers.ReturnRuleHandler
+	SettlementHandler *handlers.SettlementHandler
+	ShipmentHandler   *handlers.ShipmentHandler
 	WebhookHandler    *handlers.WebhookHandler
 	AnalyticsHandler  *handlers.AnalyticsHandler
 	JWTManager        *libauth.JWTManager
@@ -50,6 +54,7 @@ func SetupRoutes(router *gin.Engine, cfg *RouteConfig) {
 			connections.GET("/:id", cfg.ConnectionHandler.GetConnection)
 			connections.DELETE("/:id", cfg.ConnectionHandler.Disconnect)
 			connections.POST("/:id/refresh", cfg.ConnectionHandler.RefreshToken)
+			connections.PUT("/:id/settings", cfg.ConnectionHandler.UpdateSettings)
 
 			// Product sync routes
 			connections.GET("/:id/products", cfg.ProductHandler.GetMappedProducts)
@@ -72,13 +77,53 @@ func SetupRoutes(router *gin.Engine, cfg *RouteConfig) {
 			// Inventory sync routes
 			connections.POST("/:id/inventory/push", cfg.InventoryHandler.PushInventory)
 			connections.POST("/:id/inventory/status", cfg.InventoryHandler.GetInventoryStatus)
+			connections.GET("/:id/inventory/logs", cfg.InventoryHandler.GetInventoryLogs)
+			connections.POST("/:id/inventory/reconcile", cfg.InventoryHandler.ReconcileInventory)
+			connections.GET("/:id/inventory/reconciliations", cfg.InventoryHandler.GetReconciliations)
+			connections.GET("/:id/inventory/reserved", cfg.InventoryHandler.GetReservedStock)
+			connections.GET("/:id/warehouses", cfg.InventoryHandler.GetWarehouseMappings)
+			connections.PUT("/:id/warehouses", cfg.InventoryHandler.SetWarehouseMapping)
+			connections.GET("/:id/warehouses/external", cfg.InventoryHandler.GetMarketplaceWarehouses)
+			connections.DELETE("/:id/warehouses/:warehouse_id", cfg.InventoryHandler.DeleteWarehouseMapping)
 
 			// Order sync routes
 			connections.GET("/:id/orders", cfg.OrderHandler.GetOrders)
 			connections.POST("/:id/orders/sync", cfg.OrderHandler.SyncOrders)
+			connections.GET("/:id/orders/poll-state", cfg.OrderHandler.GetPollState)
+			connections.POST("/:id/orders/backfill", cfg.OrderHandler.StartBackfill)
+			connections.GET("/:id/orders/backfills", cfg.OrderHandler.GetBackfills)
+			connections.POST("/:id/orders/backfills/:job_id/resume", cfg.OrderHandler.ResumeBackfill)
+			connections.POST("/:id/orders/batch-ship", cfg.ShipmentHandler.BatchShip)
+			connections.POST("/:id/orders/batch-awb", cfg.ShipmentHandler.BatchAWB)
 			connections.PUT("/:id/orders/:order_id/status", cfg.OrderHandler.UpdateOrderStatus)
+			connections.GET("/:id/orders/:order_id/status-history", cfg.OrderHandler.GetOrderStatusHistory)
+			connections.POST("/:id/orders/:order_id/financials/sync", cfg.OrderHandler.SyncOrderFinancials)
+			connections.GET("/:id/orders/:order_id/shipping-options", cfg.OrderHandler.GetShippingOptions)
 			connections.POST("/:id/orders/:order_id/ship", cfg.OrderHandler.ArrangeShipment)
-			connections.POST("/:id/orders/:order_id/awb", cfg.OrderHandler.GetAWB)
+			connections.POST("/:id/orders/:order_id/awb", cfg.ShipmentHandler.GetAWB)
+			connections.GET("/:id/orders/:order_id/documents", cfg.ShipmentHandler.GetDocuments)
+			connections.GET("/:id/orders/:order_id/documents/:document_id/file", cfg.ShipmentHandler.DownloadDocument)
+
+			// Return routes
+			connections.GET("/:id/returns", cfg.ReturnHandler.GetReturns)
+			connections.POST("/:id/returns/sync", cfg.ReturnHandler.SyncReturns)
+			connections.GET("/:id/returns/:return_id", cfg.ReturnHandler.GetReturn)
+			connections.POST("/:id/returns/:return_id/accept", cfg.ReturnHandler.AcceptReturn)
+			connections.POST("/:id/returns/:return_id/dispute", cfg.ReturnHandler.DisputeReturn)
+			connections.GET("/:id/returns/:return_id/reject-reasons", cfg.ReturnHandler.GetRejectReasons)
+			connections.POST("/:id/returns/:return_id/decision/revert", cfg.ReturnRuleHandler.RevertDecision)
+
+			// Return rules
+			connections.GET("/:id/return-rules", cfg.ReturnRuleHandler.GetRules)
+			connections.POST("/:id/return-rules", cfg.ReturnRuleHandler.CreateRule)
+			connections.PUT("/:id/return-rules/:rule_id", cfg.ReturnRuleHandler.UpdateRule)
+			connections.DELETE("/:id/return-rules/:rule_id", cfg.ReturnRuleHandler.DeleteRule)
+			connections.GET("/:id/return-decisions", cfg.ReturnRuleHandler.GetDecisions)
+
+			// Settlement routes
+			connections.POST("/:id/settlements/sync", cfg.SettlementHandler.SyncSettlements)
+			connections.POST("/:id/settlements/match", cfg.SettlementHandler.MatchSettlements)
+			connections.GET("/:id/settlements/report", cfg.SettlementHandler.GetReport)
 
 			// Analytics routes
 			if cfg.AnalyticsHandler != nil {
@@ -89,6 +134,18 @@ func SetupRoutes(router *gin.Engine, cfg *RouteConfig) {
 			}
 		}
 
+		// Webhook inspection and replay
+		if cfg.WebhookHandler != nil {
+			webhookEvents := admin.Group("/webhooks")
+			{
+				webhookEvents.GET("/events", cfg.WebhookHandler.ListWebhookEvents)
+				webhookEvents.GET("/events/:event_id", cfg.WebhookHandler.GetWebhookEvent)
+				webhookEvents.POST("/events/:event_id/replay", cfg.WebhookHandler.ReplayWebhookEvent)
+				webhookEvents.POST("/replay", cfg.WebhookHandler.ReplayWebhookEvents)
+				webhookEvents.POST("/mark-processed", cfg.WebhookHandler.MarkWebhookEventsProcessed)
+			}
+		}
+
 		// OAuth flow
 		admin.POST("/:platform/auth-url", cfg.ConnectionHandler.GetAuthURL)
 		admin.GET("/shopee/callback", cfg.ConnectionHandler.HandleShopeeCallback)
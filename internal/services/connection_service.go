@@ -4,6 +4,7 @@ import (
 	"context"
 	"crypto/rand"
 	"encoding/hex"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"strconv"
@@ -25,6 +26,7 @@ var (
 	ErrConnectionNotFound = errors.New("connection not found")
 	ErrConnectionExists   = errors.New("connection already exists for this shop")
 	ErrEncryptionRequired = errors.New("encryption key is required")
+	ErrInvalidSettings    = errors.New("invalid connection settings")
 )
 
 // ConnectionService handles marketplace connection operations
@@ -310,6 +312,75 @@ func (s *ConnectionService) Disconnect(ctx context.Context, id uuid.UUID) error
 	return s.repo.Update(ctx, conn)
 }
 
+// HandleDeauthorization deactivates the connection of a shop that revoked access
+// on the marketplace side
+func (s *ConnectionService) HandleDeauthorization(ctx context.Context, platform, shopID string) error {
+	conn, err := s.repo.GetByPlatformAndShopID(ctx, platform, shopID)
+	if err != nil {
+		s.logger.Warn("Deauthorization for unknown shop",
+			zap.String("platform", platform),
+			zap.String("shop_id", shopID),
+		)
+		return ErrConnectionNotFound
+	}
+
+	if !conn.IsActive {
+		return nil
+	}
+	if err := s.repo.Deactivate(ctx, conn.ID); err != nil {
+		return fmt.Errorf("failed to deactivate connection: %w", err)
+	}
+
+	s.logger.Warn("Connection deactivated after shop deauthorization",
+		zap.String("connection_id", conn.ID.String()),
+		zap.String("platform", platform),
+		zap.String("shop_id", shopID),
+	)
+	return nil
+}
+
+// UpdateSettings merges top-level settings sections (e.g. "inventory") into
+// the connection's settings, leaving other sections untouched
+func (s *ConnectionService) UpdateSettings(ctx context.Context, id uuid.UUID, updates map[string]json.RawMessage) (*models.ConnectionResponse, error) {
+	conn, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, ErrConnectionNotFound
+	}
+
+	settings := make(map[string]json.RawMessage)
+	if len(conn.Settings) > 0 {
+		if err := json.Unmarshal(conn.Settings, &settings); err != nil {
+			return nil, fmt.Errorf("failed to decode existing settings: %w", err)
+		}
+	}
+	for key, value := range updates {
+		settings[key] = value
+	}
+
+	merged, err := json.Marshal(settings)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode settings: %w", err)
+	}
+
+	// Reject values that don't fit the typed settings
+	var typed models.ConnectionSettings
+	if err := json.Unmarshal(merged, &typed); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidSettings, err)
+	}
+	switch typed.Shipping.Method {
+	case "", providers.ShippingMethodDropoff, providers.ShippingMethodPickup:
+	default:
+		return nil, fmt.Errorf("%w: shipping method must be dropoff or pickup", ErrInvalidSettings)
+	}
+
+	conn.Settings = merged
+	if err := s.repo.Update(ctx, conn); err != nil {
+		return nil, fmt.Errorf("failed to update settings: %w", err)
+	}
+
+	return conn.ToResponse(), nil
+}
+
 // RefreshConnectionToken refreshes the access token for a connection
 func (s *ConnectionService) RefreshConnectionToken(ctx context.Context, id uuid.UUID) error {
 	conn, err := s.repo.GetByID(ctx, id)
@@ -0,0 +1,529 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"gorm.io/datatypes"
+
+	"github.com/niaga-platform/service-marketplace/internal/clients"
+	"github.com/niaga-platform/service-marketplace/internal/models"
+	"github.com/niaga-platform/service-marketplace/internal/providers"
+	"github.com/niaga-platform/service-marketplace/internal/repository"
+)
+
+// InventoryReconciliationConfig holds configuration for the reconciliation job.
+type InventoryReconciliationConfig struct {
+	Interval    time.Duration // How often to reconcile every active connection
+	PageSize    int           // Mappings compared per marketplace request
+	AutoCorrect bool          // Push expected stock for every connection when drift is found
+}
+
+// InventoryReconciliationService compares marketplace stock with catalog stock
+// and reports (and optionally corrects) drift caused by missed events or
+// manual edits in Seller Center.
+type InventoryReconciliationService struct {
+	connectionRepo     *repository.ConnectionRepository
+	productMappingRepo *repository.ProductMappingRepository
+	syncJobRepo        *repository.SyncJobRepository
+	catalogClient      *clients.CatalogClient
+	inventoryService   *InventorySyncService
+	config             InventoryReconciliationConfig
+	logger             *zap.Logger
+
+	// Lifecycle management
+	stopChan chan struct{}
+	wg       sync.WaitGroup
+	running  bool
+	mu       sync.Mutex
+}
+
+// NewInventoryReconciliationService creates a new reconciliation service.
+func NewInventoryReconciliationService(
+	connectionRepo *repository.ConnectionRepository,
+	productMappingRepo *repository.ProductMappingRepository,
+	syncJobRepo *repository.SyncJobRepository,
+	catalogClient *clients.CatalogClient,
+	inventoryService *InventorySyncService,
+	cfg InventoryReconciliationConfig,
+	logger *zap.Logger,
+) *InventoryReconciliationService {
+	// Set defaults
+	if cfg.Interval == 0 {
+		cfg.Interval = 6 * time.Hour
+	}
+	if cfg.PageSize == 0 {
+		cfg.PageSize = 50
+	}
+
+	return &InventoryReconciliationService{
+		connectionRepo:     connectionRepo,
+		productMappingRepo: productMappingRepo,
+		syncJobRepo:        syncJobRepo,
+		catalogClient:      catalogClient,
+		inventoryService:   inventoryService,
+		config:             cfg,
+		logger:             logger,
+		stopChan:           make(chan struct{}),
+	}
+}
+
+// Start begins the scheduled reconciliation process.
+func (s *InventoryReconciliationService) Start(ctx context.Context) error {
+	s.mu.Lock()
+	if s.running {
+		s.mu.Unlock()
+		return fmt.Errorf("inventory reconciliation already running")
+	}
+	s.running = true
+	s.mu.Unlock()
+
+	s.wg.Add(1)
+	go s.run(ctx)
+
+	s.logger.Info("inventory reconciliation started",
+		zap.Duration("interval", s.config.Interval),
+		zap.Bool("auto_correct", s.config.AutoCorrect),
+	)
+
+	return nil
+}
+
+// Stop gracefully stops the scheduled reconciliation.
+func (s *InventoryReconciliationService) Stop() {
+	s.mu.Lock()
+	if !s.running {
+		s.mu.Unlock()
+		return
+	}
+	s.running = false
+	s.mu.Unlock()
+
+	close(s.stopChan)
+	s.wg.Wait()
+
+	s.logger.Info("inventory reconciliation stopped")
+}
+
+// run is the main background loop.
+func (s *InventoryReconciliationService) run(ctx context.Context) {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(s.config.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-s.stopChan:
+			return
+		case <-ticker.C:
+			s.reconcileAll(ctx)
+		}
+	}
+}
+
+// reconcileAll reconciles every active connection in turn.
+func (s *InventoryReconciliationService) reconcileAll(ctx context.Context) {
+	connections, err := s.connectionRepo.GetActiveConnections(ctx)
+	if err != nil {
+		s.logger.Error("failed to get connections for reconciliation", zap.Error(err))
+		return
+	}
+
+	for _, conn := range connections {
+		autoCorrect := s.config.AutoCorrect || conn.GetSettings().Inventory.AutoCorrect
+		job, err := s.createJob(ctx, conn.ID, autoCorrect)
+		if err != nil {
+			s.logger.Error("failed to create reconciliation job",
+				zap.String("connection_id", conn.ID.String()),
+				zap.Error(err),
+			)
+			continue
+		}
+		s.runJob(ctx, job, &conn, autoCorrect)
+	}
+}
+
+// StartReconciliation queues a reconciliation run for one connection and
+// processes it in the background. The returned job holds the report once done.
+func (s *InventoryReconciliationService) StartReconciliation(ctx context.Context, connectionID uuid.UUID, autoCorrect *bool) (*models.SyncJob, error) {
+	conn, err := s.connectionRepo.GetByID(ctx, connectionID)
+	if err != nil {
+		return nil, ErrConnectionNotFound
+	}
+
+	correct := s.config.AutoCorrect || conn.GetSettings().Inventory.AutoCorrect
+	if autoCorrect != nil {
+		correct = *autoCorrect
+	}
+
+	job, err := s.createJob(ctx, conn.ID, correct)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create reconciliation job: %w", err)
+	}
+
+	go s.runJob(context.Background(), job, conn, correct)
+
+	return job, nil
+}
+
+// GetReconciliations lists previous reconciliation runs for a connection
+func (s *InventoryReconciliationService) GetReconciliations(ctx context.Context, connectionID uuid.UUID, page, pageSize int) ([]models.SyncJob, int64, error) {
+	return s.syncJobRepo.GetByConnectionID(ctx, connectionID, &models.SyncJobFilter{
+		JobType:  models.JobTypeInventoryReconcile,
+		Page:     page,
+		PageSize: pageSize,
+	})
+}
+
+// createJob records a pending reconciliation job
+func (s *InventoryReconciliationService) createJob(ctx context.Context, connectionID uuid.UUID, autoCorrect bool) (*models.SyncJob, error) {
+	payload, _ := json.Marshal(&models.InventoryReconciliationReport{
+		ConnectionID: connectionID,
+		AutoCorrect:  autoCorrect,
+	})
+
+	job := &models.SyncJob{
+		ConnectionID: connectionID,
+		JobType:      models.JobTypeInventoryReconcile,
+		Payload:      datatypes.JSON(payload),
+		Status:       models.JobStatusPending,
+		ScheduledAt:  time.Now(),
+	}
+	if err := s.syncJobRepo.Create(ctx, job); err != nil {
+		return nil, err
+	}
+	return job, nil
+}
+
+// runJob reconciles a connection and stores the report on the job
+func (s *InventoryReconciliationService) runJob(ctx context.Context, job *models.SyncJob, conn *models.Connection, autoCorrect bool) {
+	if err := s.syncJobRepo.MarkProcessing(ctx, job.ID); err != nil {
+		s.logger.Warn("failed to mark reconciliation job processing", zap.Error(err))
+	}
+
+	report, err := s.Reconcile(ctx, conn, autoCorrect)
+
+	payload, _ := json.Marshal(report)
+	job.Payload = datatypes.JSON(payload)
+	if updateErr := s.syncJobRepo.UpdatePayload(ctx, job.ID, job.Payload); updateErr != nil {
+		s.logger.Error("failed to store reconciliation report", zap.Error(updateErr))
+	}
+
+	if err != nil {
+		s.logger.Error("inventory reconciliation failed",
+			zap.String("connection_id", conn.ID.String()),
+			zap.Error(err),
+		)
+		_ = s.syncJobRepo.MarkFailed(ctx, job.ID, err.Error())
+		return
+	}
+
+	_ = s.syncJobRepo.MarkCompleted(ctx, job.ID)
+
+	s.logger.Info("inventory reconciliation completed",
+		zap.String("connection_id", conn.ID.String()),
+		zap.Int("mappings_checked", report.MappingsChecked),
+		zap.Int("drift_count", report.DriftCount),
+		zap.Int("corrected_count", report.CorrectedCount),
+	)
+}
+
+// Reconcile pages through a connection's product mappings, compares marketplace
+// stock with allocated catalog stock and, when autoCorrect is set, pushes the
+// expected quantity for every drifted listing.
+func (s *InventoryReconciliationService) Reconcile(ctx context.Context, conn *models.Connection, autoCorrect bool) (*models.InventoryReconciliationReport, error) {
+	report := &models.InventoryReconciliationReport{
+		ConnectionID: conn.ID,
+		AutoCorrect:  autoCorrect,
+		Drifts:       []models.InventoryDrift{},
+		StartedAt:    time.Now(),
+	}
+	settings := conn.GetSettings().Inventory
+
+	for page := 1; ; page++ {
+		mappings, total, err := s.productMappingRepo.GetByConnectionID(ctx, conn.ID, &models.ProductMappingFilter{
+			WithVariants: true,
+			Page:         page,
+			PageSize:     s.config.PageSize,
+		})
+		if err != nil {
+			return report, fmt.Errorf("failed to get product mappings: %w", err)
+		}
+		if len(mappings) == 0 {
+			break
+		}
+
+		drifts, err := s.reconcilePage(ctx, conn, mappings, settings)
+		if err != nil {
+			report.ErrorCount += len(mappings)
+			report.Errors = append(report.Errors, fmt.Sprintf("page %d: %s", page, err.Error()))
+		}
+		report.MappingsChecked += len(mappings)
+
+		if autoCorrect && len(drifts) > 0 {
+			s.correctDrifts(ctx, conn, mappings, drifts)
+		}
+		for _, drift := range drifts {
+			if drift.Corrected {
+				report.CorrectedCount++
+			}
+			if drift.Error != "" {
+				report.ErrorCount++
+			}
+		}
+		report.DriftCount += len(drifts)
+		report.Drifts = append(report.Drifts, drifts...)
+
+		if int64(page*s.config.PageSize) >= total {
+			break
+		}
+	}
+
+	completedAt := time.Now()
+	report.CompletedAt = &completedAt
+
+	return report, nil
+}
+
+// reconcilePage compares one page of mappings against marketplace and catalog stock
+func (s *InventoryReconciliationService) reconcilePage(ctx context.Context, conn *models.Connection, mappings []models.ProductMapping, settings models.InventorySettings) ([]models.InventoryDrift, error) {
+	externalIDs := make([]string, len(mappings))
+	internalIDs := make([]string, len(mappings))
+	for i, mapping := range mappings {
+		externalIDs[i] = mapping.ExternalProductID
+		internalIDs[i] = mapping.InternalProductID.String()
+	}
+
+	items, err := s.inventoryService.GetMarketplaceInventory(ctx, conn, externalIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get marketplace inventory: %w", err)
+	}
+
+	products, err := s.catalogClient.GetProducts(ctx, internalIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get catalog products: %w", err)
+	}
+
+	catalogProducts := make(map[string]*clients.Product, len(products))
+	for i := range products {
+		catalogProducts[products[i].ID] = &products[i]
+	}
+
+	drifts := make([]models.InventoryDrift, 0)
+	for _, mapping := range mappings {
+		product, ok := catalogProducts[mapping.InternalProductID.String()]
+		if !ok {
+			s.logger.Debug("product not found in catalog, skipping reconciliation",
+				zap.String("product_id", mapping.InternalProductID.String()),
+			)
+			continue
+		}
+
+		expected := func(externalVariantID string, quantity int) int {
+			return s.inventoryService.channelQuantity(ctx, conn, mapping.ExternalProductID, externalVariantID, "", quantity)
+		}
+
+		// Variant listings are compared model by model; per-warehouse
+		// stock is only known per product, so locations are not split
+		if len(mapping.VariantMappings) > 0 {
+			drifts = append(drifts, listingDrifts(&mapping, product, items, expected)...)
+			continue
+		}
+
+		// Connections with mapped warehouses offer stock per location; the
+		// listing total is then the sum of the allocated location stock
+		targets, perLocation, err := s.inventoryService.LocationStockTargets(ctx, conn.ID, mapping.InternalProductID)
+		if err != nil {
+			s.logger.Warn("failed to resolve stock locations, comparing product total",
+				zap.String("product_id", mapping.InternalProductID.String()),
+				zap.Error(err),
+			)
+			perLocation = false
+		}
+		if !perLocation {
+			drifts = append(drifts, listingDrifts(&mapping, product, items, expected)...)
+			continue
+		}
+
+		marketplaceQty, found := marketplaceQuantity(items, mapping.ExternalProductID, "")
+		if !found {
+			s.logger.Debug("listing not returned by marketplace, skipping reconciliation",
+				zap.String("external_product_id", mapping.ExternalProductID),
+			)
+			continue
+		}
+		expectedQty := 0
+		locations := make(map[string]int, len(targets))
+		for _, target := range targets {
+			quantity := settings.AllocateStock(target.Quantity)
+			locations[target.LocationID] = quantity
+			expectedQty += quantity
+		}
+		if marketplaceQty == expectedQty {
+			continue
+		}
+
+		drifts = append(drifts, models.InventoryDrift{
+			ProductMappingID:    mapping.ID,
+			InternalProductID:   mapping.InternalProductID,
+			ExternalProductID:   mapping.ExternalProductID,
+			CatalogQuantity:     product.StockQuantity,
+			ExpectedQuantity:    expectedQty,
+			MarketplaceQuantity: marketplaceQty,
+			Difference:          marketplaceQty - expectedQty,
+			Locations:           locations,
+		})
+	}
+
+	return drifts, nil
+}
+
+// listingDrifts compares the marketplace stock of a listing with the catalog.
+// Mappings with variant mappings are compared per variant against the
+// marketplace model or SKU it is mapped to; other listings compare the sum of
+// all models with the catalog variant of the mapping's seller SKU, or with the
+// product stock. expected turns a catalog quantity into the quantity the
+// listing or model should show. Listings or models the marketplace did not
+// return are skipped.
+func listingDrifts(mapping *models.ProductMapping, product *clients.Product, items []providers.InventoryItem, expected func(externalVariantID string, quantity int) int) []models.InventoryDrift {
+	drifts := make([]models.InventoryDrift, 0)
+	compare := func(externalVariantID, externalSKU string, internalVariantID *uuid.UUID, catalogQty int) {
+		marketplaceQty, found := marketplaceQuantity(items, mapping.ExternalProductID, externalVariantID)
+		if !found {
+			return
+		}
+		expectedQty := expected(externalVariantID, catalogQty)
+		if marketplaceQty == expectedQty {
+			return
+		}
+		drifts = append(drifts, models.InventoryDrift{
+			ProductMappingID:    mapping.ID,
+			InternalProductID:   mapping.InternalProductID,
+			InternalVariantID:   internalVariantID,
+			ExternalProductID:   mapping.ExternalProductID,
+			ExternalVariantID:   externalVariantID,
+			ExternalSKU:         externalSKU,
+			CatalogQuantity:     catalogQty,
+			ExpectedQuantity:    expectedQty,
+			MarketplaceQuantity: marketplaceQty,
+			Difference:          marketplaceQty - expectedQty,
+		})
+	}
+
+	if len(mapping.VariantMappings) > 0 {
+		variantStock := make(map[string]int, len(product.Variants))
+		for _, variant := range product.Variants {
+			variantStock[variant.ID] = variant.StockQuantity
+		}
+		for _, vm := range mapping.VariantMappings {
+			catalogQty, ok := variantStock[vm.InternalVariantID.String()]
+			if !ok {
+				continue
+			}
+			variantID := vm.InternalVariantID
+			compare(vm.ExternalVariantID, vm.ExternalSKU, &variantID, catalogQty)
+		}
+		return drifts
+	}
+
+	if mapping.ExternalSKU != "" {
+		// A single-SKU mapping follows the catalog variant with the same SKU
+		for _, variant := range product.Variants {
+			if variant.SKU == mapping.ExternalSKU {
+				compare("", mapping.ExternalSKU, nil, variant.StockQuantity)
+				return drifts
+			}
+		}
+	}
+
+	compare("", mapping.ExternalSKU, nil, product.StockQuantity)
+	return drifts
+}
+
+// correctDrifts pushes the expected quantity for drifted listings in one batch
+func (s *InventoryReconciliationService) correctDrifts(ctx context.Context, conn *models.Connection, mappings []models.ProductMapping, drifts []models.InventoryDrift) {
+	byID := make(map[uuid.UUID]*models.ProductMapping, len(mappings))
+	for i := range mappings {
+		byID[mappings[i].ID] = &mappings[i]
+	}
+
+	// Per-location drifts fan out into one push per location
+	pushes := make([]StockPush, 0, len(drifts))
+	owners := make([]int, 0, len(drifts))
+	for i, drift := range drifts {
+		push := StockPush{
+			Mapping:           byID[drift.ProductMappingID],
+			ExternalProductID: drift.ExternalProductID,
+			ExternalVariantID: drift.ExternalVariantID,
+			ExternalSKU:       drift.ExternalSKU,
+			Quantity:          drift.ExpectedQuantity,
+			SourceReference:   fmt.Sprintf("drift %+d", drift.Difference),
+		}
+		if len(drift.Locations) == 0 {
+			pushes = append(pushes, push)
+			owners = append(owners, i)
+			continue
+		}
+		for locationID, quantity := range drift.Locations {
+			push.LocationID = locationID
+			push.Quantity = quantity
+			pushes = append(pushes, push)
+			owners = append(owners, i)
+		}
+	}
+
+	results, err := s.inventoryService.PushStock(ctx, conn, pushes, models.InventorySyncSourceReconciliation)
+	for i := range drifts {
+		if err != nil {
+			drifts[i].Error = err.Error()
+		}
+	}
+	if err != nil {
+		return
+	}
+
+	// A drift is corrected only when every one of its pushes succeeded
+	failed := make(map[int]string)
+	for j, owner := range owners {
+		if j >= len(results) {
+			failed[owner] = "no result returned"
+			continue
+		}
+		if !results[j].Success {
+			failed[owner] = results[j].Error
+		}
+	}
+	for i := range drifts {
+		if msg, ok := failed[i]; ok {
+			drifts[i].Error = msg
+			continue
+		}
+		drifts[i].Corrected = true
+	}
+}
+
+// marketplaceQuantity finds the stock reported for a listing model or SKU ID.
+// Without one the stock of all models of the listing is summed.
+func marketplaceQuantity(items []providers.InventoryItem, externalProductID, externalVariantID string) (int, bool) {
+	quantity := 0
+	found := false
+	for _, item := range items {
+		if item.ExternalProductID != externalProductID {
+			continue
+		}
+		if externalVariantID != "" && item.ExternalSKU != externalVariantID {
+			continue
+		}
+		quantity += item.Quantity
+		found = true
+	}
+	return quantity, found
+}
@@ -0,0 +1,170 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+
+	"github.com/niaga-platform/service-marketplace/internal/clients"
+	"github.com/niaga-platform/service-marketplace/internal/models"
+	"github.com/niaga-platform/service-marketplace/internal/providers"
+)
+
+func TestListingDrifts(t *testing.T) {
+	redID := uuid.New()
+	blueID := uuid.New()
+
+	product := &clients.Product{
+		ID:            uuid.New().String(),
+		StockQuantity: 15,
+		Variants: []clients.ProductVariant{
+			{ID: redID.String(), SKU: "SHIRT-RED", StockQuantity: 10},
+			{ID: blueID.String(), SKU: "SHIRT-BLUE", StockQuantity: 5},
+		},
+	}
+	modelItems := []providers.InventoryItem{
+		{ExternalProductID: "100", ExternalSKU: "1001", Quantity: 10},
+		{ExternalProductID: "100", ExternalSKU: "1002", Quantity: 5},
+	}
+	variantMappings := []models.VariantMapping{
+		{InternalVariantID: redID, ExternalVariantID: "1001", ExternalSKU: "SHIRT-RED"},
+		{InternalVariantID: blueID, ExternalVariantID: "1002", ExternalSKU: "SHIRT-BLUE"},
+	}
+	unchanged := func(_ string, quantity int) int { return quantity }
+
+	tests := []struct {
+		name     string
+		mapping  models.ProductMapping
+		items    []providers.InventoryItem
+		expected func(string, int) int
+		want     []models.InventoryDrift
+	}{
+		{
+			name:     "variants in sync",
+			mapping:  models.ProductMapping{ExternalProductID: "100", VariantMappings: variantMappings},
+			items:    modelItems,
+			expected: unchanged,
+			want:     nil,
+		},
+		{
+			name:    "variant drift reported per model",
+			mapping: models.ProductMapping{ExternalProductID: "100", VariantMappings: variantMappings},
+			items: []providers.InventoryItem{
+				{ExternalProductID: "100", ExternalSKU: "1001", Quantity: 10},
+				{ExternalProductID: "100", ExternalSKU: "1002", Quantity: 8},
+			},
+			expected: unchanged,
+			want: []models.InventoryDrift{
+				{InternalVariantID: &blueID, ExternalVariantID: "1002", ExternalSKU: "SHIRT-BLUE", CatalogQuantity: 5, ExpectedQuantity: 5, MarketplaceQuantity: 8, Difference: 3},
+			},
+		},
+		{
+			name:     "allocation applied per model",
+			mapping:  models.ProductMapping{ExternalProductID: "100", VariantMappings: variantMappings},
+			items:    modelItems,
+			expected: func(_ string, quantity int) int { return quantity / 2 },
+			want: []models.InventoryDrift{
+				{InternalVariantID: &redID, ExternalVariantID: "1001", ExternalSKU: "SHIRT-RED", CatalogQuantity: 10, ExpectedQuantity: 5, MarketplaceQuantity: 10, Difference: 5},
+				{InternalVariantID: &blueID, ExternalVariantID: "1002", ExternalSKU: "SHIRT-BLUE", CatalogQuantity: 5, ExpectedQuantity: 2, MarketplaceQuantity: 5, Difference: 3},
+			},
+		},
+		{
+			name:     "single SKU mapping follows the catalog variant",
+			mapping:  models.ProductMapping{ExternalProductID: "100", ExternalSKU: "SHIRT-BLUE"},
+			items:    []providers.InventoryItem{{ExternalProductID: "100", Quantity: 5}},
+			expected: unchanged,
+			want:     nil,
+		},
+		{
+			name:     "single SKU mapping drift",
+			mapping:  models.ProductMapping{ExternalProductID: "100", ExternalSKU: "SHIRT-BLUE"},
+			items:    []providers.InventoryItem{{ExternalProductID: "100", Quantity: 7}},
+			expected: unchanged,
+			want: []models.InventoryDrift{
+				{ExternalSKU: "SHIRT-BLUE", CatalogQuantity: 5, ExpectedQuantity: 5, MarketplaceQuantity: 7, Difference: 2},
+			},
+		},
+		{
+			name:     "seller SKU is not a model ID",
+			mapping:  models.ProductMapping{ExternalProductID: "100", ExternalSKU: "1001"},
+			items:    modelItems,
+			expected: unchanged,
+			want:     nil,
+		},
+		{
+			name:     "product mapping compares the listing total",
+			mapping:  models.ProductMapping{ExternalProductID: "100"},
+			items:    modelItems,
+			expected: unchanged,
+			want:     nil,
+		},
+		{
+			name:     "product mapping drift",
+			mapping:  models.ProductMapping{ExternalProductID: "100"},
+			items:    []providers.InventoryItem{{ExternalProductID: "100", Quantity: 20}},
+			expected: unchanged,
+			want: []models.InventoryDrift{
+				{CatalogQuantity: 15, ExpectedQuantity: 15, MarketplaceQuantity: 20, Difference: 5},
+			},
+		},
+		{
+			name:     "listing not returned",
+			mapping:  models.ProductMapping{ExternalProductID: "200", VariantMappings: variantMappings},
+			items:    modelItems,
+			expected: unchanged,
+			want:     nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := listingDrifts(&tt.mapping, product, tt.items, tt.expected)
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %d drifts, want %d: %+v", len(got), len(tt.want), got)
+			}
+			for i, want := range tt.want {
+				d := got[i]
+				if d.ExternalVariantID != want.ExternalVariantID || d.ExternalSKU != want.ExternalSKU || d.CatalogQuantity != want.CatalogQuantity ||
+					d.ExpectedQuantity != want.ExpectedQuantity || d.MarketplaceQuantity != want.MarketplaceQuantity ||
+					d.Difference != want.Difference {
+					t.Errorf("drift %d = %+v, want %+v", i, d, want)
+				}
+				if (d.InternalVariantID == nil) != (want.InternalVariantID == nil) ||
+					(d.InternalVariantID != nil && *d.InternalVariantID != *want.InternalVariantID) {
+					t.Errorf("drift %d variant = %v, want %v", i, d.InternalVariantID, want.InternalVariantID)
+				}
+			}
+		})
+	}
+}
+
+func TestMarketplaceQuantity(t *testing.T) {
+	items := []providers.InventoryItem{
+		{ExternalProductID: "100", ExternalSKU: "1001", Quantity: 3},
+		{ExternalProductID: "100", ExternalSKU: "1002", Quantity: 4},
+		{ExternalProductID: "200", Quantity: 9},
+	}
+
+	tests := []struct {
+		name        string
+		productID   string
+		sku         string
+		wantQty     int
+		wantPresent bool
+	}{
+		{"sum of all models", "100", "", 7, true},
+		{"one model", "100", "1002", 4, true},
+		{"unknown model", "100", "1003", 0, false},
+		{"item without models", "200", "", 9, true},
+		{"unknown listing", "300", "", 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			qty, found := marketplaceQuantity(items, tt.productID, tt.sku)
+			if qty != tt.wantQty || found != tt.wantPresent {
+				t.Errorf("marketplaceQuantity(%q, %q) = %d, %v; want %d, %v", tt.productID, tt.sku, qty, found, tt.wantQty, tt.wantPresent)
+			}
+		})
+	}
+}
@@ -9,6 +9,7 @@ import (
 
 	"github.com/google/uuid"
 	"go.uber.org/zap"
+	"gorm.io/gorm"
 
 	"github.com/niaga-platform/service-marketplace/internal/events"
 	"github.com/niaga-platform/service-marketplace/internal/models"
@@ -20,15 +21,20 @@ import (
 )
 
 var (
-	ErrNoMappingFound = errors.New("no product mapping found for this product")
+	ErrNoMappingFound               = errors.New("no product mapping found for this product")
+	ErrWarehouseMappingsUnavailable = errors.New("warehouse mappings are not configured")
 )
 
 // InventorySyncService handles inventory synchronization
 type InventorySyncService struct {
 	connectionRepo     *repository.ConnectionRepository
 	productMappingRepo *repository.ProductMappingRepository
+	inventoryLogRepo   *repository.InventorySyncLogRepository
+	warehouseRepo      *repository.WarehouseMappingRepository
+	reservedRepo       *repository.ReservedStockRepository
 	encryptor          *utils.Encryptor
 	publisher          *events.Publisher
+	debouncer          *StockDebouncer
 	logger             *zap.Logger
 
 	shopeePartnerID  string
@@ -46,12 +52,21 @@ type InventorySyncServiceConfig struct {
 	TikTokAppKey     string
 	TikTokAppSecret  string
 	EncryptionKey    string
+
+	// Stock events are coalesced per mapping for this long before being
+	// pushed in one batch per connection. Zero pushes every event immediately,
+	// still in order per connection.
+	StockDebounceWindow time.Duration
+	StockBatchSize      int
 }
 
 // NewInventorySyncService creates a new InventorySyncService
 func NewInventorySyncService(
 	connectionRepo *repository.ConnectionRepository,
 	productMappingRepo *repository.ProductMappingRepository,
+	inventoryLogRepo *repository.InventorySyncLogRepository,
+	warehouseRepo *repository.WarehouseMappingRepository,
+	reservedRepo *repository.ReservedStockRepository,
 	publisher *events.Publisher,
 	cfg *InventorySyncServiceConfig,
 	logger *zap.Logger,
@@ -65,9 +80,12 @@ func NewInventorySyncService(
 		}
 	}
 
-	return &InventorySyncService{
+	s := &InventorySyncService{
 		connectionRepo:     connectionRepo,
 		productMappingRepo: productMappingRepo,
+		inventoryLogRepo:   inventoryLogRepo,
+		warehouseRepo:      warehouseRepo,
+		reservedRepo:       reservedRepo,
 		encryptor:          encryptor,
 		publisher:          publisher,
 		logger:             logger,
@@ -76,13 +94,42 @@ func NewInventorySyncService(
 		shopeeSandbox:      cfg.ShopeeSandbox,
 		tiktokAppKey:       cfg.TikTokAppKey,
 		tiktokAppSecret:    cfg.TikTokAppSecret,
-	}, nil
+	}
+
+	s.debouncer = NewStockDebouncer(cfg.StockDebounceWindow, cfg.StockBatchSize, s.flushStockUpdates, logger)
+
+	return s, nil
+}
+
+// Stop pushes any stock changes still waiting in the debounce window
+func (s *InventorySyncService) Stop() {
+	s.debouncer.Flush()
 }
 
 // HandleStockChanged implements events.EventHandler
 func (s *InventorySyncService) HandleStockChanged(event *events.StockChangedEvent) error {
 	ctx := context.Background()
 
+	// Record per-warehouse stock so connections can aggregate it by location
+	if event.WarehouseID != "" && s.warehouseRepo != nil {
+		changedAt := event.Timestamp
+		if changedAt.IsZero() {
+			changedAt = time.Now()
+		}
+		if err := s.warehouseRepo.UpsertStockLevel(ctx, &models.WarehouseStockLevel{
+			InternalProductID: event.ProductID,
+			WarehouseID:       event.WarehouseID,
+			Quantity:          event.NewQuantity,
+			ChangedAt:         changedAt,
+		}); err != nil {
+			s.logger.Error("Failed to record warehouse stock level",
+				zap.String("product_id", event.ProductID.String()),
+				zap.String("warehouse_id", event.WarehouseID),
+				zap.Error(err),
+			)
+		}
+	}
+
 	// Find all product mappings for this product
 	mappings, err := s.productMappingRepo.GetByInternalProductID(ctx, event.ProductID)
 	if err != nil {
@@ -96,12 +143,220 @@ func (s *InventorySyncService) HandleStockChanged(event *events.StockChangedEven
 
 	// Update each marketplace
 	for _, mapping := range mappings {
-		go s.syncInventoryForMapping(ctx, &mapping, event.NewQuantity)
+		externalVariantID := s.eventVariantID(ctx, &mapping, event)
+		targets, err := s.eventStockTargets(ctx, mapping.ConnectionID, event)
+		if err != nil {
+			s.logger.Error("Failed to resolve stock locations",
+				zap.String("connection_id", mapping.ConnectionID.String()),
+				zap.String("product_id", event.ProductID.String()),
+				zap.Error(err),
+			)
+			continue
+		}
+		for _, target := range targets {
+			s.QueueStockChange(mapping, externalVariantID, target.LocationID, target.Quantity, event.Reason, event.Timestamp)
+		}
 	}
 
 	return nil
 }
 
+// eventVariantID returns the marketplace model or SKU ID a stock event's
+// variant is mapped to. Product events and unmapped variants return an empty
+// ID, which pushes to the listing itself.
+func (s *InventorySyncService) eventVariantID(ctx context.Context, mapping *models.ProductMapping, event *events.StockChangedEvent) string {
+	if event.VariantID == nil {
+		return ""
+	}
+	variant, err := s.productMappingRepo.GetVariantMappingByInternalVariant(ctx, mapping.ID, *event.VariantID)
+	if err != nil {
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			s.logger.Warn("Failed to get variant mapping",
+				zap.String("mapping_id", mapping.ID.String()),
+				zap.String("variant_id", event.VariantID.String()),
+				zap.Error(err),
+			)
+		}
+		return ""
+	}
+	return variant.ExternalVariantID
+}
+
+// LocationStock is the stock to offer in one marketplace location
+type LocationStock struct {
+	LocationID string `json:"location_id"` // Empty for the platform default location
+	Quantity   int    `json:"quantity"`
+}
+
+// LocationStockTargets aggregates the known per-warehouse stock of a product
+// into the marketplace locations of a connection. The boolean reports whether
+// the connection has warehouse mappings at all; when it doesn't, callers should
+// treat the product total as the stock of the default location.
+func (s *InventorySyncService) LocationStockTargets(ctx context.Context, connectionID, productID uuid.UUID) ([]LocationStock, bool, error) {
+	if s.warehouseRepo == nil {
+		return nil, false, nil
+	}
+
+	warehouseMappings, err := s.warehouseRepo.GetByConnectionID(ctx, connectionID)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to get warehouse mappings: %w", err)
+	}
+	if len(warehouseMappings) == 0 {
+		return nil, false, nil
+	}
+
+	levels, err := s.warehouseRepo.GetStockLevels(ctx, productID)
+	if err != nil {
+		return nil, true, fmt.Errorf("failed to get warehouse stock levels: %w", err)
+	}
+	stockByWarehouse := make(map[string]int, len(levels))
+	for _, level := range levels {
+		stockByWarehouse[level.WarehouseID] = level.Quantity
+	}
+
+	// Sum warehouses that share a location, keeping the mapping order
+	order := make([]string, 0)
+	totals := make(map[string]int)
+	for _, wm := range warehouseMappings {
+		if _, ok := totals[wm.ExternalLocationID]; !ok {
+			order = append(order, wm.ExternalLocationID)
+		}
+		totals[wm.ExternalLocationID] += stockByWarehouse[wm.InternalWarehouseID]
+	}
+
+	targets := make([]LocationStock, len(order))
+	for i, locationID := range order {
+		targets[i] = LocationStock{LocationID: locationID, Quantity: totals[locationID]}
+	}
+	return targets, true, nil
+}
+
+// eventStockTargets works out which locations of a connection a stock event
+// affects and how much stock each of them should now show
+func (s *InventorySyncService) eventStockTargets(ctx context.Context, connectionID uuid.UUID, event *events.StockChangedEvent) ([]LocationStock, error) {
+	targets, perLocation, err := s.LocationStockTargets(ctx, connectionID, event.ProductID)
+	if err != nil {
+		return nil, err
+	}
+
+	if !perLocation {
+		// Everything sums into the listing's single stock
+		if event.WarehouseID == "" || s.warehouseRepo == nil {
+			return []LocationStock{{Quantity: event.NewQuantity}}, nil
+		}
+		levels, err := s.warehouseRepo.GetStockLevels(ctx, event.ProductID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get warehouse stock levels: %w", err)
+		}
+		total := 0
+		for _, level := range levels {
+			total += level.Quantity
+		}
+		return []LocationStock{{Quantity: total}}, nil
+	}
+
+	if event.WarehouseID == "" {
+		// The product total cannot be split across locations; the listing
+		// stays as it is until an event names the warehouse that changed
+		s.logger.Warn("Stock event without warehouse not pushed to a connection with warehouse mappings",
+			zap.String("connection_id", connectionID.String()),
+			zap.String("product_id", event.ProductID.String()),
+			zap.Int("new_quantity", event.NewQuantity),
+			zap.String("reason", event.Reason),
+		)
+		return nil, nil
+	}
+
+	// Only the location the changed warehouse feeds needs updating
+	warehouseMappings, err := s.warehouseRepo.GetByConnectionID(ctx, connectionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get warehouse mappings: %w", err)
+	}
+	for _, wm := range warehouseMappings {
+		if wm.InternalWarehouseID != event.WarehouseID {
+			continue
+		}
+		for _, target := range targets {
+			if target.LocationID == wm.ExternalLocationID {
+				return []LocationStock{target}, nil
+			}
+		}
+	}
+
+	// Warehouse is not offered on this connection
+	return nil, nil
+}
+
+// QueueStockChange schedules a stock push for a mapping model and location.
+// With debouncing enabled the change is coalesced with others for the same
+// mapping model and location and sent in a batch; otherwise it is pushed
+// straight away. Either way pushes to a connection run in order, and older
+// events are dropped. An empty externalVariantID targets the listing itself.
+func (s *InventorySyncService) QueueStockChange(mapping models.ProductMapping, externalVariantID, locationID string, quantity int, reason string, timestamp time.Time) {
+	s.debouncer.Add(mapping, externalVariantID, locationID, quantity, reason, timestamp)
+}
+
+// flushStockUpdates pushes a coalesced batch of stock changes to one connection
+func (s *InventorySyncService) flushStockUpdates(ctx context.Context, connectionID uuid.UUID, updates []PendingStockUpdate) {
+	conn, err := s.connectionRepo.GetByID(ctx, connectionID)
+	if err != nil {
+		s.logger.Error("Failed to get connection", zap.Error(err))
+		return
+	}
+
+	if !conn.IsActive {
+		s.logger.Debug("Connection is inactive, skipping", zap.String("connection_id", conn.ID.String()))
+		return
+	}
+
+	pushes := make([]StockPush, len(updates))
+	for i := range updates {
+		reference := updates[i].Reason
+		if updates[i].Events > 1 {
+			reference = fmt.Sprintf("%s (coalesced %d events)", reference, updates[i].Events)
+		}
+		pushes[i] = StockPush{
+			Mapping:           &updates[i].Mapping,
+			ExternalProductID: updates[i].Mapping.ExternalProductID,
+			ExternalVariantID: updates[i].ExternalVariantID,
+			ExternalSKU:       updates[i].Mapping.ExternalSKU,
+			LocationID:        updates[i].LocationID,
+			Quantity:          s.channelQuantity(ctx, conn, updates[i].Mapping.ExternalProductID, updates[i].ExternalVariantID, updates[i].LocationID, updates[i].Quantity),
+			SourceReference:   reference,
+		}
+	}
+
+	results, err := s.PushStock(ctx, conn, pushes, models.InventorySyncSourceEvent)
+	if err != nil {
+		s.logger.Error("Failed to push stock batch",
+			zap.String("connection_id", conn.ID.String()),
+			zap.Int("count", len(pushes)),
+			zap.Error(err),
+		)
+		for i := range updates {
+			s.publishSyncFailed(conn, &updates[i].Mapping, err.Error())
+		}
+		return
+	}
+
+	successCount := 0
+	for i, result := range results {
+		if result.Success {
+			successCount++
+			s.publishSyncCompleted(conn, &updates[i].Mapping)
+		} else {
+			s.publishSyncFailed(conn, &updates[i].Mapping, result.Error)
+		}
+	}
+
+	s.logger.Info("Stock batch pushed",
+		zap.String("platform", conn.Platform),
+		zap.String("connection_id", conn.ID.String()),
+		zap.Int("success_count", successCount),
+		zap.Int("total_count", len(results)),
+	)
+}
+
 // HandleProductUpdated implements events.EventHandler
 // Note: For full product sync support, use MarketplaceSyncHandler instead
 func (s *InventorySyncService) HandleProductUpdated(event *events.ProductUpdatedEvent) error {
@@ -122,86 +377,326 @@ func (s *InventorySyncService) HandleProductDeleted(event *events.ProductDeleted
 	return nil
 }
 
-// syncInventoryForMapping syncs inventory to a single marketplace
-func (s *InventorySyncService) syncInventoryForMapping(ctx context.Context, mapping *models.ProductMapping, quantity int) {
-	// Get connection
-	conn, err := s.connectionRepo.GetByID(ctx, mapping.ConnectionID)
+// channelQuantity turns a catalog quantity into the stock to offer on a
+// connection: the allocation rules are applied first, then stock the
+// marketplace already holds for promotions is taken out of the default
+// location. A push to one model only loses that model's reservation; pushes
+// to the listing lose the reservations of the whole listing.
+func (s *InventorySyncService) channelQuantity(ctx context.Context, conn *models.Connection, externalProductID, externalVariantID, locationID string, quantity int) int {
+	quantity = conn.GetSettings().Inventory.AllocateStock(quantity)
+	if locationID != "" || s.reservedRepo == nil {
+		return quantity
+	}
+
+	var reserved int
+	var err error
+	if externalVariantID != "" {
+		var model *models.ReservedStock
+		model, err = s.reservedRepo.GetByListing(ctx, conn.ID, externalProductID, externalVariantID)
+		if err == nil {
+			reserved = model.ReservedQuantity
+		} else if errors.Is(err, gorm.ErrRecordNotFound) {
+			err = nil
+		}
+	} else {
+		reserved, err = s.reservedRepo.GetTotalForProduct(ctx, conn.ID, externalProductID)
+	}
 	if err != nil {
-		s.logger.Error("Failed to get connection", zap.Error(err))
-		return
+		s.logger.Warn("Failed to get reserved stock",
+			zap.String("connection_id", conn.ID.String()),
+			zap.String("external_product_id", externalProductID),
+			zap.String("external_variant_id", externalVariantID),
+			zap.Error(err),
+		)
+		return quantity
 	}
+	if reserved >= quantity {
+		return 0
+	}
+	return quantity - reserved
+}
 
-	if !conn.IsActive {
-		s.logger.Debug("Connection is inactive, skipping", zap.String("connection_id", conn.ID.String()))
-		return
+// HandleShopeeReservedStockChange stores stock Shopee locked or released for a
+// promotion and tells the inventory service about it
+func (s *InventorySyncService) HandleShopeeReservedStockChange(shopID int64, data *shopee.ReservedStockChangeData) error {
+	ctx := context.Background()
+
+	conn, err := s.connectionRepo.GetByPlatformAndShopID(ctx, "shopee", fmt.Sprintf("%d", shopID))
+	if err != nil {
+		s.logger.Error("Connection not found for shop", zap.Int64("shop_id", shopID))
+		return ErrConnectionNotFound
+	}
+
+	previous, current, ok := data.ReservedStock()
+	if !ok {
+		s.logger.Debug("Reserved stock push without reserved_stock change, skipping",
+			zap.Int64("shop_id", shopID),
+			zap.Int64("item_id", data.ItemID),
+		)
+		return nil
+	}
+
+	changedAt := time.Now()
+	if data.UpdateTime > 0 {
+		changedAt = time.Unix(data.UpdateTime, 0)
+	}
+
+	reserved := &models.ReservedStock{
+		ConnectionID:      conn.ID,
+		ExternalProductID: strconv.FormatInt(data.ItemID, 10),
+		ReservedQuantity:  current,
+		Action:            data.Action,
+		PromotionType:     data.PromotionType,
+		ChangedAt:         changedAt,
+	}
+	if data.ModelID > 0 {
+		reserved.ExternalModelID = strconv.FormatInt(data.ModelID, 10)
+	}
+	if data.PromotionID > 0 {
+		reserved.PromotionID = strconv.FormatInt(data.PromotionID, 10)
+	}
+
+	return s.RecordReservedStock(ctx, conn, reserved, previous)
+}
+
+// RecordReservedStock saves the reserved quantity of a listing and publishes a
+// marketplace.stock.reserved event
+func (s *InventorySyncService) RecordReservedStock(ctx context.Context, conn *models.Connection, reserved *models.ReservedStock, previous int) error {
+	if s.reservedRepo == nil {
+		return errors.New("reserved stock repository not configured")
+	}
+	if err := s.reservedRepo.Upsert(ctx, reserved); err != nil {
+		return fmt.Errorf("failed to save reserved stock: %w", err)
+	}
+
+	s.logger.Info("Reserved stock changed",
+		zap.String("platform", conn.Platform),
+		zap.String("connection_id", conn.ID.String()),
+		zap.String("external_product_id", reserved.ExternalProductID),
+		zap.String("external_model_id", reserved.ExternalModelID),
+		zap.Int("previous", previous),
+		zap.Int("reserved", reserved.ReservedQuantity),
+		zap.String("promotion_type", reserved.PromotionType),
+	)
+
+	if s.publisher == nil {
+		return nil
+	}
+	event := &events.StockReservedEvent{
+		ConnectionID:      conn.ID,
+		Platform:          conn.Platform,
+		ExternalProductID: reserved.ExternalProductID,
+		ExternalModelID:   reserved.ExternalModelID,
+		PreviousReserved:  previous,
+		ReservedQuantity:  reserved.ReservedQuantity,
+		Action:            reserved.Action,
+		PromotionType:     reserved.PromotionType,
+		PromotionID:       reserved.PromotionID,
+		Timestamp:         reserved.ChangedAt,
+	}
+	if mapping, err := s.productMappingRepo.GetByConnectionAndExternalProduct(ctx, conn.ID, reserved.ExternalProductID); err == nil {
+		event.ProductID = &mapping.InternalProductID
+	}
+	if err := s.publisher.PublishStockReserved(event); err != nil {
+		s.logger.Warn("Failed to publish stock reserved event", zap.Error(err))
+	}
+
+	return nil
+}
+
+// GetReservedStock lists listings with stock reserved by marketplace promotions
+func (s *InventorySyncService) GetReservedStock(ctx context.Context, connectionID uuid.UUID, page, pageSize int) ([]models.ReservedStock, int64, error) {
+	if s.reservedRepo == nil {
+		return []models.ReservedStock{}, 0, nil
+	}
+	if _, err := s.connectionRepo.GetByID(ctx, connectionID); err != nil {
+		return nil, 0, ErrConnectionNotFound
+	}
+	return s.reservedRepo.GetByConnectionID(ctx, connectionID, page, pageSize)
+}
+
+// StockPush describes a single stock update destined for a marketplace listing
+type StockPush struct {
+	Mapping           *models.ProductMapping // nil when pushing to an unmapped listing
+	ExternalProductID string
+	ExternalVariantID string // Marketplace model or SKU ID, empty for the listing itself
+	ExternalSKU       string
+	LocationID        string // Marketplace warehouse/location, empty for the default
+	Quantity          int
+	SourceReference   string
+}
+
+// PushStock pushes a set of stock updates to a connection and records an
+// inventory sync log entry for every update, whatever the outcome.
+func (s *InventorySyncService) PushStock(ctx context.Context, conn *models.Connection, pushes []StockPush, source string) ([]providers.InventoryUpdateResult, error) {
+	updates := make([]providers.InventoryUpdate, len(pushes))
+	for i, push := range pushes {
+		updates[i] = providers.InventoryUpdate{
+			ExternalProductID: push.ExternalProductID,
+			ExternalVariantID: push.ExternalVariantID,
+			ExternalSKU:       push.ExternalSKU,
+			LocationID:        push.LocationID,
+			Quantity:          push.Quantity,
+		}
+	}
+
+	// Capture what we last told the marketplace before overwriting it
+	previous := make([]*int, len(pushes))
+	for i, push := range pushes {
+		previous[i] = s.lastPushedQuantity(ctx, conn.ID, push.ExternalProductID, push.ExternalSKU, push.LocationID)
+	}
+
+	results, err := s.updateBatchStock(ctx, conn, updates)
+	if err != nil {
+		results = make([]providers.InventoryUpdateResult, len(pushes))
+		for i, push := range pushes {
+			results[i] = providers.InventoryUpdateResult{
+				ExternalProductID: push.ExternalProductID,
+				ExternalVariantID: push.ExternalVariantID,
+				ExternalSKU:       push.ExternalSKU,
+				LocationID:        push.LocationID,
+				Error:             err.Error(),
+			}
+		}
+	}
+
+	s.recordStockPushes(ctx, conn, pushes, previous, results, source)
+
+	if err != nil {
+		return nil, err
 	}
+	return results, nil
+}
 
-	// Decrypt token
+// updateBatchStock routes a batch of stock updates to the connection's platform
+func (s *InventorySyncService) updateBatchStock(ctx context.Context, conn *models.Connection, updates []providers.InventoryUpdate) ([]providers.InventoryUpdateResult, error) {
 	accessToken := conn.AccessToken
 	if s.encryptor != nil {
+		var err error
 		accessToken, err = s.encryptor.Decrypt(conn.AccessToken)
 		if err != nil {
-			s.logger.Error("Failed to decrypt token", zap.Error(err))
-			s.publishSyncFailed(conn, mapping, "failed to decrypt token")
-			return
+			return nil, fmt.Errorf("failed to decrypt token: %w", err)
 		}
 	}
 
-	// Update marketplace
 	switch conn.Platform {
 	case "shopee":
-		err = s.updateShopeeInventory(ctx, conn, mapping, accessToken, quantity)
+		shopID, _ := strconv.ParseInt(conn.ShopID, 10, 64)
+		client, _ := shopee.NewClient(&shopee.ClientConfig{
+			PartnerID:  s.shopeePartnerID,
+			PartnerKey: s.shopeePartnerKey,
+			IsSandbox:  s.shopeeSandbox,
+			Logger:     s.logger,
+		})
+		client.SetTokens(accessToken, shopID)
+		provider := shopee.NewInventoryProvider(client)
+		return provider.UpdateBatchStock(ctx, updates)
+
 	case "tiktok":
-		err = s.updateTikTokInventory(ctx, conn, mapping, accessToken, quantity)
+		client := tiktok.NewClient(&tiktok.ClientConfig{
+			AppKey:    s.tiktokAppKey,
+			AppSecret: s.tiktokAppSecret,
+			Logger:    s.logger,
+		})
+		client.SetTokens(accessToken, conn.ShopID)
+		provider := tiktok.NewInventoryProvider(client)
+		return provider.UpdateBatchStock(ctx, updates)
+
 	default:
-		s.logger.Error("Unknown platform", zap.String("platform", conn.Platform))
-		return
+		return nil, ErrInvalidPlatform
 	}
+}
 
-	if err != nil {
-		s.logger.Error("Failed to sync inventory",
-			zap.String("platform", conn.Platform),
-			zap.String("product_id", mapping.InternalProductID.String()),
-			zap.Error(err),
-		)
-		s.publishSyncFailed(conn, mapping, err.Error())
-		return
+// GetMarketplaceInventory reads current stock levels for listings on a connection
+func (s *InventorySyncService) GetMarketplaceInventory(ctx context.Context, conn *models.Connection, externalProductIDs []string) ([]providers.InventoryItem, error) {
+	accessToken := conn.AccessToken
+	if s.encryptor != nil {
+		var err error
+		accessToken, err = s.encryptor.Decrypt(conn.AccessToken)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decrypt token: %w", err)
+		}
 	}
 
-	s.logger.Info("Inventory synced successfully",
-		zap.String("platform", conn.Platform),
-		zap.String("external_product_id", mapping.ExternalProductID),
-		zap.Int("quantity", quantity),
-	)
-
-	s.publishSyncCompleted(conn, mapping)
-}
+	switch conn.Platform {
+	case "shopee":
+		shopID, _ := strconv.ParseInt(conn.ShopID, 10, 64)
+		client, _ := shopee.NewClient(&shopee.ClientConfig{
+			PartnerID:  s.shopeePartnerID,
+			PartnerKey: s.shopeePartnerKey,
+			IsSandbox:  s.shopeeSandbox,
+			Logger:     s.logger,
+		})
+		client.SetTokens(accessToken, shopID)
+		return shopee.NewProductProvider(client).GetInventory(ctx, externalProductIDs)
 
-func (s *InventorySyncService) updateShopeeInventory(ctx context.Context, conn *models.Connection, mapping *models.ProductMapping, accessToken string, quantity int) error {
-	shopID, _ := strconv.ParseInt(conn.ShopID, 10, 64)
+	case "tiktok":
+		client := tiktok.NewClient(&tiktok.ClientConfig{
+			AppKey:    s.tiktokAppKey,
+			AppSecret: s.tiktokAppSecret,
+			Logger:    s.logger,
+		})
+		client.SetTokens(accessToken, conn.ShopID)
+		return tiktok.NewProductProvider(client).GetInventory(ctx, externalProductIDs)
 
-	client, _ := shopee.NewClient(&shopee.ClientConfig{
-		PartnerID:  s.shopeePartnerID,
-		PartnerKey: s.shopeePartnerKey,
-		IsSandbox:  s.shopeeSandbox,
-		Logger:     s.logger,
-	})
-	client.SetTokens(accessToken, shopID)
+	default:
+		return nil, ErrInvalidPlatform
+	}
+}
 
-	provider := shopee.NewInventoryProvider(client)
-	return provider.UpdateStock(ctx, mapping.ExternalProductID, quantity)
+// lastPushedQuantity returns the quantity from the last successful push to a listing
+func (s *InventorySyncService) lastPushedQuantity(ctx context.Context, connectionID uuid.UUID, externalProductID, externalSKU, locationID string) *int {
+	if s.inventoryLogRepo == nil {
+		return nil
+	}
+	last, err := s.inventoryLogRepo.GetLatestSuccessful(ctx, connectionID, externalProductID, externalSKU, locationID)
+	if err != nil {
+		return nil
+	}
+	quantity := last.NewQuantity
+	return &quantity
 }
 
-func (s *InventorySyncService) updateTikTokInventory(ctx context.Context, conn *models.Connection, mapping *models.ProductMapping, accessToken string, quantity int) error {
-	client := tiktok.NewClient(&tiktok.ClientConfig{
-		AppKey:    s.tiktokAppKey,
-		AppSecret: s.tiktokAppSecret,
-		Logger:    s.logger,
-	})
-	client.SetTokens(accessToken, conn.ShopID)
+// recordStockPushes writes one inventory sync log entry per pushed update
+func (s *InventorySyncService) recordStockPushes(ctx context.Context, conn *models.Connection, pushes []StockPush, previous []*int, results []providers.InventoryUpdateResult, source string) {
+	if s.inventoryLogRepo == nil {
+		return
+	}
+
+	logs := make([]models.InventorySyncLog, len(pushes))
+	for i, push := range pushes {
+		entry := models.InventorySyncLog{
+			ConnectionID:       conn.ID,
+			ExternalProductID:  push.ExternalProductID,
+			ExternalSKU:        push.ExternalSKU,
+			ExternalLocationID: push.LocationID,
+			PreviousQuantity:   previous[i],
+			NewQuantity:        push.Quantity,
+			Source:             source,
+			SourceReference:    push.SourceReference,
+			SyncStatus:         models.InventorySyncStatusFailed,
+		}
+		if push.Mapping != nil {
+			entry.ProductMappingID = &push.Mapping.ID
+			entry.InternalProductID = &push.Mapping.InternalProductID
+		}
+		if i < len(results) {
+			if results[i].Success {
+				entry.SyncStatus = models.InventorySyncStatusSuccess
+			} else {
+				entry.ErrorMessage = results[i].Error
+			}
+		}
+		logs[i] = entry
+	}
 
-	provider := tiktok.NewInventoryProvider(client)
-	return provider.UpdateStock(ctx, mapping.ExternalProductID, mapping.ExternalSKU, quantity)
+	if err := s.inventoryLogRepo.CreateBatch(ctx, logs); err != nil {
+		s.logger.Error("Failed to record inventory sync logs",
+			zap.String("connection_id", conn.ID.String()),
+			zap.Int("count", len(logs)),
+			zap.Error(err),
+		)
+	}
 }
 
 func (s *InventorySyncService) publishSyncCompleted(conn *models.Connection, mapping *models.ProductMapping) {
@@ -238,6 +733,88 @@ func (s *InventorySyncService) PushInventory(ctx context.Context, connectionID u
 		return nil, ErrConnectionNotFound
 	}
 
+	pushes := make([]StockPush, len(updates))
+	for i, update := range updates {
+		pushes[i] = StockPush{
+			ExternalProductID: update.ExternalProductID,
+			ExternalVariantID: update.ExternalVariantID,
+			ExternalSKU:       update.ExternalSKU,
+			LocationID:        update.LocationID,
+			Quantity:          update.Quantity,
+		}
+		// Link the log entry to the mapping when the listing is mapped
+		if mapping, err := s.productMappingRepo.GetByConnectionAndExternalProduct(ctx, connectionID, update.ExternalProductID); err == nil {
+			pushes[i].Mapping = mapping
+		}
+	}
+
+	return s.PushStock(ctx, conn, pushes, models.InventorySyncSourceManual)
+}
+
+// GetInventoryLogs retrieves the stock push audit log for a connection
+func (s *InventorySyncService) GetInventoryLogs(ctx context.Context, connectionID uuid.UUID, filter *models.InventorySyncLogFilter) ([]models.InventorySyncLog, int64, error) {
+	if _, err := s.connectionRepo.GetByID(ctx, connectionID); err != nil {
+		return nil, 0, ErrConnectionNotFound
+	}
+	return s.inventoryLogRepo.GetByConnectionID(ctx, connectionID, filter)
+}
+
+// GetWarehouseMappings lists the warehouse to location mappings of a connection
+func (s *InventorySyncService) GetWarehouseMappings(ctx context.Context, connectionID uuid.UUID) ([]models.WarehouseMapping, error) {
+	if s.warehouseRepo == nil {
+		return nil, ErrWarehouseMappingsUnavailable
+	}
+	if _, err := s.connectionRepo.GetByID(ctx, connectionID); err != nil {
+		return nil, ErrConnectionNotFound
+	}
+	return s.warehouseRepo.GetByConnectionID(ctx, connectionID)
+}
+
+// SetWarehouseMapping maps an internal warehouse to a marketplace location
+func (s *InventorySyncService) SetWarehouseMapping(ctx context.Context, connectionID uuid.UUID, req *models.SetWarehouseMappingRequest) (*models.WarehouseMapping, error) {
+	if s.warehouseRepo == nil {
+		return nil, ErrWarehouseMappingsUnavailable
+	}
+	if _, err := s.connectionRepo.GetByID(ctx, connectionID); err != nil {
+		return nil, ErrConnectionNotFound
+	}
+
+	mapping := &models.WarehouseMapping{
+		ConnectionID:        connectionID,
+		InternalWarehouseID: req.InternalWarehouseID,
+		ExternalLocationID:  req.ExternalLocationID,
+	}
+	if err := s.warehouseRepo.Upsert(ctx, mapping); err != nil {
+		return nil, fmt.Errorf("failed to save warehouse mapping: %w", err)
+	}
+
+	s.logger.Info("Warehouse mapped",
+		zap.String("connection_id", connectionID.String()),
+		zap.String("internal_warehouse_id", req.InternalWarehouseID),
+		zap.String("external_location_id", req.ExternalLocationID),
+	)
+
+	return mapping, nil
+}
+
+// DeleteWarehouseMapping stops offering an internal warehouse on a connection
+func (s *InventorySyncService) DeleteWarehouseMapping(ctx context.Context, connectionID uuid.UUID, internalWarehouseID string) error {
+	if s.warehouseRepo == nil {
+		return ErrWarehouseMappingsUnavailable
+	}
+	if _, err := s.connectionRepo.GetByID(ctx, connectionID); err != nil {
+		return ErrConnectionNotFound
+	}
+	return s.warehouseRepo.Delete(ctx, connectionID, internalWarehouseID)
+}
+
+// GetMarketplaceWarehouses lists the warehouses/locations configured on the marketplace shop
+func (s *InventorySyncService) GetMarketplaceWarehouses(ctx context.Context, connectionID uuid.UUID) ([]providers.Warehouse, error) {
+	conn, err := s.connectionRepo.GetByID(ctx, connectionID)
+	if err != nil {
+		return nil, ErrConnectionNotFound
+	}
+
 	accessToken := conn.AccessToken
 	if s.encryptor != nil {
 		accessToken, err = s.encryptor.Decrypt(conn.AccessToken)
@@ -257,7 +834,7 @@ func (s *InventorySyncService) PushInventory(ctx context.Context, connectionID u
 		})
 		client.SetTokens(accessToken, shopID)
 		provider := shopee.NewInventoryProvider(client)
-		return provider.UpdateBatchStock(ctx, updates)
+		return provider.GetWarehouses(ctx)
 
 	case "tiktok":
 		client := tiktok.NewClient(&tiktok.ClientConfig{
@@ -267,7 +844,7 @@ func (s *InventorySyncService) PushInventory(ctx context.Context, connectionID u
 		})
 		client.SetTokens(accessToken, conn.ShopID)
 		provider := tiktok.NewInventoryProvider(client)
-		return provider.UpdateBatchStock(ctx, updates)
+		return provider.GetWarehouses(ctx)
 
 	default:
 		return nil, ErrInvalidPlatform
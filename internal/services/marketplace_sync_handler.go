@@ -25,6 +25,7 @@ type MarketplaceSyncHandler struct {
 	productMappingRepo  *repository.ProductMappingRepository
 	categoryMappingRepo *repository.CategoryMappingRepository
 	catalogClient       *clients.CatalogClient
+	inventoryService    *InventorySyncService
 	eventPublisher      *events.Publisher
 	encryptor           *utils.Encryptor
 	logger              *zap.Logger
@@ -53,6 +54,7 @@ func NewMarketplaceSyncHandler(
 	productMappingRepo *repository.ProductMappingRepository,
 	categoryMappingRepo *repository.CategoryMappingRepository,
 	catalogClient *clients.CatalogClient,
+	inventoryService *InventorySyncService,
 	eventPublisher *events.Publisher,
 	cfg *MarketplaceSyncHandlerConfig,
 	logger *zap.Logger,
@@ -71,6 +73,7 @@ func NewMarketplaceSyncHandler(
 		productMappingRepo:  productMappingRepo,
 		categoryMappingRepo: categoryMappingRepo,
 		catalogClient:       catalogClient,
+		inventoryService:    inventoryService,
 		eventPublisher:      eventPublisher,
 		encryptor:           encryptor,
 		logger:              logger,
@@ -88,32 +91,13 @@ func (h *MarketplaceSyncHandler) HandleStockChanged(event *events.StockChangedEv
 		return nil
 	}
 
-	ctx := context.Background()
-
-	// Find all product mappings for this product
-	mappings, err := h.productMappingRepo.GetByInternalProductID(ctx, event.ProductID)
-	if err != nil {
-		return fmt.Errorf("failed to get product mappings: %w", err)
+	if h.inventoryService == nil {
+		return fmt.Errorf("inventory sync service not configured")
 	}
 
-	if len(mappings) == 0 {
-		h.logger.Debug("No marketplace mappings found for product", zap.String("product_id", event.ProductID.String()))
-		return nil
-	}
-
-	// Sync inventory to each connected marketplace
-	for _, mapping := range mappings {
-		if err := h.syncInventoryToMarketplace(ctx, &mapping, event.NewQuantity); err != nil {
-			h.logger.Error("Failed to sync inventory to marketplace",
-				zap.String("connection_id", mapping.ConnectionID.String()),
-				zap.String("product_id", event.ProductID.String()),
-				zap.Error(err),
-			)
-			continue
-		}
-	}
-
-	return nil
+	// The inventory sync service resolves warehouse locations per connection
+	// and coalesces bursts into batched pushes
+	return h.inventoryService.HandleStockChanged(event)
 }
 
 // HandleProductUpdated handles product update events from catalog service
@@ -302,66 +286,6 @@ func (h *MarketplaceSyncHandler) updateProductOnShopee(ctx context.Context, conn
 	return nil
 }
 
-// syncInventoryToMarketplace syncs inventory to a specific marketplace
-func (h *MarketplaceSyncHandler) syncInventoryToMarketplace(ctx context.Context, mapping *models.ProductMapping, quantity int) error {
-	conn, err := h.connectionRepo.GetByID(ctx, mapping.ConnectionID)
-	if err != nil || !conn.IsActive {
-		return fmt.Errorf("connection not found or inactive")
-	}
-
-	switch conn.Platform {
-	case "shopee":
-		return h.updateInventoryOnShopee(ctx, conn, mapping, quantity)
-	default:
-		return fmt.Errorf("unsupported platform: %s", conn.Platform)
-	}
-}
-
-// updateInventoryOnShopee updates inventory on Shopee
-func (h *MarketplaceSyncHandler) updateInventoryOnShopee(ctx context.Context, conn *models.Connection, mapping *models.ProductMapping, quantity int) error {
-	accessToken := conn.AccessToken
-	if h.encryptor != nil {
-		var err error
-		accessToken, err = h.encryptor.Decrypt(conn.AccessToken)
-		if err != nil {
-			return fmt.Errorf("failed to decrypt token: %w", err)
-		}
-	}
-
-	client, err := shopee.NewClient(&shopee.ClientConfig{
-		PartnerID:  h.shopeePartnerID,
-		PartnerKey: h.shopeePartnerKey,
-		IsSandbox:  h.shopeeSandbox,
-		Logger:     h.logger,
-	})
-	if err != nil {
-		return err
-	}
-
-	shopID, _ := strconv.ParseInt(conn.ShopID, 10, 64)
-	client.SetTokens(accessToken, shopID)
-
-	productProvider := shopee.NewProductProvider(client)
-
-	updates := []providers.InventoryUpdate{
-		{
-			ExternalProductID: mapping.ExternalProductID,
-			Quantity:          quantity,
-		},
-	}
-
-	if err := productProvider.UpdateInventory(ctx, updates); err != nil {
-		return fmt.Errorf("failed to update inventory on Shopee: %w", err)
-	}
-
-	h.logger.Info("Successfully synced inventory to Shopee",
-		zap.String("external_product_id", mapping.ExternalProductID),
-		zap.Int("quantity", quantity),
-	)
-
-	return nil
-}
-
 // deleteProductFromMarketplace deletes a product from a specific marketplace
 func (h *MarketplaceSyncHandler) deleteProductFromMarketplace(ctx context.Context, mapping *models.ProductMapping) {
 	conn, err := h.connectionRepo.GetByID(ctx, mapping.ConnectionID)
@@ -0,0 +1,349 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"gorm.io/datatypes"
+
+	"github.com/niaga-platform/service-marketplace/internal/models"
+	"github.com/niaga-platform/service-marketplace/internal/providers"
+	"github.com/niaga-platform/service-marketplace/internal/repository"
+)
+
+// Order backfill errors
+var (
+	ErrBackfillJobNotFound = errors.New("backfill job not found")
+	ErrBackfillRunning     = errors.New("backfill job is already running")
+	ErrInvalidBackfill     = errors.New("invalid backfill request")
+)
+
+// defaultBackfillDays is the range backfilled when none is given
+const defaultBackfillDays = 90
+
+// backfillClaimTimeout is how long a processing backfill may go without storing
+// progress before another worker may take it over
+const backfillClaimTimeout = time.Hour
+
+// orderFetcher fetches a single marketplace order
+type orderFetcher interface {
+	GetOrder(ctx context.Context, externalOrderID string) (*providers.ExternalOrder, error)
+}
+
+// OrderBackfillConfig holds configuration for historical order backfills
+type OrderBackfillConfig struct {
+	Window time.Duration // Range imported between checkpoints, at most the platform list window
+}
+
+// OrderBackfillService imports historical orders of a connection. The range is
+// split into windows the platforms accept and progress is checkpointed on the
+// sync job after each window, so an interrupted backfill resumes where it stopped.
+// A job is claimed in the database before it runs, so the API and the backfill
+// command never process the same job at once.
+type OrderBackfillService struct {
+	connectionRepo *repository.ConnectionRepository
+	syncJobRepo    *repository.SyncJobRepository
+	orderService   *OrderSyncService
+	config         OrderBackfillConfig
+	logger         *zap.Logger
+}
+
+// NewOrderBackfillService creates a new OrderBackfillService
+func NewOrderBackfillService(
+	connectionRepo *repository.ConnectionRepository,
+	syncJobRepo *repository.SyncJobRepository,
+	orderService *OrderSyncService,
+	cfg OrderBackfillConfig,
+	logger *zap.Logger,
+) *OrderBackfillService {
+	// Set defaults
+	if cfg.Window == 0 {
+		cfg.Window = 24 * time.Hour
+	}
+	if cfg.Window > orderListWindow {
+		cfg.Window = orderListWindow
+	}
+
+	return &OrderBackfillService{
+		connectionRepo: connectionRepo,
+		syncJobRepo:    syncJobRepo,
+		orderService:   orderService,
+		config:         cfg,
+		logger:         logger,
+	}
+}
+
+// CreateBackfill records a pending backfill job for a connection without running it
+func (s *OrderBackfillService) CreateBackfill(ctx context.Context, connectionID uuid.UUID, req *models.OrderBackfillRequest) (*models.SyncJob, error) {
+	if _, err := s.connectionRepo.GetByID(ctx, connectionID); err != nil {
+		return nil, ErrConnectionNotFound
+	}
+
+	now := time.Now()
+	days := req.Days
+	if days == 0 {
+		days = defaultBackfillDays
+	}
+	if days < 0 {
+		return nil, fmt.Errorf("%w: days must be positive", ErrInvalidBackfill)
+	}
+
+	timeTo := now
+	if req.TimeTo != nil {
+		timeTo = *req.TimeTo
+	}
+	timeFrom := timeTo.AddDate(0, 0, -days)
+	if req.TimeFrom != nil {
+		timeFrom = *req.TimeFrom
+	}
+	if !timeFrom.Before(timeTo) {
+		return nil, fmt.Errorf("%w: time_from must be before time_to", ErrInvalidBackfill)
+	}
+	if timeTo.After(now) {
+		timeTo = now
+	}
+
+	payload, _ := json.Marshal(&models.OrderBackfillPayload{
+		TimeFrom:     timeFrom,
+		TimeTo:       timeTo,
+		SkipPush:     req.SkipPush,
+		WindowsTotal: len(orderWindows(timeFrom, timeTo, s.config.Window)),
+	})
+
+	job := &models.SyncJob{
+		ConnectionID: connectionID,
+		JobType:      models.JobTypeOrderBackfill,
+		Payload:      datatypes.JSON(payload),
+		Status:       models.JobStatusPending,
+		ScheduledAt:  now,
+	}
+	if err := s.syncJobRepo.Create(ctx, job); err != nil {
+		return nil, fmt.Errorf("failed to create backfill job: %w", err)
+	}
+	return job, nil
+}
+
+// StartBackfill creates a backfill job and processes it in the background
+func (s *OrderBackfillService) StartBackfill(ctx context.Context, connectionID uuid.UUID, req *models.OrderBackfillRequest) (*models.SyncJob, error) {
+	job, err := s.CreateBackfill(ctx, connectionID, req)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.claim(ctx, job.ID); err != nil {
+		return nil, err
+	}
+	go s.runJob(context.Background(), job)
+
+	return job, nil
+}
+
+// ResumeBackfill continues an unfinished backfill job from its checkpoint in the background
+func (s *OrderBackfillService) ResumeBackfill(ctx context.Context, connectionID, jobID uuid.UUID) (*models.SyncJob, error) {
+	job, err := s.getJob(ctx, jobID)
+	if err != nil {
+		return nil, err
+	}
+	if job.ConnectionID != connectionID {
+		return nil, ErrBackfillJobNotFound
+	}
+	if job.Status == models.JobStatusCompleted {
+		return nil, fmt.Errorf("%w: job already completed", ErrInvalidBackfill)
+	}
+	if err := s.claim(ctx, job.ID); err != nil {
+		return nil, err
+	}
+
+	go s.runJob(context.Background(), job)
+
+	return job, nil
+}
+
+// RunBackfill processes a backfill job in the foreground, resuming from its
+// checkpoint. It is used by the backfill command.
+func (s *OrderBackfillService) RunBackfill(ctx context.Context, jobID uuid.UUID) error {
+	job, err := s.getJob(ctx, jobID)
+	if err != nil {
+		return err
+	}
+	if job.Status == models.JobStatusCompleted {
+		return nil
+	}
+	if err := s.claim(ctx, job.ID); err != nil {
+		return err
+	}
+
+	return s.runJob(ctx, job)
+}
+
+// GetBackfills lists the backfill jobs of a connection
+func (s *OrderBackfillService) GetBackfills(ctx context.Context, connectionID uuid.UUID, page, pageSize int) ([]models.SyncJob, int64, error) {
+	return s.syncJobRepo.GetByConnectionID(ctx, connectionID, &models.SyncJobFilter{
+		JobType:  models.JobTypeOrderBackfill,
+		Page:     page,
+		PageSize: pageSize,
+	})
+}
+
+// getJob loads a backfill job by ID
+func (s *OrderBackfillService) getJob(ctx context.Context, jobID uuid.UUID) (*models.SyncJob, error) {
+	job, err := s.syncJobRepo.GetByID(ctx, jobID)
+	if err != nil || job.JobType != models.JobTypeOrderBackfill {
+		return nil, ErrBackfillJobNotFound
+	}
+	return job, nil
+}
+
+// claim marks a job as processing in the database, returning ErrBackfillRunning
+// if another worker is processing it
+func (s *OrderBackfillService) claim(ctx context.Context, jobID uuid.UUID) error {
+	claimed, err := s.syncJobRepo.ClaimProcessing(ctx, jobID, time.Now().Add(-backfillClaimTimeout))
+	if err != nil {
+		return fmt.Errorf("failed to claim backfill job: %w", err)
+	}
+	if !claimed {
+		return ErrBackfillRunning
+	}
+	return nil
+}
+
+// runJob retries the orders that failed on an earlier run, then imports the
+// orders of every window after the job's checkpoint, storing progress on the
+// job after each window. Orders that fail to import are kept on the job and
+// it ends failed, so a resume retries them. The job must be claimed.
+func (s *OrderBackfillService) runJob(ctx context.Context, job *models.SyncJob) error {
+	// Progress is stored even when ctx is cancelled, so the job can resume
+	storeCtx := context.WithoutCancel(ctx)
+
+	var payload models.OrderBackfillPayload
+	if err := json.Unmarshal(job.Payload, &payload); err != nil {
+		_ = s.syncJobRepo.MarkFailed(storeCtx, job.ID, "invalid backfill payload")
+		return fmt.Errorf("invalid backfill payload: %w", err)
+	}
+
+	conn, err := s.connectionRepo.GetByID(ctx, job.ConnectionID)
+	if err != nil {
+		_ = s.syncJobRepo.MarkFailed(storeCtx, job.ID, ErrConnectionNotFound.Error())
+		return ErrConnectionNotFound
+	}
+
+	if len(payload.FailedOrderIDs) > 0 {
+		if err := s.retryFailedOrders(ctx, conn, &payload); err != nil {
+			return s.failJob(storeCtx, job, conn, &payload, err)
+		}
+		s.storeProgress(storeCtx, job, &payload)
+	}
+
+	from := payload.TimeFrom
+	if payload.Checkpoint != nil {
+		from = *payload.Checkpoint
+	}
+	windows := orderWindows(from, payload.TimeTo, s.config.Window)
+	payload.WindowsTotal = payload.WindowsDone + len(windows)
+
+	for _, window := range windows {
+		err := s.orderService.fetchOrders(ctx, conn, &providers.OrderListParams{
+			TimeFrom:  window[0],
+			TimeTo:    window[1],
+			TimeField: providers.OrderTimeFieldCreate,
+		}, func(orders []providers.ExternalOrder) error {
+			for _, order := range orders {
+				payload.OrdersSeen++
+				if err := s.orderService.importOrder(ctx, conn, &order, models.OrderStatusSourcePoll, !payload.SkipPush); err != nil {
+					s.logger.Error("Failed to import backfilled order", zap.String("order_id", order.ExternalOrderID), zap.Error(err))
+					payload.FailedOrderIDs = append(payload.FailedOrderIDs, order.ExternalOrderID)
+					payload.OrdersFailed = len(payload.FailedOrderIDs)
+					continue
+				}
+				payload.OrdersImported++
+			}
+			return ctx.Err()
+		})
+		if err != nil {
+			return s.failJob(storeCtx, job, conn, &payload, err)
+		}
+
+		end := window[1]
+		payload.Checkpoint = &end
+		payload.WindowsDone++
+		s.storeProgress(storeCtx, job, &payload)
+	}
+
+	if len(payload.FailedOrderIDs) > 0 {
+		return s.failJob(storeCtx, job, conn, &payload, fmt.Errorf("%d orders failed to import", len(payload.FailedOrderIDs)))
+	}
+
+	_ = s.syncJobRepo.MarkCompleted(storeCtx, job.ID)
+
+	s.logger.Info("order backfill completed",
+		zap.String("job_id", job.ID.String()),
+		zap.String("connection_id", conn.ID.String()),
+		zap.Int("orders_seen", payload.OrdersSeen),
+		zap.Int("orders_imported", payload.OrdersImported),
+		zap.Int("orders_failed", payload.OrdersFailed),
+	)
+	return nil
+}
+
+// retryFailedOrders fetches and imports the orders that failed on an earlier
+// run, keeping those that fail again on the payload
+func (s *OrderBackfillService) retryFailedOrders(ctx context.Context, conn *models.Connection, payload *models.OrderBackfillPayload) error {
+	lister, err := s.orderService.newOrderLister(conn)
+	if err != nil {
+		return err
+	}
+	fetcher, ok := lister.(orderFetcher)
+	if !ok {
+		return ErrInvalidPlatform
+	}
+
+	var stillFailed []string
+	for i, orderID := range payload.FailedOrderIDs {
+		if err := ctx.Err(); err != nil {
+			payload.FailedOrderIDs = append(stillFailed, payload.FailedOrderIDs[i:]...)
+			payload.OrdersFailed = len(payload.FailedOrderIDs)
+			return err
+		}
+
+		order, err := fetcher.GetOrder(ctx, orderID)
+		if err == nil {
+			err = s.orderService.importOrder(ctx, conn, order, models.OrderStatusSourcePoll, !payload.SkipPush)
+		}
+		if err != nil {
+			s.logger.Error("Failed to import backfilled order", zap.String("order_id", orderID), zap.Error(err))
+			stillFailed = append(stillFailed, orderID)
+			continue
+		}
+		payload.OrdersImported++
+	}
+
+	payload.FailedOrderIDs = stillFailed
+	payload.OrdersFailed = len(stillFailed)
+	return nil
+}
+
+// failJob stores the progress of a job and marks it failed so it can be resumed
+func (s *OrderBackfillService) failJob(ctx context.Context, job *models.SyncJob, conn *models.Connection, payload *models.OrderBackfillPayload, err error) error {
+	s.storeProgress(ctx, job, payload)
+	s.logger.Error("order backfill failed",
+		zap.String("job_id", job.ID.String()),
+		zap.String("connection_id", conn.ID.String()),
+		zap.Error(err),
+	)
+	_ = s.syncJobRepo.MarkFailed(ctx, job.ID, err.Error())
+	return err
+}
+
+// storeProgress writes the backfill progress to the job payload
+func (s *OrderBackfillService) storeProgress(ctx context.Context, job *models.SyncJob, payload *models.OrderBackfillPayload) {
+	data, _ := json.Marshal(payload)
+	job.Payload = datatypes.JSON(data)
+	if err := s.syncJobRepo.UpdatePayload(ctx, job.ID, job.Payload); err != nil {
+		s.logger.Error("failed to store backfill checkpoint", zap.Error(err))
+	}
+}
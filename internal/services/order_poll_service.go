@@ -0,0 +1,306 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	"github.com/niaga-platform/service-marketplace/internal/models"
+	"github.com/niaga-platform/service-marketplace/internal/providers"
+	"github.com/niaga-platform/service-marketplace/internal/repository"
+)
+
+// OrderPollConfig holds configuration for scheduled order polling
+type OrderPollConfig struct {
+	Interval time.Duration // How often every active connection is polled
+	Overlap  time.Duration // How far before the high-water mark each poll starts
+	Lookback time.Duration // Range of the first poll of a connection
+
+	// MaxImportAttempts is how many polls an order may fail to import before it
+	// is dead-lettered and no longer holds back the high-water mark
+	MaxImportAttempts int
+
+	// MaxFulfillmentAttempts is how many times shipping an order from a
+	// fulfillment event may fail before the poll stops retrying it
+	MaxFulfillmentAttempts int
+}
+
+// OrderPollService polls every active connection for orders updated since its
+// last poll, so orders whose webhooks were missed are still imported. Progress
+// is kept per connection as a high-water mark on the order update time.
+type OrderPollService struct {
+	connectionRepo *repository.ConnectionRepository
+	pollStateRepo  *repository.OrderPollStateRepository
+	orderService   *OrderSyncService
+	config         OrderPollConfig
+	logger         *zap.Logger
+
+	// Lifecycle management
+	stopChan chan struct{}
+	wg       sync.WaitGroup
+	running  bool
+	mu       sync.Mutex
+}
+
+// NewOrderPollService creates a new OrderPollService
+func NewOrderPollService(
+	connectionRepo *repository.ConnectionRepository,
+	pollStateRepo *repository.OrderPollStateRepository,
+	orderService *OrderSyncService,
+	cfg OrderPollConfig,
+	logger *zap.Logger,
+) *OrderPollService {
+	// Set defaults
+	if cfg.Interval == 0 {
+		cfg.Interval = 10 * time.Minute
+	}
+	if cfg.Overlap == 0 {
+		cfg.Overlap = 10 * time.Minute
+	}
+	if cfg.Lookback == 0 {
+		cfg.Lookback = 24 * time.Hour
+	}
+	if cfg.MaxImportAttempts == 0 {
+		cfg.MaxImportAttempts = 5
+	}
+	if cfg.MaxFulfillmentAttempts == 0 {
+		cfg.MaxFulfillmentAttempts = 5
+	}
+
+	return &OrderPollService{
+		connectionRepo: connectionRepo,
+		pollStateRepo:  pollStateRepo,
+		orderService:   orderService,
+		config:         cfg,
+		logger:         logger,
+		stopChan:       make(chan struct{}),
+	}
+}
+
+// Start begins polling in the background.
+func (s *OrderPollService) Start(ctx context.Context) error {
+	s.mu.Lock()
+	if s.running {
+		s.mu.Unlock()
+		return fmt.Errorf("order poll already running")
+	}
+	s.running = true
+	s.mu.Unlock()
+
+	s.wg.Add(1)
+	go s.run(ctx)
+
+	s.logger.Info("order poll started",
+		zap.Duration("interval", s.config.Interval),
+		zap.Duration("overlap", s.config.Overlap),
+	)
+
+	return nil
+}
+
+// Stop gracefully stops polling.
+func (s *OrderPollService) Stop() {
+	s.mu.Lock()
+	if !s.running {
+		s.mu.Unlock()
+		return
+	}
+	s.running = false
+	s.mu.Unlock()
+
+	close(s.stopChan)
+	s.wg.Wait()
+
+	s.logger.Info("order poll stopped")
+}
+
+// run is the main background loop.
+func (s *OrderPollService) run(ctx context.Context) {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(s.config.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-s.stopChan:
+			return
+		case <-ticker.C:
+			s.pollAll(ctx)
+		}
+	}
+}
+
+// orderPollClaimTimeout is how long a replica holds a connection's poll. A
+// claim left by a replica that stopped mid-poll expires after it.
+const orderPollClaimTimeout = time.Hour
+
+// pollAll polls every active connection in turn, then retries its failed
+// fulfillment syncs against the refreshed order statuses. Connections another
+// replica is polling are skipped.
+func (s *OrderPollService) pollAll(ctx context.Context) {
+	connections, err := s.connectionRepo.GetActiveConnections(ctx)
+	if err != nil {
+		s.logger.Error("failed to get connections for order poll", zap.Error(err))
+		return
+	}
+
+	for _, conn := range connections {
+		claimed, err := s.pollStateRepo.Claim(ctx, conn.ID, time.Now().Add(orderPollClaimTimeout))
+		if err != nil {
+			s.logger.Warn("failed to claim order poll",
+				zap.String("connection_id", conn.ID.String()),
+				zap.Error(err),
+			)
+			continue
+		}
+		if !claimed {
+			s.logger.Debug("order poll held by another replica, skipping",
+				zap.String("connection_id", conn.ID.String()),
+			)
+			continue
+		}
+
+		if err := s.pollConnection(ctx, &conn); err != nil {
+			s.logger.Warn("order poll failed",
+				zap.String("connection_id", conn.ID.String()),
+				zap.Error(err),
+			)
+		}
+		if err := s.orderService.RetryFailedFulfillments(ctx, &conn, s.config.MaxFulfillmentAttempts); err != nil {
+			s.logger.Warn("fulfillment retry failed",
+				zap.String("connection_id", conn.ID.String()),
+				zap.Error(err),
+			)
+		}
+
+		if err := s.pollStateRepo.Release(ctx, conn.ID); err != nil {
+			s.logger.Warn("failed to release order poll",
+				zap.String("connection_id", conn.ID.String()),
+				zap.Error(err),
+			)
+		}
+	}
+}
+
+// GetPollState returns the poll state of a connection. Connections not polled
+// yet return an empty state.
+func (s *OrderPollService) GetPollState(ctx context.Context, connectionID uuid.UUID) (*models.OrderPollState, error) {
+	if _, err := s.connectionRepo.GetByID(ctx, connectionID); err != nil {
+		return nil, ErrConnectionNotFound
+	}
+
+	state, err := s.pollStateRepo.GetByConnectionID(ctx, connectionID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return &models.OrderPollState{ConnectionID: connectionID}, nil
+		}
+		return nil, err
+	}
+	return state, nil
+}
+
+// pollConnection imports the orders of a connection updated since its
+// high-water mark, less the overlap. The mark advances after each completed
+// window, so a failed poll resumes where it stopped. An order that keeps
+// failing is dead-lettered after MaxImportAttempts polls so the mark moves on.
+func (s *OrderPollService) pollConnection(ctx context.Context, conn *models.Connection) error {
+	state, err := s.pollStateRepo.GetByConnectionID(ctx, conn.ID)
+	if err != nil {
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return fmt.Errorf("failed to load poll state: %w", err)
+		}
+		state = &models.OrderPollState{ConnectionID: conn.ID}
+	}
+
+	now := time.Now()
+	from := now.Add(-s.config.Lookback)
+	if state.HighWaterMark != nil {
+		from = state.HighWaterMark.Add(-s.config.Overlap)
+	}
+	state.LastRunAt = &now
+	failures := state.GetImportFailures()
+
+	seen := 0
+	var pollErr error
+	for _, window := range orderWindows(from, now, orderListWindow) {
+		failed := 0
+		err := s.orderService.fetchOrders(ctx, conn, &providers.OrderListParams{
+			TimeFrom:  window[0],
+			TimeTo:    window[1],
+			TimeField: providers.OrderTimeFieldUpdate,
+		}, func(orders []providers.ExternalOrder) error {
+			for _, order := range orders {
+				seen++
+				err := s.orderService.importOrder(ctx, conn, &order, models.OrderStatusSourcePoll, true)
+				if err != nil {
+					s.logger.Error("Failed to import polled order", zap.String("order_id", order.ExternalOrderID), zap.Error(err))
+				}
+				if recordImportAttempt(failures, order.ExternalOrderID, err, s.config.MaxImportAttempts, now) {
+					failed++
+				} else if err != nil {
+					s.logger.Error("Dead-lettered polled order after repeated import failures",
+						zap.String("connection_id", conn.ID.String()),
+						zap.String("order_id", order.ExternalOrderID),
+						zap.Int("attempts", failures[order.ExternalOrderID].Attempts),
+					)
+				}
+			}
+			return nil
+		})
+		if err == nil && failed > 0 {
+			// Keep the mark so the window is fetched again
+			err = fmt.Errorf("%d orders failed to import", failed)
+		}
+		if err != nil {
+			pollErr = err
+			break
+		}
+
+		end := window[1]
+		state.HighWaterMark = &end
+	}
+
+	state.LastOrdersSeen = seen
+	state.SetImportFailures(failures)
+	if pollErr != nil {
+		state.LastError = pollErr.Error()
+		state.ConsecutiveFailures++
+	} else {
+		state.LastError = ""
+		state.ConsecutiveFailures = 0
+		state.LastSuccessAt = &now
+	}
+
+	if err := s.pollStateRepo.Save(ctx, state); err != nil {
+		return fmt.Errorf("failed to save poll state: %w", err)
+	}
+	return pollErr
+}
+
+// recordImportAttempt records the outcome of importing a polled order and
+// reports whether the order still holds back the high-water mark. A successful
+// import clears the order's failure; a failure at the attempt limit
+// dead-letters it.
+func recordImportAttempt(failures map[string]models.OrderImportFailure, orderID string, importErr error, maxAttempts int, now time.Time) bool {
+	if importErr == nil {
+		delete(failures, orderID)
+		return false
+	}
+
+	failure := failures[orderID]
+	failure.Attempts++
+	failure.LastError = importErr.Error()
+	failure.FailedAt = now
+	failure.DeadLettered = failure.Attempts >= maxAttempts
+	failures[orderID] = failure
+	return !failure.DeadLettered
+}
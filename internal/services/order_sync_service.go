@@ -3,15 +3,20 @@ package services
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
 	"go.uber.org/zap"
 	"gorm.io/datatypes"
+	"gorm.io/gorm"
 
 	"github.com/niaga-platform/service-marketplace/internal/clients"
+	"github.com/niaga-platform/service-marketplace/internal/domain/shared"
+	"github.com/niaga-platform/service-marketplace/internal/events"
 	"github.com/niaga-platform/service-marketplace/internal/models"
 	"github.com/niaga-platform/service-marketplace/internal/providers"
 	"github.com/niaga-platform/service-marketplace/internal/providers/shopee"
@@ -20,13 +25,28 @@ import (
 	"github.com/niaga-platform/service-marketplace/internal/utils"
 )
 
+// Order errors
+var (
+	ErrOrderNotFound        = errors.New("order not found")
+	ErrShipmentNotSupported = errors.New("arranging shipment is not supported for this platform")
+	ErrInvalidShipment      = errors.New("invalid shipment selection")
+)
+
+// ShippingDocumentStore keeps the shipping documents of shipped orders
+type ShippingDocumentStore interface {
+	GetDocument(ctx context.Context, orderID uuid.UUID, documentType string, regenerate bool) (*models.ShippingDocument, error)
+}
+
 // OrderSyncService handles order synchronization
 type OrderSyncService struct {
-	connectionRepo *repository.ConnectionRepository
-	orderRepo      *repository.MarketplaceOrderRepository
-	orderClient    *clients.OrderClient
-	encryptor      *utils.Encryptor
-	logger         *zap.Logger
+	connectionRepo     *repository.ConnectionRepository
+	orderRepo          *repository.MarketplaceOrderRepository
+	statusHistoryRepo  *repository.OrderStatusHistoryRepository
+	productMappingRepo *repository.ProductMappingRepository
+	orderClient        *clients.OrderClient
+	documents          ShippingDocumentStore
+	encryptor          *utils.Encryptor
+	logger             *zap.Logger
 
 	shopeePartnerID  string
 	shopeePartnerKey string
@@ -49,6 +69,8 @@ type OrderSyncServiceConfig struct {
 func NewOrderSyncService(
 	connectionRepo *repository.ConnectionRepository,
 	orderRepo *repository.MarketplaceOrderRepository,
+	statusHistoryRepo *repository.OrderStatusHistoryRepository,
+	productMappingRepo *repository.ProductMappingRepository,
 	orderClient *clients.OrderClient,
 	cfg *OrderSyncServiceConfig,
 	logger *zap.Logger,
@@ -63,114 +85,157 @@ func NewOrderSyncService(
 	}
 
 	return &OrderSyncService{
-		connectionRepo:   connectionRepo,
-		orderRepo:        orderRepo,
-		orderClient:      orderClient,
-		encryptor:        encryptor,
-		logger:           logger,
-		shopeePartnerID:  cfg.ShopeePartnerID,
-		shopeePartnerKey: cfg.ShopeePartnerKey,
-		shopeeSandbox:    cfg.ShopeeSandbox,
-		tiktokAppKey:     cfg.TikTokAppKey,
-		tiktokAppSecret:  cfg.TikTokAppSecret,
+		connectionRepo:     connectionRepo,
+		orderRepo:          orderRepo,
+		statusHistoryRepo:  statusHistoryRepo,
+		productMappingRepo: productMappingRepo,
+		orderClient:        orderClient,
+		encryptor:          encryptor,
+		logger:             logger,
+		shopeePartnerID:    cfg.ShopeePartnerID,
+		shopeePartnerKey:   cfg.ShopeePartnerKey,
+		shopeeSandbox:      cfg.ShopeeSandbox,
+		tiktokAppKey:       cfg.TikTokAppKey,
+		tiktokAppSecret:    cfg.TikTokAppSecret,
 	}, nil
 }
 
+// SetShippingDocuments registers the store that keeps the AWB of orders shipped here
+func (s *OrderSyncService) SetShippingDocuments(documents ShippingDocumentStore) {
+	s.documents = documents
+}
+
 // GetOrders retrieves marketplace orders for a connection
 func (s *OrderSyncService) GetOrders(ctx context.Context, connectionID uuid.UUID, filter *models.MarketplaceOrderFilter) ([]models.MarketplaceOrder, int64, error) {
 	return s.orderRepo.GetByConnectionID(ctx, connectionID, filter)
 }
 
-// SyncOrders manually syncs orders from marketplace
+// orderListWindow is the widest update or create time range requested at once.
+// Shopee rejects order list ranges over 15 days.
+const orderListWindow = 15 * 24 * time.Hour
+
+// orderLister lists marketplace orders page by page
+type orderLister interface {
+	GetOrders(ctx context.Context, params *providers.OrderListParams) ([]providers.ExternalOrder, string, error)
+}
+
+// SyncOrders manually syncs orders created in a time range from the marketplace
 func (s *OrderSyncService) SyncOrders(ctx context.Context, connectionID uuid.UUID, timeFrom, timeTo time.Time) (int, error) {
 	conn, err := s.connectionRepo.GetByID(ctx, connectionID)
 	if err != nil {
 		return 0, ErrConnectionNotFound
 	}
 
-	accessToken := conn.AccessToken
-	if s.encryptor != nil {
-		accessToken, err = s.encryptor.Decrypt(conn.AccessToken)
+	importedCount := 0
+	for _, window := range orderWindows(timeFrom, timeTo, orderListWindow) {
+		err := s.fetchOrders(ctx, conn, &providers.OrderListParams{
+			TimeFrom:  window[0],
+			TimeTo:    window[1],
+			TimeField: providers.OrderTimeFieldCreate,
+		}, func(orders []providers.ExternalOrder) error {
+			for _, order := range orders {
+				if err := s.importOrder(ctx, conn, &order, models.OrderStatusSourcePoll, true); err != nil {
+					s.logger.Error("Failed to import order", zap.String("order_id", order.ExternalOrderID), zap.Error(err))
+					continue
+				}
+				importedCount++
+			}
+			return nil
+		})
 		if err != nil {
-			return 0, fmt.Errorf("failed to decrypt token: %w", err)
+			return importedCount, err
 		}
 	}
 
-	var orders []providers.ExternalOrder
-	cursor := ""
+	return importedCount, nil
+}
 
-	for {
-		var fetchedOrders []providers.ExternalOrder
-		var nextCursor string
-
-		switch conn.Platform {
-		case "shopee":
-			shopID, _ := strconv.ParseInt(conn.ShopID, 10, 64)
-			client, _ := shopee.NewClient(&shopee.ClientConfig{
-				PartnerID:  s.shopeePartnerID,
-				PartnerKey: s.shopeePartnerKey,
-				IsSandbox:  s.shopeeSandbox,
-				Logger:     s.logger,
-			})
-			client.SetTokens(accessToken, shopID)
-			provider := shopee.NewOrderProvider(client)
-			fetchedOrders, nextCursor, err = provider.GetOrders(ctx, &providers.OrderListParams{
-				TimeFrom: timeFrom,
-				TimeTo:   timeTo,
-				PageSize: 50,
-				Cursor:   cursor,
-			})
-
-		case "tiktok":
-			client := tiktok.NewClient(&tiktok.ClientConfig{
-				AppKey:    s.tiktokAppKey,
-				AppSecret: s.tiktokAppSecret,
-				Logger:    s.logger,
-			})
-			client.SetTokens(accessToken, conn.ShopID)
-			provider := tiktok.NewOrderProvider(client)
-			fetchedOrders, nextCursor, err = provider.GetOrders(ctx, &providers.OrderListParams{
-				TimeFrom: timeFrom,
-				TimeTo:   timeTo,
-				PageSize: 50,
-				Cursor:   cursor,
-			})
-
-		default:
-			return 0, ErrInvalidPlatform
+// orderWindows splits a time range into consecutive windows no wider than size
+func orderWindows(from, to time.Time, size time.Duration) [][2]time.Time {
+	var windows [][2]time.Time
+	for start := from; start.Before(to); start = start.Add(size) {
+		end := start.Add(size)
+		if end.After(to) {
+			end = to
 		}
+		windows = append(windows, [2]time.Time{start, end})
+	}
+	return windows
+}
 
+// fetchOrders lists the orders matching params page by page and passes each
+// page to handle. An error from handle stops the listing.
+func (s *OrderSyncService) fetchOrders(ctx context.Context, conn *models.Connection, params *providers.OrderListParams, handle func([]providers.ExternalOrder) error) error {
+	lister, err := s.newOrderLister(conn)
+	if err != nil {
+		return err
+	}
+	if params.PageSize == 0 {
+		params.PageSize = 50
+	}
+
+	for {
+		orders, nextCursor, err := lister.GetOrders(ctx, params)
 		if err != nil {
-			return len(orders), fmt.Errorf("failed to fetch orders: %w", err)
+			return fmt.Errorf("failed to fetch orders: %w", err)
+		}
+		if err := handle(orders); err != nil {
+			return err
 		}
-
-		orders = append(orders, fetchedOrders...)
-
 		if nextCursor == "" {
-			break
+			return nil
 		}
-		cursor = nextCursor
+		params.Cursor = nextCursor
 	}
+}
 
-	// Import orders
-	importedCount := 0
-	for _, order := range orders {
-		if err := s.importOrder(ctx, conn, &order); err != nil {
-			s.logger.Error("Failed to import order", zap.String("order_id", order.ExternalOrderID), zap.Error(err))
-			continue
+// newOrderLister builds the order provider of a connection
+func (s *OrderSyncService) newOrderLister(conn *models.Connection) (orderLister, error) {
+	accessToken := conn.AccessToken
+	if s.encryptor != nil {
+		var err error
+		accessToken, err = s.encryptor.Decrypt(conn.AccessToken)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decrypt token: %w", err)
 		}
-		importedCount++
 	}
 
-	return importedCount, nil
+	switch conn.Platform {
+	case "shopee":
+		shopID, _ := strconv.ParseInt(conn.ShopID, 10, 64)
+		client, _ := shopee.NewClient(&shopee.ClientConfig{
+			PartnerID:  s.shopeePartnerID,
+			PartnerKey: s.shopeePartnerKey,
+			IsSandbox:  s.shopeeSandbox,
+			Logger:     s.logger,
+		})
+		client.SetTokens(accessToken, shopID)
+		return shopee.NewOrderProvider(client), nil
+
+	case "tiktok":
+		client := tiktok.NewClient(&tiktok.ClientConfig{
+			AppKey:    s.tiktokAppKey,
+			AppSecret: s.tiktokAppSecret,
+			Logger:    s.logger,
+		})
+		client.SetTokens(accessToken, conn.ShopID)
+		return tiktok.NewOrderProvider(client), nil
+
+	default:
+		return nil, ErrInvalidPlatform
+	}
 }
 
-func (s *OrderSyncService) importOrder(ctx context.Context, conn *models.Connection, order *providers.ExternalOrder) error {
+// importOrder creates or updates the local record of a marketplace order. The
+// source (webhook or poll) is recorded with any status change. New orders are
+// created in service-order unless push is false; an order with unresolved
+// lines is held back and created on the update that resolves its last line.
+func (s *OrderSyncService) importOrder(ctx context.Context, conn *models.Connection, order *providers.ExternalOrder, source string, push bool) error {
 	// Check if order already exists
 	existing, _ := s.orderRepo.GetByExternalOrderID(ctx, conn.ID, order.ExternalOrderID)
 	if existing != nil {
 		// Update existing order status
-		existing.Status = order.Status
+		change := s.applyStatus(existing, order.Status, order.PlatformStatus, source)
 		// Update shipping info if tracking available
 		if order.TrackingNumber != "" {
 			shippingInfo := models.ShippingInfoJSON{
@@ -187,23 +252,44 @@ func (s *OrderSyncService) importOrder(ctx context.Context, conn *models.Connect
 			shippingJSON, _ := json.Marshal(shippingInfo)
 			existing.ShippingInfo = datatypes.JSON(shippingJSON)
 		}
-		return s.orderRepo.Update(ctx, existing)
+		// Fetch the payout breakdown once the order completes
+		if existing.Status == models.OrderStatusCompleted && (change != nil || existing.NetPayout == nil) {
+			if err := s.fetchFinancials(ctx, conn, existing); err != nil {
+				s.logger.Warn("Failed to fetch order financials", zap.String("order_id", order.ExternalOrderID), zap.Error(err))
+			}
+		}
+		// Retry lines that were unresolved; mappings may have been added since
+		var orderData models.OrderDataJSON
+		if err := json.Unmarshal(existing.OrderData, &orderData); err == nil && existing.UnresolvedItems > 0 {
+			orderData.Items, existing.UnresolvedItems = s.resolveItems(ctx, conn.ID, order.Items)
+			orderDataJSON, _ := json.Marshal(orderData)
+			existing.OrderData = datatypes.JSON(orderDataJSON)
+		}
+		if err := s.orderRepo.Update(ctx, existing); err != nil {
+			return err
+		}
+		s.recordStatusChange(ctx, existing, change)
+
+		if push && serviceOrderReady(existing) {
+			s.createServiceOrder(ctx, conn, order, existing, orderData.Items)
+		}
+		return nil
 	}
 
 	// Build order data JSON
-	orderItems := make([]models.OrderItemJSON, len(order.Items))
-	for i, item := range order.Items {
-		orderItems[i] = models.OrderItemJSON{
-			ExternalProductID: item.ExternalProductID,
-			SKU:               item.ExternalSKU,
-			Name:              item.Name,
-			Quantity:          item.Quantity,
-			UnitPrice:         item.UnitPrice,
-			TotalPrice:        item.TotalPrice,
-		}
+	orderItems, unresolved := s.resolveItems(ctx, conn.ID, order.Items)
+	if unresolved > 0 {
+		s.logger.Warn("Order has lines without a product mapping",
+			zap.String("order_id", order.ExternalOrderID),
+			zap.Int("unresolved_items", unresolved),
+		)
 	}
 	orderData := models.OrderDataJSON{
-		Items: orderItems,
+		Items:          orderItems,
+		SubtotalAmount: order.SubtotalAmount,
+		ShippingFee:    order.ShippingFee,
+		DiscountAmount: order.DiscountAmount,
+		PaymentMethod:  order.PaymentMethod,
 	}
 	orderDataJSON, _ := json.Marshal(orderData)
 
@@ -235,74 +321,382 @@ func (s *OrderSyncService) importOrder(ctx context.Context, conn *models.Connect
 		ConnectionID:    conn.ID,
 		ExternalOrderID: order.ExternalOrderID,
 		Platform:        conn.Platform,
-		Status:          order.Status,
+		PlatformStatus:  order.PlatformStatus,
 		OrderData:       datatypes.JSON(orderDataJSON),
 		BuyerInfo:       datatypes.JSON(buyerInfoJSON),
 		ShippingInfo:    datatypes.JSON(shippingInfoJSON),
 		TotalAmount:     order.TotalAmount,
 		Currency:        order.Currency,
+		UnresolvedItems: unresolved,
 		SyncedAt:        &now,
 	}
+	// Created in service-order below, or once its unresolved lines resolve
+	mpOrder.ServiceOrderPending = push && s.orderClient != nil
+	change := s.applyStatus(mpOrder, order.Status, order.PlatformStatus, source)
+	if mpOrder.Status == models.OrderStatusCompleted {
+		if err := s.fetchFinancials(ctx, conn, mpOrder); err != nil {
+			s.logger.Warn("Failed to fetch order financials", zap.String("order_id", order.ExternalOrderID), zap.Error(err))
+		}
+	}
 
 	if err := s.orderRepo.Create(ctx, mpOrder); err != nil {
 		return fmt.Errorf("failed to create marketplace order: %w", err)
 	}
+	s.recordStatusChange(ctx, mpOrder, change)
+
+	if serviceOrderReady(mpOrder) {
+		s.createServiceOrder(ctx, conn, order, mpOrder, orderItems)
+	} else if mpOrder.ServiceOrderPending {
+		s.logger.Info("Holding service-order create until every line resolves",
+			zap.String("order_id", order.ExternalOrderID),
+			zap.Int("unresolved_items", unresolved),
+		)
+	}
 
-	// Push to service-order if client configured
-	if s.orderClient != nil {
-		items := make([]clients.OrderItemRequest, len(order.Items))
-		for i, item := range order.Items {
-			items[i] = clients.OrderItemRequest{
-				SKU:        item.ExternalSKU,
-				Name:       item.Name,
-				Quantity:   item.Quantity,
-				UnitPrice:  item.UnitPrice,
-				TotalPrice: item.TotalPrice,
-			}
+	return nil
+}
+
+// serviceOrderReady reports whether an order waiting for service-order can be
+// created there: every line resolves to an internal product
+func serviceOrderReady(order *models.MarketplaceOrder) bool {
+	return order.ServiceOrderPending && order.InternalOrderID == nil && order.UnresolvedItems == 0
+}
+
+// createServiceOrder creates a marketplace order in service-order with its
+// resolved lines and links it. The create is claimed first so concurrent
+// imports of the order create it once; a failed create releases the claim and
+// leaves the order pending, so the next update of the order retries it.
+func (s *OrderSyncService) createServiceOrder(ctx context.Context, conn *models.Connection, order *providers.ExternalOrder, mpOrder *models.MarketplaceOrder, lines []models.OrderItemJSON) {
+	if s.orderClient == nil {
+		return
+	}
+
+	claimed, err := s.orderRepo.ClaimServiceOrder(ctx, mpOrder.ID)
+	if err != nil {
+		s.logger.Error("Failed to claim service-order create",
+			zap.String("order_id", order.ExternalOrderID),
+			zap.Error(err),
+		)
+		return
+	}
+	if !claimed {
+		return // Created or being created by another import
+	}
+
+	items := make([]clients.OrderItemRequest, len(lines))
+	for i, item := range lines {
+		items[i] = clients.OrderItemRequest{
+			ProductID:  item.InternalProductID,
+			VariantID:  item.InternalVariantID,
+			SKU:        item.SKU,
+			Name:       item.Name,
+			Quantity:   item.Quantity,
+			UnitPrice:  item.UnitPrice,
+			TotalPrice: item.TotalPrice,
 		}
+	}
 
-		internalOrderID, err := s.orderClient.CreateOrder(ctx, &clients.CreateOrderRequest{
-			ExternalOrderID: order.ExternalOrderID,
-			Source:          conn.Platform,
-			CustomerName:    order.BuyerName,
-			CustomerPhone:   order.ShippingAddress.Phone,
-			ShippingAddress: clients.AddressRequest{
-				Name:       order.ShippingAddress.Name,
-				Phone:      order.ShippingAddress.Phone,
-				Address1:   order.ShippingAddress.Address,
-				City:       order.ShippingAddress.City,
-				State:      order.ShippingAddress.State,
-				Country:    order.ShippingAddress.Country,
-				PostalCode: order.ShippingAddress.ZipCode,
-			},
-			Items:       items,
-			TotalAmount: order.TotalAmount,
-			Currency:    order.Currency,
-			Status:      order.Status,
-			PaidAt:      order.PaidAt,
-		})
+	internalOrderID, err := s.orderClient.CreateOrder(ctx, &clients.CreateOrderRequest{
+		ExternalOrderID: order.ExternalOrderID,
+		Source:          conn.Platform,
+		CustomerName:    order.BuyerName,
+		CustomerPhone:   order.ShippingAddress.Phone,
+		ShippingAddress: clients.AddressRequest{
+			Name:       order.ShippingAddress.Name,
+			Phone:      order.ShippingAddress.Phone,
+			Address1:   order.ShippingAddress.Address,
+			City:       order.ShippingAddress.City,
+			State:      order.ShippingAddress.State,
+			Country:    order.ShippingAddress.Country,
+			PostalCode: order.ShippingAddress.ZipCode,
+		},
+		Items:       items,
+		TotalAmount: order.TotalAmount,
+		Currency:    order.Currency,
+		Status:      mpOrder.Status,
+		PaidAt:      order.PaidAt,
+	})
+	if err != nil {
+		s.logger.Error("Failed to create order in service-order",
+			zap.String("order_id", order.ExternalOrderID),
+			zap.Error(err),
+		)
+		if releaseErr := s.orderRepo.ReleaseServiceOrder(ctx, mpOrder.ID); releaseErr != nil {
+			s.logger.Error("Failed to release service-order claim",
+				zap.String("order_id", order.ExternalOrderID),
+				zap.Error(releaseErr),
+			)
+		}
+		return
+	}
 
-		if err != nil {
-			s.logger.Error("Failed to create order in service-order", zap.Error(err))
-		} else {
-			internalID, _ := uuid.Parse(internalOrderID)
-			mpOrder.InternalOrderID = &internalID
-			s.orderRepo.Update(ctx, mpOrder)
+	internalID, _ := uuid.Parse(internalOrderID)
+	mpOrder.InternalOrderID = &internalID
+	mpOrder.ServiceOrderPending = false
+	if err := s.orderRepo.LinkServiceOrder(ctx, mpOrder.ID, internalID); err != nil {
+		s.logger.Error("Failed to link service-order order",
+			zap.String("order_id", order.ExternalOrderID),
+			zap.String("internal_order_id", internalOrderID),
+			zap.Error(err),
+		)
+	}
+}
+
+// orderFinancialsProvider fetches the escrow or settlement breakdown of an order
+type orderFinancialsProvider interface {
+	GetOrderFinancials(ctx context.Context, externalOrderID string) (*providers.OrderFinancials, error)
+}
+
+// SyncOrderFinancials fetches the escrow or settlement breakdown of an order
+// and stores it with the order's net payout
+func (s *OrderSyncService) SyncOrderFinancials(ctx context.Context, orderID uuid.UUID) (*models.MarketplaceOrder, error) {
+	order, err := s.orderRepo.GetByID(ctx, orderID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrOrderNotFound
 		}
+		return nil, fmt.Errorf("failed to load order: %w", err)
+	}
+
+	conn, err := s.connectionRepo.GetByID(ctx, order.ConnectionID)
+	if err != nil {
+		return nil, ErrConnectionNotFound
 	}
 
+	if err := s.fetchFinancials(ctx, conn, order); err != nil {
+		return nil, err
+	}
+	if err := s.orderRepo.Update(ctx, order); err != nil {
+		return nil, fmt.Errorf("failed to update order: %w", err)
+	}
+	return order, nil
+}
+
+// fetchFinancials fetches the payout breakdown of an order from the marketplace
+// and applies it to the order; the caller saves the order.
+func (s *OrderSyncService) fetchFinancials(ctx context.Context, conn *models.Connection, order *models.MarketplaceOrder) error {
+	lister, err := s.newOrderLister(conn)
+	if err != nil {
+		return err
+	}
+	provider, ok := lister.(orderFinancialsProvider)
+	if !ok {
+		return ErrInvalidPlatform
+	}
+
+	financials, err := provider.GetOrderFinancials(ctx, order.ExternalOrderID)
+	if err != nil {
+		return fmt.Errorf("failed to fetch financials: %w", err)
+	}
+
+	var orderData models.OrderDataJSON
+	if len(order.OrderData) > 0 {
+		if err := json.Unmarshal(order.OrderData, &orderData); err != nil {
+			return fmt.Errorf("failed to decode order data: %w", err)
+		}
+	}
+	applyFinancials(&orderData, financials)
+	orderDataJSON, err := json.Marshal(orderData)
+	if err != nil {
+		return fmt.Errorf("failed to encode order data: %w", err)
+	}
+
+	now := time.Now()
+	order.OrderData = datatypes.JSON(orderDataJSON)
+	order.NetPayout = &financials.NetPayout
+	order.FinancialsAt = &now
 	return nil
 }
 
+// applyFinancials copies a payout breakdown into the order data. Amounts the
+// breakdown does not carry, such as the subtotal on TikTok Shop settlements,
+// keep the values taken from the order.
+func applyFinancials(orderData *models.OrderDataJSON, financials *providers.OrderFinancials) {
+	if financials.SubtotalAmount != 0 {
+		orderData.SubtotalAmount = financials.SubtotalAmount
+	}
+	if financials.ShippingFee != 0 {
+		orderData.ShippingFee = financials.ShippingFee
+	}
+	if financials.DiscountAmount != 0 {
+		orderData.DiscountAmount = financials.DiscountAmount
+	}
+	if financials.PaymentMethod != "" {
+		orderData.PaymentMethod = financials.PaymentMethod
+	}
+	orderData.SellerVoucher = financials.SellerVoucher
+	orderData.PlatformVoucher = financials.PlatformVoucher
+	orderData.Coins = financials.Coins
+	orderData.CommissionFee = financials.CommissionFee
+	orderData.TransactionFee = financials.TransactionFee
+	orderData.ServiceFee = financials.ServiceFee
+	orderData.PlatformFee = financials.CommissionFee + financials.TransactionFee + financials.ServiceFee
+	orderData.NetPayout = financials.NetPayout
+}
+
+// resolveItems builds the order lines and matches them to internal products and
+// variants. It returns the lines and the number left unresolved.
+func (s *OrderSyncService) resolveItems(ctx context.Context, connectionID uuid.UUID, items []providers.ExternalOrderItem) ([]models.OrderItemJSON, int) {
+	lines := make([]models.OrderItemJSON, len(items))
+	unresolved := 0
+	for i, item := range items {
+		line := models.OrderItemJSON{
+			ExternalProductID: item.ExternalProductID,
+			ExternalVariantID: item.ExternalVariantID,
+			SKU:               item.ExternalSKU,
+			Name:              item.Name,
+			Quantity:          item.Quantity,
+			UnitPrice:         item.UnitPrice,
+			OriginalPrice:     item.OriginalPrice,
+			TotalPrice:        item.TotalPrice,
+			VariantName:       item.VariantName,
+		}
+
+		productID, variantID, resolved := s.resolveItem(ctx, connectionID, &item)
+		if productID != uuid.Nil {
+			line.InternalProductID = productID.String()
+		}
+		if variantID != nil {
+			line.InternalVariantID = variantID.String()
+		}
+		if !resolved {
+			line.Unresolved = true
+			unresolved++
+		}
+		lines[i] = line
+	}
+	return lines, unresolved
+}
+
+// resolveItem matches an order line through the product mappings, by external
+// product and variant ID first and by seller SKU second. A product mapped with
+// variants only resolves when one of its variants matches; the product ID is
+// still returned so the line shows what it belongs to.
+func (s *OrderSyncService) resolveItem(ctx context.Context, connectionID uuid.UUID, item *providers.ExternalOrderItem) (uuid.UUID, *uuid.UUID, bool) {
+	if item.ExternalProductID != "" {
+		mapping, err := s.productMappingRepo.GetByConnectionAndExternalProductWithVariants(ctx, connectionID, item.ExternalProductID)
+		if err == nil {
+			if len(mapping.VariantMappings) == 0 {
+				return mapping.InternalProductID, nil, true
+			}
+			if item.ExternalVariantID != "" {
+				for _, variant := range mapping.VariantMappings {
+					if variant.ExternalVariantID == item.ExternalVariantID {
+						return mapping.InternalProductID, &variant.InternalVariantID, true
+					}
+				}
+			}
+			if item.ExternalSKU != "" {
+				for _, variant := range mapping.VariantMappings {
+					if variant.ExternalSKU == item.ExternalSKU {
+						return mapping.InternalProductID, &variant.InternalVariantID, true
+					}
+				}
+			}
+			return mapping.InternalProductID, nil, false
+		}
+	}
+
+	if item.ExternalSKU == "" {
+		return uuid.Nil, nil, false
+	}
+	if variant, err := s.productMappingRepo.GetVariantMappingBySKU(ctx, connectionID, item.ExternalSKU); err == nil && variant.ProductMapping != nil {
+		return variant.ProductMapping.InternalProductID, &variant.InternalVariantID, true
+	}
+	if mapping, err := s.productMappingRepo.GetByConnectionAndSKU(ctx, connectionID, item.ExternalSKU); err == nil {
+		return mapping.InternalProductID, nil, true
+	}
+	return uuid.Nil, nil, false
+}
+
+// applyStatus moves the order to the given canonical status and returns the
+// history entry to record once the order is saved. Transitions the lifecycle
+// does not allow, such as a late webhook reporting to_ship for an order already
+// shipped, keep the current status. A platform status with no mapping keeps
+// the current status too, or gives a new order shared.FallbackOrderStatus; the
+// raw platform status is stored either way.
+func (s *OrderSyncService) applyStatus(order *models.MarketplaceOrder, status, platformStatus, source string) *models.OrderStatusHistory {
+	current := shared.OrderStatus(order.Status)
+	next := shared.OrderStatus(status)
+
+	if !next.IsValid() {
+		s.logger.Warn("Unmapped platform order status",
+			zap.String("order_id", order.ExternalOrderID),
+			zap.String("platform", order.Platform),
+			zap.String("platform_status", platformStatus),
+			zap.String("current_status", current.String()),
+		)
+		if platformStatus != "" {
+			order.PlatformStatus = platformStatus
+		}
+		if current.IsValid() {
+			return nil
+		}
+		next = shared.FallbackOrderStatus
+	}
+	if current == next {
+		if platformStatus != "" {
+			order.PlatformStatus = platformStatus
+		}
+		return nil
+	}
+	if current.IsValid() && !current.CanTransitionTo(next) {
+		s.logger.Info("Ignoring order status transition",
+			zap.String("order_id", order.ExternalOrderID),
+			zap.String("from", current.String()),
+			zap.String("to", next.String()),
+			zap.String("source", source),
+		)
+		return nil
+	}
+
+	order.Status = next.String()
+	if platformStatus != "" {
+		order.PlatformStatus = platformStatus
+	}
+	return &models.OrderStatusHistory{
+		FromStatus:     current.String(),
+		ToStatus:       next.String(),
+		PlatformStatus: platformStatus,
+		Source:         source,
+	}
+}
+
+// recordStatusChange stores a status change of a saved order. Failures are
+// logged only; the order itself is already up to date.
+func (s *OrderSyncService) recordStatusChange(ctx context.Context, order *models.MarketplaceOrder, change *models.OrderStatusHistory) {
+	if change == nil {
+		return
+	}
+	change.OrderID = order.ID
+	if err := s.statusHistoryRepo.Create(ctx, change); err != nil {
+		s.logger.Warn("Failed to record order status change",
+			zap.String("order_id", order.ID.String()),
+			zap.Error(err),
+		)
+	}
+}
+
+// GetOrderStatusHistory retrieves the status changes of an order, oldest first
+func (s *OrderSyncService) GetOrderStatusHistory(ctx context.Context, orderID uuid.UUID) ([]models.OrderStatusHistory, error) {
+	if _, err := s.orderRepo.GetByID(ctx, orderID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrOrderNotFound
+		}
+		return nil, err
+	}
+	return s.statusHistoryRepo.GetByOrderID(ctx, orderID)
+}
+
 // HandleShopeeOrderEvent handles webhook order events from Shopee
-func (s *OrderSyncService) HandleShopeeOrderEvent(shopID int64, orderSN, status string) {
+func (s *OrderSyncService) HandleShopeeOrderEvent(shopID int64, orderSN, status string) error {
 	ctx := context.Background()
 
 	// Find connection by shop ID
 	conn, err := s.connectionRepo.GetByPlatformAndShopID(ctx, "shopee", fmt.Sprintf("%d", shopID))
 	if err != nil {
 		s.logger.Error("Connection not found for shop", zap.Int64("shop_id", shopID))
-		return
+		return ErrConnectionNotFound
 	}
 
 	accessToken := conn.AccessToken
@@ -323,23 +717,138 @@ func (s *OrderSyncService) HandleShopeeOrderEvent(shopID int64, orderSN, status
 	order, err := provider.GetOrder(ctx, orderSN)
 	if err != nil {
 		s.logger.Error("Failed to fetch order", zap.String("order_sn", orderSN), zap.Error(err))
-		return
+		return fmt.Errorf("failed to fetch order %s: %w", orderSN, err)
 	}
 
-	if err := s.importOrder(ctx, conn, order); err != nil {
+	if err := s.importOrder(ctx, conn, order, models.OrderStatusSourceWebhook, true); err != nil {
 		s.logger.Error("Failed to import order from webhook", zap.Error(err))
+		return fmt.Errorf("failed to import order %s: %w", orderSN, err)
+	}
+
+	return nil
+}
+
+// HandleShopeeTrackingUpdate records the tracking number and logistics status
+// pushed by Shopee on the order's shipping info. Orders not imported yet are
+// fetched in full instead.
+func (s *OrderSyncService) HandleShopeeTrackingUpdate(shopID int64, data *shopee.TrackingUpdateData) error {
+	ctx := context.Background()
+
+	conn, err := s.connectionRepo.GetByPlatformAndShopID(ctx, "shopee", fmt.Sprintf("%d", shopID))
+	if err != nil {
+		s.logger.Error("Connection not found for shop", zap.Int64("shop_id", shopID))
+		return ErrConnectionNotFound
 	}
+
+	order, err := s.orderRepo.GetByExternalOrderID(ctx, conn.ID, data.OrderSN)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return s.HandleShopeeOrderEvent(shopID, data.OrderSN, "")
+		}
+		return fmt.Errorf("failed to load order %s: %w", data.OrderSN, err)
+	}
+
+	var shippingInfo models.ShippingInfoJSON
+	if len(order.ShippingInfo) > 0 {
+		if err := json.Unmarshal(order.ShippingInfo, &shippingInfo); err != nil {
+			return fmt.Errorf("failed to decode shipping info of order %s: %w", data.OrderSN, err)
+		}
+	}
+	if data.TrackingNumber != "" {
+		shippingInfo.TrackingNumber = data.TrackingNumber
+	}
+	if data.LogisticsStatus != "" {
+		shippingInfo.ShippingStatus = data.LogisticsStatus
+	}
+
+	shippingJSON, err := json.Marshal(shippingInfo)
+	if err != nil {
+		return fmt.Errorf("failed to encode shipping info: %w", err)
+	}
+	order.ShippingInfo = datatypes.JSON(shippingJSON)
+
+	if err := s.orderRepo.Update(ctx, order); err != nil {
+		return fmt.Errorf("failed to update order %s: %w", data.OrderSN, err)
+	}
+
+	s.logger.Info("Updated order tracking from webhook",
+		zap.String("order_sn", data.OrderSN),
+		zap.String("tracking_number", data.TrackingNumber),
+		zap.String("logistics_status", data.LogisticsStatus),
+	)
+	return nil
 }
 
 // HandleTikTokOrderEvent handles webhook order events from TikTok
-func (s *OrderSyncService) HandleTikTokOrderEvent(shopID, orderID string, status int) {
+func (s *OrderSyncService) HandleTikTokOrderEvent(shopID, orderID, status string) error {
+	ctx := context.Background()
+
+	conn, order, err := s.fetchTikTokOrder(ctx, shopID, orderID)
+	if err != nil {
+		return err
+	}
+
+	if err := s.importOrder(ctx, conn, order, models.OrderStatusSourceWebhook, true); err != nil {
+		s.logger.Error("Failed to import order from webhook", zap.Error(err))
+		return fmt.Errorf("failed to import order %s: %w", orderID, err)
+	}
+
+	return nil
+}
+
+// HandleTikTokAddressUpdate refreshes the recipient address of a TikTok order
+// after the buyer changed it. Tracking details already recorded are kept.
+func (s *OrderSyncService) HandleTikTokAddressUpdate(shopID, orderID string) error {
 	ctx := context.Background()
 
+	conn, order, err := s.fetchTikTokOrder(ctx, shopID, orderID)
+	if err != nil {
+		return err
+	}
+
+	existing, err := s.orderRepo.GetByExternalOrderID(ctx, conn.ID, orderID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return s.importOrder(ctx, conn, order, models.OrderStatusSourceWebhook, true)
+		}
+		return fmt.Errorf("failed to load order %s: %w", orderID, err)
+	}
+
+	var shippingInfo models.ShippingInfoJSON
+	if len(existing.ShippingInfo) > 0 {
+		if err := json.Unmarshal(existing.ShippingInfo, &shippingInfo); err != nil {
+			return fmt.Errorf("failed to decode shipping info of order %s: %w", orderID, err)
+		}
+	}
+	shippingInfo.RecipientName = order.ShippingAddress.Name
+	shippingInfo.Phone = order.ShippingAddress.Phone
+	shippingInfo.AddressLine1 = order.ShippingAddress.Address
+	shippingInfo.City = order.ShippingAddress.City
+	shippingInfo.State = order.ShippingAddress.State
+	shippingInfo.PostalCode = order.ShippingAddress.ZipCode
+	shippingInfo.Country = order.ShippingAddress.Country
+
+	shippingJSON, err := json.Marshal(shippingInfo)
+	if err != nil {
+		return fmt.Errorf("failed to encode shipping info: %w", err)
+	}
+	existing.ShippingInfo = datatypes.JSON(shippingJSON)
+
+	if err := s.orderRepo.Update(ctx, existing); err != nil {
+		return fmt.Errorf("failed to update order %s: %w", orderID, err)
+	}
+
+	s.logger.Info("Updated order recipient address from webhook", zap.String("order_id", orderID))
+	return nil
+}
+
+// fetchTikTokOrder loads the connection of a TikTok shop and the current state of one of its orders
+func (s *OrderSyncService) fetchTikTokOrder(ctx context.Context, shopID, orderID string) (*models.Connection, *providers.ExternalOrder, error) {
 	// Find connection by shop ID
 	conn, err := s.connectionRepo.GetByPlatformAndShopID(ctx, "tiktok", shopID)
 	if err != nil {
 		s.logger.Error("Connection not found for shop", zap.String("shop_id", shopID))
-		return
+		return nil, nil, ErrConnectionNotFound
 	}
 
 	accessToken := conn.AccessToken
@@ -359,156 +868,472 @@ func (s *OrderSyncService) HandleTikTokOrderEvent(shopID, orderID string, status
 	order, err := provider.GetOrder(ctx, orderID)
 	if err != nil {
 		s.logger.Error("Failed to fetch order", zap.String("order_id", orderID), zap.Error(err))
-		return
+		return nil, nil, fmt.Errorf("failed to fetch order %s: %w", orderID, err)
 	}
 
-	if err := s.importOrder(ctx, conn, order); err != nil {
-		s.logger.Error("Failed to import order from webhook", zap.Error(err))
-	}
+	return conn, order, nil
 }
 
 // ArrangeShipmentResult contains the result of arranging shipment
 type ArrangeShipmentResult struct {
-	Success        bool   `json:"success"`
-	TrackingNumber string `json:"tracking_number,omitempty"`
-	AWBUrl         string `json:"awb_url,omitempty"`
-	Message        string `json:"message"`
+	Success        bool                         `json:"success"`
+	TrackingNumber string                       `json:"tracking_number,omitempty"`
+	Document       *models.ShippingDocument     `json:"document,omitempty"` // Stored AWB, if it could be downloaded
+	Shipment       *providers.ShipmentSelection `json:"shipment,omitempty"`
+	Message        string                       `json:"message"`
 }
 
-// ArrangeShipment arranges shipment for an order on the marketplace
-func (s *OrderSyncService) ArrangeShipment(ctx context.Context, orderID uuid.UUID) (*ArrangeShipmentResult, error) {
-	order, err := s.orderRepo.GetByID(ctx, orderID)
+// shippingProvider arranges shipment of orders with a chosen shipping option
+type shippingProvider interface {
+	GetShippingOptions(ctx context.Context, externalOrderID string) (*providers.ShippingOptions, error)
+	ArrangeShipment(ctx context.Context, externalOrderID string, selection *providers.ShipmentSelection) error
+}
+
+// GetShippingOptions returns the dropoff branches, pickup addresses and time
+// slots, and non-integrated option available to ship an order
+func (s *OrderSyncService) GetShippingOptions(ctx context.Context, orderID uuid.UUID) (*providers.ShippingOptions, error) {
+	order, conn, err := s.getOrderWithConnection(ctx, orderID)
 	if err != nil {
-		return nil, fmt.Errorf("order not found: %w", err)
+		return nil, err
 	}
 
-	conn, err := s.connectionRepo.GetByID(ctx, order.ConnectionID)
+	provider, err := s.newShippingProvider(conn)
 	if err != nil {
-		return nil, ErrConnectionNotFound
+		return nil, err
 	}
 
-	accessToken := conn.AccessToken
-	if s.encryptor != nil {
-		accessToken, err = s.encryptor.Decrypt(conn.AccessToken)
-		if err != nil {
-			return nil, fmt.Errorf("failed to decrypt token: %w", err)
-		}
+	options, err := provider.GetShippingOptions(ctx, order.ExternalOrderID)
+	if err != nil {
+		return nil, shippingOptionsError(err)
 	}
+	return options, nil
+}
 
-	switch conn.Platform {
-	case "shopee":
-		shopID, _ := strconv.ParseInt(conn.ShopID, 10, 64)
-		client, _ := shopee.NewClient(&shopee.ClientConfig{
-			PartnerID:  s.shopeePartnerID,
-			PartnerKey: s.shopeePartnerKey,
-			IsSandbox:  s.shopeeSandbox,
-			Logger:     s.logger,
-		})
-		client.SetTokens(accessToken, shopID)
-		provider := shopee.NewOrderProvider(client)
+// ArrangeShipment arranges shipment for an order on the marketplace. Parts of
+// the shipping option left out of the request are filled from the
+// connection's shipping settings, then from the first available option.
+func (s *OrderSyncService) ArrangeShipment(ctx context.Context, orderID uuid.UUID, requested *providers.ShipmentSelection) (*ArrangeShipmentResult, error) {
+	order, conn, err := s.getOrderWithConnection(ctx, orderID)
+	if err != nil {
+		return nil, err
+	}
+
+	provider, err := s.newShippingProvider(conn)
+	if err != nil {
+		return nil, err
+	}
+
+	selection, err := s.shipOrder(ctx, conn, provider, order, requested)
+	if err != nil {
+		return nil, err
+	}
+
+	s.recordShipped(ctx, order, models.OrderStatusSourceAdmin)
 
-		// Arrange shipment
-		if err := provider.ArrangeShipment(ctx, order.ExternalOrderID, nil); err != nil {
-			return nil, fmt.Errorf("failed to arrange shipment: %w", err)
+	// Keep the AWB or shipping label; failing here does not undo the shipment
+	var document *models.ShippingDocument
+	if s.documents != nil {
+		if document, err = s.documents.GetDocument(ctx, order.ID, "", false); err != nil {
+			s.logger.Warn("Failed to store shipping document", zap.String("order_id", order.ID.String()), zap.Error(err))
 		}
+	}
 
-		// Update local order status
-		order.Status = "shipped"
-		if err := s.orderRepo.Update(ctx, order); err != nil {
-			s.logger.Warn("Failed to update local order status", zap.Error(err))
+	return &ArrangeShipmentResult{
+		Success:        true,
+		TrackingNumber: selection.TrackingNumber,
+		Document:       document,
+		Shipment:       selection,
+		Message:        "Shipment arranged successfully",
+	}, nil
+}
+
+// HandleOrderShipped ships an order on its marketplace once the warehouse has
+// shipped it in service-order. Orders that did not come from a marketplace or
+// are already shipped there are skipped; the result is recorded on the order.
+func (s *OrderSyncService) HandleOrderShipped(event *events.OrderShippedEvent) error {
+	ctx := context.Background()
+
+	order, err := s.orderRepo.GetByInternalOrderID(ctx, event.OrderID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil // Not a marketplace order
 		}
+		return fmt.Errorf("failed to load order: %w", err)
+	}
+
+	if current := shared.OrderStatus(order.Status); current.IsFulfilled() || current.IsFinal() {
+		s.logger.Debug("Skipping fulfillment of order already shipped or closed",
+			zap.String("order_id", order.ID.String()),
+			zap.String("status", order.Status),
+		)
+		return nil
+	}
+
+	tracking := &providers.TrackingInfo{
+		Courier:        event.Courier,
+		TrackingNumber: event.TrackingNumber,
+		ShippedAt:      event.ShippedAt,
+	}
+
+	return s.syncFulfillment(ctx, order, tracking)
+}
+
+// RetryFailedFulfillments ships the orders of a connection whose fulfillment
+// sync failed fewer than maxAttempts times, with the tracking of their event.
+// Orders since shipped or closed on the marketplace are recorded as synced.
+func (s *OrderSyncService) RetryFailedFulfillments(ctx context.Context, conn *models.Connection, maxAttempts int) error {
+	orders, err := s.orderRepo.GetFailedFulfillments(ctx, conn.ID, maxAttempts, fulfillmentRetryBatch)
+	if err != nil {
+		return fmt.Errorf("failed to load failed fulfillments: %w", err)
+	}
+
+	failed := 0
+	for i := range orders {
+		order := &orders[i]
+		tracking := fulfillmentTracking(order)
 
-		// Try to get AWB URL
-		awbUrl := ""
-		if err := provider.CreateShippingDocument(ctx, order.ExternalOrderID, "NORMAL_AIR_WAYBILL"); err == nil {
-			if result, err := provider.GetShippingDocumentResult(ctx, order.ExternalOrderID, "NORMAL_AIR_WAYBILL"); err == nil && result.Status == "READY" {
-				awbUrl, _ = provider.DownloadShippingDocument(ctx, order.ExternalOrderID, "NORMAL_AIR_WAYBILL")
+		if current := shared.OrderStatus(order.Status); current.IsFulfilled() || current.IsFinal() {
+			if err := s.orderRepo.UpdateFulfillmentSync(ctx, order.ID, models.FulfillmentSyncSynced, "", order.FulfillmentTracking); err != nil {
+				s.logger.Warn("Failed to record fulfillment sync", zap.String("order_id", order.ID.String()), zap.Error(err))
 			}
+			continue
 		}
 
-		return &ArrangeShipmentResult{
-			Success: true,
-			AWBUrl:  awbUrl,
-			Message: "Shipment arranged successfully",
-		}, nil
+		if err := s.syncFulfillment(ctx, order, tracking); err != nil {
+			failed++
+			s.logger.Warn("Fulfillment sync retry failed",
+				zap.String("order_id", order.ID.String()),
+				zap.Int("attempt", order.FulfillmentAttempts+1),
+				zap.Error(err),
+			)
+		}
+	}
 
-	case "tiktok":
-		return nil, fmt.Errorf("arrange shipment not yet implemented for TikTok")
+	if failed > 0 {
+		return fmt.Errorf("%d of %d fulfillment retries failed", failed, len(orders))
+	}
+	return nil
+}
 
-	default:
-		return nil, ErrInvalidPlatform
+// fulfillmentRetryBatch is how many failed fulfillments of a connection are retried per run
+const fulfillmentRetryBatch = 50
+
+// syncFulfillment ships an order from a fulfillment and records the result
+// with the tracking used, so a failed sync can be retried.
+func (s *OrderSyncService) syncFulfillment(ctx context.Context, order *models.MarketplaceOrder, tracking *providers.TrackingInfo) error {
+	result, syncError := models.FulfillmentSyncSynced, ""
+	err := s.shipFulfilledOrder(ctx, order, tracking)
+	if err != nil {
+		result, syncError = models.FulfillmentSyncFailed, err.Error()
+	}
+
+	trackingJSON, _ := json.Marshal(tracking)
+	if recErr := s.orderRepo.UpdateFulfillmentSync(ctx, order.ID, result, syncError, datatypes.JSON(trackingJSON)); recErr != nil {
+		s.logger.Warn("Failed to record fulfillment sync", zap.String("order_id", order.ID.String()), zap.Error(recErr))
+	}
+	return err
+}
+
+// fulfillmentTracking returns the tracking kept for an order's fulfillment sync.
+// Orders without one ship through the marketplace logistics.
+func fulfillmentTracking(order *models.MarketplaceOrder) *providers.TrackingInfo {
+	var tracking providers.TrackingInfo
+	if len(order.FulfillmentTracking) > 0 {
+		_ = json.Unmarshal(order.FulfillmentTracking, &tracking)
+	}
+	return &tracking
+}
+
+// shipFulfilledOrder arranges shipment for an order shipped by the warehouse.
+// A tracking number ships the order non-integrated when the marketplace
+// offers it; otherwise the marketplace logistics are used with the
+// connection's shipping settings.
+func (s *OrderSyncService) shipFulfilledOrder(ctx context.Context, order *models.MarketplaceOrder, tracking *providers.TrackingInfo) error {
+	conn, err := s.connectionRepo.GetByID(ctx, order.ConnectionID)
+	if err != nil {
+		return ErrConnectionNotFound
+	}
+
+	provider, err := s.newShippingProvider(conn)
+	if err != nil {
+		return err
+	}
+
+	options, err := provider.GetShippingOptions(ctx, order.ExternalOrderID)
+	if err != nil {
+		return shippingOptionsError(err)
+	}
+
+	var requested *providers.ShipmentSelection
+	if tracking.TrackingNumber != "" && options.HasMethod(providers.ShippingMethodNonIntegrated) {
+		requested = &providers.ShipmentSelection{
+			Method:             providers.ShippingMethodNonIntegrated,
+			TrackingNumber:     tracking.TrackingNumber,
+			ShippingProviderID: matchShippingProvider(options, tracking.Courier),
+		}
+	}
+
+	if _, err := s.arrangeWithOptions(ctx, conn, provider, order, options, requested); err != nil {
+		return err
+	}
+
+	s.recordShipped(ctx, order, models.OrderStatusSourceFulfillment)
+	return nil
+}
+
+// matchShippingProvider returns the offered shipping provider with the ID or
+// name of a courier, or an empty string
+func matchShippingProvider(options *providers.ShippingOptions, courier string) string {
+	if courier == "" {
+		return ""
 	}
+	for _, provider := range options.ShippingProviders {
+		if provider.ID == courier || strings.EqualFold(provider.Name, courier) {
+			return provider.ID
+		}
+	}
+	return ""
 }
 
-// GetAWBDownloadURL gets the AWB download URL for an order
-func (s *OrderSyncService) GetAWBDownloadURL(ctx context.Context, orderID uuid.UUID, documentType string) (string, error) {
+// recordShipped moves the local order to shipped after shipment was arranged
+func (s *OrderSyncService) recordShipped(ctx context.Context, order *models.MarketplaceOrder, source string) {
+	change := s.applyStatus(order, models.OrderStatusShipped, "", source)
+	if err := s.orderRepo.Update(ctx, order); err != nil {
+		s.logger.Warn("Failed to update local order status", zap.Error(err))
+		return
+	}
+	s.recordStatusChange(ctx, order, change)
+}
+
+// getOrderWithConnection loads an order and its connection
+func (s *OrderSyncService) getOrderWithConnection(ctx context.Context, orderID uuid.UUID) (*models.MarketplaceOrder, *models.Connection, error) {
 	order, err := s.orderRepo.GetByID(ctx, orderID)
 	if err != nil {
-		return "", fmt.Errorf("order not found: %w", err)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil, ErrOrderNotFound
+		}
+		return nil, nil, fmt.Errorf("failed to load order: %w", err)
 	}
 
 	conn, err := s.connectionRepo.GetByID(ctx, order.ConnectionID)
 	if err != nil {
-		return "", ErrConnectionNotFound
+		return nil, nil, ErrConnectionNotFound
 	}
+	return order, conn, nil
+}
 
-	accessToken := conn.AccessToken
-	if s.encryptor != nil {
-		accessToken, err = s.encryptor.Decrypt(conn.AccessToken)
-		if err != nil {
-			return "", fmt.Errorf("failed to decrypt token: %w", err)
+// shippingOptionsError wraps a failure to get the shipping options of an
+// order. Orders that cannot be shipped from here are not supported.
+func shippingOptionsError(err error) error {
+	if errors.Is(err, tiktok.ErrMultiplePackages) {
+		return fmt.Errorf("%w: %v", ErrShipmentNotSupported, err)
+	}
+	return fmt.Errorf("failed to get shipping options: %w", err)
+}
+
+// newShippingProvider builds the order provider of a connection for arranging shipment
+func (s *OrderSyncService) newShippingProvider(conn *models.Connection) (shippingProvider, error) {
+	lister, err := s.newOrderLister(conn)
+	if err != nil {
+		return nil, err
+	}
+	provider, ok := lister.(shippingProvider)
+	if !ok {
+		return nil, ErrShipmentNotSupported
+	}
+	return provider, nil
+}
+
+// shipOrder resolves the shipping option of an order against what the
+// marketplace offers and arranges the shipment with it
+func (s *OrderSyncService) shipOrder(ctx context.Context, conn *models.Connection, provider shippingProvider, order *models.MarketplaceOrder, requested *providers.ShipmentSelection) (*providers.ShipmentSelection, error) {
+	options, err := provider.GetShippingOptions(ctx, order.ExternalOrderID)
+	if err != nil {
+		return nil, shippingOptionsError(err)
+	}
+	return s.arrangeWithOptions(ctx, conn, provider, order, options, requested)
+}
+
+// arrangeWithOptions resolves the shipping option of an order against the
+// options already fetched and arranges the shipment with it
+func (s *OrderSyncService) arrangeWithOptions(ctx context.Context, conn *models.Connection, provider shippingProvider, order *models.MarketplaceOrder, options *providers.ShippingOptions, requested *providers.ShipmentSelection) (*providers.ShipmentSelection, error) {
+	selection, err := resolveShipment(options, requested, conn.GetSettings().Shipping)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := provider.ArrangeShipment(ctx, order.ExternalOrderID, selection); err != nil {
+		return nil, fmt.Errorf("failed to arrange shipment: %w", err)
+	}
+
+	s.logger.Info("shipment arranged",
+		zap.String("order_id", order.ID.String()),
+		zap.String("method", selection.Method),
+	)
+	return selection, nil
+}
+
+// resolveShipment completes a requested shipping option. Values given in the
+// request must be offered for the order; missing values come from the
+// connection defaults when still offered, then from the first option offered.
+func resolveShipment(options *providers.ShippingOptions, requested *providers.ShipmentSelection, defaults models.ShippingSettings) (*providers.ShipmentSelection, error) {
+	selection := providers.ShipmentSelection{}
+	if requested != nil {
+		selection = *requested
+	}
+
+	if selection.Method == "" {
+		for _, method := range []string{defaults.Method, providers.ShippingMethodDropoff, providers.ShippingMethodPickup} {
+			if method != "" && options.HasMethod(method) {
+				selection.Method = method
+				break
+			}
 		}
+		if selection.Method == "" {
+			return nil, fmt.Errorf("%w: no dropoff or pickup available, ship non_integrated with a tracking number", ErrInvalidShipment)
+		}
+	}
+	if !options.HasMethod(selection.Method) {
+		return nil, fmt.Errorf("%w: method %q is not available for this order", ErrInvalidShipment, selection.Method)
 	}
 
-	switch conn.Platform {
-	case "shopee":
-		shopID, _ := strconv.ParseInt(conn.ShopID, 10, 64)
-		client, _ := shopee.NewClient(&shopee.ClientConfig{
-			PartnerID:  s.shopeePartnerID,
-			PartnerKey: s.shopeePartnerKey,
-			IsSandbox:  s.shopeeSandbox,
-			Logger:     s.logger,
-		})
-		client.SetTokens(accessToken, shopID)
-		provider := shopee.NewOrderProvider(client)
+	switch selection.Method {
+	case providers.ShippingMethodDropoff:
+		if selection.BranchID != 0 {
+			if !hasDropoffBranch(options, selection.BranchID) {
+				return nil, fmt.Errorf("%w: dropoff branch %d is not available", ErrInvalidShipment, selection.BranchID)
+			}
+			break
+		}
+		if !options.BranchRequired {
+			break
+		}
+		if defaults.DropoffBranchID != 0 && hasDropoffBranch(options, defaults.DropoffBranchID) {
+			selection.BranchID = defaults.DropoffBranchID
+		} else if len(options.DropoffBranches) > 0 {
+			selection.BranchID = options.DropoffBranches[0].BranchID
+		} else {
+			return nil, fmt.Errorf("%w: no dropoff branch available", ErrInvalidShipment)
+		}
 
-		// First, create the shipping document
-		if err := provider.CreateShippingDocument(ctx, order.ExternalOrderID, documentType); err != nil {
-			s.logger.Warn("Create shipping document warning", zap.Error(err))
-			// Continue anyway - document might already exist
+	case providers.ShippingMethodPickup:
+		var address *providers.PickupAddress
+		if selection.AddressID != 0 {
+			if address = findPickupAddress(options, selection.AddressID); address == nil {
+				return nil, fmt.Errorf("%w: pickup address %d is not available", ErrInvalidShipment, selection.AddressID)
+			}
+		} else {
+			address = defaultPickupAddress(options, defaults.PickupAddressID)
+			if address == nil {
+				return nil, fmt.Errorf("%w: no pickup address available", ErrInvalidShipment)
+			}
+			selection.AddressID = address.AddressID
 		}
 
-		// Check if document is ready
-		result, err := provider.GetShippingDocumentResult(ctx, order.ExternalOrderID, documentType)
-		if err != nil {
-			return "", fmt.Errorf("failed to get document status: %w", err)
+		if selection.PickupTimeID != "" {
+			found := false
+			for _, slot := range address.TimeSlots {
+				if slot.PickupTimeID == selection.PickupTimeID {
+					found = true
+					break
+				}
+			}
+			if !found {
+				return nil, fmt.Errorf("%w: pickup time %q is not available for address %d", ErrInvalidShipment, selection.PickupTimeID, address.AddressID)
+			}
+		} else if len(address.TimeSlots) > 0 {
+			selection.PickupTimeID = address.TimeSlots[0].PickupTimeID
+		} else {
+			return nil, fmt.Errorf("%w: no pickup time slot available for address %d", ErrInvalidShipment, address.AddressID)
 		}
 
-		if result.Status != "READY" {
-			return "", fmt.Errorf("document not ready, status: %s", result.Status)
+	case providers.ShippingMethodNonIntegrated:
+		if selection.TrackingNumber == "" {
+			return nil, fmt.Errorf("%w: tracking_number is required for non_integrated shipments", ErrInvalidShipment)
+		}
+		if len(options.ShippingProviders) == 0 {
+			break
+		}
+		if selection.ShippingProviderID == "" {
+			return nil, fmt.Errorf("%w: shipping_provider_id is required for non_integrated shipments", ErrInvalidShipment)
 		}
+		if !hasShippingProvider(options, selection.ShippingProviderID) {
+			return nil, fmt.Errorf("%w: shipping provider %q is not available", ErrInvalidShipment, selection.ShippingProviderID)
+		}
+	}
 
-		// Download the document URL
-		url, err := provider.DownloadShippingDocument(ctx, order.ExternalOrderID, documentType)
-		if err != nil {
-			return "", fmt.Errorf("failed to get download URL: %w", err)
+	return &selection, nil
+}
+
+// hasDropoffBranch reports whether a dropoff branch is offered
+func hasDropoffBranch(options *providers.ShippingOptions, branchID int64) bool {
+	for _, branch := range options.DropoffBranches {
+		if branch.BranchID == branchID {
+			return true
 		}
+	}
+	return false
+}
 
-		return url, nil
+// hasShippingProvider reports whether a shipping provider is offered
+func hasShippingProvider(options *providers.ShippingOptions, providerID string) bool {
+	for _, provider := range options.ShippingProviders {
+		if provider.ID == providerID {
+			return true
+		}
+	}
+	return false
+}
 
-	case "tiktok":
-		return "", fmt.Errorf("AWB download not yet implemented for TikTok")
+// findPickupAddress returns an offered pickup address, or nil
+func findPickupAddress(options *providers.ShippingOptions, addressID int64) *providers.PickupAddress {
+	for i := range options.PickupAddresses {
+		if options.PickupAddresses[i].AddressID == addressID {
+			return &options.PickupAddresses[i]
+		}
+	}
+	return nil
+}
 
-	default:
-		return "", ErrInvalidPlatform
+// defaultPickupAddress returns the preferred pickup address if offered, then
+// the marketplace default address, then the first address offered
+func defaultPickupAddress(options *providers.ShippingOptions, preferredID int64) *providers.PickupAddress {
+	if preferredID != 0 {
+		if address := findPickupAddress(options, preferredID); address != nil {
+			return address
+		}
+	}
+	for i := range options.PickupAddresses {
+		if options.PickupAddresses[i].IsDefault {
+			return &options.PickupAddresses[i]
+		}
+	}
+	if len(options.PickupAddresses) > 0 {
+		return &options.PickupAddresses[0]
 	}
+	return nil
 }
 
-// UpdateOrderStatus updates order status on the marketplace
+// UpdateOrderStatus updates order status on the marketplace. The status must be
+// a canonical order status the order can move to from its current status.
 func (s *OrderSyncService) UpdateOrderStatus(ctx context.Context, orderID uuid.UUID, status string) error {
+	next, err := shared.ParseOrderStatus(status)
+	if err != nil {
+		return err
+	}
+
 	order, err := s.orderRepo.GetByID(ctx, orderID)
 	if err != nil {
-		return fmt.Errorf("order not found: %w", err)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrOrderNotFound
+		}
+		return fmt.Errorf("failed to load order: %w", err)
+	}
+
+	if current := shared.OrderStatus(order.Status); current.IsValid() {
+		if err := current.ValidateTransition(next); err != nil {
+			return err
+		}
 	}
 
 	conn, err := s.connectionRepo.GetByID(ctx, order.ConnectionID)
@@ -535,7 +1360,12 @@ func (s *OrderSyncService) UpdateOrderStatus(ctx context.Context, orderID uuid.U
 		})
 		client.SetTokens(accessToken, shopID)
 		provider := shopee.NewOrderProvider(client)
-		if err := provider.UpdateOrderStatus(ctx, order.ExternalOrderID, status); err != nil {
+		if next == shared.OrderShipped {
+			// Ship with the connection's shipping defaults
+			if _, err := s.shipOrder(ctx, conn, provider, order, nil); err != nil {
+				return err
+			}
+		} else if err := provider.UpdateOrderStatus(ctx, order.ExternalOrderID, status); err != nil {
 			return err
 		}
 
@@ -547,7 +1377,12 @@ func (s *OrderSyncService) UpdateOrderStatus(ctx context.Context, orderID uuid.U
 		})
 		client.SetTokens(accessToken, conn.ShopID)
 		provider := tiktok.NewOrderProvider(client)
-		if err := provider.UpdateOrderStatus(ctx, order.ExternalOrderID, status); err != nil {
+		if next == shared.OrderShipped {
+			// Ship the package with the connection's shipping defaults
+			if _, err := s.shipOrder(ctx, conn, provider, order, nil); err != nil {
+				return err
+			}
+		} else if err := provider.UpdateOrderStatus(ctx, order.ExternalOrderID, status); err != nil {
 			return err
 		}
 
@@ -556,6 +1391,10 @@ func (s *OrderSyncService) UpdateOrderStatus(ctx context.Context, orderID uuid.U
 	}
 
 	// Update local record
-	order.Status = status
-	return s.orderRepo.Update(ctx, order)
+	change := s.applyStatus(order, next.String(), "", models.OrderStatusSourceAdmin)
+	if err := s.orderRepo.Update(ctx, order); err != nil {
+		return err
+	}
+	s.recordStatusChange(ctx, order, change)
+	return nil
 }
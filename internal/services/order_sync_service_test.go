@@ -0,0 +1,257 @@
+package services
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+	"gorm.io/datatypes"
+
+	"github.com/niaga-platform/service-marketplace/internal/models"
+	"github.com/niaga-platform/service-marketplace/internal/providers"
+)
+
+func TestServiceOrderReady(t *testing.T) {
+	internalID := uuid.New()
+
+	tests := []struct {
+		name  string
+		order models.MarketplaceOrder
+		want  bool
+	}{
+		{
+			name:  "new order with every line resolved",
+			order: models.MarketplaceOrder{ServiceOrderPending: true},
+			want:  true,
+		},
+		{
+			name:  "held while lines are unresolved",
+			order: models.MarketplaceOrder{ServiceOrderPending: true, UnresolvedItems: 2},
+			want:  false,
+		},
+		{
+			name:  "already created",
+			order: models.MarketplaceOrder{ServiceOrderPending: true, InternalOrderID: &internalID},
+			want:  false,
+		},
+		{
+			name:  "imported without push",
+			order: models.MarketplaceOrder{ServiceOrderPending: false},
+			want:  false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := serviceOrderReady(&tt.order); got != tt.want {
+				t.Errorf("serviceOrderReady() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestServiceOrderCreatedWhenLinesResolveLater(t *testing.T) {
+	// First import: one line has no mapping, so the create is held back
+	order := &models.MarketplaceOrder{ServiceOrderPending: true, UnresolvedItems: 1}
+	if serviceOrderReady(order) {
+		t.Fatal("order with an unresolved line is ready for service-order")
+	}
+
+	// A later update resolves the line after a mapping was added
+	order.UnresolvedItems = 0
+	if !serviceOrderReady(order) {
+		t.Fatal("order is not ready for service-order once every line resolves")
+	}
+
+	// createServiceOrder links the order and clears the pending flag
+	internalID := uuid.New()
+	order.InternalOrderID = &internalID
+	order.ServiceOrderPending = false
+	if serviceOrderReady(order) {
+		t.Fatal("linked order is ready for service-order again")
+	}
+}
+
+func TestResolveShipment(t *testing.T) {
+	options := &providers.ShippingOptions{
+		Methods:         []string{providers.ShippingMethodDropoff, providers.ShippingMethodPickup, providers.ShippingMethodNonIntegrated},
+		BranchRequired:  true,
+		DropoffBranches: []providers.DropoffBranch{{BranchID: 11}, {BranchID: 12}},
+		PickupAddresses: []providers.PickupAddress{
+			{AddressID: 21, TimeSlots: []providers.PickupTimeSlot{{PickupTimeID: "21-am"}}},
+			{AddressID: 22, IsDefault: true, TimeSlots: []providers.PickupTimeSlot{{PickupTimeID: "22-am"}, {PickupTimeID: "22-pm"}}},
+			{AddressID: 23},
+		},
+	}
+	pickupOnly := &providers.ShippingOptions{
+		Methods:         []string{providers.ShippingMethodPickup},
+		PickupAddresses: []providers.PickupAddress{{AddressID: 21, TimeSlots: []providers.PickupTimeSlot{{PickupTimeID: "21-am"}}}},
+	}
+	withCouriers := &providers.ShippingOptions{
+		Methods:           []string{providers.ShippingMethodNonIntegrated},
+		ShippingProviders: []providers.ShippingProvider{{ID: "courier-1"}},
+	}
+
+	tests := []struct {
+		name      string
+		options   *providers.ShippingOptions
+		requested *providers.ShipmentSelection
+		defaults  models.ShippingSettings
+		want      providers.ShipmentSelection
+		wantErr   bool
+	}{
+		{
+			name:    "dropoff first with the first branch",
+			options: options,
+			want:    providers.ShipmentSelection{Method: providers.ShippingMethodDropoff, BranchID: 11},
+		},
+		{
+			name:     "default dropoff branch",
+			options:  options,
+			defaults: models.ShippingSettings{DropoffBranchID: 12},
+			want:     providers.ShipmentSelection{Method: providers.ShippingMethodDropoff, BranchID: 12},
+		},
+		{
+			name:     "default branch no longer offered",
+			options:  options,
+			defaults: models.ShippingSettings{DropoffBranchID: 99},
+			want:     providers.ShipmentSelection{Method: providers.ShippingMethodDropoff, BranchID: 11},
+		},
+		{
+			name:      "requested branch",
+			options:   options,
+			requested: &providers.ShipmentSelection{Method: providers.ShippingMethodDropoff, BranchID: 12},
+			want:      providers.ShipmentSelection{Method: providers.ShippingMethodDropoff, BranchID: 12},
+		},
+		{
+			name:      "requested branch not offered",
+			options:   options,
+			requested: &providers.ShipmentSelection{Method: providers.ShippingMethodDropoff, BranchID: 99},
+			wantErr:   true,
+		},
+		{
+			name:     "default method pickup at the marketplace default address",
+			options:  options,
+			defaults: models.ShippingSettings{Method: providers.ShippingMethodPickup},
+			want:     providers.ShipmentSelection{Method: providers.ShippingMethodPickup, AddressID: 22, PickupTimeID: "22-am"},
+		},
+		{
+			name:     "default pickup address",
+			options:  options,
+			defaults: models.ShippingSettings{Method: providers.ShippingMethodPickup, PickupAddressID: 21},
+			want:     providers.ShipmentSelection{Method: providers.ShippingMethodPickup, AddressID: 21, PickupTimeID: "21-am"},
+		},
+		{
+			name:      "requested pickup time",
+			options:   options,
+			requested: &providers.ShipmentSelection{Method: providers.ShippingMethodPickup, AddressID: 22, PickupTimeID: "22-pm"},
+			want:      providers.ShipmentSelection{Method: providers.ShippingMethodPickup, AddressID: 22, PickupTimeID: "22-pm"},
+		},
+		{
+			name:      "pickup time of another address",
+			options:   options,
+			requested: &providers.ShipmentSelection{Method: providers.ShippingMethodPickup, AddressID: 21, PickupTimeID: "22-pm"},
+			wantErr:   true,
+		},
+		{
+			name:      "address without time slots",
+			options:   options,
+			requested: &providers.ShipmentSelection{Method: providers.ShippingMethodPickup, AddressID: 23},
+			wantErr:   true,
+		},
+		{
+			name:      "requested address not offered",
+			options:   options,
+			requested: &providers.ShipmentSelection{Method: providers.ShippingMethodPickup, AddressID: 99},
+			wantErr:   true,
+		},
+		{
+			name:     "default method not offered",
+			options:  pickupOnly,
+			defaults: models.ShippingSettings{Method: providers.ShippingMethodDropoff},
+			want:     providers.ShipmentSelection{Method: providers.ShippingMethodPickup, AddressID: 21, PickupTimeID: "21-am"},
+		},
+		{
+			name:      "requested method not offered",
+			options:   pickupOnly,
+			requested: &providers.ShipmentSelection{Method: providers.ShippingMethodDropoff},
+			wantErr:   true,
+		},
+		{
+			name:    "only non integrated offered",
+			options: withCouriers,
+			wantErr: true,
+		},
+		{
+			name:      "non integrated without tracking number",
+			options:   options,
+			requested: &providers.ShipmentSelection{Method: providers.ShippingMethodNonIntegrated},
+			wantErr:   true,
+		},
+		{
+			name:      "non integrated without courier list",
+			options:   options,
+			requested: &providers.ShipmentSelection{Method: providers.ShippingMethodNonIntegrated, TrackingNumber: "TRK1"},
+			want:      providers.ShipmentSelection{Method: providers.ShippingMethodNonIntegrated, TrackingNumber: "TRK1"},
+		},
+		{
+			name:      "non integrated with offered courier",
+			options:   withCouriers,
+			requested: &providers.ShipmentSelection{Method: providers.ShippingMethodNonIntegrated, TrackingNumber: "TRK1", ShippingProviderID: "courier-1"},
+			want:      providers.ShipmentSelection{Method: providers.ShippingMethodNonIntegrated, TrackingNumber: "TRK1", ShippingProviderID: "courier-1"},
+		},
+		{
+			name:      "non integrated without courier",
+			options:   withCouriers,
+			requested: &providers.ShipmentSelection{Method: providers.ShippingMethodNonIntegrated, TrackingNumber: "TRK1"},
+			wantErr:   true,
+		},
+		{
+			name:      "non integrated with unknown courier",
+			options:   withCouriers,
+			requested: &providers.ShipmentSelection{Method: providers.ShippingMethodNonIntegrated, TrackingNumber: "TRK1", ShippingProviderID: "courier-9"},
+			wantErr:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := resolveShipment(tt.options, tt.requested, tt.defaults)
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidShipment) {
+					t.Fatalf("resolveShipment() error = %v, want %v", err, ErrInvalidShipment)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("resolveShipment() error = %v", err)
+			}
+			if *got != tt.want {
+				t.Errorf("resolveShipment() = %+v, want %+v", *got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFulfillmentTracking(t *testing.T) {
+	tests := []struct {
+		name     string
+		tracking string
+		want     providers.TrackingInfo
+	}{
+		{name: "none kept", tracking: "", want: providers.TrackingInfo{}},
+		{name: "courier and tracking number", tracking: `{"courier":"J&T","tracking_number":"TRK1"}`, want: providers.TrackingInfo{Courier: "J&T", TrackingNumber: "TRK1"}},
+		{name: "marketplace logistics", tracking: `{"courier":"","tracking_number":""}`, want: providers.TrackingInfo{}},
+		{name: "unreadable", tracking: `not json`, want: providers.TrackingInfo{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			order := &models.MarketplaceOrder{FulfillmentTracking: datatypes.JSON(tt.tracking)}
+			got := fulfillmentTracking(order)
+			if got.Courier != tt.want.Courier || got.TrackingNumber != tt.want.TrackingNumber {
+				t.Errorf("fulfillmentTracking() = %+v, want %+v", *got, tt.want)
+			}
+		})
+	}
+}
@@ -596,3 +596,81 @@ func (s *ProductSyncService) DeleteManualMapping(ctx context.Context, mappingID
 	// Delete the mapping
 	return s.productMappingRepo.Delete(ctx, mappingID)
 }
+
+// HandleListingStatusChange records a marketplace-side listing status change
+// (ban, deletion, unlisting) on the product mapping of the listing
+func (s *ProductSyncService) HandleListingStatusChange(ctx context.Context, platform, shopID, externalProductID, status, reason string) error {
+	conn, err := s.connectionRepo.GetByPlatformAndShopID(ctx, platform, shopID)
+	if err != nil {
+		return ErrConnectionNotFound
+	}
+
+	mapping, err := s.productMappingRepo.GetByConnectionAndExternalProduct(ctx, conn.ID, externalProductID)
+	if err != nil {
+		// Listings that were never mapped have nothing to flag
+		s.logger.Debug("Listing status change for unmapped product",
+			zap.String("platform", platform),
+			zap.String("external_product_id", externalProductID),
+			zap.String("status", status),
+		)
+		return nil
+	}
+
+	if err := s.productMappingRepo.UpdateListingStatus(ctx, mapping.ID, status, reason); err != nil {
+		return fmt.Errorf("failed to update listing status: %w", err)
+	}
+
+	s.logger.Warn("Marketplace listing status changed",
+		zap.String("mapping_id", mapping.ID.String()),
+		zap.String("external_product_id", externalProductID),
+		zap.String("status", status),
+		zap.String("reason", reason),
+	)
+	return nil
+}
+
+// HandleShopeeItemViolation flags the mapping of a Shopee item that was banned,
+// deleted or unlisted for a policy violation
+func (s *ProductSyncService) HandleShopeeItemViolation(shopID int64, data *shopee.ViolationItemData) error {
+	status := models.ListingStatusNormal
+	switch data.ItemStatus {
+	case "BANNED":
+		status = models.ListingStatusBanned
+	case "SHOPEE_DELETE", "DELETED":
+		status = models.ListingStatusDeleted
+	case "UNLIST":
+		status = models.ListingStatusUnlisted
+	}
+
+	reason := data.ViolationReason
+	if reason == "" {
+		reason = data.ViolationType
+	}
+
+	return s.HandleListingStatusChange(context.Background(), "shopee",
+		strconv.FormatInt(shopID, 10), strconv.FormatInt(data.ItemID, 10), status, reason)
+}
+
+// HandleTikTokProductStatus records the audit outcome of a TikTok product on its
+// mapping. Statuses that say nothing about visibility (e.g. pending audit) are ignored.
+func (s *ProductSyncService) HandleTikTokProductStatus(shopID string, data *tiktok.ProductStatusData) error {
+	var status string
+	switch data.Status {
+	case "ACTIVATE", "LIVE":
+		status = models.ListingStatusNormal
+	case "FAILED", "FREEZE", "PLATFORM_DEACTIVATED":
+		status = models.ListingStatusBanned
+	case "SELLER_DEACTIVATED":
+		status = models.ListingStatusUnlisted
+	case "DELETED":
+		status = models.ListingStatusDeleted
+	default:
+		s.logger.Debug("Ignoring TikTok product status",
+			zap.String("product_id", data.ProductID),
+			zap.String("status", data.Status),
+		)
+		return nil
+	}
+
+	return s.HandleListingStatusChange(context.Background(), "tiktok", shopID, data.ProductID, status, data.SuspendedReason)
+}
@@ -0,0 +1,527 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"gorm.io/datatypes"
+
+	"github.com/niaga-platform/service-marketplace/internal/models"
+	"github.com/niaga-platform/service-marketplace/internal/providers"
+	"github.com/niaga-platform/service-marketplace/internal/repository"
+)
+
+var (
+	ErrReturnRuleNotFound          = errors.New("return rule not found")
+	ErrInvalidReturnRule           = errors.New("invalid return rule")
+	ErrReturnDecisionNotFound      = errors.New("return decision not found")
+	ErrReturnDecisionNotRevertible = errors.New("return decision is no longer scheduled and cannot be reverted")
+)
+
+// deadlineMargin is how long before a return's ship due date a scheduled
+// decision is executed at the latest
+const deadlineMargin = time.Hour
+
+// ReturnRuleConfig holds configuration for the return decision runner
+type ReturnRuleConfig struct {
+	Interval  time.Duration // How often due decisions are executed
+	BatchSize int           // Decisions executed per tick
+}
+
+// ReturnRuleService evaluates new marketplace returns against per-connection
+// rules. Accept and dispute decisions are scheduled and executed later by a
+// background runner, so an admin can revert them until then; returns no rule
+// decides are queued for human review.
+type ReturnRuleService struct {
+	connectionRepo     *repository.ConnectionRepository
+	orderRepo          *repository.MarketplaceOrderRepository
+	statusHistoryRepo  *repository.OrderStatusHistoryRepository
+	returnRepo         *repository.ReturnRepository
+	productMappingRepo *repository.ProductMappingRepository
+	ruleRepo           *repository.ReturnRuleRepository
+	decisionRepo       *repository.ReturnDecisionRepository
+	returnService      *ReturnSyncService
+	config             ReturnRuleConfig
+	logger             *zap.Logger
+
+	// Lifecycle management
+	stopChan chan struct{}
+	wg       sync.WaitGroup
+	running  bool
+	mu       sync.Mutex
+}
+
+// NewReturnRuleService creates a new ReturnRuleService
+func NewReturnRuleService(
+	connectionRepo *repository.ConnectionRepository,
+	orderRepo *repository.MarketplaceOrderRepository,
+	statusHistoryRepo *repository.OrderStatusHistoryRepository,
+	returnRepo *repository.ReturnRepository,
+	productMappingRepo *repository.ProductMappingRepository,
+	ruleRepo *repository.ReturnRuleRepository,
+	decisionRepo *repository.ReturnDecisionRepository,
+	returnService *ReturnSyncService,
+	cfg ReturnRuleConfig,
+	logger *zap.Logger,
+) *ReturnRuleService {
+	// Set defaults
+	if cfg.Interval == 0 {
+		cfg.Interval = time.Minute
+	}
+	if cfg.BatchSize == 0 {
+		cfg.BatchSize = 50
+	}
+
+	return &ReturnRuleService{
+		connectionRepo:     connectionRepo,
+		orderRepo:          orderRepo,
+		statusHistoryRepo:  statusHistoryRepo,
+		returnRepo:         returnRepo,
+		productMappingRepo: productMappingRepo,
+		ruleRepo:           ruleRepo,
+		decisionRepo:       decisionRepo,
+		returnService:      returnService,
+		config:             cfg,
+		logger:             logger,
+		stopChan:           make(chan struct{}),
+	}
+}
+
+// Start begins executing due decisions in the background.
+func (s *ReturnRuleService) Start(ctx context.Context) error {
+	s.mu.Lock()
+	if s.running {
+		s.mu.Unlock()
+		return fmt.Errorf("return decision runner already running")
+	}
+	s.running = true
+	s.mu.Unlock()
+
+	// Decisions interrupted mid-execution are retried; accepting or disputing
+	// twice is rejected by the marketplace and recorded as skipped or failed
+	if reset, err := s.decisionRepo.ResetExecuting(ctx); err != nil {
+		s.logger.Warn("Failed to reset interrupted return decisions", zap.Error(err))
+	} else if reset > 0 {
+		s.logger.Info("Rescheduled interrupted return decisions", zap.Int64("count", reset))
+	}
+
+	s.wg.Add(1)
+	go s.run(ctx)
+
+	s.logger.Info("return decision runner started", zap.Duration("interval", s.config.Interval))
+
+	return nil
+}
+
+// Stop gracefully stops the background runner.
+func (s *ReturnRuleService) Stop() {
+	s.mu.Lock()
+	if !s.running {
+		s.mu.Unlock()
+		return
+	}
+	s.running = false
+	s.mu.Unlock()
+
+	close(s.stopChan)
+	s.wg.Wait()
+
+	s.logger.Info("return decision runner stopped")
+}
+
+// run is the main background loop.
+func (s *ReturnRuleService) run(ctx context.Context) {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(s.config.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-s.stopChan:
+			return
+		case <-ticker.C:
+			s.executeDue(ctx)
+		}
+	}
+}
+
+// GetRules lists the rules of a connection in evaluation order
+func (s *ReturnRuleService) GetRules(ctx context.Context, connectionID uuid.UUID) ([]models.ReturnRule, error) {
+	return s.ruleRepo.GetByConnectionID(ctx, connectionID)
+}
+
+// CreateRule creates a return rule for a connection
+func (s *ReturnRuleService) CreateRule(ctx context.Context, connectionID uuid.UUID, req *models.ReturnRuleRequest) (*models.ReturnRule, error) {
+	conn, err := s.connectionRepo.GetByID(ctx, connectionID)
+	if err != nil {
+		return nil, ErrConnectionNotFound
+	}
+
+	rule := &models.ReturnRule{ConnectionID: connectionID}
+	if err := s.applyRuleRequest(conn, rule, req); err != nil {
+		return nil, err
+	}
+
+	if err := s.ruleRepo.Create(ctx, rule); err != nil {
+		return nil, fmt.Errorf("failed to create return rule: %w", err)
+	}
+	return rule, nil
+}
+
+// UpdateRule replaces a return rule of a connection
+func (s *ReturnRuleService) UpdateRule(ctx context.Context, connectionID, ruleID uuid.UUID, req *models.ReturnRuleRequest) (*models.ReturnRule, error) {
+	conn, err := s.connectionRepo.GetByID(ctx, connectionID)
+	if err != nil {
+		return nil, ErrConnectionNotFound
+	}
+
+	rule, err := s.ruleRepo.GetByID(ctx, ruleID)
+	if err != nil || rule.ConnectionID != connectionID {
+		return nil, ErrReturnRuleNotFound
+	}
+
+	if err := s.applyRuleRequest(conn, rule, req); err != nil {
+		return nil, err
+	}
+
+	if err := s.ruleRepo.Update(ctx, rule); err != nil {
+		return nil, fmt.Errorf("failed to update return rule: %w", err)
+	}
+	return rule, nil
+}
+
+// DeleteRule deletes a return rule of a connection. Decisions it took are kept.
+func (s *ReturnRuleService) DeleteRule(ctx context.Context, connectionID, ruleID uuid.UUID) error {
+	rule, err := s.ruleRepo.GetByID(ctx, ruleID)
+	if err != nil || rule.ConnectionID != connectionID {
+		return ErrReturnRuleNotFound
+	}
+	return s.ruleRepo.Delete(ctx, ruleID)
+}
+
+// GetDecisions lists the automatic decisions of a connection. Filtering on the
+// review status gives the human review queue.
+func (s *ReturnRuleService) GetDecisions(ctx context.Context, connectionID uuid.UUID, filter *models.ReturnDecisionFilter) ([]models.ReturnDecision, int64, error) {
+	return s.decisionRepo.GetByConnectionID(ctx, connectionID, filter)
+}
+
+// RevertDecision cancels the scheduled decision on a return, leaving the return
+// for a human to handle
+func (s *ReturnRuleService) RevertDecision(ctx context.Context, connectionID, returnID uuid.UUID, reason string) (*models.ReturnDecision, error) {
+	decision, err := s.decisionRepo.GetByReturnID(ctx, returnID)
+	if err != nil || decision.ConnectionID != connectionID {
+		return nil, ErrReturnDecisionNotFound
+	}
+
+	reverted, err := s.decisionRepo.Revert(ctx, decision.ID, reason)
+	if err != nil {
+		return nil, fmt.Errorf("failed to revert return decision: %w", err)
+	}
+	if !reverted {
+		return nil, ErrReturnDecisionNotRevertible
+	}
+
+	s.logger.Info("Return decision reverted",
+		zap.String("decision_id", decision.ID.String()),
+		zap.String("return_id", returnID.String()),
+		zap.String("action", decision.Action),
+	)
+	return s.decisionRepo.GetByID(ctx, decision.ID)
+}
+
+// EvaluateReturn takes the automatic decision on a new return. Connections
+// without enabled rules are left alone, and a return is only ever decided once.
+func (s *ReturnRuleService) EvaluateReturn(ctx context.Context, conn *models.Connection, ret *models.MarketplaceReturn) error {
+	rules, err := s.ruleRepo.GetEnabledByConnectionID(ctx, conn.ID)
+	if err != nil {
+		return fmt.Errorf("failed to load return rules: %w", err)
+	}
+	if len(rules) == 0 {
+		return nil
+	}
+
+	if _, err := s.decisionRepo.GetByReturnID(ctx, ret.ID); err == nil {
+		return nil
+	}
+
+	inputs := s.decisionInputs(ctx, conn, ret)
+	inputsJSON, err := json.Marshal(inputs)
+	if err != nil {
+		return fmt.Errorf("failed to encode decision inputs: %w", err)
+	}
+
+	decision := &models.ReturnDecision{
+		ReturnID:     ret.ID,
+		ConnectionID: conn.ID,
+		Action:       models.ReturnActionReview,
+		Status:       models.ReturnDecisionReview,
+		Inputs:       datatypes.JSON(inputsJSON),
+		Deadline:     ret.ShipDueDate,
+	}
+
+	for i := range rules {
+		rule := &rules[i]
+		if !rule.GetConditions().Matches(inputs) {
+			continue
+		}
+
+		decision.RuleID = &rule.ID
+		decision.RuleName = rule.Name
+		decision.Action = rule.Action
+		if rule.Action != models.ReturnActionReview {
+			executeAt := s.executeAt(conn, ret, time.Now())
+			decision.Status = models.ReturnDecisionScheduled
+			decision.ExecuteAt = &executeAt
+			decision.DisputeReason = rule.DisputeReason
+			decision.DisputeReasonCode = rule.DisputeReasonCode
+			decision.DisputeEmail = rule.DisputeEmail
+		}
+		break
+	}
+
+	created, err := s.decisionRepo.Create(ctx, decision)
+	if err != nil {
+		return fmt.Errorf("failed to record return decision: %w", err)
+	}
+	if !created {
+		return nil
+	}
+
+	s.logger.Info("Return decision recorded",
+		zap.String("return_id", ret.ID.String()),
+		zap.String("external_return_id", ret.ExternalReturnID),
+		zap.String("rule", decision.RuleName),
+		zap.String("action", decision.Action),
+		zap.String("status", decision.Status),
+	)
+	return nil
+}
+
+// decisionInputs gathers the values rules are evaluated against
+func (s *ReturnRuleService) decisionInputs(ctx context.Context, conn *models.Connection, ret *models.MarketplaceReturn) *models.ReturnDecisionInputs {
+	inputs := &models.ReturnDecisionInputs{
+		Reason:       ret.Reason,
+		Type:         ret.ReturnType,
+		RefundAmount: ret.RefundAmount,
+		ProductIDs:   []string{},
+	}
+
+	var items []providers.ExternalReturnItem
+	_ = json.Unmarshal(ret.Items, &items)
+	if len(items) > 0 {
+		seen := make(map[string]bool)
+		for _, item := range items {
+			mapping, err := s.productMappingRepo.GetByConnectionAndExternalProduct(ctx, conn.ID, item.ExternalProductID)
+			if err != nil {
+				continue
+			}
+			productID := mapping.InternalProductID.String()
+			if !seen[productID] {
+				seen[productID] = true
+				inputs.ProductIDs = append(inputs.ProductIDs, productID)
+			}
+		}
+	}
+
+	if ret.BuyerID != "" && ret.BuyerID != "0" {
+		if count, err := s.returnRepo.CountByBuyer(ctx, conn.ID, ret.BuyerID, ret.ID); err == nil {
+			buyerReturns := int(count)
+			inputs.BuyerReturns = &buyerReturns
+		}
+	}
+
+	if ret.OrderID != nil {
+		if order, err := s.orderRepo.GetByID(ctx, *ret.OrderID); err == nil {
+			if deliveredAt := s.orderDeliveredAt(ctx, order); deliveredAt != nil {
+				days := int(time.Since(*deliveredAt).Hours() / 24)
+				inputs.DaysSinceDelivery = &days
+			}
+			var orderData models.OrderDataJSON
+			if err := json.Unmarshal(order.OrderData, &orderData); err == nil {
+				inputs.OnSale = returnedOnSale(items, orderData.Items)
+			}
+		}
+	}
+
+	return inputs
+}
+
+// returnedOnSale reports whether any returned item was sold below its original
+// price, matching items to order lines by product and variant. It is nil when
+// no returned item was sold on sale and the original price of some is unknown.
+func returnedOnSale(items []providers.ExternalReturnItem, lines []models.OrderItemJSON) *bool {
+	if len(items) == 0 {
+		return nil
+	}
+
+	onSale, known := false, true
+	for _, item := range items {
+		var line *models.OrderItemJSON
+		for i := range lines {
+			if lines[i].ExternalProductID == item.ExternalProductID &&
+				(item.ExternalVariantID == "" || lines[i].ExternalVariantID == item.ExternalVariantID) {
+				line = &lines[i]
+				break
+			}
+		}
+		if line == nil || line.OriginalPrice <= 0 {
+			known = false
+			continue
+		}
+		if line.UnitPrice < line.OriginalPrice {
+			onSale = true
+		}
+	}
+
+	if !onSale && !known {
+		return nil
+	}
+	return &onSale
+}
+
+// orderDeliveredAt returns when an order first reached delivered or completed
+// according to its status history. Orders imported before the history was kept
+// fall back to their last update.
+func (s *ReturnRuleService) orderDeliveredAt(ctx context.Context, order *models.MarketplaceOrder) *time.Time {
+	entry, err := s.statusHistoryRepo.GetFirstReached(ctx, order.ID, []string{models.OrderStatusDelivered, models.OrderStatusCompleted})
+	if err == nil {
+		return &entry.CreatedAt
+	}
+
+	switch order.Status {
+	case models.OrderStatusDelivered, models.OrderStatusCompleted:
+		deliveredAt := order.UpdatedAt
+		return &deliveredAt
+	default:
+		return nil
+	}
+}
+
+// executeAt schedules a decision made at now after the connection's hold, but
+// no later than just before the return's platform deadline
+func (s *ReturnRuleService) executeAt(conn *models.Connection, ret *models.MarketplaceReturn, now time.Time) time.Time {
+	holdHours := conn.GetSettings().Returns.DecisionHoldHours
+	if holdHours <= 0 {
+		holdHours = 24
+	}
+
+	executeAt := now.Add(time.Duration(holdHours) * time.Hour)
+	if ret.ShipDueDate != nil {
+		if latest := ret.ShipDueDate.Add(-deadlineMargin); executeAt.After(latest) {
+			executeAt = latest
+		}
+	}
+	if executeAt.Before(now) {
+		executeAt = now
+	}
+	return executeAt
+}
+
+// executeDue executes scheduled decisions whose hold has passed
+func (s *ReturnRuleService) executeDue(ctx context.Context) {
+	decisions, err := s.decisionRepo.GetDue(ctx, s.config.BatchSize)
+	if err != nil {
+		s.logger.Error("Failed to load due return decisions", zap.Error(err))
+		return
+	}
+
+	for i := range decisions {
+		s.execute(ctx, &decisions[i])
+	}
+}
+
+// execute accepts or disputes a return on the marketplace for a claimed decision
+func (s *ReturnRuleService) execute(ctx context.Context, decision *models.ReturnDecision) {
+	claimed, err := s.decisionRepo.Claim(ctx, decision.ID)
+	if err != nil {
+		s.logger.Error("Failed to claim return decision", zap.String("decision_id", decision.ID.String()), zap.Error(err))
+		return
+	}
+	if !claimed {
+		// Reverted or picked up by another instance
+		return
+	}
+
+	switch decision.Action {
+	case models.ReturnActionAccept:
+		_, err = s.returnService.AcceptReturn(ctx, decision.ConnectionID, decision.ReturnID)
+	case models.ReturnActionDispute:
+		_, err = s.returnService.DisputeReturn(ctx, decision.ConnectionID, decision.ReturnID, &models.DisputeReturnRequest{
+			Email:      decision.DisputeEmail,
+			Reason:     decision.DisputeReason,
+			ReasonCode: decision.DisputeReasonCode,
+		})
+	default:
+		err = fmt.Errorf("unknown return decision action %q", decision.Action)
+	}
+
+	status := models.ReturnDecisionExecuted
+	errorMessage := ""
+	switch {
+	case errors.Is(err, ErrReturnNotActionable):
+		// Handled by hand, or moved on at the marketplace, before the hold passed
+		status = models.ReturnDecisionSkipped
+		errorMessage = err.Error()
+	case err != nil:
+		status = models.ReturnDecisionFailed
+		errorMessage = err.Error()
+	}
+
+	if err := s.decisionRepo.Finish(ctx, decision.ID, status, errorMessage); err != nil {
+		s.logger.Error("Failed to record return decision outcome", zap.String("decision_id", decision.ID.String()), zap.Error(err))
+	}
+
+	s.logger.Info("Return decision executed",
+		zap.String("decision_id", decision.ID.String()),
+		zap.String("return_id", decision.ReturnID.String()),
+		zap.String("action", decision.Action),
+		zap.String("status", status),
+		zap.String("error", errorMessage),
+	)
+}
+
+// applyRuleRequest validates a rule request and copies it onto the rule
+func (s *ReturnRuleService) applyRuleRequest(conn *models.Connection, rule *models.ReturnRule, req *models.ReturnRuleRequest) error {
+	if req.Action == models.ReturnActionDispute {
+		if req.DisputeReason == "" {
+			return fmt.Errorf("%w: dispute_reason is required for dispute rules", ErrInvalidReturnRule)
+		}
+		switch conn.Platform {
+		case "shopee":
+			if req.DisputeEmail == "" {
+				return fmt.Errorf("%w: dispute_email is required for Shopee dispute rules", ErrInvalidReturnRule)
+			}
+		case "tiktok":
+			if req.DisputeReasonCode == "" {
+				return fmt.Errorf("%w: dispute_reason_code is required for TikTok dispute rules", ErrInvalidReturnRule)
+			}
+		}
+	}
+
+	conditions, err := json.Marshal(req.Conditions)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidReturnRule, err)
+	}
+
+	rule.Name = req.Name
+	rule.Priority = models.DefaultReturnRulePriority
+	if req.Priority != nil {
+		rule.Priority = *req.Priority
+	}
+	rule.Enabled = req.Enabled == nil || *req.Enabled
+	rule.Action = req.Action
+	rule.Conditions = datatypes.JSON(conditions)
+	rule.DisputeReason = req.DisputeReason
+	rule.DisputeReasonCode = req.DisputeReasonCode
+	rule.DisputeEmail = req.DisputeEmail
+	return nil
+}
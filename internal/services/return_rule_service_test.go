@@ -0,0 +1,130 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"gorm.io/datatypes"
+
+	"github.com/niaga-platform/service-marketplace/internal/models"
+	"github.com/niaga-platform/service-marketplace/internal/providers"
+)
+
+func TestReturnRuleExecuteAt(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	dueIn := func(d time.Duration) *time.Time {
+		due := now.Add(d)
+		return &due
+	}
+
+	tests := []struct {
+		name        string
+		settings    string
+		shipDueDate *time.Time
+		want        time.Time
+	}{
+		{name: "default hold", settings: `{}`, want: now.Add(24 * time.Hour)},
+		{name: "configured hold", settings: `{"returns":{"decision_hold_hours":6}}`, want: now.Add(6 * time.Hour)},
+		{name: "deadline after hold", settings: `{"returns":{"decision_hold_hours":6}}`, shipDueDate: dueIn(48 * time.Hour), want: now.Add(6 * time.Hour)},
+		{name: "clamped before deadline", settings: `{}`, shipDueDate: dueIn(10 * time.Hour), want: now.Add(10*time.Hour - deadlineMargin)},
+		{name: "deadline within margin", settings: `{}`, shipDueDate: dueIn(30 * time.Minute), want: now},
+		{name: "deadline passed", settings: `{}`, shipDueDate: dueIn(-time.Hour), want: now},
+	}
+
+	service := &ReturnRuleService{}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conn := &models.Connection{Settings: datatypes.JSON(tt.settings)}
+			ret := &models.MarketplaceReturn{ShipDueDate: tt.shipDueDate}
+
+			if got := service.executeAt(conn, ret, now); !got.Equal(tt.want) {
+				t.Errorf("executeAt() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReturnedOnSale(t *testing.T) {
+	lines := []models.OrderItemJSON{
+		{ExternalProductID: "1", ExternalVariantID: "10", UnitPrice: 80, OriginalPrice: 100},
+		{ExternalProductID: "1", ExternalVariantID: "11", UnitPrice: 100, OriginalPrice: 100},
+		{ExternalProductID: "2", UnitPrice: 50, OriginalPrice: 50},
+		{ExternalProductID: "3", UnitPrice: 30},
+	}
+
+	tests := []struct {
+		name  string
+		items []providers.ExternalReturnItem
+		want  *bool
+	}{
+		{name: "no items", items: nil, want: nil},
+		{name: "discounted variant", items: []providers.ExternalReturnItem{{ExternalProductID: "1", ExternalVariantID: "10"}}, want: boolPtr(true)},
+		{name: "full price variant", items: []providers.ExternalReturnItem{{ExternalProductID: "1", ExternalVariantID: "11"}}, want: boolPtr(false)},
+		{name: "full price product", items: []providers.ExternalReturnItem{{ExternalProductID: "2"}}, want: boolPtr(false)},
+		{name: "original price unknown", items: []providers.ExternalReturnItem{{ExternalProductID: "3"}}, want: nil},
+		{name: "line not found", items: []providers.ExternalReturnItem{{ExternalProductID: "4"}}, want: nil},
+		{
+			name:  "one discounted line is enough",
+			items: []providers.ExternalReturnItem{{ExternalProductID: "3"}, {ExternalProductID: "1", ExternalVariantID: "10"}},
+			want:  boolPtr(true),
+		},
+		{
+			name:  "full price and unknown",
+			items: []providers.ExternalReturnItem{{ExternalProductID: "2"}, {ExternalProductID: "3"}},
+			want:  nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := returnedOnSale(tt.items, lines)
+			switch {
+			case got == nil && tt.want == nil:
+			case got == nil || tt.want == nil || *got != *tt.want:
+				t.Errorf("returnedOnSale() = %v, want %v", describeBool(got), describeBool(tt.want))
+			}
+		})
+	}
+}
+
+func TestApplyRuleRequestPriority(t *testing.T) {
+	explicit := 5
+	zero := 0
+
+	tests := []struct {
+		name     string
+		priority *int
+		want     int
+	}{
+		{name: "omitted", priority: nil, want: models.DefaultReturnRulePriority},
+		{name: "explicit", priority: &explicit, want: 5},
+		{name: "explicit zero", priority: &zero, want: 0},
+	}
+
+	service := &ReturnRuleService{}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule := &models.ReturnRule{}
+			req := &models.ReturnRuleRequest{Name: "rule", Action: models.ReturnActionAccept, Priority: tt.priority}
+
+			if err := service.applyRuleRequest(&models.Connection{Platform: "shopee"}, rule, req); err != nil {
+				t.Fatalf("applyRuleRequest() error = %v", err)
+			}
+			if rule.Priority != tt.want {
+				t.Errorf("Priority = %d, want %d", rule.Priority, tt.want)
+			}
+		})
+	}
+}
+
+func boolPtr(v bool) *bool { return &v }
+
+func describeBool(v *bool) string {
+	if v == nil {
+		return "nil"
+	}
+	if *v {
+		return "true"
+	}
+	return "false"
+}
@@ -0,0 +1,669 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"gorm.io/datatypes"
+
+	"github.com/niaga-platform/service-marketplace/internal/clients"
+	"github.com/niaga-platform/service-marketplace/internal/events"
+	"github.com/niaga-platform/service-marketplace/internal/models"
+	"github.com/niaga-platform/service-marketplace/internal/providers"
+	"github.com/niaga-platform/service-marketplace/internal/providers/shopee"
+	"github.com/niaga-platform/service-marketplace/internal/providers/tiktok"
+	"github.com/niaga-platform/service-marketplace/internal/repository"
+	"github.com/niaga-platform/service-marketplace/internal/utils"
+)
+
+var (
+	ErrReturnNotFound      = errors.New("return not found")
+	ErrReturnNotActionable = errors.New("return cannot be accepted or disputed in its current status")
+	ErrReturnsNotSupported = errors.New("returns are not supported for this platform")
+	ErrInvalidDispute      = errors.New("invalid dispute request")
+)
+
+// ReturnDecisionMaker takes the automatic decision on a newly requested return
+type ReturnDecisionMaker interface {
+	EvaluateReturn(ctx context.Context, conn *models.Connection, ret *models.MarketplaceReturn) error
+}
+
+// ReturnSyncService pulls marketplace returns into marketplace.returns and lets
+// the CS team accept or dispute them without leaving the admin. When a return is
+// refunded, the connection's return settings decide whether the items are
+// restocked and the internal order refunded.
+type ReturnSyncService struct {
+	connectionRepo     *repository.ConnectionRepository
+	orderRepo          *repository.MarketplaceOrderRepository
+	returnRepo         *repository.ReturnRepository
+	productMappingRepo *repository.ProductMappingRepository
+	orderClient        *clients.OrderClient
+	publisher          *events.Publisher
+	decisionMaker      ReturnDecisionMaker
+	encryptor          *utils.Encryptor
+	logger             *zap.Logger
+
+	shopeePartnerID  string
+	shopeePartnerKey string
+	shopeeSandbox    bool
+	tiktokAppKey     string
+	tiktokAppSecret  string
+}
+
+// ReturnSyncServiceConfig holds configuration for ReturnSyncService
+type ReturnSyncServiceConfig struct {
+	ShopeePartnerID  string
+	ShopeePartnerKey string
+	ShopeeSandbox    bool
+	TikTokAppKey     string
+	TikTokAppSecret  string
+	EncryptionKey    string
+}
+
+// NewReturnSyncService creates a new ReturnSyncService
+func NewReturnSyncService(
+	connectionRepo *repository.ConnectionRepository,
+	orderRepo *repository.MarketplaceOrderRepository,
+	returnRepo *repository.ReturnRepository,
+	productMappingRepo *repository.ProductMappingRepository,
+	orderClient *clients.OrderClient,
+	publisher *events.Publisher,
+	cfg *ReturnSyncServiceConfig,
+	logger *zap.Logger,
+) (*ReturnSyncService, error) {
+	var encryptor *utils.Encryptor
+	if cfg.EncryptionKey != "" {
+		var err error
+		encryptor, err = utils.NewEncryptor(cfg.EncryptionKey)
+		if err != nil {
+			logger.Warn("Failed to initialize encryptor", zap.Error(err))
+		}
+	}
+
+	return &ReturnSyncService{
+		connectionRepo:     connectionRepo,
+		orderRepo:          orderRepo,
+		returnRepo:         returnRepo,
+		productMappingRepo: productMappingRepo,
+		orderClient:        orderClient,
+		publisher:          publisher,
+		encryptor:          encryptor,
+		logger:             logger,
+		shopeePartnerID:    cfg.ShopeePartnerID,
+		shopeePartnerKey:   cfg.ShopeePartnerKey,
+		shopeeSandbox:      cfg.ShopeeSandbox,
+		tiktokAppKey:       cfg.TikTokAppKey,
+		tiktokAppSecret:    cfg.TikTokAppSecret,
+	}, nil
+}
+
+// SetDecisionMaker registers the rules engine evaluated on newly requested returns
+func (s *ReturnSyncService) SetDecisionMaker(decisionMaker ReturnDecisionMaker) {
+	s.decisionMaker = decisionMaker
+}
+
+// GetReturns lists stored returns for a connection
+func (s *ReturnSyncService) GetReturns(ctx context.Context, connectionID uuid.UUID, filter *models.MarketplaceReturnFilter) ([]models.MarketplaceReturn, int64, error) {
+	return s.returnRepo.GetByConnectionID(ctx, connectionID, filter)
+}
+
+// GetReturn retrieves a stored return of a connection
+func (s *ReturnSyncService) GetReturn(ctx context.Context, connectionID, returnID uuid.UUID) (*models.MarketplaceReturn, error) {
+	ret, err := s.returnRepo.GetByID(ctx, returnID)
+	if err != nil || ret.ConnectionID != connectionID {
+		return nil, ErrReturnNotFound
+	}
+	return ret, nil
+}
+
+// SyncReturns pulls returns in the given window from the marketplace and
+// upserts them. Shopee filters on creation time, TikTok on last update. It
+// returns the number of returns stored.
+func (s *ReturnSyncService) SyncReturns(ctx context.Context, connectionID uuid.UUID, timeFrom, timeTo time.Time) (int, error) {
+	conn, err := s.connectionRepo.GetByID(ctx, connectionID)
+	if err != nil {
+		return 0, ErrConnectionNotFound
+	}
+
+	provider, err := s.returnProvider(conn)
+	if err != nil {
+		return 0, err
+	}
+
+	synced := 0
+	pageNo := 0
+	cursor := ""
+	for {
+		returns, nextCursor, err := provider.GetReturns(ctx, &providers.ReturnListParams{
+			CreateTimeFrom: timeFrom,
+			CreateTimeTo:   timeTo,
+			PageSize:       50,
+			PageNo:         pageNo,
+			Cursor:         cursor,
+		})
+		if err != nil {
+			return synced, fmt.Errorf("failed to fetch returns: %w", err)
+		}
+
+		for i := range returns {
+			if _, err := s.storeReturn(ctx, conn, &returns[i]); err != nil {
+				s.logger.Error("Failed to store return",
+					zap.String("return_id", returns[i].ExternalReturnID),
+					zap.Error(err),
+				)
+				continue
+			}
+			synced++
+		}
+
+		if nextCursor == "" {
+			break
+		}
+		cursor = nextCursor
+		pageNo, _ = strconv.Atoi(nextCursor)
+	}
+
+	return synced, nil
+}
+
+// AcceptReturn accepts a return on the marketplace and refreshes the stored copy
+func (s *ReturnSyncService) AcceptReturn(ctx context.Context, connectionID, returnID uuid.UUID) (*models.MarketplaceReturn, error) {
+	conn, ret, err := s.actionableReturn(ctx, connectionID, returnID)
+	if err != nil {
+		return nil, err
+	}
+
+	provider, err := s.returnProvider(conn)
+	if err != nil {
+		return nil, err
+	}
+	if err := provider.ConfirmReturn(ctx, ret.ExternalReturnID); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	ret.AcceptedAt = &now
+	if err := s.returnRepo.MarkAccepted(ctx, ret.ID, now); err != nil {
+		return nil, fmt.Errorf("failed to update return: %w", err)
+	}
+
+	s.logger.Info("Return accepted",
+		zap.String("return_id", ret.ID.String()),
+		zap.String("external_return_id", ret.ExternalReturnID),
+	)
+	return s.refreshReturn(ctx, conn, provider, ret)
+}
+
+// DisputeReturn disputes (Shopee) or rejects (TikTok) a return on the marketplace
+// with the given reason and evidence images, and refreshes the stored copy
+func (s *ReturnSyncService) DisputeReturn(ctx context.Context, connectionID, returnID uuid.UUID, req *models.DisputeReturnRequest) (*models.MarketplaceReturn, error) {
+	conn, ret, err := s.actionableReturn(ctx, connectionID, returnID)
+	if err != nil {
+		return nil, err
+	}
+
+	switch conn.Platform {
+	case "shopee":
+		if req.Email == "" {
+			return nil, fmt.Errorf("%w: email is required to dispute a Shopee return", ErrInvalidDispute)
+		}
+	case "tiktok":
+		if req.ReasonCode == "" {
+			return nil, fmt.Errorf("%w: reason_code is required to reject a TikTok return", ErrInvalidDispute)
+		}
+	}
+
+	provider, err := s.returnProvider(conn)
+	if err != nil {
+		return nil, err
+	}
+	err = provider.RejectReturn(ctx, &providers.DisputeReturnRequest{
+		ReturnID:      ret.ExternalReturnID,
+		Email:         req.Email,
+		DisputeReason: req.Reason,
+		ReasonCode:    req.ReasonCode,
+		Images:        req.Images,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	images, _ := json.Marshal(req.Images)
+	now := time.Now()
+	ret.DisputeReason = req.Reason
+	ret.DisputeImages = datatypes.JSON(images)
+	ret.DisputedAt = &now
+	if err := s.returnRepo.MarkDisputed(ctx, ret.ID, ret.DisputeReason, ret.DisputeImages, now); err != nil {
+		return nil, fmt.Errorf("failed to update return: %w", err)
+	}
+
+	s.logger.Info("Return disputed",
+		zap.String("return_id", ret.ID.String()),
+		zap.String("external_return_id", ret.ExternalReturnID),
+	)
+	return s.refreshReturn(ctx, conn, provider, ret)
+}
+
+// GetRejectReasons lists the reason codes the marketplace accepts when rejecting
+// a return. Platforms without reason codes return an empty list.
+func (s *ReturnSyncService) GetRejectReasons(ctx context.Context, connectionID, returnID uuid.UUID) ([]providers.ReturnReason, error) {
+	conn, err := s.connectionRepo.GetByID(ctx, connectionID)
+	if err != nil {
+		return nil, ErrConnectionNotFound
+	}
+
+	ret, err := s.GetReturn(ctx, connectionID, returnID)
+	if err != nil {
+		return nil, err
+	}
+
+	provider, err := s.returnProvider(conn)
+	if err != nil {
+		return nil, err
+	}
+
+	lister, ok := provider.(providers.ReturnReasonLister)
+	if !ok {
+		return []providers.ReturnReason{}, nil
+	}
+	return lister.GetRejectReasons(ctx, ret.ExternalReturnID)
+}
+
+// HandleShopeeReturnEvent fetches and stores a return pushed by a Shopee return webhook
+func (s *ReturnSyncService) HandleShopeeReturnEvent(shopID int64, returnSN, orderSN, status string) error {
+	ctx := context.Background()
+
+	conn, err := s.connectionRepo.GetByPlatformAndShopID(ctx, "shopee", strconv.FormatInt(shopID, 10))
+	if err != nil {
+		s.logger.Error("Connection not found for shop", zap.Int64("shop_id", shopID))
+		return ErrConnectionNotFound
+	}
+
+	provider, err := s.returnProvider(conn)
+	if err != nil {
+		return err
+	}
+
+	ret, err := provider.GetReturn(ctx, returnSN)
+	if err != nil {
+		return fmt.Errorf("failed to fetch return %s: %w", returnSN, err)
+	}
+
+	if _, err := s.storeReturn(ctx, conn, ret); err != nil {
+		return fmt.Errorf("failed to store return %s: %w", returnSN, err)
+	}
+
+	s.logger.Info("Return updated from webhook",
+		zap.String("return_sn", returnSN),
+		zap.String("order_sn", orderSN),
+		zap.String("status", status),
+	)
+	return nil
+}
+
+// HandleTikTokReturnEvent fetches and stores a reverse order pushed by a TikTok
+// return or reverse order webhook
+func (s *ReturnSyncService) HandleTikTokReturnEvent(shopID, returnID, orderID, status string) error {
+	ctx := context.Background()
+
+	conn, err := s.connectionRepo.GetByPlatformAndShopID(ctx, "tiktok", shopID)
+	if err != nil {
+		s.logger.Error("Connection not found for shop", zap.String("shop_id", shopID))
+		return ErrConnectionNotFound
+	}
+
+	provider, err := s.returnProvider(conn)
+	if err != nil {
+		return err
+	}
+
+	ret, err := provider.GetReturn(ctx, returnID)
+	if err != nil {
+		return fmt.Errorf("failed to fetch return %s: %w", returnID, err)
+	}
+
+	if _, err := s.storeReturn(ctx, conn, ret); err != nil {
+		return fmt.Errorf("failed to store return %s: %w", returnID, err)
+	}
+
+	s.logger.Info("Return updated from webhook",
+		zap.String("return_id", returnID),
+		zap.String("order_id", orderID),
+		zap.String("status", status),
+	)
+	return nil
+}
+
+// actionableReturn loads a return that can still be accepted or disputed, with its connection
+func (s *ReturnSyncService) actionableReturn(ctx context.Context, connectionID, returnID uuid.UUID) (*models.Connection, *models.MarketplaceReturn, error) {
+	conn, err := s.connectionRepo.GetByID(ctx, connectionID)
+	if err != nil {
+		return nil, nil, ErrConnectionNotFound
+	}
+
+	ret, err := s.GetReturn(ctx, connectionID, returnID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	switch ret.Status {
+	case providers.ReturnStatusRequested, providers.ReturnStatusProcessing:
+		return conn, ret, nil
+	default:
+		return nil, nil, ErrReturnNotActionable
+	}
+}
+
+// refreshReturn re-reads a return from the marketplace after an action. A failed
+// refresh is logged and the stored copy is returned; the next sync catches up.
+func (s *ReturnSyncService) refreshReturn(ctx context.Context, conn *models.Connection, provider providers.ReturnProvider, ret *models.MarketplaceReturn) (*models.MarketplaceReturn, error) {
+	latest, err := provider.GetReturn(ctx, ret.ExternalReturnID)
+	if err != nil {
+		s.logger.Warn("Failed to refresh return", zap.String("return_id", ret.ID.String()), zap.Error(err))
+		return ret, nil
+	}
+
+	if _, err := s.storeReturn(ctx, conn, latest); err != nil {
+		s.logger.Warn("Failed to store refreshed return", zap.String("return_id", ret.ID.String()), zap.Error(err))
+		return ret, nil
+	}
+
+	return s.GetReturn(ctx, conn.ID, ret.ID)
+}
+
+// storeReturn upserts a marketplace return, linking it to the imported order if there is one
+func (s *ReturnSyncService) storeReturn(ctx context.Context, conn *models.Connection, ext *providers.ExternalReturn) (*models.MarketplaceReturn, error) {
+	items, err := json.Marshal(ext.Items)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode return items: %w", err)
+	}
+	if ext.Items == nil {
+		items = []byte("[]")
+	}
+	images, err := json.Marshal(ext.Images)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode return images: %w", err)
+	}
+	if ext.Images == nil {
+		images = []byte("[]")
+	}
+
+	now := time.Now()
+	ret := &models.MarketplaceReturn{
+		ConnectionID:     conn.ID,
+		Platform:         conn.Platform,
+		ExternalReturnID: ext.ExternalReturnID,
+		ExternalOrderID:  ext.ExternalOrderID,
+		ReturnType:       ext.Type,
+		Status:           ext.Status,
+		PlatformStatus:   ext.PlatformStatus,
+		Reason:           ext.Reason,
+		ReasonText:       ext.ReasonText,
+		RefundAmount:     ext.RefundAmount,
+		Currency:         ext.Currency,
+		NeedsLogistics:   ext.NeedsLogistics,
+		TrackingNumber:   ext.TrackingNumber,
+		BuyerID:          ext.BuyerID,
+		BuyerName:        ext.BuyerName,
+		Items:            datatypes.JSON(items),
+		Images:           datatypes.JSON(images),
+		ShipDueDate:      ext.ShipDueDate,
+		SyncedAt:         &now,
+	}
+	if ret.Currency == "" {
+		ret.Currency = "MYR"
+	}
+	if !ext.CreatedAt.IsZero() {
+		createdAt := ext.CreatedAt
+		ret.ExternalCreatedAt = &createdAt
+	}
+	if !ext.UpdatedAt.IsZero() {
+		updatedAt := ext.UpdatedAt
+		ret.ExternalUpdatedAt = &updatedAt
+	}
+
+	if order, err := s.orderRepo.GetByExternalOrderID(ctx, conn.ID, ext.ExternalOrderID); err == nil {
+		ret.OrderID = &order.ID
+	}
+
+	if err := s.returnRepo.Upsert(ctx, ret); err != nil {
+		return nil, err
+	}
+
+	// Re-read: the upsert is skipped for stale states and never returns local fields
+	stored, err := s.returnRepo.GetByExternalReturnID(ctx, conn.ID, ext.ExternalReturnID)
+	if err != nil {
+		return nil, err
+	}
+
+	// A failed evaluation records no decision, so the next update retries it
+	if stored.Status == providers.ReturnStatusRequested && s.decisionMaker != nil {
+		if err := s.decisionMaker.EvaluateReturn(ctx, conn, stored); err != nil {
+			s.logger.Warn("Failed to evaluate return rules", zap.String("return_id", stored.ID.String()), zap.Error(err))
+		}
+	}
+
+	switch {
+	case stored.Status == providers.ReturnStatusRefunded:
+		if err := s.propagateRefund(ctx, conn, stored); err != nil {
+			return stored, err
+		}
+	case stored.Status == providers.ReturnStatusCancelled && stored.ReturnType == providers.ReturnTypeCancellation:
+		if err := s.propagateCancellation(ctx, conn, stored); err != nil {
+			return stored, err
+		}
+	}
+	return stored, nil
+}
+
+// propagateRefund applies the connection's return settings to a refunded return:
+// restocking the returned items and refunding the internal order. Each step runs
+// at most once per return; a failed step is released so the next sync or webhook
+// replay retries it.
+func (s *ReturnSyncService) propagateRefund(ctx context.Context, conn *models.Connection, ret *models.MarketplaceReturn) error {
+	settings := conn.GetSettings().Returns
+	var errs []error
+
+	// Refund-only returns never send goods back
+	if settings.Restock && ret.NeedsLogistics && ret.RestockedAt == nil {
+		if err := s.restockReturn(ctx, conn, ret, settings.RestockWarehouseID); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if settings.RefundOrder && ret.RefundPropagated == nil {
+		if err := s.updateInternalOrder(ctx, ret, models.OrderStatusRefunded); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// propagateCancellation applies a completed cancellation request: nothing was
+// shipped, so nothing is restocked and the internal order is cancelled rather
+// than refunded. It is recorded in refund_propagated_at like a refund.
+func (s *ReturnSyncService) propagateCancellation(ctx context.Context, conn *models.Connection, ret *models.MarketplaceReturn) error {
+	if !conn.GetSettings().Returns.RefundOrder || ret.RefundPropagated != nil {
+		return nil
+	}
+	return s.updateInternalOrder(ctx, ret, models.OrderStatusCancelled)
+}
+
+// restockReturn publishes a restock event for the items of a return. The
+// restock is only claimed once every item maps to an internal product, so a
+// return with unmapped items is left pending and retried as a whole after the
+// mapping is added.
+func (s *ReturnSyncService) restockReturn(ctx context.Context, conn *models.Connection, ret *models.MarketplaceReturn, warehouseID string) error {
+	if s.publisher == nil {
+		return errors.New("event publisher not configured")
+	}
+
+	var items []providers.ExternalReturnItem
+	if err := json.Unmarshal(ret.Items, &items); err != nil {
+		return fmt.Errorf("failed to decode return items: %w", err)
+	}
+	if len(items) == 0 {
+		// Details not fetched yet; the next update with items restocks them
+		s.logger.Debug("Return has no items to restock", zap.String("return_id", ret.ID.String()))
+		return nil
+	}
+
+	restockItems := make([]events.ReturnRestockItem, 0, len(items))
+	var unmapped []string
+	for _, item := range items {
+		mapping, err := s.productMappingRepo.GetByConnectionAndExternalProduct(ctx, conn.ID, item.ExternalProductID)
+		if err != nil {
+			unmapped = append(unmapped, item.ExternalProductID)
+			continue
+		}
+
+		restockItem := events.ReturnRestockItem{
+			ProductID:         mapping.InternalProductID,
+			ExternalProductID: item.ExternalProductID,
+			ExternalVariantID: item.ExternalVariantID,
+			Quantity:          item.Quantity,
+		}
+		if item.ExternalVariantID != "" && item.ExternalVariantID != "0" {
+			if variant, err := s.productMappingRepo.GetVariantMapping(ctx, mapping.ID, item.ExternalVariantID); err == nil {
+				restockItem.VariantID = &variant.InternalVariantID
+			}
+		}
+		restockItems = append(restockItems, restockItem)
+	}
+	if len(unmapped) > 0 {
+		message := fmt.Sprintf("restock pending: unmapped return items %s", strings.Join(unmapped, ", "))
+		s.logger.Warn("Return restock pending on unmapped items",
+			zap.String("return_id", ret.ID.String()),
+			zap.Strings("external_product_ids", unmapped),
+		)
+		if err := s.returnRepo.SetPropagationError(ctx, ret.ID, message); err != nil {
+			s.logger.Error("Failed to record pending restock", zap.String("return_id", ret.ID.String()), zap.Error(err))
+		}
+		return errors.New(message)
+	}
+
+	claimed, err := s.returnRepo.ClaimRestock(ctx, ret.ID)
+	if err != nil {
+		return fmt.Errorf("failed to claim restock: %w", err)
+	}
+	if !claimed {
+		return nil
+	}
+
+	err = s.publisher.PublishReturnRestock(&events.ReturnRestockEvent{
+		ConnectionID:     conn.ID,
+		Platform:         conn.Platform,
+		ReturnID:         ret.ID,
+		ExternalReturnID: ret.ExternalReturnID,
+		ExternalOrderID:  ret.ExternalOrderID,
+		WarehouseID:      warehouseID,
+		Items:            restockItems,
+		Timestamp:        time.Now(),
+	})
+	if err != nil {
+		if releaseErr := s.returnRepo.ReleaseRestock(ctx, ret.ID, err.Error()); releaseErr != nil {
+			s.logger.Error("Failed to release restock claim", zap.String("return_id", ret.ID.String()), zap.Error(releaseErr))
+		}
+		return fmt.Errorf("failed to publish restock: %w", err)
+	}
+
+	s.logger.Info("Published restock for refunded return",
+		zap.String("return_id", ret.ID.String()),
+		zap.Int("items", len(restockItems)),
+	)
+	return nil
+}
+
+// updateInternalOrder sets the status of the service-order order behind a return
+// (refunded, or cancelled for a cancellation request)
+func (s *ReturnSyncService) updateInternalOrder(ctx context.Context, ret *models.MarketplaceReturn, status string) error {
+	if s.orderClient == nil {
+		return errors.New("order client not configured")
+	}
+	if ret.OrderID == nil {
+		// Order not imported yet; retried on the next update of the return
+		return nil
+	}
+
+	order, err := s.orderRepo.GetByID(ctx, *ret.OrderID)
+	if err != nil {
+		return fmt.Errorf("failed to load order: %w", err)
+	}
+	if order.InternalOrderID == nil {
+		return nil
+	}
+
+	claimed, err := s.returnRepo.ClaimRefund(ctx, ret.ID)
+	if err != nil {
+		return fmt.Errorf("failed to claim refund: %w", err)
+	}
+	if !claimed {
+		return nil
+	}
+
+	if err := s.orderClient.UpdateOrderStatus(ctx, order.InternalOrderID.String(), status, ""); err != nil {
+		if releaseErr := s.returnRepo.ReleaseRefund(ctx, ret.ID, err.Error()); releaseErr != nil {
+			s.logger.Error("Failed to release refund claim", zap.String("return_id", ret.ID.String()), zap.Error(releaseErr))
+		}
+		return fmt.Errorf("failed to mark internal order %s: %w", status, err)
+	}
+
+	s.logger.Info("Updated internal order from return",
+		zap.String("return_id", ret.ID.String()),
+		zap.String("internal_order_id", order.InternalOrderID.String()),
+		zap.String("status", status),
+	)
+	return nil
+}
+
+// returnProvider builds the return provider for a connection's platform
+func (s *ReturnSyncService) returnProvider(conn *models.Connection) (providers.ReturnProvider, error) {
+	accessToken := conn.AccessToken
+	if s.encryptor != nil {
+		var err error
+		accessToken, err = s.encryptor.Decrypt(conn.AccessToken)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decrypt token: %w", err)
+		}
+	}
+
+	switch conn.Platform {
+	case "shopee":
+		shopID, err := strconv.ParseInt(conn.ShopID, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid shop ID: %w", err)
+		}
+
+		client, err := shopee.NewClient(&shopee.ClientConfig{
+			PartnerID:  s.shopeePartnerID,
+			PartnerKey: s.shopeePartnerKey,
+			IsSandbox:  s.shopeeSandbox,
+			Logger:     s.logger,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to create shopee client: %w", err)
+		}
+		client.SetTokens(accessToken, shopID)
+		return shopee.NewReturnProvider(client), nil
+
+	case "tiktok":
+		client := tiktok.NewClient(&tiktok.ClientConfig{
+			AppKey:    s.tiktokAppKey,
+			AppSecret: s.tiktokAppSecret,
+			Logger:    s.logger,
+		})
+		client.SetTokens(accessToken, conn.ShopID)
+		return tiktok.NewReturnProvider(client), nil
+
+	default:
+		return nil, ErrReturnsNotSupported
+	}
+}
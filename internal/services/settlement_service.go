@@ -0,0 +1,281 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"github.com/niaga-platform/service-marketplace/internal/models"
+	"github.com/niaga-platform/service-marketplace/internal/providers"
+	"github.com/niaga-platform/service-marketplace/internal/repository"
+)
+
+// Settlement errors
+var (
+	ErrSettlementsNotSupported = errors.New("settlements are not supported for this platform")
+	ErrInvalidSettlementReport = errors.New("invalid settlement report")
+)
+
+// defaultSettlementTolerance is the largest payout difference not reported as a
+// mismatch, absorbing rounding between the order and statement amounts
+const defaultSettlementTolerance = 0.01
+
+// SettlementService ingests marketplace payout statements into
+// marketplace.settlement_lines, matches them to orders and reports what does
+// not reconcile: payouts without an order, completed orders never paid out and
+// orders paid out a different amount than their net payout.
+type SettlementService struct {
+	connectionRepo *repository.ConnectionRepository
+	settlementRepo *repository.SettlementRepository
+	orderService   *OrderSyncService
+	logger         *zap.Logger
+}
+
+// NewSettlementService creates a new SettlementService
+func NewSettlementService(
+	connectionRepo *repository.ConnectionRepository,
+	settlementRepo *repository.SettlementRepository,
+	orderService *OrderSyncService,
+	logger *zap.Logger,
+) *SettlementService {
+	return &SettlementService{
+		connectionRepo: connectionRepo,
+		settlementRepo: settlementRepo,
+		orderService:   orderService,
+		logger:         logger,
+	}
+}
+
+// SyncSettlements pulls the statement lines settled in a time range and matches
+// them to orders. It returns the number of lines stored.
+func (s *SettlementService) SyncSettlements(ctx context.Context, connectionID uuid.UUID, timeFrom, timeTo time.Time) (int, error) {
+	conn, err := s.connectionRepo.GetByID(ctx, connectionID)
+	if err != nil {
+		return 0, ErrConnectionNotFound
+	}
+
+	provider, err := s.orderService.newOrderLister(conn)
+	if err != nil {
+		return 0, err
+	}
+	lister, ok := provider.(providers.SettlementLister)
+	if !ok {
+		return 0, ErrSettlementsNotSupported
+	}
+
+	synced := 0
+	for _, window := range orderWindows(timeFrom, timeTo, orderListWindow) {
+		params := &providers.SettlementListParams{
+			TimeFrom: window[0],
+			TimeTo:   window[1],
+			PageSize: 50,
+		}
+		for {
+			settlements, nextCursor, err := lister.GetSettlements(ctx, params)
+			if err != nil {
+				return synced, fmt.Errorf("failed to fetch settlements: %w", err)
+			}
+
+			lines := make([]models.SettlementLine, len(settlements))
+			for i, settlement := range settlements {
+				lines[i] = models.SettlementLine{
+					ConnectionID:    conn.ID,
+					Platform:        conn.Platform,
+					ExternalID:      settlement.ExternalID,
+					ExternalOrderID: settlement.ExternalOrderID,
+					TransactionType: settlement.Type,
+					Amount:          settlement.Amount,
+					FeeAmount:       settlement.FeeAmount,
+					CommissionFee:   settlement.CommissionFee,
+					ServiceFee:      settlement.ServiceFee,
+					TransactionFee:  settlement.TransactionFee,
+					ShippingFee:     settlement.ShippingFee,
+					Currency:        settlement.Currency,
+					Description:     settlement.Description,
+					SettledAt:       settlement.SettledAt,
+				}
+			}
+			if err := s.settlementRepo.UpsertBatch(ctx, lines); err != nil {
+				return synced, fmt.Errorf("failed to store settlements: %w", err)
+			}
+			synced += len(lines)
+
+			if nextCursor == "" {
+				break
+			}
+			params.Cursor = nextCursor
+		}
+	}
+
+	matched, err := s.settlementRepo.MatchOrders(ctx, conn.ID)
+	if err != nil {
+		return synced, fmt.Errorf("failed to match settlements: %w", err)
+	}
+
+	s.logger.Info("settlements synced",
+		zap.String("connection_id", conn.ID.String()),
+		zap.Int("lines", synced),
+		zap.Int64("matched", matched),
+	)
+	return synced, nil
+}
+
+// MatchSettlements matches the unmatched statement lines of a connection to
+// orders imported since they were synced. It returns the number of lines matched.
+func (s *SettlementService) MatchSettlements(ctx context.Context, connectionID uuid.UUID) (int64, error) {
+	if _, err := s.connectionRepo.GetByID(ctx, connectionID); err != nil {
+		return 0, ErrConnectionNotFound
+	}
+
+	matched, err := s.settlementRepo.MatchOrders(ctx, connectionID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to match settlements: %w", err)
+	}
+
+	s.logger.Info("settlements matched",
+		zap.String("connection_id", connectionID.String()),
+		zap.Int64("matched", matched),
+	)
+	return matched, nil
+}
+
+// GetReport builds a reconciliation report of a connection from the lines as
+// last matched by SyncSettlements or MatchSettlements
+func (s *SettlementService) GetReport(ctx context.Context, connectionID uuid.UUID, filter *models.SettlementReportFilter) (*models.SettlementReport, error) {
+	if _, err := s.connectionRepo.GetByID(ctx, connectionID); err != nil {
+		return nil, ErrConnectionNotFound
+	}
+	if filter.Tolerance < 0 {
+		return nil, fmt.Errorf("%w: tolerance must not be negative", ErrInvalidSettlementReport)
+	}
+	if filter.Tolerance == 0 {
+		filter.Tolerance = defaultSettlementTolerance
+	}
+
+	var entries []models.SettlementReportEntry
+	switch filter.Type {
+	case models.SettlementReportUnmatched:
+		lines, err := s.settlementRepo.GetUnmatchedPayouts(ctx, connectionID, filter)
+		if err != nil {
+			return nil, err
+		}
+		entries = make([]models.SettlementReportEntry, len(lines))
+		for i, line := range lines {
+			entries[i] = models.SettlementReportEntry{
+				ExternalOrderID: line.ExternalOrderID,
+				TransactionID:   line.ExternalID,
+				TransactionType: line.TransactionType,
+				Settled:         line.Amount,
+				Difference:      line.Amount,
+				Currency:        line.Currency,
+				Date:            line.SettledAt,
+			}
+		}
+
+	case models.SettlementReportUnpaid:
+		orders, err := s.settlementRepo.GetUnpaidOrders(ctx, connectionID, filter)
+		if err != nil {
+			return nil, err
+		}
+		entries = make([]models.SettlementReportEntry, len(orders))
+		for i, order := range orders {
+			entry := models.SettlementReportEntry{
+				ExternalOrderID: order.ExternalOrderID,
+				OrderID:         &order.ID,
+				OrderStatus:     order.Status,
+				Expected:        order.NetPayout,
+				Currency:        order.Currency,
+				Date:            order.CreatedAt,
+			}
+			if order.NetPayout != nil {
+				entry.Difference = -*order.NetPayout
+			}
+			entries[i] = entry
+		}
+
+	case models.SettlementReportFeeMismatches:
+		totals, err := s.settlementRepo.GetPayoutMismatches(ctx, connectionID, filter)
+		if err != nil {
+			return nil, err
+		}
+		entries = make([]models.SettlementReportEntry, 0, len(totals))
+		for i := range totals {
+			if entry, ok := payoutMismatch(&totals[i], filter.Tolerance); ok {
+				entries = append(entries, entry)
+			}
+		}
+
+	default:
+		return nil, fmt.Errorf("%w: unknown report type %q", ErrInvalidSettlementReport, filter.Type)
+	}
+
+	return &models.SettlementReport{
+		ConnectionID: connectionID,
+		Type:         filter.Type,
+		StartDate:    filter.StartDate,
+		EndDate:      filter.EndDate,
+		Entries:      entries,
+		Total:        len(entries),
+		GeneratedAt:  time.Now(),
+	}, nil
+}
+
+// payoutMismatch builds the fee mismatch entry of an order, listing each fee
+// the payout lines charge differently from the order financials. It returns
+// false if neither the payout nor a reported fee differs by more than tolerance.
+func payoutMismatch(totals *models.SettlementOrderTotals, tolerance float64) (models.SettlementReportEntry, bool) {
+	expected := totals.NetPayout
+	orderID := totals.OrderID
+	entry := models.SettlementReportEntry{
+		ExternalOrderID: totals.ExternalOrderID,
+		OrderID:         &orderID,
+		OrderStatus:     totals.OrderStatus,
+		Expected:        &expected,
+		Settled:         totals.Settled,
+		Difference:      roundAmount(totals.Settled - totals.NetPayout),
+		Currency:        totals.Currency,
+		Date:            totals.CreatedAt,
+	}
+
+	fees := []struct {
+		name     string
+		expected float64
+		settled  *float64
+	}{
+		{models.SettlementFeeCommission, totals.CommissionFee, totals.SettledCommissionFee},
+		{models.SettlementFeeService, totals.ServiceFee, totals.SettledServiceFee},
+		{models.SettlementFeeTransaction, totals.TransactionFee, totals.SettledTransactionFee},
+		{models.SettlementFeeShipping, totals.ShippingFee, totals.SettledShippingFee},
+	}
+	for _, fee := range fees {
+		if fee.settled == nil {
+			continue
+		}
+		difference := roundAmount(*fee.settled - fee.expected)
+		if math.Abs(difference) <= tolerance {
+			continue
+		}
+		entry.Fees = append(entry.Fees, models.SettlementFeeDifference{
+			Fee:        fee.name,
+			Expected:   fee.expected,
+			Settled:    *fee.settled,
+			Difference: difference,
+		})
+	}
+
+	if len(entry.Fees) == 0 && math.Abs(entry.Difference) <= tolerance {
+		return entry, false
+	}
+	return entry, true
+}
+
+// roundAmount rounds an amount to cents, so differences of stored decimal
+// amounts compare exactly against the tolerance
+func roundAmount(amount float64) float64 {
+	return math.Round(amount*100) / 100
+}
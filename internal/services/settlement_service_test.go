@@ -0,0 +1,85 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/niaga-platform/service-marketplace/internal/models"
+)
+
+func TestPayoutMismatch(t *testing.T) {
+	amount := func(v float64) *float64 { return &v }
+	// Settled: net payout 88 after commission 8, service 2, transaction 2
+	reconciled := func() models.SettlementOrderTotals {
+		return models.SettlementOrderTotals{
+			NetPayout:             88,
+			Settled:               88,
+			CommissionFee:         8,
+			ServiceFee:            2,
+			TransactionFee:        2,
+			ShippingFee:           5,
+			SettledCommissionFee:  amount(8),
+			SettledServiceFee:     amount(2),
+			SettledTransactionFee: amount(2),
+			SettledShippingFee:    amount(5),
+		}
+	}
+
+	tests := []struct {
+		name     string
+		modify   func(*models.SettlementOrderTotals)
+		want     bool
+		wantFees []string
+	}{
+		{name: "reconciled", modify: func(*models.SettlementOrderTotals) {}, want: false},
+		{name: "within tolerance", modify: func(o *models.SettlementOrderTotals) {
+			o.Settled = 88.01
+			o.SettledCommissionFee = amount(7.99)
+		}, want: false},
+		{name: "payout differs", modify: func(o *models.SettlementOrderTotals) { o.Settled = 85 }, want: true},
+		{name: "commission differs with same payout", modify: func(o *models.SettlementOrderTotals) {
+			o.SettledCommissionFee = amount(9)
+			o.SettledServiceFee = amount(1)
+		}, want: true, wantFees: []string{models.SettlementFeeCommission, models.SettlementFeeService}},
+		{name: "every fee differs", modify: func(o *models.SettlementOrderTotals) {
+			o.SettledCommissionFee = amount(10)
+			o.SettledServiceFee = amount(3)
+			o.SettledTransactionFee = amount(0)
+			o.SettledShippingFee = amount(6)
+		}, want: true, wantFees: []string{
+			models.SettlementFeeCommission, models.SettlementFeeService,
+			models.SettlementFeeTransaction, models.SettlementFeeShipping,
+		}},
+		{name: "fees not reported", modify: func(o *models.SettlementOrderTotals) {
+			o.SettledCommissionFee = nil
+			o.SettledServiceFee = nil
+			o.SettledTransactionFee = nil
+			o.SettledShippingFee = nil
+		}, want: false},
+		{name: "fee charged but not in financials", modify: func(o *models.SettlementOrderTotals) {
+			o.ShippingFee = 0
+		}, want: true, wantFees: []string{models.SettlementFeeShipping}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			totals := reconciled()
+			tt.modify(&totals)
+
+			entry, got := payoutMismatch(&totals, defaultSettlementTolerance)
+			if got != tt.want {
+				t.Fatalf("payoutMismatch() = %v, want %v", got, tt.want)
+			}
+			if len(entry.Fees) != len(tt.wantFees) {
+				t.Fatalf("Fees = %+v, want %v", entry.Fees, tt.wantFees)
+			}
+			for i, fee := range entry.Fees {
+				if fee.Fee != tt.wantFees[i] {
+					t.Errorf("Fees[%d] = %q, want %q", i, fee.Fee, tt.wantFees[i])
+				}
+				if want := roundAmount(fee.Settled - fee.Expected); fee.Difference != want {
+					t.Errorf("Fees[%d].Difference = %v, want %v", i, fee.Difference, want)
+				}
+			}
+		})
+	}
+}
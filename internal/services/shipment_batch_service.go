@@ -0,0 +1,368 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"github.com/niaga-platform/service-marketplace/internal/models"
+	"github.com/niaga-platform/service-marketplace/internal/providers"
+	"github.com/niaga-platform/service-marketplace/internal/providers/shopee"
+	"github.com/niaga-platform/service-marketplace/internal/repository"
+	"github.com/niaga-platform/service-marketplace/internal/utils"
+)
+
+// ErrInvalidShipmentBatch is returned when a batch has no orders or too many
+var ErrInvalidShipmentBatch = errors.New("invalid shipment batch")
+
+// maxShipmentBatchOrders is the most orders shipped or printed in one request
+const maxShipmentBatchOrders = 500
+
+// shippingOptionsWorkers is how many orders of a batch have their shipping
+// options fetched at once
+const shippingOptionsWorkers = 8
+
+// shopeeBatchProvider ships and prints Shopee orders in batches
+type shopeeBatchProvider interface {
+	shopeeDocumentProvider
+	GetShippingOptions(ctx context.Context, orderSN string) (*providers.ShippingOptions, error)
+	BatchArrangeShipment(ctx context.Context, orderSNs []string, selection *providers.ShipmentSelection) (map[string]string, error)
+}
+
+// batchDocumentStore fetches the AWBs of batch orders and keeps them with each order
+type batchDocumentStore interface {
+	fetchShopeeDocuments(ctx context.Context, provider shopeeDocumentProvider, orders []*models.MarketplaceOrder, indexes []int, documentType string) (map[int][]byte, map[int]string)
+	SaveDocument(ctx context.Context, order *models.MarketplaceOrder, documentType string, data []byte) (*models.ShippingDocument, error)
+}
+
+// shipmentRecorder records an order shipped from a batch
+type shipmentRecorder interface {
+	recordShipped(ctx context.Context, order *models.MarketplaceOrder, source string)
+}
+
+// ShipmentBatchResult contains the outcome of one order of a batch
+type ShipmentBatchResult struct {
+	OrderID         uuid.UUID                    `json:"order_id"`
+	ExternalOrderID string                       `json:"external_order_id,omitempty"`
+	Success         bool                         `json:"success"`
+	Shipment        *providers.ShipmentSelection `json:"shipment,omitempty"`
+	Error           string                       `json:"error,omitempty"`
+	DocumentError   string                       `json:"document_error,omitempty"` // Shipped, but the AWB could not be stored
+}
+
+// ShipmentBatchReport contains the outcome of a shipment or AWB printing batch
+type ShipmentBatchReport struct {
+	Results    []ShipmentBatchResult `json:"results"`
+	Succeeded  int                   `json:"succeeded"`
+	Failed     int                   `json:"failed"`
+	Document   []byte                `json:"document,omitempty"`    // Merged AWB PDF, base64 encoded in JSON
+	MergeError string                `json:"merge_error,omitempty"` // Why Document is missing although AWBs were printed
+}
+
+// fail marks the result of an order failed
+func (r *ShipmentBatchReport) fail(i int, message string) {
+	r.Results[i].Success = false
+	r.Results[i].Error = message
+}
+
+// tally counts the succeeded and failed orders
+func (r *ShipmentBatchReport) tally() {
+	r.Succeeded, r.Failed = 0, 0
+	for _, result := range r.Results {
+		if result.Success {
+			r.Succeeded++
+		} else {
+			r.Failed++
+		}
+	}
+}
+
+// ShipmentBatchService arranges shipment and prints airway bills for many
+// Shopee orders of a connection at once, using Shopee's batch logistics calls
+// of up to 50 orders each. A failing order does not fail the batch; every
+// order gets a result in the report.
+type ShipmentBatchService struct {
+	connectionRepo *repository.ConnectionRepository
+	orderRepo      *repository.MarketplaceOrderRepository
+	orderService   *OrderSyncService
+	recorder       shipmentRecorder
+	documents      batchDocumentStore
+	logger         *zap.Logger
+}
+
+// NewShipmentBatchService creates a new ShipmentBatchService
+func NewShipmentBatchService(
+	connectionRepo *repository.ConnectionRepository,
+	orderRepo *repository.MarketplaceOrderRepository,
+	orderService *OrderSyncService,
+	documents *ShippingDocumentService,
+	logger *zap.Logger,
+) *ShipmentBatchService {
+	return &ShipmentBatchService{
+		connectionRepo: connectionRepo,
+		orderRepo:      orderRepo,
+		orderService:   orderService,
+		recorder:       orderService,
+		documents:      documents,
+		logger:         logger,
+	}
+}
+
+// ArrangeShipments arranges shipment for many orders. The shipping option of
+// each order is resolved like a single shipment, from the request, the
+// connection's shipping settings and the options offered for the order;
+// orders resolving to the same option are shipped together. The AWB of each
+// shipped order is stored with its shipping documents, as for a single shipment.
+func (s *ShipmentBatchService) ArrangeShipments(ctx context.Context, connectionID uuid.UUID, orderIDs []uuid.UUID, requested *providers.ShipmentSelection) (*ShipmentBatchReport, error) {
+	conn, provider, orders, report, err := s.loadBatch(ctx, connectionID, orderIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	s.arrange(ctx, provider, orders, report, requested, conn.GetSettings().Shipping)
+
+	report.tally()
+	s.logger.Info("shipment batch arranged",
+		zap.String("connection_id", connectionID.String()),
+		zap.Int("succeeded", report.Succeeded),
+		zap.Int("failed", report.Failed),
+	)
+	return report, nil
+}
+
+// arrange ships the loaded orders of a batch and records the outcome of each
+// in the report
+func (s *ShipmentBatchService) arrange(ctx context.Context, provider shopeeBatchProvider, orders []*models.MarketplaceOrder, report *ShipmentBatchReport, requested *providers.ShipmentSelection, defaults models.ShippingSettings) {
+	options, optionErrors := fetchShippingOptions(ctx, provider, orders)
+
+	groups := make(map[providers.ShipmentSelection][]int)
+	var selections []providers.ShipmentSelection
+	for i, order := range orders {
+		if order == nil {
+			continue
+		}
+		if optionErrors[i] != nil {
+			report.fail(i, fmt.Sprintf("failed to get shipping options: %v", optionErrors[i]))
+			continue
+		}
+		selection, err := resolveShipment(options[i], requested, defaults)
+		if err != nil {
+			report.fail(i, err.Error())
+			continue
+		}
+		if _, ok := groups[*selection]; !ok {
+			selections = append(selections, *selection)
+		}
+		groups[*selection] = append(groups[*selection], i)
+	}
+
+	var shipped []int
+	for _, selection := range selections {
+		for _, chunk := range chunkIndexes(groups[selection], shopee.MaxBatchOrders) {
+			failures, err := provider.BatchArrangeShipment(ctx, externalOrderIDs(orders, chunk), &selection)
+			for _, i := range chunk {
+				order := orders[i]
+				switch {
+				case err != nil:
+					report.fail(i, err.Error())
+				case failures[order.ExternalOrderID] != "":
+					report.fail(i, failures[order.ExternalOrderID])
+				default:
+					report.Results[i].Success = true
+					report.Results[i].Shipment = &selection
+					s.recorder.recordShipped(ctx, order, models.OrderStatusSourceAdmin)
+					shipped = append(shipped, i)
+				}
+			}
+		}
+	}
+
+	// Keep the AWBs; failing here does not undo the shipments
+	if len(shipped) > 0 {
+		documentType := defaultShippingDocumentTypes["shopee"]
+		documents, failures := s.documents.fetchShopeeDocuments(ctx, provider, orders, shipped, documentType)
+		s.saveDocuments(ctx, orders, documents, documentType)
+		for i, failure := range failures {
+			report.Results[i].DocumentError = failure
+		}
+	}
+}
+
+// fetchShippingOptions gets the shipping options of the loaded orders of a
+// batch, a few orders at a time. Options and errors are returned by order index.
+func fetchShippingOptions(ctx context.Context, provider shopeeBatchProvider, orders []*models.MarketplaceOrder) ([]*providers.ShippingOptions, []error) {
+	options := make([]*providers.ShippingOptions, len(orders))
+	errs := make([]error, len(orders))
+
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < shippingOptionsWorkers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				options[i], errs[i] = provider.GetShippingOptions(ctx, orders[i].ExternalOrderID)
+			}
+		}()
+	}
+	for i, order := range orders {
+		if order != nil {
+			indexes <- i
+		}
+	}
+	close(indexes)
+	wg.Wait()
+
+	return options, errs
+}
+
+// PrintShippingDocuments creates the AWB documents of many orders, waits until
+// they are ready and merges them into one PDF, in the order requested. Each
+// AWB is also stored with the order's shipping documents. Orders
+// whose document failed or is not ready within the document timeout are
+// reported as failed. If the AWBs cannot be merged, the report is returned
+// without the merged document and with the reason in MergeError.
+func (s *ShipmentBatchService) PrintShippingDocuments(ctx context.Context, connectionID uuid.UUID, orderIDs []uuid.UUID, documentType string) (*ShipmentBatchReport, error) {
+	_, provider, orders, report, err := s.loadBatch(ctx, connectionID, orderIDs)
+	if err != nil {
+		return nil, err
+	}
+	if documentType == "" {
+		documentType = defaultShippingDocumentTypes["shopee"]
+	}
+
+	s.print(ctx, provider, orders, report, documentType)
+
+	report.tally()
+	s.logger.Info("shipping documents printed",
+		zap.String("connection_id", connectionID.String()),
+		zap.Int("succeeded", report.Succeeded),
+		zap.Int("failed", report.Failed),
+	)
+	return report, nil
+}
+
+// print downloads and stores the AWBs of the loaded orders of a batch and
+// merges them into the report's document
+func (s *ShipmentBatchService) print(ctx context.Context, provider shopeeDocumentProvider, orders []*models.MarketplaceOrder, report *ShipmentBatchReport, documentType string) {
+	var pending []int
+	for i, order := range orders {
+		if order != nil {
+			pending = append(pending, i)
+		}
+	}
+
+	fetched, failures := s.documents.fetchShopeeDocuments(ctx, provider, orders, pending, documentType)
+	s.saveDocuments(ctx, orders, fetched, documentType)
+
+	documents := make([][]byte, 0, len(fetched))
+	for _, i := range pending {
+		document, ok := fetched[i]
+		if !ok {
+			report.fail(i, failures[i])
+			continue
+		}
+		report.Results[i].Success = true
+		documents = append(documents, document)
+	}
+
+	if len(documents) > 0 {
+		merged, err := utils.MergePDFs(documents)
+		if err != nil {
+			// The AWBs are stored with each order and can be printed one by one
+			s.logger.Error("failed to merge shipping documents", zap.Error(err))
+			report.MergeError = fmt.Sprintf("failed to merge shipping documents: %v", err)
+		} else {
+			report.Document = merged
+		}
+	}
+}
+
+// saveDocuments stores downloaded documents with their orders so each can be
+// printed again on its own
+func (s *ShipmentBatchService) saveDocuments(ctx context.Context, orders []*models.MarketplaceOrder, documents map[int][]byte, documentType string) {
+	for i, document := range documents {
+		if _, err := s.documents.SaveDocument(ctx, orders[i], documentType, document); err != nil {
+			s.logger.Warn("failed to store shipping document", zap.String("order_id", orders[i].ID.String()), zap.Error(err))
+		}
+	}
+}
+
+// loadBatch loads the connection, its Shopee provider and the batch orders.
+// Orders are returned in request order, nil where the order does not exist
+// or belongs to another connection; those are already failed in the report.
+func (s *ShipmentBatchService) loadBatch(ctx context.Context, connectionID uuid.UUID, orderIDs []uuid.UUID) (*models.Connection, *shopee.OrderProvider, []*models.MarketplaceOrder, *ShipmentBatchReport, error) {
+	if len(orderIDs) == 0 {
+		return nil, nil, nil, nil, fmt.Errorf("%w: no orders", ErrInvalidShipmentBatch)
+	}
+	if len(orderIDs) > maxShipmentBatchOrders {
+		return nil, nil, nil, nil, fmt.Errorf("%w: at most %d orders per batch", ErrInvalidShipmentBatch, maxShipmentBatchOrders)
+	}
+
+	conn, err := s.connectionRepo.GetByID(ctx, connectionID)
+	if err != nil {
+		return nil, nil, nil, nil, ErrConnectionNotFound
+	}
+
+	lister, err := s.orderService.newOrderLister(conn)
+	if err != nil {
+		return nil, nil, nil, nil, err
+	}
+	provider, ok := lister.(*shopee.OrderProvider)
+	if !ok {
+		return nil, nil, nil, nil, fmt.Errorf("%w: batch shipment is only available for Shopee", ErrShipmentNotSupported)
+	}
+
+	found, err := s.orderRepo.GetByIDs(ctx, connectionID, orderIDs)
+	if err != nil {
+		return nil, nil, nil, nil, fmt.Errorf("failed to load orders: %w", err)
+	}
+	byID := make(map[uuid.UUID]*models.MarketplaceOrder, len(found))
+	for i := range found {
+		byID[found[i].ID] = &found[i]
+	}
+
+	orders := make([]*models.MarketplaceOrder, len(orderIDs))
+	report := &ShipmentBatchReport{Results: make([]ShipmentBatchResult, len(orderIDs))}
+	seen := make(map[uuid.UUID]bool, len(orderIDs))
+	for i, id := range orderIDs {
+		report.Results[i].OrderID = id
+		switch order := byID[id]; {
+		case seen[id]:
+			report.fail(i, "duplicate order in batch")
+		case order == nil:
+			report.fail(i, ErrOrderNotFound.Error())
+		default:
+			orders[i] = order
+			report.Results[i].ExternalOrderID = order.ExternalOrderID
+		}
+		seen[id] = true
+	}
+	return conn, provider, orders, report, nil
+}
+
+// chunkIndexes splits order indexes into chunks of at most size
+func chunkIndexes(indexes []int, size int) [][]int {
+	var chunks [][]int
+	for len(indexes) > size {
+		chunks = append(chunks, indexes[:size])
+		indexes = indexes[size:]
+	}
+	if len(indexes) > 0 {
+		chunks = append(chunks, indexes)
+	}
+	return chunks
+}
+
+// externalOrderIDs returns the external IDs of the orders at the given indexes
+func externalOrderIDs(orders []*models.MarketplaceOrder, indexes []int) []string {
+	ids := make([]string, len(indexes))
+	for j, i := range indexes {
+		ids[j] = orders[i].ExternalOrderID
+	}
+	return ids
+}
@@ -0,0 +1,210 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"github.com/niaga-platform/service-marketplace/internal/models"
+	"github.com/niaga-platform/service-marketplace/internal/providers"
+	"github.com/niaga-platform/service-marketplace/internal/providers/shopee"
+)
+
+func TestChunkIndexes(t *testing.T) {
+	tests := []struct {
+		name    string
+		indexes []int
+		size    int
+		want    [][]int
+	}{
+		{name: "empty", indexes: nil, size: 2, want: nil},
+		{name: "smaller than size", indexes: []int{0, 1}, size: 3, want: [][]int{{0, 1}}},
+		{name: "exactly size", indexes: []int{0, 1, 2}, size: 3, want: [][]int{{0, 1, 2}}},
+		{name: "remainder", indexes: []int{0, 1, 2, 3, 4}, size: 2, want: [][]int{{0, 1}, {2, 3}, {4}}},
+		{name: "multiple of size", indexes: []int{0, 1, 2, 3}, size: 2, want: [][]int{{0, 1}, {2, 3}}},
+		{name: "keeps order of sparse indexes", indexes: []int{7, 2, 9}, size: 1, want: [][]int{{7}, {2}, {9}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := chunkIndexes(tt.indexes, tt.size); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("chunkIndexes() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// fakeShopeeBatchProvider answers the Shopee batch calls from fixed per-order outcomes
+type fakeShopeeBatchProvider struct {
+	optionErrors    map[string]error  // order SN -> GetShippingOptions error
+	arrangeFailures map[string]string // order SN -> BatchArrangeShipment failure
+	documents       map[string][]byte // order SN -> shipping document
+
+	mu       sync.Mutex
+	arranged [][]string
+}
+
+func (p *fakeShopeeBatchProvider) GetShippingOptions(ctx context.Context, orderSN string) (*providers.ShippingOptions, error) {
+	if err := p.optionErrors[orderSN]; err != nil {
+		return nil, err
+	}
+	return &providers.ShippingOptions{Methods: []string{providers.ShippingMethodDropoff}}, nil
+}
+
+func (p *fakeShopeeBatchProvider) BatchArrangeShipment(ctx context.Context, orderSNs []string, selection *providers.ShipmentSelection) (map[string]string, error) {
+	p.mu.Lock()
+	p.arranged = append(p.arranged, orderSNs)
+	p.mu.Unlock()
+
+	failures := make(map[string]string)
+	for _, sn := range orderSNs {
+		if failure := p.arrangeFailures[sn]; failure != "" {
+			failures[sn] = failure
+		}
+	}
+	return failures, nil
+}
+
+func (p *fakeShopeeBatchProvider) CreateShippingDocuments(ctx context.Context, orderSNs []string, documentType string) (map[string]string, error) {
+	return map[string]string{}, nil
+}
+
+func (p *fakeShopeeBatchProvider) GetShippingDocumentResults(ctx context.Context, orderSNs []string, documentType string) (map[string]*shopee.ShippingDocumentInfo, error) {
+	results := make(map[string]*shopee.ShippingDocumentInfo, len(orderSNs))
+	for _, sn := range orderSNs {
+		if _, ok := p.documents[sn]; ok {
+			results[sn] = &shopee.ShippingDocumentInfo{Status: "READY"}
+		} else {
+			results[sn] = &shopee.ShippingDocumentInfo{Status: "FAILED", ErrorMsg: "no document"}
+		}
+	}
+	return results, nil
+}
+
+func (p *fakeShopeeBatchProvider) FetchShippingDocument(ctx context.Context, orderSN string, documentType string) ([]byte, error) {
+	return p.documents[orderSN], nil
+}
+
+// fakeDocumentStore fetches documents through the real ShippingDocumentService
+// and keeps saved documents in memory
+type fakeDocumentStore struct {
+	*ShippingDocumentService
+	saved map[string][]byte // order SN -> saved document
+}
+
+func newFakeDocumentStore() *fakeDocumentStore {
+	return &fakeDocumentStore{
+		ShippingDocumentService: &ShippingDocumentService{config: ShippingDocumentConfig{ReadyTimeout: time.Second}, logger: zap.NewNop()},
+		saved:                   make(map[string][]byte),
+	}
+}
+
+func (s *fakeDocumentStore) SaveDocument(ctx context.Context, order *models.MarketplaceOrder, documentType string, data []byte) (*models.ShippingDocument, error) {
+	s.saved[order.ExternalOrderID] = data
+	return &models.ShippingDocument{OrderID: order.ID, DocumentType: documentType}, nil
+}
+
+// fakeShipmentRecorder keeps the orders recorded shipped
+type fakeShipmentRecorder struct {
+	shipped []string
+}
+
+func (r *fakeShipmentRecorder) recordShipped(ctx context.Context, order *models.MarketplaceOrder, source string) {
+	r.shipped = append(r.shipped, order.ExternalOrderID)
+}
+
+// newBatch builds the loaded orders and report of a batch; an empty order SN
+// stands for an order that was not found
+func newBatch(orderSNs ...string) ([]*models.MarketplaceOrder, *ShipmentBatchReport) {
+	orders := make([]*models.MarketplaceOrder, len(orderSNs))
+	report := &ShipmentBatchReport{Results: make([]ShipmentBatchResult, len(orderSNs))}
+	for i, sn := range orderSNs {
+		report.Results[i].OrderID = uuid.New()
+		if sn == "" {
+			report.fail(i, ErrOrderNotFound.Error())
+			continue
+		}
+		orders[i] = &models.MarketplaceOrder{ID: report.Results[i].OrderID, ExternalOrderID: sn}
+		report.Results[i].ExternalOrderID = sn
+	}
+	return orders, report
+}
+
+func TestShipmentBatchArrange(t *testing.T) {
+	provider := &fakeShopeeBatchProvider{
+		optionErrors:    map[string]error{"SN-NO-OPTIONS": errors.New("order cancelled")},
+		arrangeFailures: map[string]string{"SN-REJECTED": "order already shipped"},
+		documents:       map[string][]byte{"SN-1": []byte("awb 1"), "SN-3": []byte("awb 3")},
+	}
+	documents := newFakeDocumentStore()
+	recorder := &fakeShipmentRecorder{}
+	s := &ShipmentBatchService{recorder: recorder, documents: documents, logger: zap.NewNop()}
+
+	orders, report := newBatch("SN-1", "SN-NO-OPTIONS", "", "SN-REJECTED", "SN-3", "SN-NO-AWB")
+	s.arrange(context.Background(), provider, orders, report, nil, models.ShippingSettings{})
+	report.tally()
+
+	wantSuccess := []bool{true, false, false, false, true, true}
+	for i, want := range wantSuccess {
+		if got := report.Results[i].Success; got != want {
+			t.Errorf("result %d success = %v, want %v (error %q)", i, got, want, report.Results[i].Error)
+		}
+	}
+	if report.Succeeded != 3 || report.Failed != 3 {
+		t.Errorf("succeeded/failed = %d/%d, want 3/3", report.Succeeded, report.Failed)
+	}
+	if got := report.Results[3].Error; got != "order already shipped" {
+		t.Errorf("rejected order error = %q", got)
+	}
+	if got := report.Results[5].DocumentError; got != "no document" {
+		t.Errorf("missing AWB document error = %q, want %q", got, "no document")
+	}
+	if got := report.Results[0].Shipment; got == nil || got.Method != providers.ShippingMethodDropoff {
+		t.Errorf("shipment = %+v, want dropoff", got)
+	}
+
+	if want := [][]string{{"SN-1", "SN-REJECTED", "SN-3", "SN-NO-AWB"}}; !reflect.DeepEqual(provider.arranged, want) {
+		t.Errorf("arranged = %v, want %v", provider.arranged, want)
+	}
+	if want := []string{"SN-1", "SN-3", "SN-NO-AWB"}; !reflect.DeepEqual(recorder.shipped, want) {
+		t.Errorf("recorded shipped = %v, want %v", recorder.shipped, want)
+	}
+	wantSaved := map[string][]byte{"SN-1": []byte("awb 1"), "SN-3": []byte("awb 3")}
+	if !reflect.DeepEqual(documents.saved, wantSaved) {
+		t.Errorf("saved documents = %q, want %q", documents.saved, wantSaved)
+	}
+}
+
+func TestShipmentBatchPrintMergeError(t *testing.T) {
+	provider := &fakeShopeeBatchProvider{
+		documents: map[string][]byte{"SN-1": []byte("not a pdf"), "SN-2": []byte("not a pdf either")},
+	}
+	documents := newFakeDocumentStore()
+	s := &ShipmentBatchService{documents: documents, logger: zap.NewNop()}
+
+	orders, report := newBatch("SN-1", "SN-MISSING", "SN-2")
+	s.print(context.Background(), provider, orders, report, defaultShippingDocumentTypes["shopee"])
+	report.tally()
+
+	if report.Succeeded != 2 || report.Failed != 1 {
+		t.Errorf("succeeded/failed = %d/%d, want 2/1", report.Succeeded, report.Failed)
+	}
+	if got := report.Results[1].Error; got != "no document" {
+		t.Errorf("missing document error = %q, want %q", got, "no document")
+	}
+	if report.MergeError == "" {
+		t.Error("MergeError is empty, want the merge failure")
+	}
+	if report.Document != nil {
+		t.Errorf("Document = %q, want none", report.Document)
+	}
+	if len(documents.saved) != 2 || documents.saved["SN-1"] == nil || documents.saved["SN-2"] == nil {
+		t.Errorf("saved documents = %q, want SN-1 and SN-2", documents.saved)
+	}
+}
@@ -0,0 +1,300 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	"github.com/niaga-platform/service-marketplace/internal/models"
+	"github.com/niaga-platform/service-marketplace/internal/providers/shopee"
+	"github.com/niaga-platform/service-marketplace/internal/providers/tiktok"
+	"github.com/niaga-platform/service-marketplace/internal/repository"
+	"github.com/niaga-platform/service-marketplace/internal/storage"
+)
+
+// Shipping document errors
+var (
+	ErrShippingDocumentNotFound      = errors.New("shipping document not found")
+	ErrShippingDocumentsNotSupported = errors.New("shipping documents are not supported for this platform")
+)
+
+const (
+	// shippingDocumentPollInterval is how often document results are checked
+	shippingDocumentPollInterval = 2 * time.Second
+
+	defaultShippingDocumentTimeout = 2 * time.Minute
+)
+
+// defaultShippingDocumentTypes are the documents generated per platform when none is requested
+var defaultShippingDocumentTypes = map[string]string{
+	"shopee": "NORMAL_AIR_WAYBILL",
+	"tiktok": tiktok.DocumentShippingLabel,
+}
+
+// shippingDocumentGenerator downloads the shipping document of an order,
+// creating it on the marketplace first if needed
+type shippingDocumentGenerator interface {
+	GenerateShippingDocument(ctx context.Context, externalOrderID, documentType string) ([]byte, error)
+}
+
+// shopeeDocumentProvider creates and downloads Shopee shipping documents in batches
+type shopeeDocumentProvider interface {
+	CreateShippingDocuments(ctx context.Context, orderSNs []string, documentType string) (map[string]string, error)
+	GetShippingDocumentResults(ctx context.Context, orderSNs []string, documentType string) (map[string]*shopee.ShippingDocumentInfo, error)
+	FetchShippingDocument(ctx context.Context, orderSN string, documentType string) ([]byte, error)
+}
+
+// ShippingDocumentConfig holds shipping document configuration
+type ShippingDocumentConfig struct {
+	ReadyTimeout time.Duration // How long to wait for Shopee documents to be ready
+}
+
+// ShippingDocumentService keeps the shipping documents of orders in a blob
+// store. Marketplace download URLs expire quickly, so documents are downloaded
+// once, stored with their type and generation time, and served from here;
+// every download is recorded as a print.
+type ShippingDocumentService struct {
+	orderRepo    *repository.MarketplaceOrderRepository
+	documentRepo *repository.ShippingDocumentRepository
+	orderService *OrderSyncService
+	store        storage.BlobStore
+	config       ShippingDocumentConfig
+	logger       *zap.Logger
+}
+
+// NewShippingDocumentService creates a new ShippingDocumentService
+func NewShippingDocumentService(
+	orderRepo *repository.MarketplaceOrderRepository,
+	documentRepo *repository.ShippingDocumentRepository,
+	orderService *OrderSyncService,
+	store storage.BlobStore,
+	cfg ShippingDocumentConfig,
+	logger *zap.Logger,
+) *ShippingDocumentService {
+	if cfg.ReadyTimeout <= 0 {
+		cfg.ReadyTimeout = defaultShippingDocumentTimeout
+	}
+	return &ShippingDocumentService{
+		orderRepo:    orderRepo,
+		documentRepo: documentRepo,
+		orderService: orderService,
+		store:        store,
+		config:       cfg,
+		logger:       logger,
+	}
+}
+
+// GetDocument returns the latest stored document of a type for an order. The
+// document is downloaded from the marketplace when none is stored yet, or
+// when regenerate is set; earlier versions are kept. Shopee documents are
+// created and polled like a batch print, for at most the ready timeout.
+func (s *ShippingDocumentService) GetDocument(ctx context.Context, orderID uuid.UUID, documentType string, regenerate bool) (*models.ShippingDocument, error) {
+	order, conn, err := s.orderService.getOrderWithConnection(ctx, orderID)
+	if err != nil {
+		return nil, err
+	}
+	if documentType == "" {
+		documentType = defaultShippingDocumentTypes[conn.Platform]
+	}
+
+	if !regenerate {
+		document, err := s.documentRepo.GetLatest(ctx, orderID, documentType)
+		if err == nil {
+			return document, nil
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("failed to load shipping document: %w", err)
+		}
+	}
+
+	lister, err := s.orderService.newOrderLister(conn)
+	if err != nil {
+		return nil, err
+	}
+
+	var data []byte
+	switch provider := lister.(type) {
+	case *shopee.OrderProvider:
+		documents, failures := s.fetchShopeeDocuments(ctx, provider, []*models.MarketplaceOrder{order}, []int{0}, documentType)
+		document, ok := documents[0]
+		if !ok {
+			return nil, fmt.Errorf("failed to download shipping document: %s", failures[0])
+		}
+		data = document
+	case shippingDocumentGenerator:
+		if data, err = provider.GenerateShippingDocument(ctx, order.ExternalOrderID, documentType); err != nil {
+			return nil, fmt.Errorf("failed to download shipping document: %w", err)
+		}
+	default:
+		return nil, ErrShippingDocumentsNotSupported
+	}
+	return s.SaveDocument(ctx, order, documentType, data)
+}
+
+// SaveDocument stores a downloaded shipping document of an order
+func (s *ShippingDocumentService) SaveDocument(ctx context.Context, order *models.MarketplaceOrder, documentType string, data []byte) (*models.ShippingDocument, error) {
+	now := time.Now()
+	key := fmt.Sprintf("shipping-documents/%s/%s/%s-%d.pdf", order.ConnectionID, order.ID, documentType, now.UnixNano())
+	if err := s.store.Put(ctx, key, data); err != nil {
+		return nil, fmt.Errorf("failed to store shipping document: %w", err)
+	}
+
+	document := &models.ShippingDocument{
+		ConnectionID: order.ConnectionID,
+		OrderID:      order.ID,
+		DocumentType: documentType,
+		StorageKey:   key,
+		ContentType:  http.DetectContentType(data),
+		Size:         int64(len(data)),
+		GeneratedAt:  now,
+	}
+	if err := s.documentRepo.Create(ctx, document); err != nil {
+		if delErr := s.store.Delete(ctx, key); delErr != nil {
+			s.logger.Warn("failed to delete orphaned shipping document", zap.String("key", key), zap.Error(delErr))
+		}
+		return nil, fmt.Errorf("failed to record shipping document: %w", err)
+	}
+
+	s.logger.Info("shipping document stored",
+		zap.String("order_id", order.ID.String()),
+		zap.String("document_type", documentType),
+		zap.Int64("size", document.Size),
+	)
+	return document, nil
+}
+
+// fetchShopeeDocuments creates the documents of the Shopee orders at the
+// given indexes in batches, waits until they are ready and downloads them. It
+// returns the documents by order index, and the reason for every order
+// without one. The caller stores the documents.
+func (s *ShippingDocumentService) fetchShopeeDocuments(ctx context.Context, provider shopeeDocumentProvider, orders []*models.MarketplaceOrder, indexes []int, documentType string) (map[int][]byte, map[int]string) {
+	// Creation errors are only reported if the document does not turn out
+	// ready; orders printed before fail creation but can be downloaded again
+	createErrors := make(map[int]string)
+	for _, chunk := range chunkIndexes(indexes, shopee.MaxBatchOrders) {
+		failures, err := provider.CreateShippingDocuments(ctx, externalOrderIDs(orders, chunk), documentType)
+		for _, i := range chunk {
+			if err != nil {
+				createErrors[i] = err.Error()
+			} else if failure := failures[orders[i].ExternalOrderID]; failure != "" {
+				createErrors[i] = failure
+			}
+		}
+	}
+
+	ready, failures := s.waitForDocuments(ctx, provider, orders, indexes, documentType, createErrors)
+
+	documents := make(map[int][]byte, len(ready))
+	for _, i := range ready {
+		document, err := provider.FetchShippingDocument(ctx, orders[i].ExternalOrderID, documentType)
+		if err != nil {
+			failures[i] = fmt.Sprintf("failed to download shipping document: %v", err)
+			continue
+		}
+		documents[i] = document
+	}
+	return documents, failures
+}
+
+// waitForDocuments polls the document results of the pending orders until
+// each is ready or failed, or the ready timeout passes. It returns the
+// orders with a ready document, in their original order, and the reason
+// for every other order.
+func (s *ShippingDocumentService) waitForDocuments(ctx context.Context, provider shopeeDocumentProvider, orders []*models.MarketplaceOrder, pending []int, documentType string, createErrors map[int]string) ([]int, map[int]string) {
+	isReady := make(map[int]bool)
+	failures := make(map[int]string)
+	deadline := time.Now().Add(s.config.ReadyTimeout)
+
+	for len(pending) > 0 {
+		var processing []int
+		for _, chunk := range chunkIndexes(pending, shopee.MaxBatchOrders) {
+			results, err := provider.GetShippingDocumentResults(ctx, externalOrderIDs(orders, chunk), documentType)
+			if err != nil {
+				// Retried on the next poll
+				s.logger.Warn("failed to get shipping document results", zap.Error(err))
+				processing = append(processing, chunk...)
+				continue
+			}
+			for _, i := range chunk {
+				result := results[orders[i].ExternalOrderID]
+				switch {
+				case result != nil && result.Status == "READY":
+					isReady[i] = true
+				case result != nil && result.Status == "PROCESSING":
+					processing = append(processing, i)
+				case createErrors[i] != "":
+					failures[i] = createErrors[i]
+				case result != nil && result.ErrorMsg != "":
+					failures[i] = result.ErrorMsg
+				default:
+					failures[i] = "shipping document failed"
+				}
+			}
+		}
+		pending = processing
+
+		if len(pending) == 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			for _, i := range pending {
+				failures[i] = fmt.Sprintf("shipping document not ready after %s", s.config.ReadyTimeout)
+			}
+			break
+		}
+
+		select {
+		case <-ctx.Done():
+			for _, i := range pending {
+				failures[i] = ctx.Err().Error()
+			}
+			pending = nil
+		case <-time.After(shippingDocumentPollInterval):
+		}
+	}
+
+	var ready []int
+	for i := range orders {
+		if isReady[i] {
+			ready = append(ready, i)
+		}
+	}
+	return ready, failures
+}
+
+// GetDocuments lists the stored documents of an order with their print history
+func (s *ShippingDocumentService) GetDocuments(ctx context.Context, orderID uuid.UUID) ([]models.ShippingDocument, error) {
+	return s.documentRepo.GetByOrderID(ctx, orderID)
+}
+
+// OpenDocument opens a stored document of an order for download and records
+// the print; the caller closes the reader
+func (s *ShippingDocumentService) OpenDocument(ctx context.Context, orderID, documentID uuid.UUID, clientIP string) (*models.ShippingDocument, io.ReadCloser, error) {
+	document, err := s.documentRepo.GetByID(ctx, orderID, documentID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil, ErrShippingDocumentNotFound
+		}
+		return nil, nil, fmt.Errorf("failed to load shipping document: %w", err)
+	}
+
+	reader, err := s.store.Get(ctx, document.StorageKey)
+	if err != nil {
+		if errors.Is(err, storage.ErrBlobNotFound) {
+			return nil, nil, fmt.Errorf("%w: file missing from storage", ErrShippingDocumentNotFound)
+		}
+		return nil, nil, err
+	}
+
+	if err := s.documentRepo.RecordPrint(ctx, document.ID, clientIP); err != nil {
+		s.logger.Warn("failed to record shipping document print", zap.String("document_id", document.ID.String()), zap.Error(err))
+	}
+	return document, reader, nil
+}
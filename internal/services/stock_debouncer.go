@@ -0,0 +1,229 @@
+package services
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"github.com/niaga-platform/service-marketplace/internal/models"
+)
+
+// PendingStockUpdate is the latest known stock for a mapping location waiting to be flushed
+type PendingStockUpdate struct {
+	Mapping           models.ProductMapping
+	ExternalVariantID string // Marketplace model or SKU ID, empty for the listing itself
+	LocationID        string // Marketplace location, empty for the default
+	Quantity          int
+	Reason            string
+	Timestamp         time.Time // Time the stock change happened in the inventory service
+	Events            int       // Number of events coalesced into this update
+}
+
+// stockKey identifies the stock of one mapping model in one marketplace location
+type stockKey struct {
+	mappingID  uuid.UUID
+	variantID  string
+	locationID string
+}
+
+// stockOrder is the newest event time accepted for a mapping model location
+type stockOrder struct {
+	eventTime time.Time
+	seenAt    time.Time // When the key last accepted an event
+}
+
+// stockOrderRetention is how long the newest event time of a key is kept after
+// its last event, so late or redelivered messages are dropped after the push
+const stockOrderRetention = 24 * time.Hour
+
+// stockOrderPruneInterval is how often expired event times are dropped
+const stockOrderPruneInterval = time.Hour
+
+// StockFlushFunc pushes a batch of coalesced updates for one connection
+type StockFlushFunc func(ctx context.Context, connectionID uuid.UUID, updates []PendingStockUpdate)
+
+// StockDebouncer coalesces stock changes per product mapping and location (last write wins)
+// and flushes them in one batch per connection once the window elapses. A zero
+// window flushes every change straight away through the same ordered queue.
+//
+// Ordering guarantees:
+//   - an event older than one already accepted for the same mapping and
+//     location is dropped, whether the newer one is waiting or already pushed,
+//     so a late or redelivered message never overwrites newer stock. Event
+//     times are kept for stockOrderRetention after the key's last event.
+//   - flushes for the same connection never run concurrently, so batches reach
+//     the marketplace in the order they were collected
+type StockDebouncer struct {
+	window   time.Duration
+	maxBatch int
+	flush    StockFlushFunc
+	logger   *zap.Logger
+
+	mu      sync.Mutex
+	pending map[uuid.UUID]map[stockKey]*PendingStockUpdate // connection -> mapping location -> update
+	timers  map[uuid.UUID]*time.Timer
+	latest  map[stockKey]stockOrder              // mapping location -> newest accepted event time
+	queues  map[uuid.UUID][][]PendingStockUpdate // batches waiting to be pushed, per connection
+	active  map[uuid.UUID]bool                   // connections with a running flush worker
+	wg      sync.WaitGroup
+
+	retain   time.Duration
+	prunedAt time.Time
+}
+
+// NewStockDebouncer creates a new stock debouncer
+func NewStockDebouncer(window time.Duration, maxBatch int, flush StockFlushFunc, logger *zap.Logger) *StockDebouncer {
+	if maxBatch <= 0 {
+		maxBatch = 50
+	}
+	return &StockDebouncer{
+		window:   window,
+		maxBatch: maxBatch,
+		flush:    flush,
+		logger:   logger,
+		pending:  make(map[uuid.UUID]map[stockKey]*PendingStockUpdate),
+		timers:   make(map[uuid.UUID]*time.Timer),
+		latest:   make(map[stockKey]stockOrder),
+		queues:   make(map[uuid.UUID][][]PendingStockUpdate),
+		active:   make(map[uuid.UUID]bool),
+		retain:   stockOrderRetention,
+		prunedAt: time.Now(),
+	}
+}
+
+// Add queues a stock change for a mapping model and location, replacing any pending value
+func (d *StockDebouncer) Add(mapping models.ProductMapping, externalVariantID, locationID string, quantity int, reason string, timestamp time.Time) {
+	if timestamp.IsZero() {
+		timestamp = time.Now()
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	now := time.Now()
+	if now.Sub(d.prunedAt) >= stockOrderPruneInterval {
+		d.pruneLocked(now)
+	}
+
+	key := stockKey{mappingID: mapping.ID, variantID: externalVariantID, locationID: locationID}
+	if latest, ok := d.latest[key]; ok && timestamp.Before(latest.eventTime) {
+		d.logger.Debug("Dropping out-of-order stock change",
+			zap.String("mapping_id", mapping.ID.String()),
+			zap.String("external_variant_id", externalVariantID),
+			zap.String("location_id", locationID),
+			zap.Time("event_time", timestamp),
+			zap.Time("latest_time", latest.eventTime),
+		)
+		return
+	}
+	d.latest[key] = stockOrder{eventTime: timestamp, seenAt: now}
+
+	connectionID := mapping.ConnectionID
+	updates, ok := d.pending[connectionID]
+	if !ok {
+		updates = make(map[stockKey]*PendingStockUpdate)
+		d.pending[connectionID] = updates
+	}
+
+	events := 1
+	if existing, ok := updates[key]; ok {
+		events = existing.Events + 1
+	}
+	updates[key] = &PendingStockUpdate{
+		Mapping:           mapping,
+		ExternalVariantID: externalVariantID,
+		LocationID:        locationID,
+		Quantity:          quantity,
+		Reason:            reason,
+		Timestamp:         timestamp,
+		Events:            events,
+	}
+
+	if len(updates) >= d.maxBatch || d.window <= 0 {
+		d.flushLocked(connectionID)
+		return
+	}
+
+	if _, ok := d.timers[connectionID]; !ok {
+		d.timers[connectionID] = time.AfterFunc(d.window, func() {
+			d.mu.Lock()
+			defer d.mu.Unlock()
+			d.flushLocked(connectionID)
+		})
+	}
+}
+
+// flushLocked moves the pending batch for a connection onto its flush queue.
+// Callers must hold d.mu.
+func (d *StockDebouncer) flushLocked(connectionID uuid.UUID) {
+	if timer, ok := d.timers[connectionID]; ok {
+		timer.Stop()
+		delete(d.timers, connectionID)
+	}
+
+	pending := d.pending[connectionID]
+	delete(d.pending, connectionID)
+	if len(pending) == 0 {
+		return
+	}
+
+	batch := make([]PendingStockUpdate, 0, len(pending))
+	for _, update := range pending {
+		batch = append(batch, *update)
+	}
+	d.queues[connectionID] = append(d.queues[connectionID], batch)
+
+	// One worker per connection drains batches in order
+	if d.active[connectionID] {
+		return
+	}
+	d.active[connectionID] = true
+	d.wg.Add(1)
+	go d.drain(connectionID)
+}
+
+// drain pushes queued batches for a connection one at a time
+func (d *StockDebouncer) drain(connectionID uuid.UUID) {
+	defer d.wg.Done()
+
+	for {
+		d.mu.Lock()
+		queue := d.queues[connectionID]
+		if len(queue) == 0 {
+			delete(d.queues, connectionID)
+			delete(d.active, connectionID)
+			d.mu.Unlock()
+			return
+		}
+		batch := queue[0]
+		d.queues[connectionID] = queue[1:]
+		d.mu.Unlock()
+
+		d.flush(context.Background(), connectionID, batch)
+	}
+}
+
+// pruneLocked drops the event times of keys without an event for longer than
+// the retention, so latest does not grow without bound. Callers must hold d.mu.
+func (d *StockDebouncer) pruneLocked(now time.Time) {
+	for key, order := range d.latest {
+		if now.Sub(order.seenAt) > d.retain {
+			delete(d.latest, key)
+		}
+	}
+	d.prunedAt = now
+}
+
+// Flush pushes every pending batch immediately and waits for them to finish
+func (d *StockDebouncer) Flush() {
+	d.mu.Lock()
+	for connectionID := range d.pending {
+		d.flushLocked(connectionID)
+	}
+	d.mu.Unlock()
+
+	d.wg.Wait()
+}
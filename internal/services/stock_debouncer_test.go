@@ -0,0 +1,266 @@
+package services
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"github.com/niaga-platform/service-marketplace/internal/models"
+)
+
+func TestStockDebouncerCoalescing(t *testing.T) {
+	connA := uuid.New()
+	connB := uuid.New()
+	mappingA := models.ProductMapping{ID: uuid.New(), ConnectionID: connA}
+	mappingB := models.ProductMapping{ID: uuid.New(), ConnectionID: connB}
+	base := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+
+	type event struct {
+		mapping  models.ProductMapping
+		location string
+		quantity int
+		at       time.Time
+	}
+	type want struct {
+		quantity int
+		events   int
+	}
+
+	tests := []struct {
+		name   string
+		events []event
+		want   map[uuid.UUID]map[stockKey]want // connection -> mapping location -> pushed update
+	}{
+		{
+			name: "last write wins",
+			events: []event{
+				{mappingA, "", 10, base},
+				{mappingA, "", 8, base.Add(time.Second)},
+				{mappingA, "", 5, base.Add(2 * time.Second)},
+			},
+			want: map[uuid.UUID]map[stockKey]want{
+				connA: {{mappingA.ID, "", ""}: {quantity: 5, events: 3}},
+			},
+		},
+		{
+			name: "older event is dropped",
+			events: []event{
+				{mappingA, "", 5, base.Add(time.Minute)},
+				{mappingA, "", 9, base},
+			},
+			want: map[uuid.UUID]map[stockKey]want{
+				connA: {{mappingA.ID, "", ""}: {quantity: 5, events: 1}},
+			},
+		},
+		{
+			name: "locations are kept apart",
+			events: []event{
+				{mappingA, "L1", 3, base},
+				{mappingA, "L2", 4, base},
+				{mappingA, "L1", 2, base.Add(time.Second)},
+			},
+			want: map[uuid.UUID]map[stockKey]want{
+				connA: {
+					{mappingA.ID, "", "L1"}: {quantity: 2, events: 2},
+					{mappingA.ID, "", "L2"}: {quantity: 4, events: 1},
+				},
+			},
+		},
+		{
+			name: "one batch per connection",
+			events: []event{
+				{mappingA, "", 1, base},
+				{mappingB, "", 2, base},
+			},
+			want: map[uuid.UUID]map[stockKey]want{
+				connA: {{mappingA.ID, "", ""}: {quantity: 1, events: 1}},
+				connB: {{mappingB.ID, "", ""}: {quantity: 2, events: 1}},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var mu sync.Mutex
+			got := make(map[uuid.UUID][]PendingStockUpdate)
+			flush := func(_ context.Context, connectionID uuid.UUID, updates []PendingStockUpdate) {
+				mu.Lock()
+				defer mu.Unlock()
+				got[connectionID] = append(got[connectionID], updates...)
+			}
+
+			d := NewStockDebouncer(time.Hour, 50, flush, zap.NewNop())
+			for _, e := range tt.events {
+				d.Add(e.mapping, "", e.location, e.quantity, "sale", e.at)
+			}
+			d.Flush()
+
+			if len(got) != len(tt.want) {
+				t.Fatalf("flushed %d connections, want %d", len(got), len(tt.want))
+			}
+			for connectionID, wantUpdates := range tt.want {
+				updates := got[connectionID]
+				if len(updates) != len(wantUpdates) {
+					t.Fatalf("connection flushed %d updates, want %d", len(updates), len(wantUpdates))
+				}
+				for _, update := range updates {
+					w, ok := wantUpdates[stockKey{update.Mapping.ID, update.ExternalVariantID, update.LocationID}]
+					if !ok {
+						t.Fatalf("unexpected update for location %q", update.LocationID)
+					}
+					if update.Quantity != w.quantity || update.Events != w.events {
+						t.Errorf("location %q: quantity %d events %d, want %d and %d",
+							update.LocationID, update.Quantity, update.Events, w.quantity, w.events)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestStockDebouncerOrderingAfterFlush(t *testing.T) {
+	mapping := models.ProductMapping{ID: uuid.New(), ConnectionID: uuid.New()}
+	base := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+
+	type event struct {
+		quantity int
+		at       time.Time
+	}
+
+	tests := []struct {
+		name   string
+		window time.Duration
+		first  event
+		second event
+		want   []int // Quantities pushed, in order
+	}{
+		{
+			name:   "late event after the push is dropped",
+			window: time.Hour,
+			first:  event{5, base.Add(time.Minute)},
+			second: event{9, base},
+			want:   []int{5},
+		},
+		{
+			name:   "redelivered event after the push is pushed again",
+			window: time.Hour,
+			first:  event{5, base},
+			second: event{5, base},
+			want:   []int{5, 5},
+		},
+		{
+			name:   "newer event after the push is pushed",
+			window: time.Hour,
+			first:  event{5, base},
+			second: event{3, base.Add(time.Minute)},
+			want:   []int{5, 3},
+		},
+		{
+			name:   "late event is dropped without a window",
+			window: 0,
+			first:  event{5, base.Add(time.Minute)},
+			second: event{9, base},
+			want:   []int{5},
+		},
+		{
+			name:   "events are pushed in order without a window",
+			window: 0,
+			first:  event{5, base},
+			second: event{3, base.Add(time.Minute)},
+			want:   []int{5, 3},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var mu sync.Mutex
+			var pushed []int
+			flush := func(_ context.Context, _ uuid.UUID, updates []PendingStockUpdate) {
+				mu.Lock()
+				defer mu.Unlock()
+				for _, update := range updates {
+					pushed = append(pushed, update.Quantity)
+				}
+			}
+
+			d := NewStockDebouncer(tt.window, 50, flush, zap.NewNop())
+			d.Add(mapping, "", "", tt.first.quantity, "sale", tt.first.at)
+			d.Flush()
+			d.Add(mapping, "", "", tt.second.quantity, "sale", tt.second.at)
+			d.Flush()
+
+			if len(pushed) != len(tt.want) {
+				t.Fatalf("pushed %v, want %v", pushed, tt.want)
+			}
+			for i := range tt.want {
+				if pushed[i] != tt.want[i] {
+					t.Fatalf("pushed %v, want %v", pushed, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestStockDebouncerPrune(t *testing.T) {
+	mapping := models.ProductMapping{ID: uuid.New(), ConnectionID: uuid.New()}
+	flush := func(context.Context, uuid.UUID, []PendingStockUpdate) {}
+
+	d := NewStockDebouncer(time.Hour, 50, flush, zap.NewNop())
+	d.Add(mapping, "", "", 5, "sale", time.Now())
+	d.Flush()
+
+	tests := []struct {
+		name string
+		at   time.Time
+		want int
+	}{
+		{name: "kept within the retention", at: time.Now().Add(stockOrderRetention / 2), want: 1},
+		{name: "dropped after the retention", at: time.Now().Add(stockOrderRetention + time.Minute), want: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d.mu.Lock()
+			d.pruneLocked(tt.at)
+			got := len(d.latest)
+			d.mu.Unlock()
+
+			if got != tt.want {
+				t.Errorf("latest keeps %d entries, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStockDebouncerMaxBatch(t *testing.T) {
+	connectionID := uuid.New()
+	var mu sync.Mutex
+	var batches [][]PendingStockUpdate
+	flush := func(_ context.Context, _ uuid.UUID, updates []PendingStockUpdate) {
+		mu.Lock()
+		defer mu.Unlock()
+		batches = append(batches, updates)
+	}
+
+	d := NewStockDebouncer(time.Hour, 2, flush, zap.NewNop())
+	for i := 0; i < 3; i++ {
+		d.Add(models.ProductMapping{ID: uuid.New(), ConnectionID: connectionID}, "", "", i, "sale", time.Now())
+	}
+	d.Flush()
+
+	if len(batches) != 2 || len(batches[0]) != 2 || len(batches[1]) != 1 {
+		t.Fatalf("got batches of sizes %v, want [2 1]", batchSizes(batches))
+	}
+}
+
+func batchSizes(batches [][]PendingStockUpdate) []int {
+	sizes := make([]int, len(batches))
+	for i, batch := range batches {
+		sizes[i] = len(batch)
+	}
+	return sizes
+}
@@ -0,0 +1,688 @@
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"runtime/debug"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+
+	shopeedomain "github.com/niaga-platform/service-marketplace/internal/domain/shopee"
+	"github.com/niaga-platform/service-marketplace/internal/models"
+	"github.com/niaga-platform/service-marketplace/internal/providers/shopee"
+	"github.com/niaga-platform/service-marketplace/internal/providers/tiktok"
+	"github.com/niaga-platform/service-marketplace/internal/repository"
+)
+
+var (
+	ErrUnknownWebhookPlatform = errors.New("unknown webhook platform")
+	ErrWebhookEventNotFound   = errors.New("webhook event not found")
+	ErrWebhookURLRequired     = errors.New("SHOPEE_WEBHOOK_URL is required to verify Shopee webhooks in strict mode")
+
+	// errWebhookUnhandled marks deliveries no handler acts on. They are kept
+	// unprocessed so they can be replayed once a handler exists.
+	errWebhookUnhandled = errors.New("unhandled")
+)
+
+// resumeBatchSize is how many stored events are replayed per query on startup
+const resumeBatchSize = 100
+
+// Webhook verification modes
+const (
+	WebhookVerifyStrict = "strict" // Reject deliveries that fail signature or freshness checks
+	WebhookVerifyGrace  = "grace"  // Record failures but process the delivery anyway
+)
+
+// WebhookServiceConfig holds webhook verification configuration
+type WebhookServiceConfig struct {
+	ShopeePartnerKey string
+	ShopeeWebhookURL string // Callback URL registered with Shopee; part of the signed base string
+	TikTokAppKey     string
+	TikTokAppSecret  string
+	VerifyMode       string // Empty uses strict, or grace while Shopee is set up without ShopeeWebhookURL
+}
+
+// WebhookVerification is the outcome of authenticating a delivery
+type WebhookVerification struct {
+	Status    string // models.WebhookVerification* constant
+	Reason    string // Why verification failed
+	Signature string
+}
+
+// ReturnEventHandler processes marketplace return and refund pushes
+type ReturnEventHandler interface {
+	HandleShopeeReturnEvent(shopID int64, returnSN, orderSN, status string) error
+	HandleTikTokReturnEvent(shopID, returnID, orderID, status string) error
+}
+
+// WebhookService stores inbound marketplace webhooks and processes them
+// asynchronously. An event is written to webhook_events before the webhook is
+// acknowledged, so a crash during processing never loses it: events that were
+// stored but never attempted are picked up again by ResumePending.
+type WebhookService struct {
+	webhookRepo       *repository.WebhookEventRepository
+	connectionService *ConnectionService
+	productService    *ProductSyncService
+	orderService      *OrderSyncService
+	inventoryService  *InventorySyncService
+	returnHandler     ReturnEventHandler
+	shopeeWebhook     *shopee.WebhookHandler
+	tiktokWebhook     *tiktok.WebhookHandler
+	config            WebhookServiceConfig
+	logger            *zap.Logger
+
+	wg sync.WaitGroup
+}
+
+// NewWebhookService creates a new WebhookService
+func NewWebhookService(
+	webhookRepo *repository.WebhookEventRepository,
+	connectionService *ConnectionService,
+	productService *ProductSyncService,
+	orderService *OrderSyncService,
+	inventoryService *InventorySyncService,
+	cfg WebhookServiceConfig,
+	logger *zap.Logger,
+) (*WebhookService, error) {
+	missingShopeeURL := cfg.ShopeePartnerKey != "" && cfg.ShopeeWebhookURL == ""
+	switch {
+	case cfg.VerifyMode == "" && missingShopeeURL:
+		// Deployments that predate SHOPEE_WEBHOOK_URL keep accepting pushes
+		// until it is configured; strict mode must be chosen explicitly
+		cfg.VerifyMode = WebhookVerifyGrace
+	case cfg.VerifyMode != WebhookVerifyGrace:
+		cfg.VerifyMode = WebhookVerifyStrict
+	}
+	if missingShopeeURL {
+		// Every Shopee push would be rejected as unverifiable
+		if cfg.VerifyMode == WebhookVerifyStrict {
+			return nil, ErrWebhookURLRequired
+		}
+		logger.Warn("SHOPEE_WEBHOOK_URL is not set; Shopee webhook signatures cannot be verified, accepting webhooks in grace mode")
+	}
+
+	return &WebhookService{
+		webhookRepo:       webhookRepo,
+		connectionService: connectionService,
+		productService:    productService,
+		orderService:      orderService,
+		inventoryService:  inventoryService,
+		shopeeWebhook:     shopee.NewWebhookHandler(cfg.ShopeePartnerKey, cfg.ShopeeWebhookURL, logger),
+		tiktokWebhook:     tiktok.NewWebhookHandler(cfg.TikTokAppKey, cfg.TikTokAppSecret, logger),
+		config:            cfg,
+		logger:            logger,
+	}, nil
+}
+
+// SetReturnHandler sets the handler for return and refund pushes. Until one is
+// set, return pushes are recorded as unhandled and stay stored for replay.
+func (s *WebhookService) SetReturnHandler(handler ReturnEventHandler) {
+	s.returnHandler = handler
+}
+
+// Verify checks the signature and timestamp of a delivery. Platforms without a
+// configured secret are reported as skipped.
+func (s *WebhookService) Verify(ctx context.Context, platform string, body []byte, headers map[string]string) *WebhookVerification {
+	result := s.verifySignature(ctx, platform, body, headers)
+	if result.Status == models.WebhookVerificationFailed {
+		return result
+	}
+
+	// A valid signature on an old delivery may be a replay. Marketplace retries
+	// resend the original body and timestamp, so a retry of a stored delivery
+	// is exempt: Receive acknowledges it by fingerprint without processing it.
+	if reason := deliveryFreshness(body, time.Now()); reason != "" {
+		if reason == "stale timestamp" && s.isRedelivery(ctx, platform, body) {
+			return result
+		}
+		result.Status, result.Reason = models.WebhookVerificationFailed, reason
+	}
+
+	return result
+}
+
+// verifySignature checks the platform signature of a delivery
+func (s *WebhookService) verifySignature(ctx context.Context, platform string, body []byte, headers map[string]string) *WebhookVerification {
+	result := &WebhookVerification{Status: models.WebhookVerificationSkipped}
+
+	switch platform {
+	case "shopee":
+		result.Signature = headers["Authorization"]
+		if s.config.ShopeePartnerKey != "" {
+			switch {
+			case s.config.ShopeeWebhookURL == "":
+				result.Status, result.Reason = models.WebhookVerificationFailed, "webhook URL not configured"
+			case result.Signature == "":
+				result.Status, result.Reason = models.WebhookVerificationFailed, "missing signature"
+			default:
+				if ok, _ := s.shopeeWebhook.VerifyWebhook(ctx, body, headers); ok {
+					result.Status = models.WebhookVerificationVerified
+				} else {
+					result.Status, result.Reason = models.WebhookVerificationFailed, "invalid signature"
+				}
+			}
+		}
+
+	case "tiktok":
+		result.Signature = headers["Authorization"]
+		if result.Signature == "" {
+			result.Signature = headers["X-Tts-Signature"]
+		}
+		if s.config.TikTokAppSecret != "" {
+			switch {
+			case result.Signature == "":
+				result.Status, result.Reason = models.WebhookVerificationFailed, "missing signature"
+			default:
+				if ok, _ := s.tiktokWebhook.VerifyWebhook(ctx, body, headers); ok {
+					result.Status = models.WebhookVerificationVerified
+				} else {
+					result.Status, result.Reason = models.WebhookVerificationFailed, "invalid signature"
+				}
+			}
+		}
+
+	default:
+		result.Status, result.Reason = models.WebhookVerificationFailed, ErrUnknownWebhookPlatform.Error()
+	}
+
+	return result
+}
+
+// deliveryFreshness returns why a delivery's timestamp is unacceptable at now,
+// or "" when it is recent. Both platforms send the delivery time in seconds.
+func deliveryFreshness(body []byte, now time.Time) string {
+	var envelope struct {
+		Timestamp int64 `json:"timestamp"`
+	}
+	_ = json.Unmarshal(body, &envelope)
+	if envelope.Timestamp == 0 {
+		return "missing timestamp"
+	}
+	if !shopeedomain.ValidateTimestamp(envelope.Timestamp, now.Unix()) {
+		return "stale timestamp"
+	}
+	return ""
+}
+
+// isRedelivery reports whether a delivery with the same body was already stored
+func (s *WebhookService) isRedelivery(ctx context.Context, platform string, body []byte) bool {
+	_, err := s.webhookRepo.GetByFingerprint(ctx, deliveryFingerprint(platform, body))
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		s.logger.Warn("Failed to look up webhook fingerprint", zap.String("platform", platform), zap.Error(err))
+	}
+	return err == nil
+}
+
+// Accepts reports whether a delivery should be processed given its verification
+func (s *WebhookService) Accepts(verification *WebhookVerification) bool {
+	return verification.Status != models.WebhookVerificationFailed || s.config.VerifyMode == WebhookVerifyGrace
+}
+
+// Receive stores a raw webhook and schedules it for processing. A redelivery
+// of an already stored webhook is not processed again; the stored event is
+// returned and duplicate is true.
+func (s *WebhookService) Receive(ctx context.Context, platform string, body []byte, verification *WebhookVerification) (event *models.WebhookEvent, duplicate bool, err error) {
+	event = s.newEvent(platform, body, verification)
+	fingerprint := deliveryFingerprint(platform, body)
+	event.Fingerprint = &fingerprint
+
+	created, err := s.webhookRepo.CreateIfNew(ctx, event)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to store webhook event: %w", err)
+	}
+	if !created {
+		existing, err := s.webhookRepo.GetByFingerprint(ctx, fingerprint)
+		if err != nil {
+			return nil, true, fmt.Errorf("failed to load duplicate webhook event: %w", err)
+		}
+		s.logger.Info("Duplicate webhook delivery skipped",
+			zap.String("event_id", existing.ID.String()),
+			zap.String("platform", platform),
+			zap.String("event_type", existing.EventType),
+		)
+		return existing, true, nil
+	}
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		s.processEvent(context.Background(), event)
+	}()
+
+	return event, false, nil
+}
+
+// Reject stores a webhook that will not be processed because it failed
+// verification, and records why
+func (s *WebhookService) Reject(ctx context.Context, platform string, body []byte, verification *WebhookVerification) (*models.WebhookEvent, error) {
+	event := s.newEvent(platform, body, verification)
+	if err := s.webhookRepo.Create(ctx, event); err != nil {
+		return nil, fmt.Errorf("failed to store webhook event: %w", err)
+	}
+	if err := s.webhookRepo.MarkFailed(ctx, event.ID, "rejected: "+verification.Reason); err != nil {
+		return event, fmt.Errorf("failed to mark webhook event: %w", err)
+	}
+	return event, nil
+}
+
+// Start processes, in the background, events that were stored but never
+// attempted, e.g. because the service stopped before picking them up
+func (s *WebhookService) Start() {
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		s.ResumePending(context.Background())
+	}()
+}
+
+// ResumePending processes events received before the call that were stored but
+// never attempted
+func (s *WebhookService) ResumePending(ctx context.Context) {
+	startedAt := time.Now()
+	resumed := 0
+
+	for {
+		pending, err := s.webhookRepo.GetPending(ctx, startedAt, resumeBatchSize)
+		if err != nil {
+			s.logger.Error("Failed to load pending webhook events", zap.Error(err))
+			return
+		}
+		if len(pending) == 0 {
+			break
+		}
+
+		for i := range pending {
+			if !s.processEvent(ctx, &pending[i]) {
+				// Outcome could not be stored; stop rather than loop on the same events
+				return
+			}
+			resumed++
+		}
+	}
+
+	if resumed > 0 {
+		s.logger.Info("Resumed pending webhook events", zap.Int("count", resumed))
+	}
+}
+
+// Stop waits for in-flight webhook processing to finish
+func (s *WebhookService) Stop() {
+	s.wg.Wait()
+}
+
+// WebhookReplayResult is the outcome of replaying one stored event
+type WebhookReplayResult struct {
+	EventID    uuid.UUID `json:"event_id"`
+	Success    bool      `json:"success"`
+	Error      string    `json:"error,omitempty"`
+	DurationMs int64     `json:"duration_ms"`
+}
+
+// ListEvents lists stored webhook events
+func (s *WebhookService) ListEvents(ctx context.Context, filter *models.WebhookEventFilter) ([]models.WebhookEvent, int64, error) {
+	return s.webhookRepo.GetAll(ctx, filter)
+}
+
+// GetEvent retrieves a stored webhook event with its replay history
+func (s *WebhookService) GetEvent(ctx context.Context, id uuid.UUID) (*models.WebhookEvent, []models.WebhookEventReplay, error) {
+	event, err := s.webhookRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, nil, ErrWebhookEventNotFound
+	}
+
+	replays, err := s.webhookRepo.GetReplays(ctx, id)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get replays: %w", err)
+	}
+
+	return event, replays, nil
+}
+
+// Replay re-runs stored events through the normal processing pipeline, one at
+// a time, and records each outcome on the event and in its replay history
+func (s *WebhookService) Replay(ctx context.Context, ids []uuid.UUID) ([]WebhookReplayResult, error) {
+	found, err := s.webhookRepo.GetByIDs(ctx, ids)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get webhook events: %w", err)
+	}
+	byID := make(map[uuid.UUID]*models.WebhookEvent, len(found))
+	for i := range found {
+		byID[found[i].ID] = &found[i]
+	}
+
+	results := make([]WebhookReplayResult, len(ids))
+	for i, id := range ids {
+		results[i].EventID = id
+
+		event, ok := byID[id]
+		if !ok {
+			results[i].Error = ErrWebhookEventNotFound.Error()
+			continue
+		}
+
+		start := time.Now()
+		processErr := s.dispatchSafely(event)
+		results[i].DurationMs = time.Since(start).Milliseconds()
+		results[i].Success = processErr == nil
+		if processErr != nil {
+			results[i].Error = processErr.Error()
+		}
+
+		if err := s.recordOutcome(ctx, event, processErr); err != nil {
+			results[i].Error = fmt.Sprintf("replayed but outcome not saved: %v", err)
+		}
+
+		replay := &models.WebhookEventReplay{
+			WebhookEventID: id,
+			Success:        results[i].Success,
+			ErrorMessage:   results[i].Error,
+			DurationMs:     results[i].DurationMs,
+		}
+		if err := s.webhookRepo.CreateReplay(ctx, replay); err != nil {
+			s.logger.Error("Failed to record webhook replay", zap.String("event_id", id.String()), zap.Error(err))
+		}
+	}
+
+	return results, nil
+}
+
+// ReplayEvent re-runs a single stored event
+func (s *WebhookService) ReplayEvent(ctx context.Context, id uuid.UUID) (*WebhookReplayResult, error) {
+	if _, err := s.webhookRepo.GetByID(ctx, id); err != nil {
+		return nil, ErrWebhookEventNotFound
+	}
+
+	results, err := s.Replay(ctx, []uuid.UUID{id})
+	if err != nil {
+		return nil, err
+	}
+	return &results[0], nil
+}
+
+// MarkProcessed flags events as handled without running them, e.g. after
+// support fixed the data by hand
+func (s *WebhookService) MarkProcessed(ctx context.Context, req *models.MarkProcessedRequest) (int64, error) {
+	return s.webhookRepo.MarkResolved(ctx, req.EventIDs, req.ErrorMessage)
+}
+
+// newEvent builds the stored form of a raw webhook with its platform event type and shop
+func (s *WebhookService) newEvent(platform string, body []byte, verification *WebhookVerification) *models.WebhookEvent {
+	payload := body
+	if !json.Valid(body) {
+		// jsonb needs valid JSON; keep the raw body so the event can still be inspected
+		payload, _ = json.Marshal(map[string]string{"raw": string(body)})
+	}
+
+	eventType, shopID := s.describe(platform, body)
+	return &models.WebhookEvent{
+		Platform:           platform,
+		EventType:          eventType,
+		ShopID:             shopID,
+		Payload:            datatypes.JSON(payload),
+		Signature:          verification.Signature,
+		VerificationStatus: verification.Status,
+	}
+}
+
+// deliveryFingerprint identifies a delivery; marketplaces resend the exact
+// same body when they retry
+func deliveryFingerprint(platform string, body []byte) string {
+	sum := sha256.Sum256(append([]byte(platform+"|"), body...))
+	return hex.EncodeToString(sum[:])
+}
+
+// describe extracts the mapped event type and shop ID of a raw webhook
+func (s *WebhookService) describe(platform string, body []byte) (string, string) {
+	switch platform {
+	case "shopee":
+		parsed, err := s.shopeeWebhook.ParseWebhookEvent(body)
+		if err != nil {
+			return "unknown", ""
+		}
+		return parsed.Type, parsed.ShopID
+
+	case "tiktok":
+		parsed, err := s.tiktokWebhook.ParseWebhookEvent(body)
+		if err != nil {
+			return "unknown", ""
+		}
+		return parsed.Type, parsed.ShopID
+
+	default:
+		return "unknown", ""
+	}
+}
+
+// processEvent runs the handler for a stored event and records the outcome.
+// It reports whether the outcome was saved.
+func (s *WebhookService) processEvent(ctx context.Context, event *models.WebhookEvent) bool {
+	err := s.dispatchSafely(event)
+	return s.recordOutcome(ctx, event, err) == nil
+}
+
+// recordOutcome stores the result of a processing attempt on the event
+func (s *WebhookService) recordOutcome(ctx context.Context, event *models.WebhookEvent, processErr error) error {
+	if errors.Is(processErr, errWebhookUnhandled) {
+		s.logger.Warn("Webhook not handled",
+			zap.String("event_id", event.ID.String()),
+			zap.String("platform", event.Platform),
+			zap.String("event_type", event.EventType),
+			zap.String("reason", processErr.Error()),
+		)
+		if err := s.webhookRepo.MarkFailed(ctx, event.ID, processErr.Error()); err != nil {
+			s.logger.Error("Failed to mark webhook event unhandled", zap.String("event_id", event.ID.String()), zap.Error(err))
+			return err
+		}
+		return nil
+	}
+	if processErr != nil {
+		s.logger.Error("Webhook processing failed",
+			zap.String("event_id", event.ID.String()),
+			zap.String("platform", event.Platform),
+			zap.String("event_type", event.EventType),
+			zap.Error(processErr),
+		)
+		if err := s.webhookRepo.MarkFailed(ctx, event.ID, processErr.Error()); err != nil {
+			s.logger.Error("Failed to mark webhook event failed", zap.String("event_id", event.ID.String()), zap.Error(err))
+			return err
+		}
+		return nil
+	}
+
+	if err := s.webhookRepo.MarkProcessed(ctx, event.ID); err != nil {
+		s.logger.Error("Failed to mark webhook event processed", zap.String("event_id", event.ID.String()), zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// dispatchSafely runs dispatch, turning a panic into an error
+func (s *WebhookService) dispatchSafely(event *models.WebhookEvent) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			s.logger.Error("Panic while processing webhook",
+				zap.String("event_id", event.ID.String()),
+				zap.Any("panic", r),
+				zap.ByteString("stack", debug.Stack()),
+			)
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+
+	return s.dispatch(event)
+}
+
+// dispatch routes a stored event to the service that handles it
+func (s *WebhookService) dispatch(event *models.WebhookEvent) error {
+	switch event.Platform {
+	case "shopee":
+		return s.dispatchShopee(event)
+	case "tiktok":
+		return s.dispatchTikTok(event)
+	default:
+		return ErrUnknownWebhookPlatform
+	}
+}
+
+// dispatchShopee routes a stored Shopee push to the handler for its push code
+func (s *WebhookService) dispatchShopee(event *models.WebhookEvent) error {
+	var envelope shopee.WebhookPayload
+	if err := json.Unmarshal(event.Payload, &envelope); err != nil {
+		return fmt.Errorf("failed to parse Shopee webhook: %w", err)
+	}
+	parsed, err := s.shopeeWebhook.ParseWebhookEvent(event.Payload)
+	if err != nil {
+		return err
+	}
+	shopID, err := parseShopeeShopID(parsed.ShopID)
+	if err != nil {
+		return err
+	}
+
+	switch envelope.Code {
+	case shopee.PushCodeShopAuthorization:
+		s.logger.Info("Shopee shop authorised", zap.Int64("shop_id", shopID))
+		return nil
+
+	case shopee.PushCodeShopDeauthorization:
+		return s.connectionService.HandleDeauthorization(context.Background(), "shopee", parsed.ShopID)
+
+	case shopee.PushCodeOrderStatusUpdate:
+		data, ok := parsed.Payload.(shopee.OrderStatusData)
+		if !ok || data.OrderSN == "" {
+			return errors.New("order status push without ordersn")
+		}
+		return s.orderService.HandleShopeeOrderEvent(shopID, data.OrderSN, data.Status)
+
+	case shopee.PushCodeTrackingUpdate:
+		data, ok := parsed.Payload.(shopee.TrackingUpdateData)
+		if !ok || data.OrderSN == "" {
+			return errors.New("tracking push without ordersn")
+		}
+		return s.orderService.HandleShopeeTrackingUpdate(shopID, &data)
+
+	case shopee.PushCodeItemPromotion:
+		// Promotions only matter once they lock stock, which arrives as a reserved stock push
+		return fmt.Errorf("%w: Shopee item promotion push", errWebhookUnhandled)
+
+	case shopee.PushCodeReservedStockChange:
+		data, ok := parsed.Payload.(shopee.ReservedStockChangeData)
+		if !ok {
+			return errors.New("malformed reserved stock push")
+		}
+		if s.inventoryService == nil {
+			return errors.New("inventory sync service not configured")
+		}
+		return s.inventoryService.HandleShopeeReservedStockChange(shopID, &data)
+
+	case shopee.PushCodeReturnStatusUpdate:
+		data, ok := parsed.Payload.(shopee.ReturnStatusData)
+		if !ok || data.ReturnSN == "" {
+			return errors.New("return push without returnsn")
+		}
+		if s.returnHandler == nil {
+			return fmt.Errorf("%w: return handler not configured", errWebhookUnhandled)
+		}
+		return s.returnHandler.HandleShopeeReturnEvent(shopID, data.ReturnSN, data.OrderSN, data.Status)
+
+	case shopee.PushCodeViolationItem:
+		data, ok := parsed.Payload.(shopee.ViolationItemData)
+		if !ok || data.ItemID == 0 {
+			return errors.New("item violation push without item_id")
+		}
+		return s.productService.HandleShopeeItemViolation(shopID, &data)
+
+	case shopee.PushCodeWebhookTest:
+		s.logger.Info("Shopee webhook test push received", zap.Int64("shop_id", shopID))
+		return nil
+
+	default:
+		return fmt.Errorf("%w: Shopee push code %d", errWebhookUnhandled, envelope.Code)
+	}
+}
+
+// dispatchTikTok routes a stored TikTok webhook to the handler for its type
+func (s *WebhookService) dispatchTikTok(event *models.WebhookEvent) error {
+	var envelope tiktok.WebhookPayload
+	if err := json.Unmarshal(event.Payload, &envelope); err != nil {
+		return fmt.Errorf("failed to parse TikTok webhook: %w", err)
+	}
+	parsed, err := s.tiktokWebhook.ParseWebhookEvent(event.Payload)
+	if err != nil {
+		return err
+	}
+
+	switch envelope.TypeCode() {
+	case tiktok.WebhookTypeOrderStatusChange:
+		data, ok := parsed.Payload.(tiktok.OrderStatusData)
+		if !ok || data.OrderID == "" {
+			return errors.New("order status webhook without order_id")
+		}
+		return s.orderService.HandleTikTokOrderEvent(parsed.ShopID, data.OrderID, string(data.OrderStatus))
+
+	case tiktok.WebhookTypeReverseOrderStatusChange, tiktok.WebhookTypeReturnStatusChange:
+		data, ok := parsed.Payload.(tiktok.ReturnStatusData)
+		if !ok || data.ReturnID == "" {
+			return errors.New("return webhook without return_id")
+		}
+		if s.returnHandler == nil {
+			return fmt.Errorf("%w: return handler not configured", errWebhookUnhandled)
+		}
+		return s.returnHandler.HandleTikTokReturnEvent(parsed.ShopID, data.ReturnID, data.OrderID, string(data.ReturnStatus))
+
+	case tiktok.WebhookTypeCancellationStatusChange:
+		// A cancellation changes the order status, so refresh the order itself
+		data, ok := parsed.Payload.(tiktok.CancellationStatusData)
+		if !ok || data.OrderID == "" {
+			return errors.New("cancellation webhook without order_id")
+		}
+		return s.orderService.HandleTikTokOrderEvent(parsed.ShopID, data.OrderID, string(data.CancelStatus))
+
+	case tiktok.WebhookTypePackageUpdate:
+		// Tracking number and carrier come with the order detail
+		data, ok := parsed.Payload.(tiktok.PackageUpdateData)
+		if !ok || data.OrderID == "" {
+			return errors.New("package webhook without order_id")
+		}
+		return s.orderService.HandleTikTokOrderEvent(parsed.ShopID, data.OrderID, "")
+
+	case tiktok.WebhookTypeRecipientAddressUpdate:
+		data, ok := parsed.Payload.(tiktok.AddressUpdateData)
+		if !ok || data.OrderID == "" {
+			return errors.New("address webhook without order_id")
+		}
+		return s.orderService.HandleTikTokAddressUpdate(parsed.ShopID, data.OrderID)
+
+	case tiktok.WebhookTypeProductStatusChange:
+		data, ok := parsed.Payload.(tiktok.ProductStatusData)
+		if !ok || data.ProductID == "" {
+			return errors.New("product status webhook without product_id")
+		}
+		return s.productService.HandleTikTokProductStatus(parsed.ShopID, &data)
+
+	case tiktok.WebhookTypeSellerDeauthorization:
+		return s.connectionService.HandleDeauthorization(context.Background(), "tiktok", parsed.ShopID)
+
+	default:
+		return fmt.Errorf("%w: TikTok webhook type %s", errWebhookUnhandled, envelope.Type)
+	}
+}
+
+// parseShopeeShopID converts the shop ID of a parsed Shopee event
+func parseShopeeShopID(shopID string) (int64, error) {
+	id, err := strconv.ParseInt(shopID, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid shop_id %q: %w", shopID, err)
+	}
+	return id, nil
+}
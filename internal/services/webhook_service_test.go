@@ -0,0 +1,183 @@
+package services
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/niaga-platform/service-marketplace/internal/models"
+)
+
+func hmacHex(secret, base string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(base))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func TestWebhookVerifySignature(t *testing.T) {
+	const (
+		partnerKey = "shopee-partner-key"
+		webhookURL = "https://api.example.com/api/v1/webhooks/shopee"
+		appKey     = "tiktok-app-key"
+		appSecret  = "tiktok-app-secret"
+	)
+	body := []byte(`{"code":3,"shop_id":1,"timestamp":1700000000}`)
+
+	configured := WebhookServiceConfig{
+		ShopeePartnerKey: partnerKey,
+		ShopeeWebhookURL: webhookURL,
+		TikTokAppKey:     appKey,
+		TikTokAppSecret:  appSecret,
+	}
+
+	tests := []struct {
+		name       string
+		config     WebhookServiceConfig
+		platform   string
+		headers    map[string]string
+		wantStatus string
+		wantReason string
+	}{
+		{
+			name:       "shopee valid signature",
+			config:     configured,
+			platform:   "shopee",
+			headers:    map[string]string{"Authorization": hmacHex(partnerKey, webhookURL+"|"+string(body))},
+			wantStatus: models.WebhookVerificationVerified,
+		},
+		{
+			name:       "shopee signed for another url",
+			config:     configured,
+			platform:   "shopee",
+			headers:    map[string]string{"Authorization": hmacHex(partnerKey, "https://other.example.com|"+string(body))},
+			wantStatus: models.WebhookVerificationFailed,
+			wantReason: "invalid signature",
+		},
+		{
+			name:       "shopee missing signature",
+			config:     configured,
+			platform:   "shopee",
+			headers:    map[string]string{},
+			wantStatus: models.WebhookVerificationFailed,
+			wantReason: "missing signature",
+		},
+		{
+			name:       "shopee without partner key",
+			config:     WebhookServiceConfig{},
+			platform:   "shopee",
+			headers:    map[string]string{},
+			wantStatus: models.WebhookVerificationSkipped,
+		},
+		{
+			name:       "tiktok valid signature",
+			config:     configured,
+			platform:   "tiktok",
+			headers:    map[string]string{"Authorization": hmacHex(appSecret, appKey+string(body))},
+			wantStatus: models.WebhookVerificationVerified,
+		},
+		{
+			name:       "tiktok valid legacy signature",
+			config:     configured,
+			platform:   "tiktok",
+			headers:    map[string]string{"X-Tts-Signature": hmacHex(appSecret, string(body))},
+			wantStatus: models.WebhookVerificationVerified,
+		},
+		{
+			name:       "tiktok signed with another secret",
+			config:     configured,
+			platform:   "tiktok",
+			headers:    map[string]string{"Authorization": hmacHex("wrong-secret", appKey+string(body))},
+			wantStatus: models.WebhookVerificationFailed,
+			wantReason: "invalid signature",
+		},
+		{
+			name:       "tiktok missing signature",
+			config:     configured,
+			platform:   "tiktok",
+			headers:    map[string]string{},
+			wantStatus: models.WebhookVerificationFailed,
+			wantReason: "missing signature",
+		},
+		{
+			name:       "unknown platform",
+			config:     configured,
+			platform:   "lazada",
+			headers:    map[string]string{},
+			wantStatus: models.WebhookVerificationFailed,
+			wantReason: ErrUnknownWebhookPlatform.Error(),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service, err := NewWebhookService(nil, nil, nil, nil, nil, tt.config, zap.NewNop())
+			if err != nil {
+				t.Fatalf("NewWebhookService() error = %v", err)
+			}
+
+			got := service.verifySignature(context.Background(), tt.platform, body, tt.headers)
+			if got.Status != tt.wantStatus || got.Reason != tt.wantReason {
+				t.Errorf("verifySignature() = %q (%q), want %q (%q)", got.Status, got.Reason, tt.wantStatus, tt.wantReason)
+			}
+		})
+	}
+}
+
+func TestDeliveryFreshness(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+
+	tests := []struct {
+		name string
+		body string
+		want string
+	}{
+		{name: "current", body: fmt.Sprintf(`{"timestamp":%d}`, now.Unix()), want: ""},
+		{name: "within window", body: fmt.Sprintf(`{"timestamp":%d}`, now.Add(-4*time.Minute).Unix()), want: ""},
+		{name: "too old", body: fmt.Sprintf(`{"timestamp":%d}`, now.Add(-6*time.Minute).Unix()), want: "stale timestamp"},
+		{name: "too far ahead", body: fmt.Sprintf(`{"timestamp":%d}`, now.Add(6*time.Minute).Unix()), want: "stale timestamp"},
+		{name: "missing", body: `{"code":3}`, want: "missing timestamp"},
+		{name: "not json", body: `not json`, want: "missing timestamp"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := deliveryFreshness([]byte(tt.body), now); got != tt.want {
+				t.Errorf("deliveryFreshness() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewWebhookServiceVerifyMode(t *testing.T) {
+	tests := []struct {
+		name     string
+		config   WebhookServiceConfig
+		wantMode string
+		wantErr  error
+	}{
+		{name: "strict without url", config: WebhookServiceConfig{ShopeePartnerKey: "key", VerifyMode: WebhookVerifyStrict}, wantErr: ErrWebhookURLRequired},
+		{name: "default mode without url", config: WebhookServiceConfig{ShopeePartnerKey: "key"}, wantMode: WebhookVerifyGrace},
+		{name: "grace without url", config: WebhookServiceConfig{ShopeePartnerKey: "key", VerifyMode: WebhookVerifyGrace}, wantMode: WebhookVerifyGrace},
+		{name: "default mode with url", config: WebhookServiceConfig{ShopeePartnerKey: "key", ShopeeWebhookURL: "https://api.example.com/webhooks/shopee"}, wantMode: WebhookVerifyStrict},
+		{name: "shopee not configured", config: WebhookServiceConfig{}, wantMode: WebhookVerifyStrict},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := NewWebhookService(nil, nil, nil, nil, nil, tt.config, zap.NewNop())
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("NewWebhookService() error = %v, want %v", err, tt.wantErr)
+			}
+			if err == nil && s.config.VerifyMode != tt.wantMode {
+				t.Errorf("verify mode = %q, want %q", s.config.VerifyMode, tt.wantMode)
+			}
+		})
+	}
+}
@@ -0,0 +1,42 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Blob store errors
+var (
+	ErrBlobNotFound      = errors.New("blob not found")
+	ErrUnsupportedDriver = errors.New("unsupported blob store driver")
+)
+
+// Blob store drivers
+const (
+	DriverLocal = "local"
+)
+
+// BlobStore stores files under slash-separated keys
+type BlobStore interface {
+	Put(ctx context.Context, key string, data []byte) error
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	Delete(ctx context.Context, key string) error
+}
+
+// Config holds blob store configuration
+type Config struct {
+	Driver    string // local (default)
+	LocalPath string // Root directory of the local driver
+}
+
+// New creates the blob store of the configured driver
+func New(cfg Config) (BlobStore, error) {
+	switch cfg.Driver {
+	case "", DriverLocal:
+		return NewLocalStore(cfg.LocalPath)
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrUnsupportedDriver, cfg.Driver)
+	}
+}
@@ -0,0 +1,38 @@
+package utils
+
+import (
+	"bytes"
+	"errors"
+	"io"
+
+	"github.com/pdfcpu/pdfcpu/pkg/api"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/model"
+)
+
+var ErrNoDocuments = errors.New("no documents to merge")
+
+func init() {
+	// Keep pdfcpu from reading or creating a configuration directory
+	model.ConfigPath = "disable"
+}
+
+// MergePDFs concatenates PDF documents into a single document, in order
+func MergePDFs(documents [][]byte) ([]byte, error) {
+	if len(documents) == 0 {
+		return nil, ErrNoDocuments
+	}
+	if len(documents) == 1 {
+		return documents[0], nil
+	}
+
+	readers := make([]io.ReadSeeker, len(documents))
+	for i, document := range documents {
+		readers[i] = bytes.NewReader(document)
+	}
+
+	var merged bytes.Buffer
+	if err := api.MergeRaw(readers, &merged, false, model.NewDefaultConfiguration()); err != nil {
+		return nil, err
+	}
+	return merged.Bytes(), nil
+}
@@ -0,0 +1,17 @@
+-- Inventory Sync Log Audit Columns
+-- Records where every stock push came from so support can trace marketplace stock history
+
+ALTER TABLE marketplace.inventory_sync_logs
+    ALTER COLUMN internal_product_id DROP NOT NULL; -- Manual pushes may target unmapped listings
+
+ALTER TABLE marketplace.inventory_sync_logs
+    ADD COLUMN IF NOT EXISTS external_product_id VARCHAR(100),
+    ADD COLUMN IF NOT EXISTS external_sku VARCHAR(100),
+    ADD COLUMN IF NOT EXISTS source VARCHAR(50) NOT NULL DEFAULT 'event', -- event, manual, reconciliation
+    ADD COLUMN IF NOT EXISTS source_reference VARCHAR(255); -- e.g. stock change reason from inventory service
+
+CREATE INDEX IF NOT EXISTS idx_inventory_logs_product ON marketplace.inventory_sync_logs(internal_product_id);
+CREATE INDEX IF NOT EXISTS idx_inventory_logs_mapping ON marketplace.inventory_sync_logs(product_mapping_id);
+CREATE INDEX IF NOT EXISTS idx_inventory_logs_external_product ON marketplace.inventory_sync_logs(connection_id, external_product_id);
+
+COMMENT ON TABLE marketplace.inventory_sync_logs IS 'Audit log of every stock push made to a marketplace';
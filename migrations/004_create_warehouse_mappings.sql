@@ -0,0 +1,47 @@
+-- Warehouse Mappings
+-- Maps internal warehouses to marketplace warehouses/locations per connection
+
+-- =====================================================
+-- WAREHOUSE MAPPINGS TABLE
+-- =====================================================
+-- A connection without rows sums every warehouse into the listing's single stock.
+-- Warehouses mapped to the same location are summed; unmapped warehouses are
+-- not offered on connections that have mappings.
+CREATE TABLE IF NOT EXISTS marketplace.warehouse_mappings (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    connection_id UUID NOT NULL REFERENCES marketplace.connections(id) ON DELETE CASCADE,
+    internal_warehouse_id VARCHAR(100) NOT NULL,
+    external_location_id VARCHAR(100) NOT NULL DEFAULT '', -- Empty = platform default location
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+
+    CONSTRAINT unique_connection_warehouse UNIQUE (connection_id, internal_warehouse_id)
+);
+
+CREATE INDEX idx_warehouse_mappings_connection ON marketplace.warehouse_mappings(connection_id);
+
+CREATE TRIGGER update_warehouse_mappings_updated_at
+    BEFORE UPDATE ON marketplace.warehouse_mappings
+    FOR EACH ROW EXECUTE FUNCTION marketplace.update_updated_at_column();
+
+-- =====================================================
+-- WAREHOUSE STOCK LEVELS TABLE
+-- =====================================================
+-- Last known stock per product and warehouse, taken from inventory.stock.changed events
+CREATE TABLE IF NOT EXISTS marketplace.warehouse_stock_levels (
+    internal_product_id UUID NOT NULL,
+    warehouse_id VARCHAR(100) NOT NULL,
+    quantity INTEGER NOT NULL DEFAULT 0,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+
+    PRIMARY KEY (internal_product_id, warehouse_id)
+);
+
+-- =====================================================
+-- INVENTORY SYNC LOG LOCATION
+-- =====================================================
+ALTER TABLE marketplace.inventory_sync_logs
+    ADD COLUMN IF NOT EXISTS external_location_id VARCHAR(100);
+
+COMMENT ON TABLE marketplace.warehouse_mappings IS 'Internal warehouse to marketplace location mapping per connection';
+COMMENT ON TABLE marketplace.warehouse_stock_levels IS 'Last known stock per product and internal warehouse';
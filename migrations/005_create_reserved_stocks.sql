@@ -0,0 +1,31 @@
+-- Reserved Stocks
+-- Stock locked by marketplace promotions/campaigns, pushed by Shopee reserved stock webhooks
+
+-- =====================================================
+-- RESERVED STOCKS TABLE
+-- =====================================================
+-- One row per listing model holding the latest reserved quantity. Pushes carry
+-- an update time so a late delivery never overwrites a newer value.
+CREATE TABLE IF NOT EXISTS marketplace.reserved_stocks (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    connection_id UUID NOT NULL REFERENCES marketplace.connections(id) ON DELETE CASCADE,
+    external_product_id VARCHAR(100) NOT NULL,
+    external_model_id VARCHAR(100) NOT NULL DEFAULT '',
+    reserved_quantity INTEGER NOT NULL DEFAULT 0,
+    action VARCHAR(100),
+    promotion_type VARCHAR(100),
+    promotion_id VARCHAR(100),
+    changed_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+
+    CONSTRAINT unique_connection_reserved_model UNIQUE (connection_id, external_product_id, external_model_id)
+);
+
+CREATE INDEX idx_reserved_stocks_connection ON marketplace.reserved_stocks(connection_id);
+
+CREATE TRIGGER update_reserved_stocks_updated_at
+    BEFORE UPDATE ON marketplace.reserved_stocks
+    FOR EACH ROW EXECUTE FUNCTION marketplace.update_updated_at_column();
+
+COMMENT ON TABLE marketplace.reserved_stocks IS 'Marketplace stock locked by promotions, excluded from pushed available stock';
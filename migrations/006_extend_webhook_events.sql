@@ -0,0 +1,27 @@
+-- Webhook Event Store
+-- Every inbound webhook is stored raw before it is acknowledged, then processed asynchronously
+
+-- The model has always read received_at; align the original created_at column
+DO $$
+BEGIN
+    IF EXISTS (
+        SELECT 1 FROM information_schema.columns
+        WHERE table_schema = 'marketplace' AND table_name = 'webhook_events' AND column_name = 'created_at'
+    ) THEN
+        ALTER TABLE marketplace.webhook_events RENAME COLUMN created_at TO received_at;
+    END IF;
+END $$;
+
+ALTER TABLE marketplace.webhook_events
+    ADD COLUMN IF NOT EXISTS shop_id VARCHAR(100),
+    ADD COLUMN IF NOT EXISTS signature TEXT,
+    ADD COLUMN IF NOT EXISTS verification_status VARCHAR(20) NOT NULL DEFAULT 'skipped', -- verified, failed, skipped
+    ADD COLUMN IF NOT EXISTS attempts INTEGER NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS processed_at TIMESTAMP WITH TIME ZONE;
+
+DROP INDEX IF EXISTS marketplace.idx_webhook_events_created_at;
+CREATE INDEX IF NOT EXISTS idx_webhook_events_received_at ON marketplace.webhook_events(received_at DESC);
+CREATE INDEX IF NOT EXISTS idx_webhook_events_pending ON marketplace.webhook_events(received_at)
+    WHERE processed = false AND attempts = 0;
+
+COMMENT ON TABLE marketplace.webhook_events IS 'Raw inbound marketplace webhooks and their processing outcome';
@@ -0,0 +1,21 @@
+-- Webhook Event Replays
+-- Result of every admin-triggered re-run of a stored webhook
+
+-- =====================================================
+-- WEBHOOK EVENT REPLAYS TABLE
+-- =====================================================
+CREATE TABLE IF NOT EXISTS marketplace.webhook_event_replays (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    webhook_event_id UUID NOT NULL REFERENCES marketplace.webhook_events(id) ON DELETE CASCADE,
+    success BOOLEAN NOT NULL,
+    error_message TEXT,
+    duration_ms INTEGER NOT NULL DEFAULT 0,
+    replayed_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX idx_webhook_event_replays_event ON marketplace.webhook_event_replays(webhook_event_id, replayed_at DESC);
+
+CREATE INDEX IF NOT EXISTS idx_webhook_events_shop ON marketplace.webhook_events(platform, shop_id);
+CREATE INDEX IF NOT EXISTS idx_webhook_events_type ON marketplace.webhook_events(event_type);
+
+COMMENT ON TABLE marketplace.webhook_event_replays IS 'Outcome of each manual replay of a stored webhook event';
@@ -0,0 +1,8 @@
+-- Webhook Delivery Fingerprint
+-- Marketplaces retry deliveries they consider unacknowledged; the fingerprint lets
+-- a redelivered webhook be recognised and skipped instead of processed twice
+
+ALTER TABLE marketplace.webhook_events
+    ADD COLUMN IF NOT EXISTS fingerprint VARCHAR(64); -- sha256(platform|body), NULL for rejected deliveries
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_webhook_events_fingerprint ON marketplace.webhook_events(fingerprint);
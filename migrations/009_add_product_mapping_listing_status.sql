@@ -0,0 +1,10 @@
+-- Product Mapping Listing Status
+-- Tracks whether the marketplace still shows a mapped listing, so banned or
+-- deleted listings are visible to merchants without opening the seller centre
+
+ALTER TABLE marketplace.product_mappings
+    ADD COLUMN IF NOT EXISTS listing_status VARCHAR(50) NOT NULL DEFAULT 'normal', -- normal, banned, deleted, unlisted
+    ADD COLUMN IF NOT EXISTS listing_status_reason TEXT,
+    ADD COLUMN IF NOT EXISTS listing_status_updated_at TIMESTAMPTZ;
+
+CREATE INDEX IF NOT EXISTS idx_product_mappings_listing_status ON marketplace.product_mappings(connection_id, listing_status);
@@ -0,0 +1,52 @@
+-- Returns
+-- Marketplace return/refund requests, synced from the marketplace APIs and return webhooks
+
+-- =====================================================
+-- RETURNS TABLE
+-- =====================================================
+-- One row per marketplace return, linked to the imported order when we have it.
+-- Rows are upserted on every sync or push, keeping the latest marketplace state.
+CREATE TABLE IF NOT EXISTS marketplace.returns (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    connection_id UUID NOT NULL REFERENCES marketplace.connections(id) ON DELETE CASCADE,
+    order_id UUID REFERENCES marketplace.orders(id) ON DELETE SET NULL,
+    platform VARCHAR(50) NOT NULL,
+    external_return_id VARCHAR(100) NOT NULL,
+    external_order_id VARCHAR(100) NOT NULL,
+    status VARCHAR(50) NOT NULL, -- requested, accepted, judging, processing, disputed, refunded, cancelled, closed
+    platform_status VARCHAR(100), -- Status as reported by the marketplace
+    reason VARCHAR(100),
+    reason_text TEXT,
+    refund_amount DECIMAL(12,2) NOT NULL DEFAULT 0,
+    currency VARCHAR(10) DEFAULT 'MYR',
+    needs_logistics BOOLEAN NOT NULL DEFAULT false,
+    tracking_number VARCHAR(100),
+    buyer_id VARCHAR(100),
+    buyer_name VARCHAR(255),
+    items JSONB NOT NULL DEFAULT '[]',
+    images JSONB NOT NULL DEFAULT '[]',
+    ship_due_date TIMESTAMP WITH TIME ZONE,
+    dispute_reason TEXT,
+    dispute_images JSONB,
+    accepted_at TIMESTAMP WITH TIME ZONE,
+    disputed_at TIMESTAMP WITH TIME ZONE,
+    external_created_at TIMESTAMP WITH TIME ZONE,
+    external_updated_at TIMESTAMP WITH TIME ZONE,
+    synced_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+
+    CONSTRAINT unique_connection_return UNIQUE (connection_id, external_return_id)
+);
+
+CREATE INDEX idx_returns_connection ON marketplace.returns(connection_id);
+CREATE INDEX idx_returns_order ON marketplace.returns(order_id);
+CREATE INDEX idx_returns_external_order ON marketplace.returns(connection_id, external_order_id);
+CREATE INDEX idx_returns_status ON marketplace.returns(status);
+CREATE INDEX idx_returns_created ON marketplace.returns(external_created_at DESC);
+
+CREATE TRIGGER update_returns_updated_at
+    BEFORE UPDATE ON marketplace.returns
+    FOR EACH ROW EXECUTE FUNCTION marketplace.update_updated_at_column();
+
+COMMENT ON TABLE marketplace.returns IS 'Marketplace return and refund requests';
@@ -0,0 +1,8 @@
+-- Return Propagation
+-- Records when a completed return was restocked and refunded internally, so a
+-- redelivered webhook or repeated sync never restocks or refunds twice
+
+ALTER TABLE marketplace.returns
+    ADD COLUMN IF NOT EXISTS restocked_at TIMESTAMPTZ, -- Restock event published to the inventory service
+    ADD COLUMN IF NOT EXISTS refund_propagated_at TIMESTAMPTZ, -- Internal order marked refunded in service-order
+    ADD COLUMN IF NOT EXISTS propagation_error TEXT;
@@ -0,0 +1,8 @@
+-- Return Type
+-- Distinguishes return-and-refund, refund-only and cancellation requests, since
+-- TikTok Shop reports all three as reverse orders
+
+ALTER TABLE marketplace.returns
+    ADD COLUMN IF NOT EXISTS return_type VARCHAR(50); -- providers.ReturnType* value
+
+CREATE INDEX IF NOT EXISTS idx_returns_type ON marketplace.returns(return_type);
@@ -0,0 +1,69 @@
+-- Return Rules
+-- Per-connection rules that accept, dispute or flag new returns for review, and
+-- a log of every automatic decision taken on a return
+
+-- =====================================================
+-- RETURN RULES TABLE
+-- =====================================================
+-- Rules are evaluated in priority order (lowest first); the first rule whose
+-- conditions all hold decides the return.
+CREATE TABLE IF NOT EXISTS marketplace.return_rules (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    connection_id UUID NOT NULL REFERENCES marketplace.connections(id) ON DELETE CASCADE,
+    name VARCHAR(255) NOT NULL,
+    priority INT NOT NULL DEFAULT 100,
+    enabled BOOLEAN NOT NULL DEFAULT true,
+    action VARCHAR(20) NOT NULL, -- accept, dispute, review
+    conditions JSONB NOT NULL DEFAULT '{}', -- reasons, types, refund amount range, product_ids, buyer returns, days since delivery
+    dispute_reason TEXT, -- Sent with dispute actions
+    dispute_reason_code VARCHAR(100), -- TikTok reject reason key
+    dispute_email VARCHAR(255), -- Shopee dispute contact email
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX idx_return_rules_connection ON marketplace.return_rules(connection_id, priority);
+
+CREATE TRIGGER update_return_rules_updated_at
+    BEFORE UPDATE ON marketplace.return_rules
+    FOR EACH ROW EXECUTE FUNCTION marketplace.update_updated_at_column();
+
+-- =====================================================
+-- RETURN DECISIONS TABLE
+-- =====================================================
+-- One automatic decision per return. Accept and dispute decisions are scheduled
+-- and can be reverted until they are executed, which happens no later than the
+-- return's ship_due_date.
+CREATE TABLE IF NOT EXISTS marketplace.return_decisions (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    return_id UUID NOT NULL REFERENCES marketplace.returns(id) ON DELETE CASCADE,
+    connection_id UUID NOT NULL REFERENCES marketplace.connections(id) ON DELETE CASCADE,
+    rule_id UUID REFERENCES marketplace.return_rules(id) ON DELETE SET NULL,
+    rule_name VARCHAR(255),
+    action VARCHAR(20) NOT NULL, -- accept, dispute, review
+    status VARCHAR(20) NOT NULL, -- scheduled, executing, executed, review, reverted, skipped, failed
+    inputs JSONB NOT NULL DEFAULT '{}', -- Values the rules were evaluated against
+    dispute_reason TEXT,
+    dispute_reason_code VARCHAR(100),
+    dispute_email VARCHAR(255),
+    execute_at TIMESTAMP WITH TIME ZONE,
+    deadline TIMESTAMP WITH TIME ZONE, -- Platform deadline (ship_due_date) at decision time
+    executed_at TIMESTAMP WITH TIME ZONE,
+    reverted_at TIMESTAMP WITH TIME ZONE,
+    revert_reason TEXT,
+    error_message TEXT,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+
+    CONSTRAINT unique_return_decision UNIQUE (return_id)
+);
+
+CREATE INDEX idx_return_decisions_connection ON marketplace.return_decisions(connection_id, status);
+CREATE INDEX idx_return_decisions_due ON marketplace.return_decisions(execute_at) WHERE status = 'scheduled';
+
+CREATE TRIGGER update_return_decisions_updated_at
+    BEFORE UPDATE ON marketplace.return_decisions
+    FOR EACH ROW EXECUTE FUNCTION marketplace.update_updated_at_column();
+
+COMMENT ON TABLE marketplace.return_rules IS 'Automatic return decision rules per connection';
+COMMENT ON TABLE marketplace.return_decisions IS 'Automatic return decisions and their execution';
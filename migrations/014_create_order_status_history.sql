@@ -0,0 +1,45 @@
+-- Order Status History
+-- Orders now carry a canonical status (unpaid, to_ship, shipped, delivered,
+-- completed, cancelled, returned) with the raw platform status kept alongside,
+-- and every change of the canonical status is recorded
+
+ALTER TABLE marketplace.orders
+    ADD COLUMN IF NOT EXISTS platform_status VARCHAR(50); -- Raw status reported by the platform
+
+-- Keep the previously stored value as the platform status and map it onto the
+-- canonical lifecycle. Legacy values were either the providers' old mapped
+-- names or the raw platform status.
+UPDATE marketplace.orders SET platform_status = status WHERE platform_status IS NULL;
+
+UPDATE marketplace.orders SET status = CASE
+    WHEN status IN ('pending_payment', 'UNPAID', 'unknown_100') THEN 'unpaid'
+    WHEN status IN ('pending_shipment', 'processing', 'cancellation_requested',
+                    'READY_TO_SHIP', 'PROCESSED', 'RETRY_SHIP', 'IN_CANCEL',
+                    'unknown_105', 'unknown_114') THEN 'to_ship'
+    WHEN status IN ('SHIPPED') THEN 'shipped'
+    WHEN status IN ('TO_CONFIRM_RECEIVE', 'unknown_122') THEN 'delivered'
+    WHEN status IN ('COMPLETED') THEN 'completed'
+    WHEN status IN ('CANCELLED') THEN 'cancelled'
+    WHEN status IN ('TO_RETURN') THEN 'returned'
+    ELSE status
+END
+WHERE status NOT IN ('unpaid', 'to_ship', 'shipped', 'delivered', 'completed', 'cancelled', 'returned');
+
+-- =====================================================
+-- ORDER STATUS HISTORY TABLE
+-- =====================================================
+CREATE TABLE IF NOT EXISTS marketplace.order_status_history (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    order_id UUID NOT NULL REFERENCES marketplace.orders(id) ON DELETE CASCADE,
+    from_status VARCHAR(50), -- Empty for the first status of the order
+    to_status VARCHAR(50) NOT NULL,
+    platform_status VARCHAR(50), -- Raw platform status that caused the change
+    source VARCHAR(20) NOT NULL, -- webhook, poll, admin
+    note TEXT,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX idx_order_status_history_order ON marketplace.order_status_history(order_id, created_at);
+CREATE INDEX idx_order_status_history_status ON marketplace.order_status_history(to_status);
+
+COMMENT ON TABLE marketplace.order_status_history IS 'Canonical status changes of marketplace orders';
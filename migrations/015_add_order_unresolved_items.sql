@@ -0,0 +1,12 @@
+-- Order Line Resolution
+-- Order lines are matched to internal products and variants through the product
+-- and variant mappings; orders keep a count of the lines that did not match
+
+ALTER TABLE marketplace.orders
+    ADD COLUMN IF NOT EXISTS unresolved_items INTEGER NOT NULL DEFAULT 0; -- Lines without a product or variant mapping
+
+CREATE INDEX IF NOT EXISTS idx_orders_unresolved ON marketplace.orders(connection_id) WHERE unresolved_items > 0;
+
+-- SKU fallback lookups
+CREATE INDEX IF NOT EXISTS idx_product_mappings_external_sku ON marketplace.product_mappings(connection_id, external_sku);
+CREATE INDEX IF NOT EXISTS idx_variant_mappings_external_sku ON marketplace.variant_mappings(external_sku);
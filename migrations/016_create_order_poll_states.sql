@@ -0,0 +1,24 @@
+-- Order Poll States
+-- Scheduled order polling by update time catches orders whose webhooks were
+-- missed. Each connection keeps the point up to which orders have been fetched.
+
+-- =====================================================
+-- ORDER POLL STATES TABLE
+-- =====================================================
+CREATE TABLE IF NOT EXISTS marketplace.order_poll_states (
+    connection_id UUID PRIMARY KEY REFERENCES marketplace.connections(id) ON DELETE CASCADE,
+    high_water_mark TIMESTAMP WITH TIME ZONE, -- Orders updated before this have been fetched
+    last_run_at TIMESTAMP WITH TIME ZONE,
+    last_success_at TIMESTAMP WITH TIME ZONE,
+    last_error TEXT,
+    last_orders_seen INTEGER NOT NULL DEFAULT 0,
+    consecutive_failures INTEGER NOT NULL DEFAULT 0,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE TRIGGER update_order_poll_states_updated_at
+    BEFORE UPDATE ON marketplace.order_poll_states
+    FOR EACH ROW EXECUTE FUNCTION marketplace.update_updated_at_column();
+
+COMMENT ON TABLE marketplace.order_poll_states IS 'Scheduled order poll progress per connection';
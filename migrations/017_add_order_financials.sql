@@ -0,0 +1,10 @@
+-- Order Financials
+-- Orders keep the payout reported by the marketplace (Shopee escrow, TikTok Shop
+-- settlements); the fee, voucher and coin breakdown is stored in order_data
+
+ALTER TABLE marketplace.orders
+    ADD COLUMN IF NOT EXISTS net_payout DECIMAL(12,2), -- Amount paid out to the seller, NULL until known
+    ADD COLUMN IF NOT EXISTS financials_at TIMESTAMP WITH TIME ZONE; -- Last fetch of the escrow or settlement breakdown
+
+CREATE INDEX IF NOT EXISTS idx_orders_financials_pending ON marketplace.orders(connection_id)
+    WHERE net_payout IS NULL AND status = 'completed';
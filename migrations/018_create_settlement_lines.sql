@@ -0,0 +1,28 @@
+-- Settlement Lines
+-- Lines of marketplace payout statements (Shopee wallet transactions, TikTok Shop
+-- settlements), matched to the orders they pay out for reconciliation
+
+CREATE TABLE IF NOT EXISTS marketplace.settlement_lines (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    connection_id UUID NOT NULL REFERENCES marketplace.connections(id) ON DELETE CASCADE,
+    platform VARCHAR(50) NOT NULL,
+    external_id VARCHAR(100) NOT NULL, -- Wallet transaction ID or settlement key
+    external_order_id VARCHAR(100), -- Empty for lines not tied to an order, such as withdrawals
+    order_id UUID REFERENCES marketplace.orders(id) ON DELETE SET NULL, -- Matched order
+    transaction_type VARCHAR(100),
+    amount DECIMAL(12,2) NOT NULL, -- Credited to the seller; negative when debited
+    fee_amount DECIMAL(12,2) NOT NULL DEFAULT 0,
+    currency VARCHAR(10),
+    description TEXT,
+    settled_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    UNIQUE(connection_id, external_id)
+);
+
+CREATE INDEX idx_settlement_lines_order ON marketplace.settlement_lines(order_id);
+CREATE INDEX idx_settlement_lines_unmatched ON marketplace.settlement_lines(connection_id, settled_at)
+    WHERE order_id IS NULL;
+CREATE INDEX idx_settlement_lines_external_order ON marketplace.settlement_lines(connection_id, external_order_id);
+
+COMMENT ON TABLE marketplace.settlement_lines IS 'Marketplace payout statement lines matched to orders';
@@ -0,0 +1,31 @@
+-- Shipping Documents
+-- AWBs and labels downloaded from the marketplace and kept in the blob store,
+-- with every download recorded as a print
+
+CREATE TABLE IF NOT EXISTS marketplace.shipping_documents (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    connection_id UUID NOT NULL REFERENCES marketplace.connections(id) ON DELETE CASCADE,
+    order_id UUID NOT NULL REFERENCES marketplace.orders(id) ON DELETE CASCADE,
+    document_type VARCHAR(50) NOT NULL, -- e.g. NORMAL_AIR_WAYBILL, THERMAL_AIR_WAYBILL
+    storage_key VARCHAR(500) NOT NULL, -- Key in the blob store
+    content_type VARCHAR(100) NOT NULL,
+    size BIGINT NOT NULL,
+    print_count INTEGER NOT NULL DEFAULT 0,
+    last_printed_at TIMESTAMP WITH TIME ZONE,
+    generated_at TIMESTAMP WITH TIME ZONE NOT NULL, -- When the document was downloaded from the marketplace
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX idx_shipping_documents_order ON marketplace.shipping_documents(order_id, document_type, generated_at DESC);
+
+CREATE TABLE IF NOT EXISTS marketplace.shipping_document_prints (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    document_id UUID NOT NULL REFERENCES marketplace.shipping_documents(id) ON DELETE CASCADE,
+    client_ip VARCHAR(100),
+    printed_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
+
+CREATE INDEX idx_shipping_document_prints_document ON marketplace.shipping_document_prints(document_id, printed_at);
+
+COMMENT ON TABLE marketplace.shipping_documents IS 'Stored marketplace shipping documents per order';
+COMMENT ON TABLE marketplace.shipping_document_prints IS 'Download history of stored shipping documents';
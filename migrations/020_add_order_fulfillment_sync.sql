@@ -0,0 +1,11 @@
+-- Order Fulfillment Sync
+-- Orders shipped by the warehouse in service-order are shipped on the marketplace
+-- from the order.shipped event; the result of the last attempt is kept on the order
+
+ALTER TABLE marketplace.orders
+    ADD COLUMN IF NOT EXISTS fulfillment_sync VARCHAR(20), -- synced, failed; NULL until an event is handled
+    ADD COLUMN IF NOT EXISTS fulfillment_sync_error TEXT,
+    ADD COLUMN IF NOT EXISTS fulfillment_synced_at TIMESTAMP WITH TIME ZONE;
+
+CREATE INDEX IF NOT EXISTS idx_orders_fulfillment_failed ON marketplace.orders(connection_id)
+    WHERE fulfillment_sync = 'failed';
@@ -0,0 +1,11 @@
+-- Warehouse Stock Change Time
+-- Stock levels keep the time of the change in the inventory service, so a late
+-- or redelivered stock event never replaces a newer level
+
+ALTER TABLE marketplace.warehouse_stock_levels
+    ADD COLUMN IF NOT EXISTS changed_at TIMESTAMP WITH TIME ZONE;
+
+UPDATE marketplace.warehouse_stock_levels SET changed_at = COALESCE(updated_at, NOW()) WHERE changed_at IS NULL;
+
+ALTER TABLE marketplace.warehouse_stock_levels
+    ALTER COLUMN changed_at SET NOT NULL;
@@ -0,0 +1,9 @@
+-- Deferred service-order Creation
+-- Orders with lines that match no internal product are created in service-order
+-- once every line resolves, so service-order never receives lines without a product
+
+ALTER TABLE marketplace.orders
+    ADD COLUMN IF NOT EXISTS service_order_pending BOOLEAN NOT NULL DEFAULT FALSE; -- Waiting to be created in service-order
+
+CREATE INDEX IF NOT EXISTS idx_orders_service_order_pending ON marketplace.orders(connection_id)
+    WHERE service_order_pending;
@@ -0,0 +1,6 @@
+-- Order Poll Import Failures
+-- Polled orders that fail to import hold back the high-water mark until they
+-- reach the attempt limit; they are then recorded as dead-lettered and skipped
+
+ALTER TABLE marketplace.order_poll_states
+    ADD COLUMN IF NOT EXISTS import_failures JSONB NOT NULL DEFAULT '{}'; -- External order ID -> attempts, last error, dead_lettered
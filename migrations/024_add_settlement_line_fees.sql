@@ -0,0 +1,10 @@
+-- Settlement Line Fees
+-- Fees charged on a payout statement line, compared against the order's
+-- financials by the fee mismatch report. NULL when the statement does not
+-- break the fee out (Shopee wallet transactions)
+
+ALTER TABLE marketplace.settlement_lines
+    ADD COLUMN IF NOT EXISTS commission_fee DECIMAL(12,2),
+    ADD COLUMN IF NOT EXISTS service_fee DECIMAL(12,2),
+    ADD COLUMN IF NOT EXISTS transaction_fee DECIMAL(12,2),
+    ADD COLUMN IF NOT EXISTS shipping_fee DECIMAL(12,2);
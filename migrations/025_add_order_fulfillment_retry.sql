@@ -0,0 +1,7 @@
+-- Order Fulfillment Retry
+-- Failed fulfillment syncs are retried by the order poll; the tracking of the
+-- order.shipped event is kept for the retry, which stops after a number of attempts
+
+ALTER TABLE marketplace.orders
+    ADD COLUMN IF NOT EXISTS fulfillment_tracking JSONB, -- Courier and tracking number from the event
+    ADD COLUMN IF NOT EXISTS fulfillment_attempts INTEGER NOT NULL DEFAULT 0; -- Failed attempts since the last sync
@@ -0,0 +1,6 @@
+-- Order Poll Lock
+-- Each replica claims a connection's poll before running it, so a connection is
+-- polled by one replica at a time; a claim left by a stopped replica expires
+
+ALTER TABLE marketplace.order_poll_states
+    ADD COLUMN IF NOT EXISTS locked_until TIMESTAMP WITH TIME ZONE; -- NULL when no replica is polling the connection